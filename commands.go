@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/repository"
+	"google-calendar-api/internal/service"
+)
+
+// The command structs hold what each subcommand needs and nothing more, so a
+// one-off task doesn't start OIDC discovery or the HTTP routes.
+
+// MigrateCommand holds what the migrate command needs.
+type MigrateCommand struct {
+	SqlDB  *sql.DB
+	Driver string // Picks the set of migrations
+}
+
+// NewMigrateCommand creates a new MigrateCommand instance. This is a *provider*.
+func NewMigrateCommand(cfg *config.Config, sqlDB *sql.DB) *MigrateCommand {
+	return &MigrateCommand{SqlDB: sqlDB, Driver: cfg.DatabaseDriver}
+}
+
+// SyncCommand holds what the sync command needs.
+type SyncCommand struct {
+	SqlDB *sql.DB
+	Sync  service.SyncService
+}
+
+// NewSyncCommand creates a new SyncCommand instance. This is a *provider*.
+func NewSyncCommand(sqlDB *sql.DB, sync service.SyncService) *SyncCommand {
+	return &SyncCommand{SqlDB: sqlDB, Sync: sync}
+}
+
+// ReconcileCommand holds what the reconcile command needs.
+type ReconcileCommand struct {
+	SqlDB      *sql.DB
+	Reconciler service.ReconcileService
+}
+
+// NewReconcileCommand creates a new ReconcileCommand instance. This is a *provider*.
+func NewReconcileCommand(sqlDB *sql.DB, reconciler service.ReconcileService) *ReconcileCommand {
+	return &ReconcileCommand{SqlDB: sqlDB, Reconciler: reconciler}
+}
+
+// ExportCommand holds what the export command needs.
+type ExportCommand struct {
+	SqlDB    *sql.DB
+	Exporter service.ExportService
+}
+
+// NewExportCommand creates a new ExportCommand instance. This is a *provider*.
+func NewExportCommand(sqlDB *sql.DB, exporter service.ExportService) *ExportCommand {
+	return &ExportCommand{SqlDB: sqlDB, Exporter: exporter}
+}
+
+// runMigrate is the migrate command: "up" applies pending migrations, "down"
+// reverts the latest ones and "status" lists them. Returns the exit code.
+func runMigrate(args []string) int {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "how many migrations down reverts")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: migrate up | down [-steps N] | status")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return 2
+	}
+	action := args[0]
+	flags.Parse(args[1:])
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("❌ Configuration Error: %v", err)
+		return 1
+	}
+	cmd, err := InitializeMigrateCommand(&cfg)
+	if err != nil {
+		log.Printf("❌ Database Error: %v", err)
+		return 1
+	}
+	defer cmd.SqlDB.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch action {
+	case "up":
+		applied, err := repository.MigrateUp(ctx, cmd.SqlDB, cmd.Driver)
+		for _, m := range applied {
+			log.Printf("✅ Applied %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Printf("❌ Migration failed: %v", err)
+			return 1
+		}
+		if len(applied) == 0 {
+			log.Println("✅ Schema is up to date")
+		}
+	case "down":
+		if *steps < 1 {
+			log.Printf("❌ -steps must be at least 1")
+			return 2
+		}
+		reverted, err := repository.MigrateDown(ctx, cmd.SqlDB, cmd.Driver, *steps)
+		for _, m := range reverted {
+			log.Printf("✅ Reverted %04d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Printf("❌ Migration failed: %v", err)
+			return 1
+		}
+	case "status":
+		states, err := repository.MigrationStatus(ctx, cmd.SqlDB, cmd.Driver)
+		if err != nil {
+			log.Printf("❌ Failed to read migration status: %v", err)
+			return 1
+		}
+		for _, state := range states {
+			applied := "pending"
+			if state.AppliedAt != nil {
+				applied = state.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-40s %s\n", state.Version, state.Name, applied)
+		}
+	default:
+		flags.Usage()
+		return 2
+	}
+	return 0
+}
+
+// runSync is the sync command: it syncs one calendar of a user from Google
+// and prints the result as JSON. Returns the exit code.
+func runSync(args []string) int {
+	flags := flag.NewFlagSet("sync", flag.ExitOnError)
+	userEmail := flags.String("user", "", "email of the user whose calendar is synced (required)")
+	calendarID := flags.String("calendar", "primary", "calendar to sync")
+	flags.Parse(args)
+	if *userEmail == "" {
+		fmt.Fprintln(os.Stderr, "-user is required")
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("❌ Configuration Error: %v", err)
+		return 1
+	}
+	cmd, err := InitializeSyncCommand(context.Background(), &cfg)
+	if err != nil {
+		log.Printf("❌ Application Initialization Error: %v", err)
+		return 1
+	}
+	defer cmd.SqlDB.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	result, err := cmd.Sync.SyncCalendar(ctx, *userEmail, *calendarID)
+	if err != nil {
+		log.Printf("❌ Sync failed: %v", err)
+		return 1
+	}
+	return writeJSON(os.Stdout, result)
+}
+
+// runReconcile is the reconcile command: it compares stored meetings with
+// Google once and writes the report as JSON. Returns the exit code.
+func runReconcile(args []string) int {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	dryRun := flags.Bool("dry-run", false, "only report differences, change nothing")
+	userEmail := flags.String("user", "", "only reconcile this user's meetings")
+	output := flags.String("output", "", "write the report to this file instead of stdout")
+	flags.Parse(args)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("❌ Configuration Error: %v", err)
+		return 1
+	}
+	cmd, err := InitializeReconcileCommand(context.Background(), &cfg)
+	if err != nil {
+		log.Printf("❌ Application Initialization Error: %v", err)
+		return 1
+	}
+	defer cmd.SqlDB.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	report, err := cmd.Reconciler.Reconcile(ctx, service.ReconcileInput{UserEmail: *userEmail, DryRun: *dryRun})
+	if err != nil {
+		log.Printf("❌ Reconcile failed: %v", err)
+		return 1
+	}
+
+	out, closeOut, err := createOutput(*output)
+	if err != nil {
+		log.Printf("❌ Failed to create %s: %v", *output, err)
+		return 1
+	}
+	defer closeOut()
+	if code := writeJSON(out, report); code != 0 {
+		return code
+	}
+	if report.Errors > 0 {
+		fmt.Fprintf(os.Stderr, "%d meetings could not be reconciled\n", report.Errors)
+		return 2
+	}
+	return 0
+}
+
+// runExport is the export command: it writes a user's stored meetings as an
+// iCalendar file or as JSON. Returns the exit code.
+func runExport(args []string) int {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	userEmail := flags.String("user", "", "email of the user whose meetings are exported (required)")
+	format := flags.String("format", "ics", "output format: ics or json")
+	calendarID := flags.String("calendar", "", "only export this calendar")
+	from := flags.String("from", "", "only export meetings ending after this date or RFC 3339 time")
+	to := flags.String("to", "", "only export meetings starting before this date or RFC 3339 time")
+	output := flags.String("output", "", "write to this file instead of stdout")
+	flags.Parse(args)
+	if *userEmail == "" {
+		fmt.Fprintln(os.Stderr, "-user is required")
+		flags.Usage()
+		return 2
+	}
+	if *format != "ics" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format %q, use ics or json\n", *format)
+		return 2
+	}
+	input := service.ExportInput{UserEmail: *userEmail, CalendarID: *calendarID}
+	var err error
+	if input.TimeMin, err = parseFlagTime(*from); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -from: %v\n", err)
+		return 2
+	}
+	if input.TimeMax, err = parseFlagTime(*to); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid -to: %v\n", err)
+		return 2
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Printf("❌ Configuration Error: %v", err)
+		return 1
+	}
+	cmd, err := InitializeExportCommand(context.Background(), &cfg)
+	if err != nil {
+		log.Printf("❌ Application Initialization Error: %v", err)
+		return 1
+	}
+	defer cmd.SqlDB.Close()
+
+	out, closeOut, err := createOutput(*output)
+	if err != nil {
+		log.Printf("❌ Failed to create %s: %v", *output, err)
+		return 1
+	}
+	defer closeOut()
+
+	ctx := context.Background()
+	if *format == "json" {
+		events, err := cmd.Exporter.ExportEvents(ctx, input)
+		if err != nil {
+			log.Printf("❌ Export failed: %v", err)
+			return 1
+		}
+		return writeJSON(out, events)
+	}
+	if err := cmd.Exporter.ExportICS(ctx, out, input); err != nil {
+		log.Printf("❌ Export failed: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// createOutput opens path for writing, or returns stdout when it is empty.
+func createOutput(path string) (io.Writer, func(), error) {
+	if path == "" {
+		return os.Stdout, func() {}, nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// writeJSON writes v as indented JSON and returns the exit code.
+func writeJSON(w io.Writer, v interface{}) int {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Printf("❌ Failed to write output: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// parseFlagTime accepts a date (2006-01-02, midnight UTC) or an RFC 3339
+// time. Empty gives the zero time.
+func parseFlagTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
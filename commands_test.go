@@ -0,0 +1,45 @@
+// commands_test.go
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFlagTime(t *testing.T) {
+	for value, want := range map[string]time.Time{
+		"":                          {},
+		"2030-03-04":                time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC),
+		"2030-03-04T09:30:00+01:00": time.Date(2030, 3, 4, 8, 30, 0, 0, time.UTC),
+	} {
+		got, err := parseFlagTime(value)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("parseFlagTime(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"04.03.2030", "2030-03-04 09:30", "tomorrow"} {
+		if _, err := parseFlagTime(value); err == nil {
+			t.Errorf("parseFlagTime(%q) succeeded; want an error", value)
+		}
+	}
+}
+
+// TestCommandUsage checks that the subcommands turn bad arguments down with
+// exit code 2 before they load the configuration or open the database.
+func TestCommandUsage(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		run  func([]string) int
+		args []string
+	}{
+		{"migrate without action", runMigrate, nil},
+		{"sync without user", runSync, nil},
+		{"export without user", runExport, []string{"-format", "ics"}},
+		{"export unknown format", runExport, []string{"-user", "alice@example.com", "-format", "pdf"}},
+		{"export invalid from", runExport, []string{"-user", "alice@example.com", "-from", "yesterday"}},
+	} {
+		if code := tt.run(tt.args); code != 2 {
+			t.Errorf("%s: exit code %d; want 2", tt.name, code)
+		}
+	}
+}
@@ -0,0 +1,470 @@
+// internal/caldav/caldav.go
+package caldav
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Errors the client returns for answers the caller handles.
+var (
+	ErrNotFound           = errors.New("caldav: resource not found")
+	ErrPreconditionFailed = errors.New("caldav: resource changed on the server") // The ETag no longer matches
+	ErrNoCalendarHome     = errors.New("caldav: no calendar home found")
+)
+
+// maxRedirects caps the redirects followed while looking for the principal.
+const maxRedirects = 3
+
+// Client talks CalDAV (RFC 4791) to a server as one user, authenticating
+// with HTTP Basic. Hrefs it returns are absolute URLs.
+type Client struct {
+	httpClient *http.Client
+	username   string
+	password   string
+}
+
+// NewClient creates a Client. Redirects are not followed by httpClient,
+// since Go would turn a redirected PROPFIND into a GET; the client follows
+// them itself where servers use them.
+func NewClient(httpClient *http.Client, username, password string) *Client {
+	c := *httpClient
+	c.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	return &Client{httpClient: &c, username: username, password: password}
+}
+
+// StatusError is an unexpected answer from the server.
+type StatusError struct {
+	Method     string
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("caldav: %s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// IsStatus reports whether err is a StatusError with status.
+func IsStatus(err error, status int) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == status
+}
+
+// Calendar is a calendar collection.
+type Calendar struct {
+	Href        string
+	Name        string
+	Description string
+	Color       string
+	Components  []string // VEVENT, VTODO, ...; empty when the server doesn't say
+	CanWrite    bool
+}
+
+// SupportsEvents reports whether the calendar can hold VEVENTs.
+func (c *Calendar) SupportsEvents() bool {
+	if len(c.Components) == 0 {
+		return true
+	}
+	for _, name := range c.Components {
+		if strings.EqualFold(name, "VEVENT") {
+			return true
+		}
+	}
+	return false
+}
+
+// Object is a calendar object resource: one iCalendar file holding an event,
+// or a recurring event with its changed occurrences.
+type Object struct {
+	Href string
+	ETag string
+	Data []byte
+}
+
+// Query selects the events of a calendar-query REPORT.
+type Query struct {
+	Start   time.Time // Events overlapping [Start, End); no bound when zero
+	End     time.Time
+	Expand  bool   // Ask the server for the occurrences of recurring events instead of the rules
+	Summary string // Case-insensitive substring of the SUMMARY
+}
+
+// FindCalendarHome looks up the principal of the user at serverURL and the
+// collection their calendars live in. serverURL may be the server's root, in
+// which case /.well-known/caldav is tried (RFC 6764), the principal or the
+// calendar home itself.
+func (c *Client) FindCalendarHome(ctx context.Context, serverURL string) (principal, home string, err error) {
+	body := `<D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:prop>` +
+		`<D:current-user-principal/><C:calendar-home-set/><D:resourcetype/>` +
+		`</D:prop></D:propfind>`
+
+	target := serverURL
+	responses, err := c.propfind(ctx, &target, "0", body)
+	if (err != nil || len(responses) == 0 || responses[0].props().CurrentUserPrincipal.Href == "") && isRoot(serverURL) {
+		target = strings.TrimSuffix(serverURL, "/") + "/.well-known/caldav"
+		responses, err = c.propfind(ctx, &target, "0", body)
+	}
+	if err != nil {
+		return "", "", err
+	}
+	if len(responses) == 0 {
+		return "", "", ErrNoCalendarHome
+	}
+
+	props := responses[0].props()
+	principal = resolve(target, props.CurrentUserPrincipal.Href)
+	if props.CalendarHomeSet.Href != "" {
+		return principal, resolve(target, props.CalendarHomeSet.Href), nil
+	}
+	if principal == "" {
+		return "", "", ErrNoCalendarHome
+	}
+
+	target = principal
+	responses, err = c.propfind(ctx, &target, "0", body)
+	if err != nil {
+		return "", "", err
+	}
+	if len(responses) == 0 || responses[0].props().CalendarHomeSet.Href == "" {
+		return "", "", ErrNoCalendarHome
+	}
+	return principal, resolve(target, responses[0].props().CalendarHomeSet.Href), nil
+}
+
+// ListCalendars lists the calendar collections in home, sorted by href.
+func (c *Client) ListCalendars(ctx context.Context, home string) ([]Calendar, error) {
+	body := `<D:propfind xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav" xmlns:A="http://apple.com/ns/ical/"><D:prop>` +
+		`<D:resourcetype/><D:displayname/><C:calendar-description/><A:calendar-color/>` +
+		`<C:supported-calendar-component-set/><D:current-user-privilege-set/>` +
+		`</D:prop></D:propfind>`
+	target := home
+	responses, err := c.propfind(ctx, &target, "1", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendars []Calendar
+	for _, r := range responses {
+		props := r.props()
+		if props.ResourceType.Calendar == nil {
+			continue
+		}
+		calendar := Calendar{
+			Href:        resolve(target, r.Href),
+			Name:        props.DisplayName,
+			Description: props.CalendarDescription,
+			Color:       props.CalendarColor,
+		}
+		for _, comp := range props.SupportedComponents.Comps {
+			calendar.Components = append(calendar.Components, comp.Name)
+		}
+		for _, privilege := range props.Privileges.Privileges {
+			if privilege.All != nil || privilege.Write != nil || privilege.WriteContent != nil {
+				calendar.CanWrite = true
+			}
+		}
+		if len(props.Privileges.Privileges) == 0 {
+			calendar.CanWrite = true // Not reported; a failing write will tell
+		}
+		calendars = append(calendars, calendar)
+	}
+	sort.Slice(calendars, func(i, j int) bool { return calendars[i].Href < calendars[j].Href })
+	return calendars, nil
+}
+
+// QueryEvents runs a calendar-query REPORT for the VEVENTs of calendar.
+func (c *Client) QueryEvents(ctx context.Context, calendar string, query Query) ([]Object, error) {
+	var b strings.Builder
+	b.WriteString(`<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav"><D:prop><D:getetag/>`)
+	if query.Expand && !query.Start.IsZero() {
+		fmt.Fprintf(&b, `<C:calendar-data><C:expand start="%s" end="%s"/></C:calendar-data>`, formatTime(query.Start), formatTime(query.End))
+	} else {
+		b.WriteString(`<C:calendar-data/>`)
+	}
+	b.WriteString(`</D:prop><C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">`)
+	if !query.Start.IsZero() {
+		fmt.Fprintf(&b, `<C:time-range start="%s" end="%s"/>`, formatTime(query.Start), formatTime(query.End))
+	}
+	if query.Summary != "" {
+		b.WriteString(`<C:prop-filter name="SUMMARY"><C:text-match collation="i;unicode-casemap">`)
+		xml.EscapeText(&b, []byte(query.Summary))
+		b.WriteString(`</C:text-match></C:prop-filter>`)
+	}
+	b.WriteString(`</C:comp-filter></C:comp-filter></C:filter></C:calendar-query>`)
+
+	resp, err := c.do(ctx, "REPORT", calendar, "1", "application/xml; charset=utf-8", []byte(b.String()), nil)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := readMultistatus(resp, "REPORT", calendar)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []Object
+	for _, r := range responses {
+		props := r.props()
+		if props.CalendarData == "" {
+			continue
+		}
+		objects = append(objects, Object{
+			Href: resolve(calendar, r.Href),
+			ETag: props.GetETag,
+			Data: []byte(props.CalendarData),
+		})
+	}
+	return objects, nil
+}
+
+// GetObject fetches a calendar object resource.
+func (c *Client) GetObject(ctx context.Context, href string) (*Object, error) {
+	resp, err := c.do(ctx, http.MethodGet, href, "", "", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError(http.MethodGet, href, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{Href: href, ETag: resp.Header.Get("ETag"), Data: data}, nil
+}
+
+// PutObject stores data at href and returns the new ETag, which servers may
+// leave out. With an empty etag the resource must not exist yet; otherwise
+// it must still have that ETag, or just exist for "*". Either way
+// ErrPreconditionFailed tells it didn't.
+func (c *Client) PutObject(ctx context.Context, href string, data []byte, etag string) (string, error) {
+	header := http.Header{}
+	if etag == "" {
+		header.Set("If-None-Match", "*")
+	} else {
+		header.Set("If-Match", etag)
+	}
+	resp, err := c.do(ctx, http.MethodPut, href, "", "text/calendar; charset=utf-8", data, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusCreated, http.StatusNoContent:
+		return resp.Header.Get("ETag"), nil
+	default:
+		return "", statusError(http.MethodPut, href, resp.StatusCode)
+	}
+}
+
+// DeleteObject removes the resource at href; with an etag only while it
+// still has that ETag.
+func (c *Client) DeleteObject(ctx context.Context, href, etag string) error {
+	header := http.Header{}
+	if etag != "" {
+		header.Set("If-Match", etag)
+	}
+	resp, err := c.do(ctx, http.MethodDelete, href, "", "", nil, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusAccepted:
+		return nil
+	default:
+		return statusError(http.MethodDelete, href, resp.StatusCode)
+	}
+}
+
+// propfind runs a PROPFIND on *target, following redirects and updating
+// *target to where the answer came from.
+func (c *Client) propfind(ctx context.Context, target *string, depth, body string) ([]response, error) {
+	for i := 0; ; i++ {
+		resp, err := c.do(ctx, "PROPFIND", *target, depth, "application/xml; charset=utf-8", []byte(body), nil)
+		if err != nil {
+			return nil, err
+		}
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return readMultistatus(resp, "PROPFIND", *target)
+		}
+		resp.Body.Close()
+		if i == maxRedirects {
+			return nil, fmt.Errorf("caldav: too many redirects from %s", *target)
+		}
+		*target = resolve(*target, location)
+	}
+}
+
+// do sends a request with the user's credentials.
+func (c *Client) do(ctx context.Context, method, href, depth, contentType string, body []byte, header http.Header) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, href, reader)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	if depth != "" {
+		req.Header.Set("Depth", depth)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.SetBasicAuth(c.username, c.password)
+	return c.httpClient.Do(req)
+}
+
+// readMultistatus decodes a 207 Multi-Status answer and closes its body.
+func readMultistatus(resp *http.Response, method, href string) ([]response, error) {
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus {
+		return nil, statusError(method, href, resp.StatusCode)
+	}
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("caldav: unreadable answer to %s %s: %w", method, href, err)
+	}
+	return ms.Responses, nil
+}
+
+// statusError turns the statuses callers check for into the package errors.
+func statusError(method, href string, status int) error {
+	switch status {
+	case http.StatusNotFound, http.StatusGone:
+		return fmt.Errorf("%w: %s", ErrNotFound, href)
+	case http.StatusPreconditionFailed:
+		return fmt.Errorf("%w: %s", ErrPreconditionFailed, href)
+	default:
+		return &StatusError{Method: method, URL: href, StatusCode: status}
+	}
+}
+
+// resolve makes href absolute against base. It returns "" for an empty href.
+func resolve(base, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return b.ResolveReference(ref).String()
+}
+
+// isRoot reports whether serverURL has no path, so the server has to be asked
+// where CalDAV lives.
+func isRoot(serverURL string) bool {
+	u, err := url.Parse(serverURL)
+	return err == nil && (u.Path == "" || u.Path == "/")
+}
+
+// formatTime writes a time as CalDAV time ranges want it.
+func formatTime(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+type multistatus struct {
+	Responses []response `xml:"DAV: response"`
+}
+
+type response struct {
+	Href     string     `xml:"DAV: href"`
+	Propstat []propstat `xml:"DAV: propstat"`
+}
+
+// props merges the properties the server found; the others come in a
+// propstat with a 404 status.
+func (r *response) props() prop {
+	var merged prop
+	for _, ps := range r.Propstat {
+		if !strings.Contains(ps.Status, " 200") {
+			continue
+		}
+		p := ps.Prop
+		if p.DisplayName != "" {
+			merged.DisplayName = p.DisplayName
+		}
+		if p.ResourceType.Calendar != nil {
+			merged.ResourceType = p.ResourceType
+		}
+		if p.CurrentUserPrincipal.Href != "" {
+			merged.CurrentUserPrincipal = p.CurrentUserPrincipal
+		}
+		if p.CalendarHomeSet.Href != "" {
+			merged.CalendarHomeSet = p.CalendarHomeSet
+		}
+		if p.CalendarDescription != "" {
+			merged.CalendarDescription = p.CalendarDescription
+		}
+		if p.CalendarColor != "" {
+			merged.CalendarColor = p.CalendarColor
+		}
+		if len(p.SupportedComponents.Comps) > 0 {
+			merged.SupportedComponents = p.SupportedComponents
+		}
+		if len(p.Privileges.Privileges) > 0 {
+			merged.Privileges = p.Privileges
+		}
+		if p.GetETag != "" {
+			merged.GetETag = p.GetETag
+		}
+		if p.CalendarData != "" {
+			merged.CalendarData = p.CalendarData
+		}
+	}
+	return merged
+}
+
+type propstat struct {
+	Status string `xml:"DAV: status"`
+	Prop   prop   `xml:"DAV: prop"`
+}
+
+type prop struct {
+	DisplayName  string `xml:"DAV: displayname"`
+	ResourceType struct {
+		Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+	} `xml:"DAV: resourcetype"`
+	CurrentUserPrincipal href   `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      href   `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	CalendarDescription  string `xml:"urn:ietf:params:xml:ns:caldav calendar-description"`
+	CalendarColor        string `xml:"http://apple.com/ns/ical/ calendar-color"`
+	SupportedComponents  struct {
+		Comps []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"urn:ietf:params:xml:ns:caldav comp"`
+	} `xml:"urn:ietf:params:xml:ns:caldav supported-calendar-component-set"`
+	Privileges struct {
+		Privileges []struct {
+			All          *struct{} `xml:"DAV: all"`
+			Write        *struct{} `xml:"DAV: write"`
+			WriteContent *struct{} `xml:"DAV: write-content"`
+		} `xml:"DAV: privilege"`
+	} `xml:"DAV: current-user-privilege-set"`
+	GetETag      string `xml:"DAV: getetag"`
+	CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+type href struct {
+	Href string `xml:"DAV: href"`
+}
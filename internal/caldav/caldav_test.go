@@ -0,0 +1,218 @@
+// internal/caldav/caldav_test.go
+package caldav
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newServer starts a server whose handler sees only requests with the
+// test credentials; the others get 401.
+func newServer(t *testing.T, handler http.HandlerFunc) (*httptest.Server, *Client) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "alice" || pass != "app-password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, NewClient(server.Client(), "alice", "app-password")
+}
+
+func writeMultistatus(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><d:multistatus xmlns:d="DAV:" xmlns:cal="urn:ietf:params:xml:ns:caldav" xmlns:ical="http://apple.com/ns/ical/">`+body+`</d:multistatus>`)
+}
+
+func TestFindCalendarHome(t *testing.T) {
+	server, client := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PROPFIND" || r.Header.Get("Depth") != "0" {
+			t.Errorf("unexpected %s with depth %q", r.Method, r.Header.Get("Depth"))
+		}
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusNotFound)
+		case "/.well-known/caldav":
+			http.Redirect(w, r, "/dav/", http.StatusMovedPermanently)
+		case "/dav/":
+			writeMultistatus(w, `<d:response><d:href>/dav/</d:href><d:propstat><d:prop>`+
+				`<d:current-user-principal><d:href>/dav/principals/alice/</d:href></d:current-user-principal>`+
+				`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>`+
+				`<d:propstat><d:prop><cal:calendar-home-set/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat></d:response>`)
+		case "/dav/principals/alice/":
+			writeMultistatus(w, `<d:response><d:href>/dav/principals/alice/</d:href><d:propstat><d:prop>`+
+				`<cal:calendar-home-set><d:href>/dav/calendars/alice/</d:href></cal:calendar-home-set>`+
+				`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	principal, home, err := client.FindCalendarHome(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("FindCalendarHome: %v", err)
+	}
+	if principal != server.URL+"/dav/principals/alice/" || home != server.URL+"/dav/calendars/alice/" {
+		t.Errorf("FindCalendarHome = %s, %s", principal, home)
+	}
+
+	bad := NewClient(server.Client(), "alice", "wrong")
+	if _, _, err := bad.FindCalendarHome(context.Background(), server.URL+"/dav/"); !IsStatus(err, http.StatusUnauthorized) {
+		t.Errorf("FindCalendarHome with a wrong password = %v; want 401", err)
+	}
+}
+
+func TestListCalendars(t *testing.T) {
+	server, client := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PROPFIND" || r.Header.Get("Depth") != "1" {
+			t.Errorf("unexpected %s with depth %q", r.Method, r.Header.Get("Depth"))
+		}
+		writeMultistatus(w, `<d:response><d:href>/cal/alice/</d:href><d:propstat><d:prop>`+
+			`<d:resourcetype><d:collection/></d:resourcetype></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`+
+			`<d:response><d:href>/cal/alice/work/</d:href><d:propstat><d:prop>`+
+			`<d:resourcetype><d:collection/><cal:calendar/></d:resourcetype><d:displayname>Work</d:displayname>`+
+			`<ical:calendar-color>#FF2968FF</ical:calendar-color>`+
+			`<cal:supported-calendar-component-set><cal:comp name="VEVENT"/></cal:supported-calendar-component-set>`+
+			`<d:current-user-privilege-set><d:privilege><d:read/></d:privilege></d:current-user-privilege-set>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>`+
+			`<d:propstat><d:prop><cal:calendar-description/></d:prop><d:status>HTTP/1.1 404 Not Found</d:status></d:propstat></d:response>`+
+			`<d:response><d:href>/cal/alice/home/</d:href><d:propstat><d:prop>`+
+			`<d:resourcetype><d:collection/><cal:calendar/></d:resourcetype><d:displayname>Home</d:displayname>`+
+			`<cal:calendar-description>Family things</cal:calendar-description>`+
+			`<cal:supported-calendar-component-set><cal:comp name="VTODO"/></cal:supported-calendar-component-set>`+
+			`<d:current-user-privilege-set><d:privilege><d:read/></d:privilege><d:privilege><d:write/></d:privilege></d:current-user-privilege-set>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`)
+	})
+
+	calendars, err := client.ListCalendars(context.Background(), server.URL+"/cal/alice/")
+	if err != nil {
+		t.Fatalf("ListCalendars: %v", err)
+	}
+	if len(calendars) != 2 {
+		t.Fatalf("ListCalendars = %+v; want the two calendars", calendars)
+	}
+	home, work := calendars[0], calendars[1]
+	if home.Href != server.URL+"/cal/alice/home/" || home.Description != "Family things" || !home.CanWrite || home.SupportsEvents() {
+		t.Errorf("home = %+v", home)
+	}
+	if work.Name != "Work" || work.Color != "#FF2968FF" || work.CanWrite || !work.SupportsEvents() {
+		t.Errorf("work = %+v", work)
+	}
+}
+
+func TestQueryEvents(t *testing.T) {
+	const event = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:1\r\nSUMMARY:R&D sync\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	var body string
+	server, client := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+		if r.Method != "REPORT" || r.Header.Get("Depth") != "1" {
+			t.Errorf("unexpected %s with depth %q", r.Method, r.Header.Get("Depth"))
+		}
+		writeMultistatus(w, `<d:response><d:href>/cal/alice/work/1.ics</d:href><d:propstat><d:prop>`+
+			`<d:getetag>"e1"</d:getetag><cal:calendar-data>`+strings.ReplaceAll(event, "&", "&amp;")+`</cal:calendar-data>`+
+			`</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`+
+			`<d:response><d:href>/cal/alice/work/gone.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status></d:response>`)
+	})
+
+	start := time.Date(2025, 3, 10, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+	objects, err := client.QueryEvents(context.Background(), server.URL+"/cal/alice/work/", Query{
+		Start:   start,
+		End:     start.Add(24 * time.Hour),
+		Expand:  true,
+		Summary: "R&D",
+	})
+	if err != nil {
+		t.Fatalf("QueryEvents: %v", err)
+	}
+	for _, want := range []string{
+		`<C:expand start="20250310T090000Z" end="20250311T090000Z"/>`,
+		`<C:time-range start="20250310T090000Z" end="20250311T090000Z"/>`,
+		`<C:text-match collation="i;unicode-casemap">R&amp;D</C:text-match>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("REPORT body lacks %s:\n%s", want, body)
+		}
+	}
+	if len(objects) != 1 {
+		t.Fatalf("QueryEvents = %+v; want one object", objects)
+	}
+	// XML turns the line breaks into plain newlines, which the iCalendar
+	// parser accepts.
+	data := strings.ReplaceAll(event, "\r\n", "\n")
+	if objects[0].Href != server.URL+"/cal/alice/work/1.ics" || objects[0].ETag != `"e1"` || string(objects[0].Data) != data {
+		t.Errorf("object = %+v", objects[0])
+	}
+}
+
+func TestObjectPreconditions(t *testing.T) {
+	etag := `"v1"`
+	server, client := newServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			if r.URL.Path != "/cal/1.ics" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			io.WriteString(w, "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+		case http.MethodPut:
+			switch {
+			case r.Header.Get("If-None-Match") == "*" && r.URL.Path == "/cal/1.ics":
+				w.WriteHeader(http.StatusPreconditionFailed) // Exists already
+			case r.Header.Get("If-None-Match") == "*":
+				w.Header().Set("ETag", `"new"`)
+				w.WriteHeader(http.StatusCreated)
+			case r.Header.Get("If-Match") != etag:
+				w.WriteHeader(http.StatusPreconditionFailed)
+			default:
+				etag = `"v2"`
+				w.Header().Set("ETag", etag)
+				w.WriteHeader(http.StatusNoContent)
+			}
+		case http.MethodDelete:
+			if match := r.Header.Get("If-Match"); match != "" && match != etag {
+				w.WriteHeader(http.StatusPreconditionFailed)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	ctx := context.Background()
+	data := []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n")
+
+	if got, err := client.PutObject(ctx, server.URL+"/cal/2.ics", data, ""); err != nil || got != `"new"` {
+		t.Errorf("PutObject of a new object = %q, %v", got, err)
+	}
+	if _, err := client.PutObject(ctx, server.URL+"/cal/1.ics", data, ""); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("PutObject over an existing object = %v; want ErrPreconditionFailed", err)
+	}
+	object, err := client.GetObject(ctx, server.URL+"/cal/1.ics")
+	if err != nil || object.ETag != `"v1"` {
+		t.Fatalf("GetObject = %+v, %v", object, err)
+	}
+	if got, err := client.PutObject(ctx, object.Href, data, object.ETag); err != nil || got != `"v2"` {
+		t.Errorf("PutObject with the current ETag = %q, %v", got, err)
+	}
+	if _, err := client.PutObject(ctx, object.Href, data, object.ETag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("PutObject with a stale ETag = %v; want ErrPreconditionFailed", err)
+	}
+	if err := client.DeleteObject(ctx, object.Href, object.ETag); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("DeleteObject with a stale ETag = %v; want ErrPreconditionFailed", err)
+	}
+	if err := client.DeleteObject(ctx, object.Href, ""); err != nil {
+		t.Errorf("DeleteObject = %v", err)
+	}
+	if _, err := client.GetObject(ctx, server.URL+"/cal/missing.ics"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetObject of a missing object = %v; want ErrNotFound", err)
+	}
+}
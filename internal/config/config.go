@@ -2,9 +2,12 @@
 package config
 
 import (
+	"encoding/base64"
 	"fmt"
 	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"golang.org/x/oauth2"
@@ -13,7 +16,8 @@ import (
 
 // Config holds application configuration.
 type Config struct {
-	DatabaseURL        string
+	DatabaseDriver     string // "postgres", or "sqlite" for a single-binary local setup
+	DatabaseURL        string // Connection URL; a file path for SQLite
 	ServerPort         string
 	GoogleClientID     string
 	GoogleClientSecret string
@@ -21,7 +25,34 @@ type Config struct {
 	JWTSecret          []byte         // Changed to byte slice
 	CSRFSecret         []byte         // Changed to byte slice
 	OAuthConfig        *oauth2.Config // OAuth configuration
+	GoogleAuthURL      string         // OAuth endpoints; point them and the URLs below at a stand-in for tests
+	GoogleTokenURL     string
+	GoogleIssuerURL    string        // OpenID Connect issuer of the ID tokens, e.g. https://accounts.google.com
+	GoogleCalendarURL  string        // Calendar API base URL; the client library's own when empty
+	GoogleWebhookURL   string        // Public HTTPS URL of /webhooks/google/calendar; push notifications are off when empty
+	WebhookAllowHTTP   bool          // Accept http:// webhook URLs, e.g. a local receiver in tests
+	WebhookAllowLocal  bool          // Let webhooks reach loopback, private and link-local addresses
+	ReconcileInterval  time.Duration // How often the reconcile job runs; off when zero
+	ReconcileDryRun    bool          // Let the scheduled reconcile job only report
+	PublicURL          string        // Base URL clients reach the server at, for feed links; taken from the request when empty
 	Env                string
+
+	// Microsoft 365 / Outlook calendars. Off unless MICROSOFT_CLIENT_ID is set.
+	MicrosoftClientID     string
+	MicrosoftClientSecret string
+	MicrosoftRedirectURL  string
+	MicrosoftTenant       string         // "common" lets both work and personal accounts sign in
+	MicrosoftLoginURL     string         // Identity platform base URL; point it at a stand-in for tests
+	MicrosoftGraphURL     string         // Graph API base URL, e.g. https://graph.microsoft.com/v1.0
+	MicrosoftOAuthConfig  *oauth2.Config // Nil when Microsoft is off
+
+	// CalDAV servers such as Nextcloud, Radicale or Fastmail. Users link their
+	// own server, which this server then connects to, so it is opt-in.
+	CalDAVEnabled    bool
+	CalDAVAllowHTTP  bool          // Accept http:// server URLs, e.g. a local server in tests
+	CalDAVAllowLocal bool          // Let CalDAV requests reach loopback, private and link-local addresses
+	CalDAVTimeout    time.Duration // Per request
+	CalDAVSecretKey  []byte        // AES-256 key the stored CalDAV passwords are encrypted with
 }
 
 // LoadConfig initializes configuration from environment variables.
@@ -35,7 +66,6 @@ func LoadConfig() (Config, error) {
 
 	//Required Environment variables.
 	requiredEnvs := []string{
-		"DB_URL",
 		"GOOGLE_CLIENT_ID",
 		"GOOGLE_CLIENT_SECRET",
 		"GOOGLE_REDIRECT_URL",
@@ -54,6 +84,7 @@ func LoadConfig() (Config, error) {
 	}
 
 	conf := Config{
+		DatabaseDriver:     envOr("DB_DRIVER", "postgres"),
 		DatabaseURL:        os.Getenv("DB_URL"),
 		ServerPort:         port,
 		GoogleClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
@@ -61,9 +92,39 @@ func LoadConfig() (Config, error) {
 		GoogleRedirectURL:  os.Getenv("GOOGLE_REDIRECT_URL"),
 		JWTSecret:          []byte(os.Getenv("JWT_SECRET")),  // Store as byte slice
 		CSRFSecret:         []byte(os.Getenv("CSRF_SECRET")), // Store as byte slice
+		GoogleWebhookURL:   os.Getenv("GOOGLE_WEBHOOK_URL"),
+		PublicURL:          strings.TrimSuffix(os.Getenv("PUBLIC_URL"), "/"),
 		Env:                os.Getenv("ENV"),
+		GoogleAuthURL:      envOr("GOOGLE_AUTH_URL", google.Endpoint.AuthURL),
+		GoogleTokenURL:     envOr("GOOGLE_TOKEN_URL", google.Endpoint.TokenURL),
+		GoogleIssuerURL:    strings.TrimSuffix(envOr("GOOGLE_ISSUER_URL", "https://accounts.google.com"), "/"),
+		GoogleCalendarURL:  os.Getenv("GOOGLE_CALENDAR_URL"),
+	}
+
+	switch conf.DatabaseDriver {
+	case "postgres":
+		if conf.DatabaseURL == "" {
+			return Config{}, fmt.Errorf("DB_URL is missing in environment variables")
+		}
+	case "sqlite":
+		if conf.DatabaseURL == "" {
+			conf.DatabaseURL = "calendar.db"
+		}
+	default:
+		return Config{}, fmt.Errorf("unknown DB_DRIVER %q, want postgres or sqlite", conf.DatabaseDriver)
 	}
 
+	if v := os.Getenv("RECONCILE_INTERVAL"); v != "" {
+		interval, err := time.ParseDuration(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("RECONCILE_INTERVAL is not a valid duration: %w", err)
+		}
+		conf.ReconcileInterval = interval
+	}
+	conf.ReconcileDryRun = os.Getenv("RECONCILE_DRY_RUN") == "true"
+	conf.WebhookAllowHTTP = os.Getenv("WEBHOOK_ALLOW_HTTP") == "true"
+	conf.WebhookAllowLocal = os.Getenv("WEBHOOK_ALLOW_LOCAL") == "true"
+
 	conf.OAuthConfig = &oauth2.Config{
 		ClientID:     conf.GoogleClientID,
 		ClientSecret: conf.GoogleClientSecret,
@@ -75,7 +136,67 @@ func LoadConfig() (Config, error) {
 			"https://www.googleapis.com/auth/calendar",
 			"https://www.googleapis.com/auth/calendar.events",
 		},
-		Endpoint: google.Endpoint,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   conf.GoogleAuthURL,
+			TokenURL:  conf.GoogleTokenURL,
+			AuthStyle: google.Endpoint.AuthStyle,
+		},
+	}
+
+	conf.MicrosoftClientID = os.Getenv("MICROSOFT_CLIENT_ID")
+	conf.MicrosoftClientSecret = os.Getenv("MICROSOFT_CLIENT_SECRET")
+	conf.MicrosoftRedirectURL = os.Getenv("MICROSOFT_REDIRECT_URL")
+	conf.MicrosoftTenant = envOr("MICROSOFT_TENANT", "common")
+	conf.MicrosoftLoginURL = strings.TrimSuffix(envOr("MICROSOFT_LOGIN_URL", "https://login.microsoftonline.com"), "/")
+	conf.MicrosoftGraphURL = strings.TrimSuffix(envOr("MICROSOFT_GRAPH_URL", "https://graph.microsoft.com/v1.0"), "/")
+	if conf.MicrosoftClientID != "" {
+		if conf.MicrosoftClientSecret == "" || conf.MicrosoftRedirectURL == "" {
+			return Config{}, fmt.Errorf("MICROSOFT_CLIENT_SECRET and MICROSOFT_REDIRECT_URL are needed with MICROSOFT_CLIENT_ID")
+		}
+		conf.MicrosoftOAuthConfig = &oauth2.Config{
+			ClientID:     conf.MicrosoftClientID,
+			ClientSecret: conf.MicrosoftClientSecret,
+			RedirectURL:  conf.MicrosoftRedirectURL,
+			Scopes: []string{
+				"openid",
+				"email",
+				"profile",
+				"offline_access", // For a refresh token
+				"User.Read",
+				"Calendars.ReadWrite",
+			},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  conf.MicrosoftLoginURL + "/" + conf.MicrosoftTenant + "/oauth2/v2.0/authorize",
+				TokenURL: conf.MicrosoftLoginURL + "/" + conf.MicrosoftTenant + "/oauth2/v2.0/token",
+			},
+		}
+	}
+
+	conf.CalDAVEnabled = os.Getenv("CALDAV_ENABLED") == "true"
+	conf.CalDAVAllowHTTP = os.Getenv("CALDAV_ALLOW_HTTP") == "true"
+	conf.CalDAVAllowLocal = os.Getenv("CALDAV_ALLOW_LOCAL") == "true"
+	conf.CalDAVTimeout = 30 * time.Second
+	if v := os.Getenv("CALDAV_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return Config{}, fmt.Errorf("invalid CALDAV_TIMEOUT %q", v)
+		}
+		conf.CalDAVTimeout = d
+	}
+	if conf.CalDAVEnabled {
+		key, err := base64.StdEncoding.DecodeString(os.Getenv("CALDAV_SECRET_KEY"))
+		if err != nil || len(key) != 32 {
+			return Config{}, fmt.Errorf("CALDAV_SECRET_KEY must be 32 bytes in base64 when CALDAV_ENABLED is set")
+		}
+		conf.CalDAVSecretKey = key
 	}
 	return conf, nil
 }
+
+// envOr returns the environment variable key, or fallback when it is empty.
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
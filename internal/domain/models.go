@@ -20,38 +20,144 @@ type User struct {
 	AccessToken  string    `json:"-"` // Don't expose in JSON responses
 	RefreshToken string    `json:"-"` // Don't expose
 	ExpiresAt    time.Time `json:"-"` // Don't expose
+	FeedToken    string    `json:"-"` // Secret in the URL of the user's iCalendar feed; empty when there is none
+
+	CalendarProvider string `gorm:"default:google" json:"calendar_provider"` // Backend new events go to: "google", "microsoft" or "caldav"
 }
 
 // Meeting represents a scheduled meeting.
 type Meeting struct {
 	gorm.Model
-	ID              uint      `gorm:"primaryKey" json:"id"`
-	Title           string    `json:"title"`
-	Description     string    `json:"description"`
-	StartTime       time.Time `json:"start_time"`
-	EndTime         time.Time `json:"end_time"`
-	EventID         string    `json:"event_id"`                  // Google Calendar Event ID
-	AttendeesString string    `gorm:"column:attendees" json:"-"` // Comma-separated attendees (for storage)
-	Attendees       []string  `gorm:"-" json:"attendees"`        // Attendees (for API response)
-	CreatedBy       string    `json:"created_by"`                // Email of the user who created the meeting
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	StartTime     time.Time  `json:"start_time"`
+	EndTime       time.Time  `json:"end_time"`
+	AllDay        bool       `json:"all_day"`                                   // Start and end are dates (end exclusive)
+	TimeZone      string     `json:"time_zone,omitempty"`                       // IANA zone Google schedules the event in
+	EventID       string     `json:"event_id"`                                  // Google Calendar Event ID
+	ICalUID       string     `gorm:"column:ical_uid" json:"ical_uid,omitempty"` // iCalendar UID, shared by all events of a series; set on import or by Google
+	CalendarID    string     `gorm:"default:primary" json:"calendar_id"`        // Google calendar the event lives on
+	Provider      string     `gorm:"default:google" json:"provider"`            // Calendar backend the event lives in
+	Attendees     []Attendee `gorm:"foreignKey:MeetingID" json:"attendees"`
+	CreatedBy     string     `json:"created_by"`               // Email of the user who created the meeting
+	ConferenceID  string     `json:"conference_id,omitempty"`  // Google Meet conference ID
+	ConferenceURL string     `json:"conference_url,omitempty"` // Google Meet join link
+
+	// Recurring events: the series master keeps its rules, every changed or
+	// cancelled occurrence is stored as an exception row pointing at the master.
+	RecurrenceString  string     `gorm:"column:recurrence" json:"-"`                // Newline-separated RRULE/EXDATE/RDATE lines (for storage)
+	Recurrence        []string   `gorm:"-" json:"recurrence,omitempty"`             // Recurrence lines (for API response)
+	RecurringEventID  string     `gorm:"index" json:"recurring_event_id,omitempty"` // Google event ID of the series master (exceptions only)
+	OriginalStartTime *time.Time `json:"original_start_time,omitempty"`             // Start the occurrence had before it was changed (exceptions only)
+	Status            string     `gorm:"default:confirmed" json:"status"`           // "confirmed" or "cancelled"
+	Exceptions        []Meeting  `gorm:"-" json:"exceptions,omitempty"`             // Exceptions of a series master (for API response)
+
+	CommitState string `gorm:"default:committed" json:"commit_state"` // "pending" until the outbox applied it to Google, then "committed" or "failed"
 }
 
 // Attendee represents a participant in a meeting.
 type Attendee struct {
 	gorm.Model
-	MeetingID uint   `json:"meeting_id"`
-	Email     string `json:"email"`
+	MeetingID      uint   `gorm:"index" json:"meeting_id"`
+	Email          string `json:"email"`
+	Name           string `json:"name,omitempty"`
+	Optional       bool   `json:"optional"`
+	Organizer      bool   `json:"organizer"`
+	ResponseStatus string `gorm:"default:needsAction" json:"response_status"` // needsAction, accepted, declined or tentative
 }
 
-// AfterFind is a GORM hook that runs after fetching a Meeting.
-func (m *Meeting) AfterFind(tx *gorm.DB) (err error) {
-	if m.AttendeesString != "" {
-		m.Attendees = splitAttendees(m.AttendeesString) // Convert to slice
+// CalendarAccount links a user to a calendar backend other than Google and
+// keeps its tokens. Google's tokens are kept on the User.
+type CalendarAccount struct {
+	gorm.Model
+	UserEmail         string    `gorm:"uniqueIndex:idx_calendar_account" json:"user_email"`
+	Provider          string    `gorm:"uniqueIndex:idx_calendar_account" json:"provider"` // "microsoft" or "caldav"
+	Subject           string    `json:"-"`                                                // The provider's ID of the account
+	Email             string    `json:"email"`                                            // Address of the account at the provider
+	AccessToken       string    `json:"-"`
+	RefreshToken      string    `json:"-"`
+	ExpiresAt         time.Time `json:"-"`
+	ServerURL         string    `json:"server_url,omitempty"` // CalDAV: the calendar home collection
+	Username          string    `json:"-"`                    // CalDAV: Basic auth credentials
+	EncryptedPassword string    `json:"-"`                    // Sealed with CALDAV_SECRET_KEY
+}
+
+// CalendarSync keeps the Google sync token of one calendar of a user.
+type CalendarSync struct {
+	gorm.Model
+	UserEmail    string    `gorm:"uniqueIndex:idx_calendar_sync" json:"user_email"`
+	CalendarID   string    `gorm:"uniqueIndex:idx_calendar_sync" json:"calendar_id"`
+	SyncToken    string    `json:"-"` // nextSyncToken of the last completed sync; empty forces a full sync
+	LastSyncedAt time.Time `json:"last_synced_at"`
+}
+
+// WatchChannel is a Google push notification channel for one calendar of a user.
+type WatchChannel struct {
+	gorm.Model
+	ChannelID  string    `gorm:"uniqueIndex" json:"channel_id"` // Our ID, sent back as X-Goog-Channel-ID
+	ResourceID string    `json:"resource_id"`                   // Google's ID of the watched resource, needed to stop the channel
+	UserEmail  string    `gorm:"index" json:"user_email"`
+	CalendarID string    `json:"calendar_id"`
+	Token      string    `json:"-"` // Sent back as X-Goog-Channel-Token
+	Expiration time.Time `json:"expiration"`
+}
+
+// WebhookSubscription is a URL a user wants meeting lifecycle events posted to.
+type WebhookSubscription struct {
+	gorm.Model
+	UserEmail        string   `gorm:"index" json:"user_email"`
+	URL              string   `json:"url"`
+	Secret           string   `json:"-"`                           // Key for the HMAC signature of every delivery
+	EventTypesString string   `gorm:"column:event_types" json:"-"` // Comma-separated event types (for storage); empty means all
+	EventTypes       []string `gorm:"-" json:"event_types"`        // Event types (for API response)
+}
+
+// WebhookDelivery is one payload queued for a subscription, along with how
+// delivering it went so far.
+type WebhookDelivery struct {
+	gorm.Model
+	SubscriptionID uint       `gorm:"index" json:"subscription_id"`
+	MessageID      string     `gorm:"index" json:"message_id"` // Stays the same when a delivery is replayed, so receivers can drop duplicates
+	EventType      string     `json:"event_type"`
+	Payload        string     `json:"payload"`             // JSON body that is posted
+	Status         string     `gorm:"index" json:"status"` // "pending", "delivered" or "failed"
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"index" json:"next_attempt_at"`
+	LastError      string     `json:"last_error,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP status of the last attempt
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+	ReplayOf       *uint      `json:"replay_of,omitempty"` // Delivery this one replays
+}
+
+// OutboxOperation is a change to Google that was recorded before it was
+// made, so a worker can finish or undo it if the request dies half way.
+type OutboxOperation struct {
+	gorm.Model
+	Kind          string    `gorm:"index" json:"kind"` // "create_event"
+	UserEmail     string    `gorm:"index" json:"user_email"`
+	MeetingID     uint      `gorm:"index" json:"meeting_id"`
+	CalendarID    string    `json:"calendar_id"`
+	EventID       string    `json:"event_id"`            // Google event ID, chosen up front so a retry can't create a second event
+	Payload       string    `json:"-"`                   // Google event JSON to send
+	Status        string    `gorm:"index" json:"status"` // "pending", "committed" or "failed"
+	Attempts      int       `json:"attempts"`
+	NextAttemptAt time.Time `gorm:"index" json:"next_attempt_at"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// AfterFind is a GORM hook that runs after fetching a WebhookSubscription.
+func (s *WebhookSubscription) AfterFind(tx *gorm.DB) (err error) {
+	if s.EventTypesString != "" {
+		s.EventTypes = strings.Split(s.EventTypesString, ",")
 	}
 	return
 }
 
-// splitAttendees splits a comma-separated string of attendees into a slice.
-func splitAttendees(attendeesStr string) []string {
-	return strings.Split(attendeesStr, ",")
+// AfterFind is a GORM hook that runs after fetching a Meeting.
+func (m *Meeting) AfterFind(tx *gorm.DB) (err error) {
+	if m.RecurrenceString != "" {
+		m.Recurrence = strings.Split(m.RecurrenceString, "\n")
+	}
+	return
 }
@@ -0,0 +1,724 @@
+// internal/fakegoogle/calendar.go
+package fakegoogle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"google.golang.org/api/calendar/v3"
+)
+
+type contextKey string
+
+const emailKey contextKey = "email"
+
+// calendarData is one calendar and its events, by ID. Deleted events stay
+// as cancelled ones, so sync can report them.
+type calendarData struct {
+	entry  calendar.CalendarListEntry
+	owner  string
+	events map[string]*storedEvent
+}
+
+type storedEvent struct {
+	event *calendar.Event
+	seq   int64 // The change that stored it
+}
+
+// calendarRoutes serves the Calendar API calls the API makes. Push
+// notification channels are accepted, but nothing is ever sent to them.
+func (s *Server) calendarRoutes(router *mux.Router) {
+	router.Use(s.authenticate)
+	router.HandleFunc("/users/me/calendarList", s.listCalendars).Methods("GET")
+	router.HandleFunc("/freeBusy", s.freeBusy).Methods("POST")
+	router.HandleFunc("/channels/stop", s.stopChannel).Methods("POST")
+	router.HandleFunc("/calendars/{calendarId}/events", s.insertEvent).Methods("POST")
+	router.HandleFunc("/calendars/{calendarId}/events", s.listEvents).Methods("GET")
+	router.HandleFunc("/calendars/{calendarId}/events/watch", s.watchEvents).Methods("POST")
+	router.HandleFunc("/calendars/{calendarId}/events/{eventId}", s.getEvent).Methods("GET")
+	router.HandleFunc("/calendars/{calendarId}/events/{eventId}", s.updateEvent).Methods("PUT", "PATCH")
+	router.HandleFunc("/calendars/{calendarId}/events/{eventId}", s.deleteEvent).Methods("DELETE")
+	router.HandleFunc("/calendars/{calendarId}/events/{eventId}/instances", s.listInstances).Methods("GET")
+}
+
+// authenticate lets requests with a valid access token through.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		s.mu.Lock()
+		email := s.access[token]
+		s.mu.Unlock()
+		if !ok || email == "" {
+			writeError(w, http.StatusUnauthorized, "authError", "Invalid Credentials")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), emailKey, email)))
+	})
+}
+
+func (s *Server) listCalendars(w http.ResponseWriter, r *http.Request) {
+	email := r.Context().Value(emailKey).(string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := []*calendar.CalendarListEntry{}
+	for _, c := range s.calendars {
+		if strings.EqualFold(c.owner, email) {
+			entry := c.entry
+			items = append(items, &entry)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Primary != items[j].Primary {
+			return items[i].Primary
+		}
+		return items[i].Id < items[j].Id
+	})
+	writeJSON(w, http.StatusOK, calendar.CalendarList{Kind: "calendar#calendarList", Items: items})
+}
+
+func (s *Server) insertEvent(w http.ResponseWriter, r *http.Request) {
+	var event calendar.Event
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Parse Error")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.calendarOf(w, r)
+	if !ok {
+		return
+	}
+
+	if event.Id == "" {
+		event.Id = randomID(13)
+	} else if !validEventID(event.Id) {
+		writeError(w, http.StatusBadRequest, "invalid", "Invalid resource id value.")
+		return
+	}
+	if _, exists := c.events[event.Id]; exists {
+		writeError(w, http.StatusConflict, "duplicate", "The requested identifier already exists.")
+		return
+	}
+	if !validTimes(w, &event) {
+		return
+	}
+	if event.ICalUID == "" {
+		event.ICalUID = event.Id + "@google.com"
+	}
+	if event.Status != "tentative" {
+		event.Status = "confirmed"
+	}
+	event.Kind = "calendar#event"
+	event.Created = time.Now().UTC().Format(time.RFC3339Nano)
+	event.HtmlLink = s.URL + "/calendar/event?eid=" + event.Id
+	event.Creator = &calendar.EventCreator{Email: c.owner, Self: true}
+	event.Organizer = &calendar.EventOrganizer{Email: c.entry.Id, Self: true}
+	event.RecurringEventId, event.OriginalStartTime = "", nil
+	applyConference(r, &event, nil)
+	normalizeAttendees(&event, c.owner)
+
+	s.store(c, &event)
+	writeJSON(w, http.StatusOK, &event)
+}
+
+func (s *Server) getEvent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.calendarOf(w, r)
+	if !ok {
+		return
+	}
+	event, _ := c.find(mux.Vars(r)["eventId"])
+	if event == nil {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+	writeJSON(w, http.StatusOK, event)
+}
+
+// updateEvent serves both update, which replaces the event, and patch,
+// which only sets the fields in the body. Changing an occurrence stores it
+// as an exception of its series.
+func (s *Server) updateEvent(w http.ResponseWriter, r *http.Request) {
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Parse Error")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.calendarOf(w, r)
+	if !ok {
+		return
+	}
+	current, _ := c.find(mux.Vars(r)["eventId"])
+	if current == nil {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+
+	fields := map[string]json.RawMessage{}
+	if r.Method == http.MethodPatch {
+		data, _ := json.Marshal(current)
+		json.Unmarshal(data, &fields)
+	}
+	for key, value := range body {
+		if string(value) == "null" {
+			delete(fields, key)
+		} else {
+			fields[key] = value
+		}
+	}
+	data, _ := json.Marshal(fields)
+	var event calendar.Event
+	if err := json.Unmarshal(data, &event); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid", err.Error())
+		return
+	}
+	if !validTimes(w, &event) {
+		return
+	}
+
+	// What the client can't change
+	event.Kind, event.Id, event.ICalUID = current.Kind, current.Id, current.ICalUID
+	event.Created, event.HtmlLink = current.Created, current.HtmlLink
+	event.Creator, event.Organizer = current.Creator, current.Organizer
+	event.RecurringEventId, event.OriginalStartTime = current.RecurringEventId, current.OriginalStartTime
+	event.Sequence = current.Sequence + 1
+	if event.Status == "" {
+		event.Status = "confirmed"
+	}
+	applyConference(r, &event, current.ConferenceData)
+	normalizeAttendees(&event, c.owner)
+
+	s.store(c, &event)
+	writeJSON(w, http.StatusOK, &event)
+}
+
+// deleteEvent cancels the event; a series takes its exceptions with it.
+func (s *Server) deleteEvent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.calendarOf(w, r)
+	if !ok {
+		return
+	}
+	event, _ := c.find(mux.Vars(r)["eventId"])
+	if event == nil {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+	if event.Status == "cancelled" {
+		writeError(w, http.StatusGone, "deleted", "Resource has been deleted")
+		return
+	}
+
+	cancelled := clone(event)
+	cancelled.Status = "cancelled"
+	s.store(c, cancelled)
+	for _, stored := range c.events {
+		if stored.event.RecurringEventId == event.Id && stored.event.Status != "cancelled" {
+			exception := clone(stored.event)
+			exception.Status = "cancelled"
+			s.store(c, exception)
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// listEvents lists the events of a calendar. With a sync token it returns
+// what changed since, deletions included; an unknown token is 410 Gone.
+func (s *Server) listEvents(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.calendarOf(w, r)
+	if !ok {
+		return
+	}
+
+	var items []*calendar.Event
+	if syncToken := query.Get("syncToken"); syncToken != "" {
+		if query.Get("timeMin") != "" || query.Get("timeMax") != "" || query.Get("q") != "" || query.Get("iCalUID") != "" {
+			writeError(w, http.StatusBadRequest, "invalid", "Sync token can't be combined with these filters.")
+			return
+		}
+		since, err := strconv.ParseInt(syncToken, 10, 64)
+		if err != nil || since < 0 || since > s.seq {
+			writeError(w, http.StatusGone, "fullSyncRequired", "Sync token is no longer valid, a full sync is required.")
+			return
+		}
+		for _, stored := range c.events {
+			if stored.seq > since {
+				items = append(items, stored.event)
+			}
+		}
+	} else {
+		timeMin, timeMax, ok := timeBounds(w, query.Get("timeMin"), query.Get("timeMax"))
+		if !ok {
+			return
+		}
+		singleEvents := query.Get("singleEvents") == "true"
+		if query.Get("orderBy") == "startTime" && !singleEvents {
+			writeError(w, http.StatusBadRequest, "invalid", "The requested ordering is not available for the particular query.")
+			return
+		}
+		for _, stored := range c.events {
+			event := stored.event
+			if singleEvents && len(event.Recurrence) > 0 {
+				items = append(items, c.instances(event, timeMin, timeMax)...)
+				continue
+			}
+			if singleEvents && c.hasSeries(event) {
+				continue // Listed with the instances of its series
+			}
+			if len(event.Recurrence) > 0 {
+				start, _, _ := eventSpan(event)
+				if start.Before(timeMax) {
+					items = append(items, event)
+				}
+				continue
+			}
+			if overlaps(event, timeMin, timeMax) {
+				items = append(items, event)
+			}
+		}
+		items = filterEvents(items, query.Get("showDeleted") == "true", query.Get("q"), query.Get("iCalUID"))
+	}
+
+	page, next, ok := paginate(w, items, query.Get("maxResults"), query.Get("pageToken"))
+	if !ok {
+		return
+	}
+	list := calendar.Events{
+		Kind:          "calendar#events",
+		Summary:       c.entry.Summary,
+		TimeZone:      c.entry.TimeZone,
+		Updated:       time.Now().UTC().Format(time.RFC3339Nano),
+		Items:         page,
+		NextPageToken: next,
+	}
+	if next == "" {
+		list.NextSyncToken = strconv.FormatInt(s.seq, 10)
+	}
+	writeJSON(w, http.StatusOK, &list)
+}
+
+func (s *Server) listInstances(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.calendarOf(w, r)
+	if !ok {
+		return
+	}
+	master, stored := c.find(mux.Vars(r)["eventId"])
+	if master == nil || !stored {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return
+	}
+	timeMin, timeMax, ok := timeBounds(w, query.Get("timeMin"), query.Get("timeMax"))
+	if !ok {
+		return
+	}
+
+	var items []*calendar.Event
+	if len(master.Recurrence) > 0 {
+		items = filterEvents(c.instances(master, timeMin, timeMax), query.Get("showDeleted") == "true", "", "")
+	}
+	page, next, ok := paginate(w, items, query.Get("maxResults"), query.Get("pageToken"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, &calendar.Events{Kind: "calendar#events", Items: page, NextPageToken: next})
+}
+
+func (s *Server) watchEvents(w http.ResponseWriter, r *http.Request) {
+	var channel calendar.Channel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil || channel.Id == "" || channel.Address == "" {
+		writeError(w, http.StatusBadRequest, "required", "Channel id and address are required.")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c, ok := s.calendarOf(w, r)
+	if !ok {
+		return
+	}
+
+	channel.Kind = "api#channel"
+	channel.ResourceId = randomID(12)
+	channel.ResourceUri = s.URL + "/calendar/v3/calendars/" + c.entry.Id + "/events"
+	channel.Expiration = time.Now().Add(7 * 24 * time.Hour).UnixMilli()
+	s.channels[channel.Id] = &channel
+	writeJSON(w, http.StatusOK, &channel)
+}
+
+func (s *Server) stopChannel(w http.ResponseWriter, r *http.Request) {
+	var channel calendar.Channel
+	if err := json.NewDecoder(r.Body).Decode(&channel); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Parse Error")
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stored, ok := s.channels[channel.Id]; !ok || stored.ResourceId != channel.ResourceId {
+		writeError(w, http.StatusNotFound, "notFound", "Channel not found")
+		return
+	}
+	delete(s.channels, channel.Id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// freeBusy reports the busy times of any calendar on the server, as within
+// one Workspace domain. Transparent and cancelled events don't count.
+func (s *Server) freeBusy(w http.ResponseWriter, r *http.Request) {
+	var request calendar.FreeBusyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "parseError", "Parse Error")
+		return
+	}
+	timeMin, timeMax, ok := timeBounds(w, request.TimeMin, request.TimeMax)
+	if !ok {
+		return
+	}
+	loc := time.UTC
+	if l, err := time.LoadLocation(request.TimeZone); request.TimeZone != "" && err == nil {
+		loc = l
+	}
+	email := r.Context().Value(emailKey).(string)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	response := calendar.FreeBusyResponse{
+		Kind:      "calendar#freeBusy",
+		TimeMin:   request.TimeMin,
+		TimeMax:   request.TimeMax,
+		Calendars: make(map[string]calendar.FreeBusyCalendar, len(request.Items)),
+	}
+	for _, item := range request.Items {
+		id := item.Id
+		if id == "primary" {
+			id = email
+		}
+		c := s.calendars[strings.ToLower(id)]
+		if c == nil {
+			response.Calendars[item.Id] = calendar.FreeBusyCalendar{
+				Busy:   []*calendar.TimePeriod{},
+				Errors: []*calendar.Error{{Domain: "global", Reason: "notFound"}},
+			}
+			continue
+		}
+
+		var events []*calendar.Event
+		for _, stored := range c.events {
+			if len(stored.event.Recurrence) > 0 {
+				events = append(events, c.instances(stored.event, timeMin, timeMax)...)
+			} else if !c.hasSeries(stored.event) && overlaps(stored.event, timeMin, timeMax) {
+				events = append(events, stored.event)
+			}
+		}
+		events = filterEvents(events, false, "", "")
+
+		busy := []*calendar.TimePeriod{}
+		var lastEnd time.Time
+		for _, event := range events {
+			if event.Transparency == "transparent" {
+				continue
+			}
+			start, end, _ := eventSpan(event)
+			start, end = later(start, timeMin), earlier(end, timeMax)
+			if n := len(busy); n > 0 && !start.After(lastEnd) {
+				lastEnd = later(lastEnd, end)
+				busy[n-1].End = lastEnd.In(loc).Format(time.RFC3339)
+				continue
+			}
+			lastEnd = end
+			busy = append(busy, &calendar.TimePeriod{Start: start.In(loc).Format(time.RFC3339), End: end.In(loc).Format(time.RFC3339)})
+		}
+		response.Calendars[item.Id] = calendar.FreeBusyCalendar{Busy: busy}
+	}
+	writeJSON(w, http.StatusOK, &response)
+}
+
+// Events returns the events stored in a calendar, cancelled ones and
+// exceptions of series included, sorted by start.
+func (s *Server) Events(calendarID string) []*calendar.Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.calendars[strings.ToLower(calendarID)]
+	if c == nil {
+		return nil
+	}
+	var events []*calendar.Event
+	for _, stored := range c.events {
+		events = append(events, clone(stored.event))
+	}
+	sortEvents(events)
+	return events
+}
+
+// addCalendar creates a calendar. The caller holds s.mu.
+func (s *Server) addCalendar(owner, id, summary string, primary bool) {
+	s.calendars[strings.ToLower(id)] = &calendarData{
+		entry: calendar.CalendarListEntry{
+			Kind:            "calendar#calendarListEntry",
+			Id:              id,
+			Summary:         summary,
+			TimeZone:        "UTC",
+			AccessRole:      "owner",
+			Primary:         primary,
+			BackgroundColor: "#9fe1e7",
+		},
+		owner:  owner,
+		events: make(map[string]*storedEvent),
+	}
+}
+
+// calendarOf returns the calendar of the request if the user owns it, or
+// answers 404 as Google does for calendars one can't see.
+func (s *Server) calendarOf(w http.ResponseWriter, r *http.Request) (*calendarData, bool) {
+	email := r.Context().Value(emailKey).(string)
+	id := mux.Vars(r)["calendarId"]
+	if id == "primary" {
+		id = email
+	}
+	c := s.calendars[strings.ToLower(id)]
+	if c == nil || !strings.EqualFold(c.owner, email) {
+		writeError(w, http.StatusNotFound, "notFound", "Not Found")
+		return nil, false
+	}
+	return c, true
+}
+
+// store saves event as the latest change. The caller holds s.mu.
+func (s *Server) store(c *calendarData, event *calendar.Event) {
+	s.seq++
+	event.Etag = `"` + strconv.FormatInt(s.seq, 10) + `"`
+	event.Updated = time.Now().UTC().Format(time.RFC3339Nano)
+	c.events[event.Id] = &storedEvent{event: event, seq: s.seq}
+}
+
+// find returns the event with id and whether it is stored; occurrences of
+// a series that weren't changed are made up from the series.
+func (c *calendarData) find(id string) (*calendar.Event, bool) {
+	if stored, ok := c.events[id]; ok {
+		return stored.event, true
+	}
+	i := strings.LastIndex(id, "_")
+	if i < 0 {
+		return nil, false
+	}
+	master, ok := c.events[id[:i]]
+	if !ok || len(master.event.Recurrence) == 0 {
+		return nil, false
+	}
+	original, err := parseInstanceTime(id[i+1:])
+	if err != nil {
+		return nil, false
+	}
+	for _, instance := range expand(master.event, original.Add(-time.Nanosecond), original.Add(time.Second)) {
+		if instance.Id == id {
+			return instance, false
+		}
+	}
+	return nil, false
+}
+
+// hasSeries reports whether event is an exception of a series stored in c.
+func (c *calendarData) hasSeries(event *calendar.Event) bool {
+	_, ok := c.events[event.RecurringEventId]
+	return event.RecurringEventId != "" && ok
+}
+
+// instances returns the occurrences of master within [timeMin, timeMax),
+// with the stored exceptions in place of the ones they changed.
+func (c *calendarData) instances(master *calendar.Event, timeMin, timeMax time.Time) []*calendar.Event {
+	var items []*calendar.Event
+	exceptions := make(map[string]bool)
+	for _, stored := range c.events {
+		if stored.event.RecurringEventId == master.Id {
+			exceptions[stored.event.Id] = true
+			if overlaps(stored.event, timeMin, timeMax) {
+				items = append(items, stored.event)
+			}
+		}
+	}
+	for _, instance := range expand(master, timeMin, timeMax) {
+		if !exceptions[instance.Id] && overlaps(instance, timeMin, timeMax) {
+			items = append(items, instance)
+		}
+	}
+	return items
+}
+
+// applyConference creates a Meet conference when the request asks for one
+// and the client opted in with conferenceDataVersion=1. Without it the
+// conference stays as it was.
+func applyConference(r *http.Request, event *calendar.Event, current *calendar.ConferenceData) {
+	if r.URL.Query().Get("conferenceDataVersion") != "1" {
+		event.ConferenceData = current
+	}
+	data := event.ConferenceData
+	if data == nil || data.CreateRequest == nil || data.ConferenceId != "" {
+		return
+	}
+	id := randomID(5)
+	code := id[:3] + "-" + id[3:7] + "-" + id[7:]
+	data.ConferenceId = code
+	data.ConferenceSolution = &calendar.ConferenceSolution{
+		Key:  &calendar.ConferenceSolutionKey{Type: "hangoutsMeet"},
+		Name: "Google Meet",
+	}
+	data.EntryPoints = []*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://meet.google.com/" + code, Label: "meet.google.com/" + code}}
+	data.CreateRequest.Status = &calendar.ConferenceRequestStatus{StatusCode: "success"}
+	event.HangoutLink = "https://meet.google.com/" + code
+}
+
+// normalizeAttendees fills in what Google adds to attendees.
+func normalizeAttendees(event *calendar.Event, owner string) {
+	for _, a := range event.Attendees {
+		if a.ResponseStatus == "" {
+			a.ResponseStatus = "needsAction"
+		}
+		a.Self = strings.EqualFold(a.Email, owner)
+		a.Organizer = strings.EqualFold(a.Email, event.Organizer.Email)
+	}
+}
+
+// validTimes checks the start and end of event, answering 400 if they are
+// missing or out of order.
+func validTimes(w http.ResponseWriter, event *calendar.Event) bool {
+	start, end, err := eventSpanErr(event)
+	switch {
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "required", "Missing or invalid time.")
+		return false
+	case end.Before(start):
+		writeError(w, http.StatusBadRequest, "timeRangeEmpty", "The specified time range is empty.")
+		return false
+	}
+	return true
+}
+
+// timeBounds parses optional RFC 3339 bounds; missing ones are open.
+func timeBounds(w http.ResponseWriter, minValue, maxValue string) (time.Time, time.Time, bool) {
+	timeMin, timeMax := time.Time{}, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+	var err error
+	if minValue != "" {
+		if timeMin, err = time.Parse(time.RFC3339, minValue); err != nil {
+			writeError(w, http.StatusBadRequest, "badRequest", "Bad Request")
+			return timeMin, timeMax, false
+		}
+	}
+	if maxValue != "" {
+		if timeMax, err = time.Parse(time.RFC3339, maxValue); err != nil {
+			writeError(w, http.StatusBadRequest, "badRequest", "Bad Request")
+			return timeMin, timeMax, false
+		}
+	}
+	return timeMin, timeMax, true
+}
+
+// filterEvents drops cancelled events unless showDeleted, and events that
+// don't match q or iCalUID. The rest is sorted by start.
+func filterEvents(events []*calendar.Event, showDeleted bool, q, iCalUID string) []*calendar.Event {
+	q = strings.ToLower(q)
+	kept := []*calendar.Event{}
+	for _, event := range events {
+		if event.Status == "cancelled" && !showDeleted {
+			continue
+		}
+		if iCalUID != "" && event.ICalUID != iCalUID {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(event.Summary+"\n"+event.Description+"\n"+event.Location), q) {
+			continue
+		}
+		kept = append(kept, event)
+	}
+	sortEvents(kept)
+	return kept
+}
+
+// paginate cuts a page out of events; page tokens are offsets.
+func paginate(w http.ResponseWriter, events []*calendar.Event, maxValue, token string) ([]*calendar.Event, string, bool) {
+	size, offset := 250, 0
+	if maxValue != "" {
+		n, err := strconv.Atoi(maxValue)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "invalid", "Invalid value for maxResults")
+			return nil, "", false
+		}
+		size = min(n, 2500)
+	}
+	if token != "" {
+		n, err := strconv.Atoi(token)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "invalid", "Invalid page token")
+			return nil, "", false
+		}
+		offset = n
+	}
+	if events == nil {
+		events = []*calendar.Event{}
+	}
+	if offset >= len(events) {
+		return []*calendar.Event{}, "", true
+	}
+	end := min(offset+size, len(events))
+	if end < len(events) {
+		return events[offset:end], strconv.Itoa(end), true
+	}
+	return events[offset:end], "", true
+}
+
+// validEventID reports whether id is base32hex, as Google requires.
+func validEventID(id string) bool {
+	if len(id) < 5 || len(id) > 1024 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'v') {
+			return false
+		}
+	}
+	return true
+}
+
+func sortEvents(events []*calendar.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		a, _, _ := eventSpan(events[i])
+		b, _, _ := eventSpan(events[j])
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+		return events[i].Id < events[j].Id
+	})
+}
+
+func clone(event *calendar.Event) *calendar.Event {
+	data, _ := json.Marshal(event)
+	var copied calendar.Event
+	json.Unmarshal(data, &copied)
+	return &copied
+}
+
+// writeError answers with an error in the format of Google APIs, which the
+// client library turns into a *googleapi.Error.
+func writeError(w http.ResponseWriter, status int, reason, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{
+			"code":    status,
+			"message": message,
+			"errors":  []map[string]string{{"domain": "global", "reason": reason, "message": message}},
+		},
+	})
+}
@@ -0,0 +1,357 @@
+// internal/fakegoogle/fakegoogle.go
+package fakegoogle
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"google-calendar-api/internal/config"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/gorilla/mux"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+)
+
+// Server is an in-process stand-in for the parts of Google the API talks
+// to: the OAuth 2.0 endpoints, the OpenID Connect issuer with its JWKS and
+// Calendar API v3. Everything lives in memory, so tests can run the whole
+// login and calendar flow without a network. Point a config at it with
+// Configure.
+type Server struct {
+	URL          string // Base URL, also the issuer of the ID tokens
+	ClientID     string // The only OAuth client the server accepts
+	ClientSecret string
+
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	keyID  string
+
+	mu        sync.Mutex
+	users     []*User
+	codes     map[string]string // Authorization code -> email
+	access    map[string]string // Access token -> email
+	refresh   map[string]string // Refresh token -> email
+	calendars map[string]*calendarData
+	channels  map[string]*calendar.Channel
+	seq       int64 // Counts changes; the current value is the sync token
+}
+
+// User is a Google account on the server.
+type User struct {
+	Subject string // The "sub" claim; made up when empty
+	Email   string
+	Name    string
+	Picture string
+}
+
+// NewServer starts a server with no users. Close it when done.
+func NewServer() *Server {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(fmt.Sprintf("fakegoogle: failed to generate signing key: %v", err))
+	}
+	s := &Server{
+		ClientID:     "fake-client.apps.googleusercontent.com",
+		ClientSecret: "fake-client-secret",
+		key:          key,
+		keyID:        randomID(8),
+		codes:        make(map[string]string),
+		access:       make(map[string]string),
+		refresh:      make(map[string]string),
+		calendars:    make(map[string]*calendarData),
+		channels:     make(map[string]*calendar.Channel),
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/.well-known/openid-configuration", s.discovery).Methods("GET")
+	router.HandleFunc("/oauth2/v3/certs", s.jwks).Methods("GET")
+	router.HandleFunc("/o/oauth2/v2/auth", s.authorize).Methods("GET")
+	router.HandleFunc("/token", s.token).Methods("POST")
+	s.calendarRoutes(router.PathPrefix("/calendar/v3").Subrouter())
+
+	s.server = httptest.NewServer(router)
+	s.URL = s.server.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Configure points the Google settings of cfg at the server and makes it
+// use the server's OAuth client. Scopes and the redirect URL are kept.
+func (s *Server) Configure(cfg *config.Config) {
+	cfg.GoogleClientID = s.ClientID
+	cfg.GoogleClientSecret = s.ClientSecret
+	cfg.GoogleAuthURL = s.URL + "/o/oauth2/v2/auth"
+	cfg.GoogleTokenURL = s.URL + "/token"
+	cfg.GoogleIssuerURL = s.URL
+	cfg.GoogleCalendarURL = s.URL + "/calendar/v3/"
+
+	scopes := []string{"openid", "email", "profile", calendar.CalendarScope, calendar.CalendarEventsScope}
+	if cfg.OAuthConfig != nil {
+		scopes = cfg.OAuthConfig.Scopes
+	}
+	cfg.OAuthConfig = &oauth2.Config{
+		ClientID:     cfg.GoogleClientID,
+		ClientSecret: cfg.GoogleClientSecret,
+		RedirectURL:  cfg.GoogleRedirectURL,
+		Scopes:       scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:   cfg.GoogleAuthURL,
+			TokenURL:  cfg.GoogleTokenURL,
+			AuthStyle: oauth2.AuthStyleInParams,
+		},
+	}
+}
+
+// AddUser creates an account with an empty primary calendar and returns it
+// with the blanks filled in. The first user is the one who logs in unless
+// the authorization request has a login_hint.
+func (s *Server) AddUser(user User) User {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.Subject == "" {
+		user.Subject = fmt.Sprintf("1%020d", len(s.users)+1)
+	}
+	if user.Name == "" {
+		user.Name, _, _ = strings.Cut(user.Email, "@")
+	}
+	s.users = append(s.users, &user)
+	s.addCalendar(user.Email, user.Email, user.Email, true)
+	return user
+}
+
+// AddCalendar gives the user with email another calendar.
+func (s *Server) AddCalendar(email, calendarID, summary string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addCalendar(email, calendarID, summary, false)
+}
+
+// Token issues tokens for the user with email as a completed login would,
+// with the ID token in the "id_token" extra.
+func (s *Server) Token(email string) (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user := s.user(email)
+	if user == nil {
+		return nil, fmt.Errorf("fakegoogle: no user %s", email)
+	}
+	body, err := s.issue(user, true)
+	if err != nil {
+		return nil, err
+	}
+	token := &oauth2.Token{
+		AccessToken:  body["access_token"].(string),
+		TokenType:    "Bearer",
+		RefreshToken: body["refresh_token"].(string),
+		Expiry:       time.Now().Add(time.Hour),
+	}
+	return token.WithExtra(map[string]any{"id_token": body["id_token"]}), nil
+}
+
+// ExpireAccessTokens makes Google reject the access tokens of the user, so
+// clients have to refresh them.
+func (s *Server) ExpireAccessTokens(email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleteTokensOf(s.access, email)
+}
+
+// RevokeTokens revokes all tokens of the user, as when they remove the app's
+// access. Only a new login helps after that.
+func (s *Server) RevokeTokens(email string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deleteTokensOf(s.access, email)
+	deleteTokensOf(s.refresh, email)
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.URL,
+		"authorization_endpoint":                s.URL + "/o/oauth2/v2/auth",
+		"token_endpoint":                        s.URL + "/token",
+		"jwks_uri":                              s.URL + "/oauth2/v3/certs",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "profile"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	encode := base64.RawURLEncoding.EncodeToString
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": s.keyID,
+			"n":   encode(s.key.N.Bytes()),
+			"e":   encode(big.NewInt(int64(s.key.E)).Bytes()),
+		}},
+	})
+}
+
+// authorize logs the user in right away and sends them back with a code,
+// as Google does once they have consented before.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURL, err := url.Parse(query.Get("redirect_uri"))
+	if query.Get("client_id") != s.ClientID || err != nil || !redirectURL.IsAbs() {
+		http.Error(w, "invalid_request: unknown client or redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	var user *User
+	if hint := query.Get("login_hint"); hint != "" {
+		user = s.user(hint)
+	} else if len(s.users) > 0 {
+		user = s.users[0]
+	}
+	code := randomID(16)
+	if user != nil {
+		s.codes[code] = user.Email
+	}
+	s.mu.Unlock()
+
+	params := redirectURL.Query()
+	if user == nil {
+		params.Set("error", "access_denied")
+	} else {
+		params.Set("code", code)
+	}
+	params.Set("state", query.Get("state"))
+	redirectURL.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != s.ClientID || clientSecret != s.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var email string
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		email = s.codes[r.PostForm.Get("code")]
+		delete(s.codes, r.PostForm.Get("code")) // Codes work once
+	case "refresh_token":
+		email = s.refresh[r.PostForm.Get("refresh_token")]
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+	user := s.user(email)
+	if user == nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant", "error_description": "Bad Request"})
+		return
+	}
+
+	body, err := s.issue(user, r.PostForm.Get("grant_type") == "authorization_code")
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, body)
+}
+
+// issue creates an access token and ID token for user, and a refresh token
+// on login. The caller holds s.mu.
+func (s *Server) issue(user *User, withRefresh bool) (map[string]any, error) {
+	now := time.Now()
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            s.URL,
+		"aud":            s.ClientID,
+		"azp":            s.ClientID,
+		"sub":            user.Subject,
+		"email":          user.Email,
+		"email_verified": true,
+		"name":           user.Name,
+		"picture":        user.Picture,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+	})
+	idToken.Header["kid"] = s.keyID
+	signed, err := idToken.SignedString(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("fakegoogle: failed to sign ID token: %w", err)
+	}
+
+	accessToken := "ya29." + randomID(16)
+	s.access[accessToken] = user.Email
+	body := map[string]any{
+		"access_token": accessToken,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+		"scope":        "openid email profile " + calendar.CalendarScope,
+	}
+	if withRefresh {
+		refreshToken := "1//" + randomID(16)
+		s.refresh[refreshToken] = user.Email
+		body["refresh_token"] = refreshToken
+	}
+	return body, nil
+}
+
+// user returns the user with email, or nil. The caller holds s.mu.
+func (s *Server) user(email string) *User {
+	for _, u := range s.users {
+		if strings.EqualFold(u.Email, email) {
+			return u
+		}
+	}
+	return nil
+}
+
+func deleteTokensOf(tokens map[string]string, email string) {
+	for token, owner := range tokens {
+		if strings.EqualFold(owner, email) {
+			delete(tokens, token)
+		}
+	}
+}
+
+func randomID(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("fakegoogle: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
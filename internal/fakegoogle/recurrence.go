@@ -0,0 +1,265 @@
+// internal/fakegoogle/recurrence.go
+package fakegoogle
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// maxSteps bounds the expansion of a series, so endless ones stop.
+const maxSteps = 20000
+
+// recurrenceRule is the part of an RRULE the fake understands: FREQ DAILY,
+// WEEKLY, MONTHLY or YEARLY with INTERVAL, COUNT, UNTIL and, for weekly
+// rules, BYDAY. Other BY parts are ignored.
+type recurrenceRule struct {
+	freq     string
+	interval int
+	count    int
+	until    time.Time // Zero when open
+	byDay    map[time.Weekday]bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// expand returns the occurrences of master that start before timeMax and
+// end after timeMin, as Google makes them up: "<series ID>_<start>" IDs, the
+// series' fields and the occurrence's times.
+func expand(master *calendar.Event, timeMin, timeMax time.Time) []*calendar.Event {
+	start, end, allDay := eventSpan(master)
+	loc := start.Location()
+	if !allDay && master.Start.TimeZone != "" {
+		if l, err := time.LoadLocation(master.Start.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	start = start.In(loc)
+	duration := end.Sub(start)
+	rule, excluded := parseRecurrence(master.Recurrence, loc)
+
+	var instances []*calendar.Event
+	generated := 0
+	for step := 0; step < maxSteps; step++ {
+		occurrence, ok := rule.occurrence(start, step)
+		if !ok {
+			continue
+		}
+		if !rule.until.IsZero() && occurrence.After(rule.until) || !occurrence.Before(timeMax) {
+			break
+		}
+		if rule.count > 0 && generated == rule.count {
+			break
+		}
+		generated++
+		if excluded[occurrence.Unix()] || !occurrence.Add(duration).After(timeMin) {
+			continue
+		}
+
+		instance := *master
+		instance.Id = master.Id + "_" + formatInstanceTime(occurrence, allDay)
+		instance.Recurrence = nil
+		instance.RecurringEventId = master.Id
+		instance.Start = eventDateTime(occurrence, allDay, master.Start.TimeZone)
+		instance.End = eventDateTime(occurrence.Add(duration), allDay, master.End.TimeZone)
+		instance.OriginalStartTime = eventDateTime(occurrence, allDay, master.Start.TimeZone)
+		instances = append(instances, &instance)
+		if rule.freq == "" {
+			break // No rule: the first occurrence is all there is
+		}
+	}
+	return instances
+}
+
+// occurrence returns the step-th candidate after start and whether the rule
+// keeps it.
+func (r recurrenceRule) occurrence(start time.Time, step int) (time.Time, bool) {
+	switch r.freq {
+	case "DAILY":
+		return start.AddDate(0, 0, step*r.interval), true
+	case "WEEKLY":
+		if len(r.byDay) == 0 {
+			return start.AddDate(0, 0, 7*step*r.interval), true
+		}
+		day := start.AddDate(0, 0, step)
+		week := (int(start.Weekday()+6)%7 + step) / 7 // Weeks start on Monday
+		return day, week%r.interval == 0 && r.byDay[day.Weekday()]
+	case "MONTHLY":
+		t := start.AddDate(0, step*r.interval, 0)
+		return t, t.Day() == start.Day() // The 31st only in months that have one
+	case "YEARLY":
+		t := start.AddDate(step*r.interval, 0, 0)
+		return t, t.Day() == start.Day()
+	default:
+		return start, step == 0
+	}
+}
+
+// parseRecurrence reads the first RRULE and the EXDATEs, given as Unix
+// times, of a series in loc.
+func parseRecurrence(lines []string, loc *time.Location) (recurrenceRule, map[int64]bool) {
+	rule := recurrenceRule{interval: 1}
+	excluded := make(map[int64]bool)
+	ruleSeen := false
+	for _, line := range lines {
+		name, value, _ := strings.Cut(line, ":")
+		name, params, _ := strings.Cut(name, ";")
+		switch strings.ToUpper(name) {
+		case "RRULE":
+			if ruleSeen {
+				continue
+			}
+			ruleSeen = true
+			for _, part := range strings.Split(value, ";") {
+				key, v, _ := strings.Cut(part, "=")
+				switch strings.ToUpper(key) {
+				case "FREQ":
+					rule.freq = strings.ToUpper(v)
+				case "INTERVAL":
+					if n, err := strconv.Atoi(v); err == nil && n > 0 {
+						rule.interval = n
+					}
+				case "COUNT":
+					rule.count, _ = strconv.Atoi(v)
+				case "UNTIL":
+					if t, err := parseRecurrenceTime(v, "", loc); err == nil {
+						rule.until = t
+						if len(v) == len("20060102") {
+							rule.until = t.AddDate(0, 0, 1).Add(-time.Nanosecond) // The whole day
+						}
+					}
+				case "BYDAY":
+					rule.byDay = make(map[time.Weekday]bool)
+					for _, day := range strings.Split(v, ",") {
+						if len(day) >= 2 {
+							rule.byDay[weekdays[strings.ToUpper(day[len(day)-2:])]] = true
+						}
+					}
+				}
+			}
+		case "EXDATE":
+			tzid := ""
+			for _, param := range strings.Split(params, ";") {
+				if key, v, _ := strings.Cut(param, "="); strings.EqualFold(key, "TZID") {
+					tzid = v
+				}
+			}
+			for _, v := range strings.Split(value, ",") {
+				if t, err := parseRecurrenceTime(v, tzid, loc); err == nil {
+					excluded[t.Unix()] = true
+				}
+			}
+		}
+	}
+	return rule, excluded
+}
+
+// parseRecurrenceTime reads an iCalendar DATE or DATE-TIME value.
+func parseRecurrenceTime(value, tzid string, loc *time.Location) (time.Time, error) {
+	if tzid != "" {
+		l, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case len(value) == len("20060102"):
+		return time.ParseInLocation("20060102", value, loc)
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+// eventSpan returns the start and end of event; zero times when they
+// can't be read. All-day dates are midnight UTC.
+func eventSpan(event *calendar.Event) (time.Time, time.Time, bool) {
+	start, end, _ := eventSpanErr(event)
+	return start, end, event.Start != nil && event.Start.Date != ""
+}
+
+func eventSpanErr(event *calendar.Event) (time.Time, time.Time, error) {
+	start, err := parseEventTime(event.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("start: %w", err)
+	}
+	end, err := parseEventTime(event.End)
+	if err != nil {
+		return start, time.Time{}, fmt.Errorf("end: %w", err)
+	}
+	return start, end, nil
+}
+
+func parseEventTime(dt *calendar.EventDateTime) (time.Time, error) {
+	switch {
+	case dt == nil:
+		return time.Time{}, fmt.Errorf("missing")
+	case dt.Date != "":
+		return time.Parse("2006-01-02", dt.Date)
+	case dt.DateTime == "":
+		return time.Time{}, fmt.Errorf("missing")
+	}
+	if t, err := time.Parse(time.RFC3339, dt.DateTime); err == nil {
+		return t, nil
+	}
+	loc, err := time.LoadLocation(dt.TimeZone) // A local time needs its zone
+	if err != nil || dt.TimeZone == "" {
+		return time.Time{}, fmt.Errorf("invalid time %q", dt.DateTime)
+	}
+	return time.ParseInLocation("2006-01-02T15:04:05", dt.DateTime, loc)
+}
+
+func eventDateTime(t time.Time, allDay bool, zone string) *calendar.EventDateTime {
+	if allDay {
+		return &calendar.EventDateTime{Date: t.Format("2006-01-02")}
+	}
+	return &calendar.EventDateTime{DateTime: t.Format(time.RFC3339), TimeZone: zone}
+}
+
+// formatInstanceTime writes the original start as the suffix of an
+// occurrence ID.
+func formatInstanceTime(t time.Time, allDay bool) string {
+	if allDay {
+		return t.Format("20060102")
+	}
+	return t.UTC().Format("20060102T150405Z")
+}
+
+func parseInstanceTime(value string) (time.Time, error) {
+	if len(value) == len("20060102") {
+		return time.Parse("20060102", value)
+	}
+	return time.Parse("20060102T150405Z", value)
+}
+
+// overlaps reports whether event lies within [timeMin, timeMax). Events
+// without length count when they start inside.
+func overlaps(event *calendar.Event, timeMin, timeMax time.Time) bool {
+	start, end, _ := eventSpan(event)
+	if !start.Before(timeMax) {
+		return false
+	}
+	return end.After(timeMin) || (end.Equal(start) && !start.Before(timeMin))
+}
+
+func later(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func earlier(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,98 @@
+// internal/handler/account.go
+package handler
+
+import (
+	"encoding/json"
+	"google-calendar-api/internal/service"
+	"log"
+	"net/http"
+)
+
+// SetProviderRequest represents the request body for choosing where new
+// events go.
+type SetProviderRequest struct {
+	Provider string `json:"provider"` // "google", "microsoft" or "caldav"
+}
+
+// LinkCalDAVRequest represents the request body for linking a CalDAV account.
+type LinkCalDAVRequest struct {
+	ServerURL string `json:"server_url"` // The server, principal or calendar home URL
+	Username  string `json:"username"`
+	Password  string `json:"password"` // Preferably an app password
+}
+
+// ListProviders shows the linked calendar providers and the active one
+// (GET /api/providers).
+func (h *Handler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	providers, err := h.accountService.ListProviders(r.Context(), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list calendar providers: %v", err)
+		writeEventError(w, err, "Failed to list calendar providers")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(providers)
+}
+
+// SetActiveProvider picks the linked provider new events are created in
+// (PUT /api/providers/active).
+func (h *Handler) SetActiveProvider(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SetProviderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Provider == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.accountService.SetProvider(r.Context(), userInfo.Email, req.Provider); err != nil {
+		log.Printf("[ERROR] Failed to set calendar provider: %v", err)
+		writeEventError(w, err, "Failed to set calendar provider")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Calendar provider updated", "active": req.Provider})
+}
+
+// LinkCalDAV links the CalDAV account at a server to the user, replacing the
+// one linked before (PUT /api/providers/caldav).
+func (h *Handler) LinkCalDAV(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req LinkCalDAVRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ServerURL == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	linked, err := h.accountService.LinkCalDAV(r.Context(), service.CalDAVAccountInput{
+		UserEmail: userInfo.Email,
+		ServerURL: req.ServerURL,
+		Username:  req.Username,
+		Password:  req.Password,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to link CalDAV account: %v", err)
+		writeEventError(w, err, "Failed to link CalDAV account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(linked)
+}
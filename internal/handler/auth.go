@@ -2,6 +2,8 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
@@ -22,9 +24,11 @@ func (h *Handler) LoginPage(w http.ResponseWriter, r *http.Request) {
 	}
 
 	data := struct {
-		Message string
+		Message   string
+		Microsoft bool
 	}{
-		Message: "Please log in to access your dashboard.",
+		Message:   "Please log in to access your dashboard.",
+		Microsoft: h.config.MicrosoftOAuthConfig != nil,
 	}
 
 	if err := tmpl.Execute(w, data); err != nil {
@@ -116,10 +120,10 @@ func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Call the AuthService to handle user creation/update and JWT generation
-	jwtToken, err := h.authService.HandleGoogleCallback(ctx, token)
+	jwtToken, err := h.authService.HandleGoogleCallback(ctx, token, h.currentUserEmail(r))
 	if err != nil {
 		log.Printf("❌ Error handling Google callback: %v", err)
-		http.Error(w, "Authentication failed", http.StatusInternalServerError) // Generic error
+		writeAuthError(w, err)
 		return
 	}
 
@@ -136,6 +140,132 @@ func (h *Handler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/api/dashboard", http.StatusSeeOther)
 }
 
+// MicrosoftLogin generates and stores a state, then redirects to Microsoft's
+// OAuth2 endpoint. Logged-in users link the account; others log in with it.
+func (h *Handler) MicrosoftLogin(w http.ResponseWriter, r *http.Request) {
+	if h.config.MicrosoftOAuthConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	state := uuid.New().String()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "msoauthstate",
+		Value:    state,
+		HttpOnly: true,
+		Secure:   h.config.Env == "production",
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+	url := h.config.MicrosoftOAuthConfig.AuthCodeURL(state)
+
+	http.Redirect(w, r, url, http.StatusSeeOther)
+}
+
+// MicrosoftCallback handles the OAuth2 callback from Microsoft.
+func (h *Handler) MicrosoftCallback(w http.ResponseWriter, r *http.Request) {
+	if h.config.MicrosoftOAuthConfig == nil {
+		http.NotFound(w, r)
+		return
+	}
+	ctx := r.Context()
+
+	stateCookie, err := r.Cookie("msoauthstate")
+	if err != nil {
+		log.Println("❌ State cookie not found:", err)
+		http.Error(w, "State cookie not found", http.StatusBadRequest)
+		return
+	}
+	receivedState := r.URL.Query().Get("state")
+	if receivedState == "" || receivedState != stateCookie.Value {
+		log.Println("❌ Invalid state parameter")
+		http.Error(w, "Invalid state parameter", http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     "msoauthstate",
+		Value:    "",
+		Expires:  time.Unix(0, 0),
+		HttpOnly: true,
+		Secure:   h.config.Env == "production",
+		SameSite: http.SameSiteLaxMode,
+		Path:     "/",
+	})
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		log.Printf("❌ No code in Microsoft callback: %s", r.URL.Query().Get("error_description"))
+		http.Error(w, "Code not found", http.StatusBadRequest)
+		return
+	}
+
+	token, err := h.config.MicrosoftOAuthConfig.Exchange(ctx, code)
+	if err != nil {
+		log.Printf("❌ error exchanging Microsoft token: %v", err)
+		http.Error(w, fmt.Sprintf("Token Exchange Failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+	jwtToken, err := h.authService.HandleMicrosoftCallback(ctx, token, h.currentUserEmail(r))
+	if err != nil {
+		log.Printf("❌ Error handling Microsoft callback: %v", err)
+		writeAuthError(w, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     "token",
+		Value:    jwtToken,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   h.config.Env == "production",
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, "/api/dashboard", http.StatusSeeOther)
+}
+
+// currentUserEmail returns the email of the user logged in with the token
+// cookie, or "" when there is none. A login callback links the account to
+// this user.
+func (h *Handler) currentUserEmail(r *http.Request) string {
+	cookie, err := r.Cookie("token")
+	if err != nil {
+		return ""
+	}
+	ctx, err := h.validateAndSetContext(r.Context(), cookie.Value)
+	if err != nil {
+		return ""
+	}
+	userInfo, _ := ctx.Value(userKey).(service.UserInfo)
+	return userInfo.Email
+}
+
+// writeAuthError answers a failed login callback.
+func writeAuthError(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrAccountConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if errors.Is(err, service.ErrAuthUnavailable) {
+		w.Header().Set("Retry-After", "30")
+		http.Error(w, "Login is temporarily unavailable, please try again shortly", http.StatusServiceUnavailable)
+		return
+	}
+	http.Error(w, "Authentication failed", http.StatusInternalServerError) // Generic error
+}
+
+// Ready is the readiness probe: 503 until logins can be verified, so load
+// balancers hold traffic back while Google's OIDC issuer is unreachable.
+func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := h.authService.Ready(r.Context()); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ready"})
+}
+
 func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Clear the token cookie
 	http.SetCookie(w, &http.Cookie{
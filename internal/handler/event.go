@@ -3,22 +3,31 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"google-calendar-api/internal/service"
+
+	"github.com/gorilla/mux"
 )
 
 // CreateEventRequest represents the request body for creating an event.
 type CreateEventRequest struct {
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	StartTime   string   `json:"start_time"`
-	EndTime     string   `json:"end_time"`
-	Attendees   []string `json:"attendees"` // Use a slice of strings
+	Title         string   `json:"title"`
+	Description   string   `json:"description"`
+	StartTime     string   `json:"start_time"`
+	EndTime       string   `json:"end_time"`
+	Attendees     []string `json:"attendees"`      // Use a slice of strings
+	AllDay        bool     `json:"all_day"`        // start_time/end_time are dates ("2006-01-02"); end_time is exclusive
+	Recurrence    []string `json:"recurrence"`     // RRULE/EXDATE/RDATE lines for recurring events
+	TimeZone      string   `json:"time_zone"`      // IANA zone, e.g. "Europe/Berlin"; required for recurring events
+	CalendarID    string   `json:"calendar_id"`    // Defaults to the primary calendar; ignored on update
+	AddConference bool     `json:"add_conference"` // Attach a Google Meet link; ignored on update
 }
 
 // CreateEvent handles the creation of a new Google Calendar event.
@@ -37,12 +46,12 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	// Validate start and end times
-	startTime, err := time.Parse(time.RFC3339, req.StartTime)
+	startTime, err := parseEventTime(req.StartTime, req.TimeZone, req.AllDay)
 	if err != nil {
 		http.Error(w, "Invalid start time format", http.StatusBadRequest)
 		return
 	}
-	endTime, err := time.Parse(time.RFC3339, req.EndTime)
+	endTime, err := parseEventTime(req.EndTime, req.TimeZone, req.AllDay)
 	if err != nil {
 		http.Error(w, "Invalid end time format", http.StatusBadRequest)
 		return
@@ -51,6 +60,11 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "start time must be before end time", http.StatusBadRequest)
 		return
 	}
+	if len(req.Recurrence) > 0 && startTime.Location().String() == "" {
+		// Google needs a named zone to expand the rule across DST changes.
+		http.Error(w, "time_zone is required for recurring events", http.StatusBadRequest)
+		return
+	}
 
 	// Validate attendees (basic email format check)
 	for _, email := range req.Attendees {
@@ -61,47 +75,362 @@ func (h *Handler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create event using the service layer
-	eventID, err := h.eventService.CreateEvent(r.Context(), service.CreateEventInput{
-		Title:       req.Title,
-		Description: req.Description,
-		StartTime:   startTime,
-		EndTime:     endTime,
-		Attendees:   req.Attendees,
-		CreatedBy:   userInfo.Email, // Use email from the validated token
+	op, err := h.eventService.CreateEvent(r.Context(), service.CreateEventInput{
+		Title:         req.Title,
+		Description:   req.Description,
+		StartTime:     startTime,
+		EndTime:       endTime,
+		AllDay:        req.AllDay,
+		Attendees:     req.Attendees,
+		Recurrence:    req.Recurrence,
+		CalendarID:    req.CalendarID,
+		AddConference: req.AddConference,
+		CreatedBy:     userInfo.Email, // Use email from the validated token
 	})
 	if err != nil {
 		log.Printf("[ERROR] Failed to create event: %v", err) // More detailed logging
+		if op != nil && op.Status == "failed" {
+			// The operation is recorded; say which one failed and why.
+			status := http.StatusBadGateway // Google refused the event
+			switch {
+			case errors.Is(err, service.ErrCalendarNotFound):
+				status = http.StatusNotFound
+			case errors.Is(err, service.ErrReauthRequired):
+				status = http.StatusUnauthorized
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Location", fmt.Sprintf("/api/operations/%d", op.OperationID))
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]interface{}{"message": "Event creation failed", "status": op.Status, "operation_id": op.OperationID, "event_id": op.EventID, "last_error": op.Error})
+			return
+		}
 		//Handle specific errors (like token refresh failures) if possible
-		http.Error(w, "Failed to create event", http.StatusInternalServerError)
+		writeEventError(w, err, "Failed to create event")
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	if op.Status == "pending" {
+		// Google couldn't be reached; the outbox keeps trying.
+		w.Header().Set("Location", fmt.Sprintf("/api/operations/%d", op.OperationID))
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{"message": "Event creation is pending", "status": op.Status, "operation_id": op.OperationID, "event_id": op.EventID})
+		return
+	}
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]string{"message": "Event created successfully", "event_id": eventID})
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": "Event created successfully", "status": op.Status, "operation_id": op.OperationID, "event_id": op.EventID, "event": op.Event})
 }
 
-// ListEvents fetches upcoming meetings from both Google Calendar and the database.
+// ListEvents fetches meetings from Google Calendar. Query parameters:
+// time_min and time_max (RFC3339), q, calendar_id, max_results and cursor.
 func (h *Handler) ListEvents(w http.ResponseWriter, r *http.Request) {
 
 	//Get User Info
 	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
 	if !ok {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
 
-	events, err := h.eventService.ListEvents(r.Context(), userInfo.Email) //Pass Email
+	query := r.URL.Query()
+	input := service.ListEventsInput{
+		UserEmail:  userInfo.Email, //Pass Email
+		CalendarID: query.Get("calendar_id"),
+		Query:      query.Get("q"),
+		Cursor:     query.Get("cursor"),
+	}
+	if v := query.Get("time_min"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid time_min format", http.StatusBadRequest)
+			return
+		}
+		input.TimeMin = t
+	}
+	if v := query.Get("time_max"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid time_max format", http.StatusBadRequest)
+			return
+		}
+		input.TimeMax = t
+	}
+	if v := query.Get("max_results"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "max_results must be a positive number", http.StatusBadRequest)
+			return
+		}
+		input.MaxResults = n
+	}
+
+	page, err := h.eventService.ListEvents(r.Context(), input)
 	if err != nil {
 		log.Printf("[ERROR] Failed to list events: %v", err) // Log the actual error
-		http.Error(w, "Failed to list events", http.StatusInternalServerError)
+		writeEventError(w, err, "Failed to list events")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"events": events})
+	json.NewEncoder(w).Encode(page)
+}
+
+// GetEvent returns a single event, merging the stored meeting with the live
+// Google Calendar event and reporting any fields that have drifted.
+func (h *Handler) GetEvent(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := mux.Vars(r)["id"]
+
+	// calendar_id is only needed for events that are not stored locally.
+	detail, err := h.eventService.GetEvent(r.Context(), id, r.URL.Query().Get("calendar_id"), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get event %s: %v", id, err)
+		writeEventError(w, err, "Failed to get event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// UpdateEventRequest represents the request body for replacing an event.
+type UpdateEventRequest = CreateEventRequest
+
+// PatchEventRequest represents the request body for a partial update.
+// Fields left out of the JSON are not changed.
+type PatchEventRequest struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	StartTime   *string   `json:"start_time"`
+	EndTime     *string   `json:"end_time"`
+	Attendees   *[]string `json:"attendees"`
+	AllDay      *bool     `json:"all_day"` // Send true along with dates when moving an all-day event
+	Recurrence  *[]string `json:"recurrence"`
+	TimeZone    string    `json:"time_zone"` // Applies to start_time and end_time
+}
+
+// UpdateEvent replaces an existing event (PUT /api/events/{id}).
+// For recurring events the scope query parameter picks "instance", "following" or "all".
+func (h *Handler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	eventID := mux.Vars(r)["id"]
+
+	var req UpdateEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Println("[ERROR] Failed to decode request body:", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	startTime, err := parseEventTime(req.StartTime, req.TimeZone, req.AllDay)
+	if err != nil {
+		http.Error(w, "Invalid start time format", http.StatusBadRequest)
+		return
+	}
+	endTime, err := parseEventTime(req.EndTime, req.TimeZone, req.AllDay)
+	if err != nil {
+		http.Error(w, "Invalid end time format", http.StatusBadRequest)
+		return
+	}
+	if !startTime.Before(endTime) {
+		http.Error(w, "start time must be before end time", http.StatusBadRequest)
+		return
+	}
+	for _, email := range req.Attendees {
+		if !isValidEmail(email) {
+			http.Error(w, fmt.Sprintf("Invalid attendee email: %s", email), http.StatusBadRequest)
+			return
+		}
+	}
+
+	err = h.eventService.UpdateEvent(r.Context(), service.UpdateEventInput{
+		EventID:     eventID,
+		Title:       req.Title,
+		Description: req.Description,
+		StartTime:   startTime,
+		EndTime:     endTime,
+		AllDay:      req.AllDay,
+		Attendees:   req.Attendees,
+		Recurrence:  req.Recurrence,
+		Scope:       service.RecurrenceScope(r.URL.Query().Get("scope")),
+		UpdatedBy:   userInfo.Email,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to update event %s: %v", eventID, err)
+		writeEventError(w, err, "Failed to update event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Event updated successfully", "event_id": eventID})
+}
+
+// PatchEvent changes only the given fields of an event (PATCH /api/events/{id}).
+// The scope query parameter works as for UpdateEvent.
+func (h *Handler) PatchEvent(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	eventID := mux.Vars(r)["id"]
+
+	var req PatchEventRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Println("[ERROR] Failed to decode request body:", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	input := service.PatchEventInput{
+		EventID:     eventID,
+		Title:       req.Title,
+		Description: req.Description,
+		AllDay:      req.AllDay,
+		Attendees:   req.Attendees,
+		Recurrence:  req.Recurrence,
+		Scope:       service.RecurrenceScope(r.URL.Query().Get("scope")),
+		UpdatedBy:   userInfo.Email,
+	}
+	if req.StartTime != nil {
+		startTime, err := parseEventTime(*req.StartTime, req.TimeZone, req.AllDay != nil && *req.AllDay)
+		if err != nil {
+			http.Error(w, "Invalid start time format", http.StatusBadRequest)
+			return
+		}
+		input.StartTime = &startTime
+	}
+	if req.EndTime != nil {
+		endTime, err := parseEventTime(*req.EndTime, req.TimeZone, req.AllDay != nil && *req.AllDay)
+		if err != nil {
+			http.Error(w, "Invalid end time format", http.StatusBadRequest)
+			return
+		}
+		input.EndTime = &endTime
+	}
+	if input.StartTime != nil && input.EndTime != nil && !input.StartTime.Before(*input.EndTime) {
+		http.Error(w, "start time must be before end time", http.StatusBadRequest)
+		return
+	}
+	if req.Attendees != nil {
+		for _, email := range *req.Attendees {
+			if !isValidEmail(email) {
+				http.Error(w, fmt.Sprintf("Invalid attendee email: %s", email), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if err := h.eventService.PatchEvent(r.Context(), input); err != nil {
+		log.Printf("[ERROR] Failed to patch event %s: %v", eventID, err)
+		writeEventError(w, err, "Failed to update event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Event updated successfully", "event_id": eventID})
+}
+
+// DeleteEvent cancels an event in Google Calendar and removes it from the database.
+// The scope query parameter works as for UpdateEvent.
+func (h *Handler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	eventID := mux.Vars(r)["id"]
+
+	scope := service.RecurrenceScope(r.URL.Query().Get("scope"))
+	if err := h.eventService.DeleteEvent(r.Context(), eventID, userInfo.Email, scope); err != nil {
+		log.Printf("[ERROR] Failed to delete event %s: %v", eventID, err)
+		writeEventError(w, err, "Failed to delete event")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Event deleted successfully", "event_id": eventID})
+}
+
+// ListCalendars returns the calendars the user can see (GET /api/calendars).
+func (h *Handler) ListCalendars(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	calendars, err := h.eventService.ListCalendars(r.Context(), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list calendars: %v", err)
+		http.Error(w, "Failed to list calendars", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"calendars": calendars})
+}
+
+// GetOperation reports whether a recorded change reached Google
+// (GET /api/operations/{id}).
+func (h *Handler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid operation id", http.StatusBadRequest)
+		return
+	}
+
+	op, err := h.eventService.GetOperation(r.Context(), uint(id), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to get operation %d: %v", id, err)
+		writeEventError(w, err, "Failed to get operation")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(op)
 }
 
 // Helper Functions
 
+// parseEventTime parses an RFC3339 time and, when timeZone is set, moves it
+// into that zone so Google receives the zone name along with the time.
+// All-day values may be plain dates and are returned as midnight UTC.
+func parseEventTime(value, timeZone string, allDay bool) (time.Time, error) {
+	if allDay {
+		if d, err := time.Parse("2006-01-02", value); err == nil {
+			return d, nil
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil || timeZone == "" {
+		return t, err
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
+
 // isValidEmail performs a basic email format check.
 func isValidEmail(email string) bool {
 	// Basic check for @ and .
@@ -115,3 +444,37 @@ func isValidEmail(email string) bool {
 	}
 	return true
 }
+
+// writeEventError maps EventService errors to an HTTP status code.
+func writeEventError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrEventNotFound):
+		http.Error(w, "Event not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrReauthRequired):
+		http.Error(w, "Calendar authorization expired, please log in again", http.StatusUnauthorized)
+	case errors.Is(err, service.ErrCalendarNotFound):
+		http.Error(w, "Calendar not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrWebhookNotFound):
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrOperationNotFound):
+		http.Error(w, "Operation not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrFeedNotFound):
+		http.Error(w, "Feed not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrNotEventOwner):
+		http.Error(w, "Forbidden: event belongs to another user", http.StatusForbidden)
+	case errors.Is(err, service.ErrAccountConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrInvalidTime),
+		errors.Is(err, service.ErrInvalidCursor),
+		errors.Is(err, service.ErrInvalidRule),
+		errors.Is(err, service.ErrInvalidScope),
+		errors.Is(err, service.ErrInvalidWebhook),
+		errors.Is(err, service.ErrInvalidCalendar),
+		errors.Is(err, service.ErrProviderNotLinked),
+		errors.Is(err, service.ErrProviderUnsupported),
+		errors.Is(err, service.ErrInvalidAccount):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,152 @@
+// internal/handler/export.go
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"google-calendar-api/internal/service"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ExportEvents renders the user's meetings and Google events as an iCalendar
+// file (GET /api/events.ics). Query parameters: calendar_id, and time_min and
+// time_max (RFC3339), which default to the last 30 and the next 365 days.
+func (h *Handler) ExportEvents(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	input := service.ExportInput{
+		UserEmail:     userInfo.Email,
+		CalendarID:    query.Get("calendar_id"),
+		IncludeGoogle: true,
+	}
+	if v := query.Get("time_min"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid time_min format", http.StatusBadRequest)
+			return
+		}
+		input.TimeMin = t
+	}
+	if v := query.Get("time_max"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid time_max format", http.StatusBadRequest)
+			return
+		}
+		input.TimeMax = t
+	}
+
+	var buf bytes.Buffer // Rendered first so a failure can still get a proper status
+	if err := h.exportService.ExportICS(r.Context(), &buf, input); err != nil {
+		log.Printf("[ERROR] Failed to export events: %v", err)
+		writeEventError(w, err, "Failed to export events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="events.ics"`)
+	w.Write(buf.Bytes())
+}
+
+// CalendarFeed serves the iCalendar feed a secret token belongs to
+// (GET /feeds/{token}.ics). Calendar clients poll it without logging in.
+func (h *Handler) CalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := mux.Vars(r)["token"]
+
+	var buf bytes.Buffer
+	if err := h.exportService.ExportFeed(r.Context(), &buf, token); err != nil {
+		log.Printf("[ERROR] Failed to render calendar feed: %v", err)
+		writeEventError(w, err, "Failed to render calendar feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(buf.Bytes())
+}
+
+// GetFeed returns the URLs of the user's calendar feed (GET /api/feed), or 404
+// while the feed is off.
+func (h *Handler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := h.exportService.FeedToken(r.Context(), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to load feed: %v", err)
+		writeEventError(w, err, "Failed to load feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.feedURLs(r, token))
+}
+
+// RotateFeed turns the calendar feed on, or moves it to a new secret URL so the
+// old one stops working (POST /api/feed).
+func (h *Handler) RotateFeed(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	token, err := h.exportService.RotateFeedToken(r.Context(), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to create feed: %v", err)
+		writeEventError(w, err, "Failed to create feed")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(h.feedURLs(r, token))
+}
+
+// RevokeFeed turns the calendar feed off (DELETE /api/feed).
+func (h *Handler) RevokeFeed(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.exportService.RevokeFeedToken(r.Context(), userInfo.Email); err != nil {
+		log.Printf("[ERROR] Failed to revoke feed: %v", err)
+		writeEventError(w, err, "Failed to revoke feed")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// feedURLs returns the https and webcal links of a feed. Without PUBLIC_URL
+// the base is taken from the request.
+func (h *Handler) feedURLs(r *http.Request, token string) map[string]string {
+	base := h.config.PublicURL
+	if base == "" {
+		scheme := "http"
+		if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + r.Host
+	}
+	url := base + "/feeds/" + token + ".ics"
+	_, rest, found := strings.Cut(url, "://")
+	if !found {
+		rest = url
+	}
+	return map[string]string{"url": url, "webcal_url": "webcal://" + rest}
+}
@@ -0,0 +1,185 @@
+// internal/handler/freebusy.go
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"google-calendar-api/internal/service"
+)
+
+// maxFreeBusyCalendars caps how many people can be looked up in one request.
+const maxFreeBusyCalendars = 200
+
+// FreeBusyRequest represents the request body for a free/busy lookup.
+type FreeBusyRequest struct {
+	Attendees []string `json:"attendees"` // Emails or calendar IDs
+	TimeMin   string   `json:"time_min"`
+	TimeMax   string   `json:"time_max"`
+	TimeZone  string   `json:"time_zone"`
+}
+
+// FreeBusy returns the busy intervals of each attendee (POST /api/freebusy).
+func (h *Handler) FreeBusy(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req FreeBusyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Println("[ERROR] Failed to decode request body:", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(req.Attendees) == 0 {
+		http.Error(w, "attendees is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Attendees) > maxFreeBusyCalendars {
+		http.Error(w, fmt.Sprintf("at most %d attendees can be looked up at once", maxFreeBusyCalendars), http.StatusBadRequest)
+		return
+	}
+	timeMin, err := time.Parse(time.RFC3339, req.TimeMin)
+	if err != nil {
+		http.Error(w, "Invalid time_min format", http.StatusBadRequest)
+		return
+	}
+	timeMax, err := time.Parse(time.RFC3339, req.TimeMax)
+	if err != nil {
+		http.Error(w, "Invalid time_max format", http.StatusBadRequest)
+		return
+	}
+	if req.TimeZone != "" {
+		if _, err := time.LoadLocation(req.TimeZone); err != nil {
+			http.Error(w, "Invalid time_zone", http.StatusBadRequest)
+			return
+		}
+	}
+
+	result, err := h.eventService.FreeBusy(r.Context(), service.FreeBusyInput{
+		UserEmail: userInfo.Email,
+		Calendars: req.Attendees,
+		TimeMin:   timeMin,
+		TimeMax:   timeMax,
+		TimeZone:  req.TimeZone,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to query free/busy: %v", err)
+		writeEventError(w, err, "Failed to query free/busy")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// SuggestTimesRequest represents the request body for finding a meeting time.
+type SuggestTimesRequest struct {
+	Attendees       []string `json:"attendees"`
+	DurationMinutes int      `json:"duration_minutes"`
+	TimeMin         string   `json:"time_min"`
+	TimeMax         string   `json:"time_max"`
+	WorkdayStart    string   `json:"workday_start"` // "HH:MM", defaults to 09:00
+	WorkdayEnd      string   `json:"workday_end"`   // "HH:MM", defaults to 17:00
+	TimeZone        string   `json:"time_zone"`
+	Quorum          int      `json:"quorum"` // Minimum number of people free, organizer included; 0 means everyone
+	IncludeWeekends bool     `json:"include_weekends"`
+	MaxResults      int      `json:"max_results"`
+}
+
+// SuggestTimes returns ranked slots in which the attendees are free
+// (POST /api/events/suggest-times).
+func (h *Handler) SuggestTimes(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req SuggestTimesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Println("[ERROR] Failed to decode request body:", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(req.Attendees) > maxFreeBusyCalendars {
+		http.Error(w, fmt.Sprintf("at most %d attendees can be looked up at once", maxFreeBusyCalendars), http.StatusBadRequest)
+		return
+	}
+	for _, email := range req.Attendees {
+		if !isValidEmail(email) {
+			http.Error(w, fmt.Sprintf("Invalid attendee email: %s", email), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.DurationMinutes <= 0 {
+		http.Error(w, "duration_minutes must be positive", http.StatusBadRequest)
+		return
+	}
+	timeMin, err := time.Parse(time.RFC3339, req.TimeMin)
+	if err != nil {
+		http.Error(w, "Invalid time_min format", http.StatusBadRequest)
+		return
+	}
+	timeMax, err := time.Parse(time.RFC3339, req.TimeMax)
+	if err != nil {
+		http.Error(w, "Invalid time_max format", http.StatusBadRequest)
+		return
+	}
+	if req.WorkdayStart == "" {
+		req.WorkdayStart = "09:00"
+	}
+	if req.WorkdayEnd == "" {
+		req.WorkdayEnd = "17:00"
+	}
+	workdayStart, err := parseClock(req.WorkdayStart)
+	if err != nil {
+		http.Error(w, "Invalid workday_start: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	workdayEnd, err := parseClock(req.WorkdayEnd)
+	if err != nil {
+		http.Error(w, "Invalid workday_end: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.eventService.SuggestTimes(r.Context(), service.SuggestTimesInput{
+		UserEmail:       userInfo.Email,
+		Attendees:       req.Attendees,
+		Duration:        time.Duration(req.DurationMinutes) * time.Minute,
+		TimeMin:         timeMin,
+		TimeMax:         timeMax,
+		WorkdayStart:    workdayStart,
+		WorkdayEnd:      workdayEnd,
+		TimeZone:        req.TimeZone,
+		Quorum:          req.Quorum,
+		IncludeWeekends: req.IncludeWeekends,
+		MaxResults:      req.MaxResults,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to suggest times: %v", err)
+		writeEventError(w, err, "Failed to suggest times")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// parseClock parses a "15:04" wall-clock time into an offset from midnight.
+// "24:00" is accepted as the end of the day.
+func parseClock(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not in HH:MM format", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -11,17 +11,29 @@ import (
 
 // Handler holds dependencies for HTTP handlers.
 type Handler struct {
-	authService  service.AuthService
-	eventService service.EventService
-	config       *config.Config // Add Config
+	authService    service.AuthService
+	eventService   service.EventService
+	syncService    service.SyncService
+	watchService   service.WatchService
+	webhookService service.WebhookService
+	exportService  service.ExportService
+	importService  service.ImportService
+	accountService service.AccountService
+	config         *config.Config // Add Config
 }
 
 // NewHandler creates a new Handler instance.
-func NewHandler(authService service.AuthService, eventService service.EventService, cfg *config.Config) *Handler {
+func NewHandler(authService service.AuthService, eventService service.EventService, syncService service.SyncService, watchService service.WatchService, webhookService service.WebhookService, exportService service.ExportService, importService service.ImportService, accountService service.AccountService, cfg *config.Config) *Handler {
 	return &Handler{
-		authService:  authService,
-		eventService: eventService,
-		config:       cfg, // Store Config
+		authService:    authService,
+		eventService:   eventService,
+		syncService:    syncService,
+		watchService:   watchService,
+		webhookService: webhookService,
+		exportService:  exportService,
+		importService:  importService,
+		accountService: accountService,
+		config:         cfg, // Store Config
 	}
 }
 
@@ -33,8 +45,13 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 
 	// Public Routes
 	router.HandleFunc("/login", h.LoginPage).Methods("GET")
+	router.HandleFunc("/readyz", h.Ready).Methods("GET")
 	router.HandleFunc("/auth/google/login", h.GoogleLogin).Methods("GET")
 	router.HandleFunc("/auth/google/callback", h.GoogleCallback).Methods("GET")
+	router.HandleFunc("/auth/microsoft/login", h.MicrosoftLogin).Methods("GET")
+	router.HandleFunc("/auth/microsoft/callback", h.MicrosoftCallback).Methods("GET")
+	router.HandleFunc("/webhooks/google/calendar", h.GoogleCalendarWebhook).Methods("POST")
+	router.HandleFunc("/feeds/{token:[0-9a-f]+}.ics", h.CalendarFeed).Methods("GET") // The token is the credential
 
 	// Protected API Routes
 	api := router.PathPrefix("/api").Subrouter()
@@ -42,6 +59,29 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	api.HandleFunc("/dashboard", h.Dashboard).Methods("GET")
 	api.HandleFunc("/events", h.CreateEvent).Methods("POST") // /api/events
 	api.HandleFunc("/events", h.ListEvents).Methods("GET")   // /api/events
+	api.HandleFunc("/events.ics", h.ExportEvents).Methods("GET")
+	api.HandleFunc("/events/suggest-times", h.SuggestTimes).Methods("POST")
+	api.HandleFunc("/events/import", h.ImportEvents).Methods("POST")
+	api.HandleFunc("/events/{id}", h.GetEvent).Methods("GET")
+	api.HandleFunc("/events/{id}", h.UpdateEvent).Methods("PUT")
+	api.HandleFunc("/events/{id}", h.PatchEvent).Methods("PATCH")
+	api.HandleFunc("/events/{id}", h.DeleteEvent).Methods("DELETE")
+	api.HandleFunc("/calendars", h.ListCalendars).Methods("GET")
+	api.HandleFunc("/operations/{id}", h.GetOperation).Methods("GET")
+	api.HandleFunc("/freebusy", h.FreeBusy).Methods("POST")
+	api.HandleFunc("/sync", h.SyncCalendar).Methods("POST")
+	api.HandleFunc("/watch", h.WatchCalendars).Methods("POST")
+	api.HandleFunc("/webhooks", h.CreateWebhook).Methods("POST")
+	api.HandleFunc("/webhooks", h.ListWebhooks).Methods("GET")
+	api.HandleFunc("/webhooks/{id}", h.DeleteWebhook).Methods("DELETE")
+	api.HandleFunc("/webhooks/{id}/deliveries", h.ListWebhookDeliveries).Methods("GET")
+	api.HandleFunc("/webhooks/deliveries/{id}/replay", h.ReplayWebhookDelivery).Methods("POST")
+	api.HandleFunc("/feed", h.GetFeed).Methods("GET")
+	api.HandleFunc("/feed", h.RotateFeed).Methods("POST")
+	api.HandleFunc("/feed", h.RevokeFeed).Methods("DELETE")
+	api.HandleFunc("/providers", h.ListProviders).Methods("GET")
+	api.HandleFunc("/providers/active", h.SetActiveProvider).Methods("PUT")
+	api.HandleFunc("/providers/caldav", h.LinkCalDAV).Methods("PUT")
 
 	// Logout Route
 	router.HandleFunc("/logout", h.Logout).Methods("GET")
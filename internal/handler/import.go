@@ -0,0 +1,74 @@
+// internal/handler/import.go
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"google-calendar-api/internal/service"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// importMaxBytes caps the size of an uploaded calendar file.
+const importMaxBytes = 10 << 20
+
+// ImportEvents creates events from an iCalendar file (POST /api/events/import).
+// The file is sent as the "file" field of a multipart form or as the raw body.
+// Query parameters: calendar_id, and dry_run=true to only report what would
+// be created.
+func (h *Handler) ImportEvents(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, importMaxBytes)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Calendar file too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Missing calendar file", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	query := r.URL.Query()
+	input := service.ImportInput{
+		UserEmail:  userInfo.Email,
+		CalendarID: query.Get("calendar_id"),
+		DryRun:     query.Get("dry_run") == "true",
+	}
+
+	// Read up front so an oversized upload isn't reported as a malformed file.
+	data, err := io.ReadAll(body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Calendar file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read calendar file", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.importService.ImportICS(r.Context(), bytes.NewReader(data), input)
+	if err != nil {
+		log.Printf("[ERROR] Failed to import events: %v", err)
+		writeEventError(w, err, "Failed to import events")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -6,6 +6,7 @@ import (
 	"google-calendar-api/internal/service"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -82,7 +83,11 @@ func (h *Handler) validateAndSetContext(ctx context.Context, tokenString string)
 // loggingMiddleware logs incoming HTTP requests.
 func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("📌 %s %s", r.Method, r.URL.Path)
+		path := r.URL.Path
+		if strings.HasPrefix(path, "/feeds/") {
+			path = "/feeds/[redacted]" // Feed tokens are credentials
+		}
+		log.Printf("📌 %s %s", r.Method, path)
 		next.ServeHTTP(w, r)
 	})
 }
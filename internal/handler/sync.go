@@ -0,0 +1,30 @@
+// internal/handler/sync.go
+package handler
+
+import (
+	"encoding/json"
+	"google-calendar-api/internal/service"
+	"log"
+	"net/http"
+)
+
+// SyncCalendar pulls the changes of one calendar from Google into the local
+// store (POST /api/sync?calendar_id=). The first run fetches everything.
+func (h *Handler) SyncCalendar(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	calendarID := r.URL.Query().Get("calendar_id")
+	result, err := h.syncService.SyncCalendar(r.Context(), userInfo.Email, calendarID)
+	if err != nil {
+		log.Printf("[ERROR] Failed to sync calendar %q: %v", calendarID, err)
+		writeEventError(w, err, "Failed to sync calendar")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,62 @@
+// internal/handler/watch.go
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"google-calendar-api/internal/service"
+	"log"
+	"net/http"
+)
+
+// WatchCalendars registers push notification channels for every calendar of
+// the user that isn't watched yet (POST /api/watch).
+func (h *Handler) WatchCalendars(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if h.config.GoogleWebhookURL == "" {
+		http.Error(w, "Push notifications are not configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	channels, err := h.watchService.WatchCalendars(r.Context(), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to watch calendars: %v", err)
+		writeEventError(w, err, "Failed to watch calendars")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"channels": channels})
+}
+
+// GoogleCalendarWebhook receives push notifications from Google
+// (POST /webhooks/google/calendar). It is public; the channel token proves
+// the message belongs to a channel we opened.
+func (h *Handler) GoogleCalendarWebhook(w http.ResponseWriter, r *http.Request) {
+	notification := service.Notification{
+		ChannelID:     r.Header.Get("X-Goog-Channel-ID"),
+		Token:         r.Header.Get("X-Goog-Channel-Token"),
+		ResourceID:    r.Header.Get("X-Goog-Resource-ID"),
+		ResourceState: r.Header.Get("X-Goog-Resource-State"),
+	}
+	if notification.ChannelID == "" || notification.Token == "" || notification.ResourceState == "" {
+		http.Error(w, "Missing channel headers", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.watchService.HandleNotification(r.Context(), notification); err != nil {
+		if errors.Is(err, service.ErrInvalidChannel) {
+			log.Printf("⚠️ Rejected notification for channel %s: %v", notification.ChannelID, err)
+			http.Error(w, "Invalid channel", http.StatusForbidden)
+			return
+		}
+		log.Printf("[ERROR] Failed to handle notification for channel %s: %v", notification.ChannelID, err)
+		http.Error(w, "Failed to handle notification", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,149 @@
+// internal/handler/webhook.go
+package handler
+
+import (
+	"encoding/json"
+	"google-calendar-api/internal/service"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateWebhookRequest represents the request body for registering a webhook.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	Secret     string   `json:"secret"`      // Generated when empty
+	EventTypes []string `json:"event_types"` // Empty means every event type
+}
+
+// CreateWebhook registers a webhook subscription (POST /api/webhooks). The
+// secret is only ever returned here.
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Println("[ERROR] Failed to decode request body:", err)
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := h.webhookService.CreateSubscription(r.Context(), service.CreateSubscriptionInput{
+		URL:        req.URL,
+		Secret:     req.Secret,
+		EventTypes: req.EventTypes,
+		UserEmail:  userInfo.Email,
+	})
+	if err != nil {
+		log.Printf("[ERROR] Failed to create webhook: %v", err)
+		writeEventError(w, err, "Failed to create webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"subscription": subscription,
+		"secret":       subscription.Secret,
+	})
+}
+
+// ListWebhooks returns the user's webhook subscriptions (GET /api/webhooks).
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	subscriptions, err := h.webhookService.ListSubscriptions(r.Context(), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list webhooks: %v", err)
+		http.Error(w, "Failed to list webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"subscriptions": subscriptions})
+}
+
+// DeleteWebhook removes a webhook subscription (DELETE /api/webhooks/{id}).
+// Deliveries still queued for it are dropped.
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.webhookService.DeleteSubscription(r.Context(), uint(id), userInfo.Email); err != nil {
+		log.Printf("[ERROR] Failed to delete webhook %d: %v", id, err)
+		writeEventError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "Webhook deleted successfully"})
+}
+
+// ListWebhookDeliveries returns the latest deliveries of a subscription
+// (GET /api/webhooks/{id}/deliveries).
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid webhook id", http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(r.Context(), uint(id), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to list deliveries of webhook %d: %v", id, err)
+		writeEventError(w, err, "Failed to list webhook deliveries")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"deliveries": deliveries})
+}
+
+// ReplayWebhookDelivery queues a delivery again
+// (POST /api/webhooks/deliveries/{id}/replay).
+func (h *Handler) ReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	userInfo, ok := r.Context().Value(userKey).(service.UserInfo)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid delivery id", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.webhookService.ReplayDelivery(r.Context(), uint(id), userInfo.Email)
+	if err != nil {
+		log.Printf("[ERROR] Failed to replay delivery %d: %v", id, err)
+		writeEventError(w, err, "Failed to replay webhook delivery")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{"delivery": delivery})
+}
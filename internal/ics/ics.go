@@ -0,0 +1,215 @@
+// internal/ics/ics.go
+package ics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Calendar is an iCalendar (RFC 5545) VCALENDAR object.
+type Calendar struct {
+	Name   string // X-WR-CALNAME, the name clients show for a subscribed feed
+	Method string // METHOD, e.g. PUBLISH for feeds; left out of objects stored on a CalDAV server
+	Events []Event
+}
+
+// Event is one VEVENT. Exceptions of a recurring event share the UID of the
+// series and set RecurrenceID.
+type Event struct {
+	UID          string
+	Stamp        time.Time // DTSTAMP, when the event was last changed
+	Summary      string
+	Description  string
+	Start        time.Time
+	End          time.Time
+	AllDay       bool       // Start and End are dates; End is exclusive
+	TimeZone     string     // IANA zone the times are written in; UTC when empty or unknown
+	Recurrence   []string   // RRULE, EXDATE and RDATE lines, e.g. "RRULE:FREQ=WEEKLY"
+	RecurrenceID *time.Time // Original start of a changed occurrence
+	Status       string     // CONFIRMED, TENTATIVE or CANCELLED
+	Organizer    string     // Email
+	Attendees    []Attendee
+	URL          string
+	Extra        []string // Content lines this package doesn't model, like X- properties and VALARMs; written back as read
+}
+
+// Attendee is an ATTENDEE of an event.
+type Attendee struct {
+	Email    string
+	Name     string
+	Optional bool
+	PartStat string // NEEDS-ACTION, ACCEPTED, DECLINED or TENTATIVE
+}
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	localFormat    = "20060102T150405"
+	maxLineLength  = 75 // Octets, not counting the CRLF
+)
+
+// Write encodes cal to w. Every time zone the events use gets a VTIMEZONE.
+func Write(w io.Writer, cal *Calendar) error {
+	e := &encoder{w: w, zones: make(map[string]*time.Location)}
+	e.line("BEGIN:VCALENDAR")
+	e.line("VERSION:2.0")
+	e.line("PRODID:-//google-calendar-api//EN")
+	e.line("CALSCALE:GREGORIAN")
+	if cal.Method != "" {
+		e.line("METHOD:" + cal.Method)
+	}
+	if cal.Name != "" {
+		e.line("X-WR-CALNAME:" + escapeText(cal.Name))
+	}
+	e.timezones(cal.Events)
+	for i := range cal.Events {
+		e.event(&cal.Events[i])
+	}
+	e.line("END:VCALENDAR")
+	return e.err
+}
+
+// encoder writes content lines and keeps the first error.
+type encoder struct {
+	w     io.Writer
+	err   error
+	zones map[string]*time.Location // Zones with a VTIMEZONE; the others fall back to UTC
+}
+
+// timezones writes a VTIMEZONE for every zone the events use.
+func (e *encoder) timezones(events []Event) {
+	firstYear := make(map[string]int)
+	for _, event := range events {
+		if event.TimeZone == "" || event.AllDay {
+			continue
+		}
+		if year, ok := firstYear[event.TimeZone]; !ok || event.Start.Year() < year {
+			firstYear[event.TimeZone] = event.Start.Year()
+		}
+	}
+	names := make([]string, 0, len(firstYear))
+	for name := range firstYear {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		loc, err := time.LoadLocation(name)
+		if err != nil || loc == time.UTC {
+			continue
+		}
+		e.zones[name] = loc
+		e.line("BEGIN:VTIMEZONE")
+		e.line("TZID:" + name)
+		for _, line := range observances(loc, firstYear[name]) {
+			e.line(line)
+		}
+		e.line("END:VTIMEZONE")
+	}
+}
+
+func (e *encoder) event(event *Event) {
+	e.line("BEGIN:VEVENT")
+	e.line("UID:" + escapeText(event.UID))
+	e.line("DTSTAMP:" + event.Stamp.UTC().Format(dateTimeFormat))
+	loc := e.zones[event.TimeZone]
+	e.line("DTSTART" + formatTime(event.Start, event.AllDay, event.TimeZone, loc))
+	e.line("DTEND" + formatTime(event.End, event.AllDay, event.TimeZone, loc))
+	if event.RecurrenceID != nil {
+		e.line("RECURRENCE-ID" + formatTime(*event.RecurrenceID, event.AllDay, event.TimeZone, loc))
+	}
+	for _, rule := range event.Recurrence {
+		e.line(rule)
+	}
+	e.line("SUMMARY:" + escapeText(event.Summary))
+	if event.Description != "" {
+		e.line("DESCRIPTION:" + escapeText(event.Description))
+	}
+	if event.Status != "" {
+		e.line("STATUS:" + event.Status)
+	}
+	if event.URL != "" {
+		e.line("URL:" + event.URL)
+	}
+	if event.Organizer != "" {
+		e.line("ORGANIZER:mailto:" + event.Organizer)
+	}
+	for _, attendee := range event.Attendees {
+		params := ";ROLE=REQ-PARTICIPANT"
+		if attendee.Optional {
+			params = ";ROLE=OPT-PARTICIPANT"
+		}
+		if attendee.PartStat != "" {
+			params += ";PARTSTAT=" + attendee.PartStat
+		}
+		if attendee.Name != "" {
+			params += ";CN=" + quoteParam(attendee.Name)
+		}
+		e.line("ATTENDEE" + params + ":mailto:" + attendee.Email)
+	}
+	for _, line := range event.Extra {
+		e.line(line)
+	}
+	e.line("END:VEVENT")
+}
+
+// line writes one content line, folded to 75 octets as RFC 5545 asks.
+func (e *encoder) line(s string) {
+	if e.err != nil {
+		return
+	}
+	var b strings.Builder
+	for len(s) > maxLineLength {
+		cut := maxLineLength
+		if b.Len() > 0 {
+			cut-- // Continuation lines start with a space
+		}
+		for cut > 0 && !isRuneStart(s[cut]) {
+			cut-- // Don't split a UTF-8 sequence
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+	_, e.err = io.WriteString(e.w, b.String())
+}
+
+// formatTime returns the parameters and value of a date or date-time
+// property, e.g. ";VALUE=DATE:20250101", ";TZID=Europe/Berlin:20250101T100000"
+// or ":20250101T090000Z" when loc is nil.
+func formatTime(t time.Time, allDay bool, zone string, loc *time.Location) string {
+	switch {
+	case allDay:
+		return ";VALUE=DATE:" + t.UTC().Format(dateFormat)
+	case loc != nil:
+		return ";TZID=" + quoteParam(zone) + ":" + t.In(loc).Format(localFormat)
+	default:
+		return ":" + t.UTC().Format(dateTimeFormat)
+	}
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// quoteParam quotes a parameter value when it holds characters that would
+// end it. Double quotes can't be escaped at all, so they are dropped.
+func quoteParam(s string) string {
+	s = strings.ReplaceAll(s, `"`, "")
+	if strings.ContainsAny(s, ",;:") {
+		return fmt.Sprintf(`"%s"`, s)
+	}
+	return s
+}
+
+func isRuneStart(b byte) bool {
+	return b&0xC0 != 0x80
+}
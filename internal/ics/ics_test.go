@@ -0,0 +1,157 @@
+// internal/ics/ics_test.go
+package ics
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lines encodes cal and returns its content lines, unfolded.
+func lines(t *testing.T, cal *Calendar) []string {
+	t.Helper()
+	var b strings.Builder
+	if err := Write(&b, cal); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out := b.String()
+	if !strings.HasSuffix(out, "\r\n") || strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\n") {
+		t.Errorf("lines don't all end in CRLF:\n%q", out)
+	}
+	for _, line := range strings.Split(out, "\r\n") {
+		if len(line) > maxLineLength {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(out, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func TestWriteEvent(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := time.Date(2030, 3, 11, 9, 0, 0, 0, berlin)
+	got := lines(t, &Calendar{
+		Name:   "alice@example.com",
+		Method: "PUBLISH",
+		Events: []Event{
+			{
+				UID:        "standup@google.com",
+				Stamp:      time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC),
+				Summary:    "Standup; daily, short",
+				Start:      time.Date(2030, 3, 4, 9, 0, 0, 0, berlin),
+				End:        time.Date(2030, 3, 4, 9, 15, 0, 0, berlin),
+				TimeZone:   "Europe/Berlin",
+				Recurrence: []string{"RRULE:FREQ=WEEKLY;BYDAY=MO"},
+				Organizer:  "alice@example.com",
+				Attendees: []Attendee{
+					{Email: "bob@example.com", Name: "Bob, the builder", PartStat: "ACCEPTED"},
+					{Email: "carol@example.com", Optional: true},
+				},
+			},
+			{
+				UID:          "standup@google.com",
+				Summary:      "Standup",
+				Start:        moved.Add(time.Hour),
+				End:          moved.Add(time.Hour + 15*time.Minute),
+				TimeZone:     "Europe/Berlin",
+				RecurrenceID: &moved,
+			},
+			{
+				UID:     "offsite@google.com",
+				Summary: "Offsite",
+				Start:   time.Date(2030, 3, 6, 0, 0, 0, 0, time.UTC),
+				End:     time.Date(2030, 3, 8, 0, 0, 0, 0, time.UTC),
+				AllDay:  true,
+				// An all-day event has no zone to describe.
+				TimeZone: "America/New_York",
+			},
+			{
+				UID:      "call@google.com",
+				Summary:  "Call",
+				Start:    time.Date(2030, 3, 5, 15, 0, 0, 0, time.UTC),
+				End:      time.Date(2030, 3, 5, 16, 0, 0, 0, time.UTC),
+				TimeZone: "Not/A_Zone",
+			},
+		},
+	})
+
+	for _, want := range []string{
+		"METHOD:PUBLISH",
+		"X-WR-CALNAME:alice@example.com",
+		"DTSTAMP:20300101T120000Z",
+		"DTSTART;TZID=Europe/Berlin:20300304T090000",
+		"DTEND;TZID=Europe/Berlin:20300304T091500",
+		"RRULE:FREQ=WEEKLY;BYDAY=MO",
+		`SUMMARY:Standup\; daily\, short`,
+		"ORGANIZER:mailto:alice@example.com",
+		`ATTENDEE;ROLE=REQ-PARTICIPANT;PARTSTAT=ACCEPTED;CN="Bob, the builder":mailto:bob@example.com`,
+		"ATTENDEE;ROLE=OPT-PARTICIPANT:mailto:carol@example.com",
+		"RECURRENCE-ID;TZID=Europe/Berlin:20300311T090000",
+		"DTSTART;TZID=Europe/Berlin:20300311T100000",
+		"DTSTART;VALUE=DATE:20300306",
+		"DTEND;VALUE=DATE:20300308",
+		"DTSTART:20300305T150000Z", // Unknown zones fall back to UTC
+	} {
+		if !slices.Contains(got, want) {
+			t.Errorf("output lacks %q", want)
+		}
+	}
+
+	var zones []string
+	for _, line := range got {
+		if strings.HasPrefix(line, "TZID:") {
+			zones = append(zones, line)
+		}
+	}
+	if !slices.Equal(zones, []string{"TZID:Europe/Berlin"}) {
+		t.Errorf("VTIMEZONEs for %q; want only Europe/Berlin", zones)
+	}
+	if got[0] != "BEGIN:VCALENDAR" || got[len(got)-1] != "END:VCALENDAR" {
+		t.Errorf("output runs from %q to %q", got[0], got[len(got)-1])
+	}
+}
+
+func TestWriteFoldsLongLines(t *testing.T) {
+	description := strings.Repeat("Grüße aus Köln. ", 20)
+	got := lines(t, &Calendar{Events: []Event{{UID: "long", Summary: "Long", Description: description}}})
+	if !slices.Contains(got, "DESCRIPTION:"+description) {
+		t.Errorf("the description didn't survive folding")
+	}
+}
+
+func TestObservances(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"BEGIN:DAYLIGHT",
+		"DTSTART:19700329T020000",
+		"TZOFFSETFROM:+0100",
+		"TZOFFSETTO:+0200",
+		"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+		"TZNAME:CEST",
+		"END:DAYLIGHT",
+		"BEGIN:STANDARD",
+		"DTSTART:19701025T030000",
+		"TZOFFSETFROM:+0200",
+		"TZOFFSETTO:+0100",
+		"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+		"TZNAME:CET",
+		"END:STANDARD",
+	}
+	if got := observances(berlin, 2030); !slices.Equal(got, want) {
+		t.Errorf("observances of Europe/Berlin = %q; want %q", got, want)
+	}
+
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := observances(kolkata, 2030); !slices.Contains(got, "TZOFFSETTO:+0530") || slices.Contains(got, "BEGIN:DAYLIGHT") {
+		t.Errorf("observances of Asia/Kolkata = %q; want one STANDARD at +0530", got)
+	}
+}
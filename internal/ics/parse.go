@@ -0,0 +1,416 @@
+// internal/ics/parse.go
+package ics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InvalidEvent is a VEVENT Parse could not read.
+type InvalidEvent struct {
+	UID     string
+	Summary string
+	Err     error
+}
+
+// property is one content line: NAME;PARAM=value:VALUE.
+type property struct {
+	name   string
+	params map[string]string // Upper-cased names; quotes removed
+	value  string
+	line   string // As read, unfolded
+}
+
+// rawEvent is a VEVENT as read, before its times can be resolved.
+type rawEvent struct {
+	props  []property
+	nested []string // Content lines of its components, such as VALARM
+}
+
+// Parse reads the VEVENTs of an iCalendar stream. Times with a TZID are read
+// in the IANA zone of that name when there is one, else with the stream's
+// VTIMEZONE of that ID; floating times are read as UTC. Events that can't be
+// read are returned in invalid rather than failing the whole stream.
+func Parse(r io.Reader) (cal *Calendar, invalid []InvalidEvent, err error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cal = &Calendar{}
+	zones := make(map[string]*vtimezone)
+	var events []rawEvent
+	var stack []string
+	var current rawEvent     // VEVENT being read
+	var zone *vtimezone      // VTIMEZONE being read
+	var part *zoneObservance // STANDARD or DAYLIGHT being read
+	for i, line := range lines {
+		if line == "" {
+			continue
+		}
+		prop, err := parseLine(line)
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+
+		switch prop.name {
+		case "BEGIN":
+			component := strings.ToUpper(prop.value)
+			if len(stack) == 0 && component != "VCALENDAR" {
+				return nil, nil, errors.New("not an iCalendar stream: missing BEGIN:VCALENDAR")
+			}
+			stack = append(stack, component)
+			switch {
+			case component == "VEVENT" && len(stack) == 2:
+				current = rawEvent{}
+			case len(stack) > 2 && stack[1] == "VEVENT":
+				current.nested = append(current.nested, line)
+			case component == "VTIMEZONE":
+				zone = &vtimezone{}
+			case (component == "STANDARD" || component == "DAYLIGHT") && zone != nil:
+				part = &zoneObservance{}
+			}
+			continue
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1] != strings.ToUpper(prop.value) {
+				return nil, nil, fmt.Errorf("line %d: END:%s does not close BEGIN:%s", i+1, prop.value, strings.Join(stack, "/"))
+			}
+			switch component := stack[len(stack)-1]; {
+			case component == "VEVENT" && len(stack) == 2:
+				events = append(events, current)
+			case len(stack) > 2 && stack[1] == "VEVENT":
+				current.nested = append(current.nested, line)
+			case component == "VTIMEZONE" && zone != nil:
+				if zone.id != "" {
+					zones[zone.id] = zone
+				}
+				zone = nil
+			case (component == "STANDARD" || component == "DAYLIGHT") && part != nil:
+				zone.observances = append(zone.observances, *part)
+				part = nil
+			}
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		if len(stack) == 0 {
+			return nil, nil, errors.New("not an iCalendar stream: missing BEGIN:VCALENDAR")
+		}
+		switch top := stack[len(stack)-1]; {
+		case top == "VCALENDAR" && prop.name == "X-WR-CALNAME":
+			cal.Name = unescapeText(prop.value)
+		case top == "VEVENT" && len(stack) == 2:
+			current.props = append(current.props, prop)
+		case len(stack) > 2 && stack[1] == "VEVENT":
+			current.nested = append(current.nested, line)
+		case top == "VTIMEZONE" && prop.name == "TZID" && zone != nil:
+			zone.id = prop.value
+		case part != nil:
+			if err := part.set(prop); err != nil && zone.err == nil {
+				zone.err = fmt.Errorf("line %d: %w", i+1, err) // Only events using the zone fail
+			}
+		}
+	}
+	if len(stack) != 0 {
+		return nil, nil, fmt.Errorf("unexpected end of stream inside %s", strings.Join(stack, "/"))
+	}
+
+	// Zones may follow the events that use them, so times are read last.
+	for _, raw := range events {
+		event, err := readEvent(raw, zones)
+		if err != nil {
+			invalid = append(invalid, InvalidEvent{UID: event.UID, Summary: event.Summary, Err: err})
+			continue
+		}
+		cal.Events = append(cal.Events, event)
+	}
+	return cal, invalid, nil
+}
+
+// unfold splits the stream into content lines, joining folded ones.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// parseLine splits a content line into its name, parameters and value.
+// Colons and semicolons inside quoted parameter values don't count.
+func parseLine(line string) (property, error) {
+	prop := property{params: make(map[string]string), line: line}
+	inQuotes := false
+	start := 0
+	var paramParts []string
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '"':
+			inQuotes = !inQuotes
+		case (c == ';' || c == ':') && !inQuotes:
+			if prop.name == "" {
+				prop.name = strings.ToUpper(line[start:i])
+			} else {
+				paramParts = append(paramParts, line[start:i])
+			}
+			start = i + 1
+			if c == ':' {
+				prop.value = line[i+1:]
+				for _, param := range paramParts {
+					key, value, _ := strings.Cut(param, "=")
+					prop.params[strings.ToUpper(key)] = strings.ReplaceAll(value, `"`, "")
+				}
+				return prop, nil
+			}
+		}
+	}
+	return prop, fmt.Errorf("malformed content line %q", line)
+}
+
+// readEvent builds an Event from a VEVENT. What it doesn't model is kept in
+// Extra as it was read.
+func readEvent(raw rawEvent, zones map[string]*vtimezone) (Event, error) {
+	var event Event
+	var dtstart, dtend, duration *property
+	var exdates, rdates []property
+	props := raw.props
+	for i := range props {
+		prop := &props[i]
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescapeText(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(prop.value)
+		case "DTSTART":
+			dtstart = prop
+		case "DTEND":
+			dtend = prop
+		case "DURATION":
+			duration = prop
+		case "DTSTAMP", "LAST-MODIFIED":
+			if t, _, _, err := readTime(*prop, prop.value, zones); err == nil && t.After(event.Stamp) {
+				event.Stamp = t
+			}
+		case "RRULE", "EXRULE":
+			event.Recurrence = append(event.Recurrence, prop.name+":"+prop.value)
+		case "EXDATE":
+			exdates = append(exdates, *prop)
+		case "RDATE":
+			rdates = append(rdates, *prop)
+		case "RECURRENCE-ID":
+			t, _, _, err := readTime(*prop, prop.value, zones)
+			if err != nil {
+				return event, fmt.Errorf("invalid RECURRENCE-ID: %w", err)
+			}
+			event.RecurrenceID = &t
+		case "STATUS":
+			event.Status = strings.ToUpper(prop.value)
+		case "URL":
+			event.URL = prop.value
+		case "ORGANIZER":
+			event.Organizer = trimMailto(prop.value)
+		case "ATTENDEE":
+			event.Attendees = append(event.Attendees, Attendee{
+				Email:    trimMailto(prop.value),
+				Name:     prop.params["CN"],
+				Optional: strings.EqualFold(prop.params["ROLE"], "OPT-PARTICIPANT") || strings.EqualFold(prop.params["ROLE"], "NON-PARTICIPANT"),
+				PartStat: strings.ToUpper(prop.params["PARTSTAT"]),
+			})
+		default:
+			event.Extra = append(event.Extra, prop.line)
+		}
+	}
+	event.Extra = append(event.Extra, raw.nested...)
+
+	if event.UID == "" {
+		return event, errors.New("missing UID")
+	}
+	if dtstart == nil {
+		return event, errors.New("missing DTSTART")
+	}
+	var err error
+	event.Start, event.AllDay, event.TimeZone, err = readTime(*dtstart, dtstart.value, zones)
+	if err != nil {
+		return event, fmt.Errorf("invalid DTSTART: %w", err)
+	}
+	switch {
+	case dtend != nil:
+		if event.End, _, _, err = readTime(*dtend, dtend.value, zones); err != nil {
+			return event, fmt.Errorf("invalid DTEND: %w", err)
+		}
+	case duration != nil:
+		d, err := parseDuration(duration.value)
+		if err != nil {
+			return event, fmt.Errorf("invalid DURATION: %w", err)
+		}
+		event.End = event.Start.Add(d)
+	case event.AllDay:
+		event.End = event.Start.AddDate(0, 0, 1) // RFC 5545: a date start alone lasts one day
+	default:
+		event.End = event.Start
+	}
+
+	// Exception and extra dates are sent as UTC instants or plain dates, so
+	// they don't depend on the TZID being known to the receiver.
+	for _, list := range []struct {
+		name  string
+		props []property
+	}{{"EXDATE", exdates}, {"RDATE", rdates}} {
+		for _, prop := range list.props {
+			var values []string
+			allDay := false
+			for _, value := range strings.Split(prop.value, ",") {
+				t, isDate, _, err := readTime(prop, value, zones)
+				if err != nil {
+					return event, fmt.Errorf("invalid %s: %w", list.name, err)
+				}
+				allDay = isDate
+				if isDate {
+					values = append(values, t.Format(dateFormat))
+				} else {
+					values = append(values, t.UTC().Format(dateTimeFormat))
+				}
+			}
+			line := list.name
+			if allDay {
+				line += ";VALUE=DATE"
+			}
+			event.Recurrence = append(event.Recurrence, line+":"+strings.Join(values, ","))
+		}
+	}
+	return event, nil
+}
+
+// readTime reads one date or date-time value of prop. It returns the time,
+// whether it is a date, and the IANA zone it was given in, if any.
+func readTime(prop property, value string, zones map[string]*vtimezone) (time.Time, bool, string, error) {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(prop.params["VALUE"], "DATE") || len(value) == len(dateFormat) {
+		t, err := time.Parse(dateFormat, value)
+		return t, true, "", err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeFormat, value)
+		return t, false, "", err
+	}
+	tzid := prop.params["TZID"]
+	if tzid == "" {
+		t, err := time.Parse(localFormat, value) // Floating time
+		return t, false, "", err
+	}
+	if loc, name := LoadZone(tzid); loc != nil {
+		t, err := time.ParseInLocation(localFormat, value, loc)
+		return t, false, name, err
+	}
+	if zone, ok := zones[tzid]; ok {
+		if zone.err != nil {
+			return time.Time{}, false, "", fmt.Errorf("time zone %q: %w", tzid, zone.err)
+		}
+		local, err := time.Parse(localFormat, value)
+		if err != nil {
+			return time.Time{}, false, "", err
+		}
+		return local.Add(-time.Duration(zone.offsetAt(local)) * time.Second), false, "", nil
+	}
+	return time.Time{}, false, "", fmt.Errorf("unknown time zone %q", tzid)
+}
+
+// windowsZones maps the zone names Outlook and Exchange write to IANA zones.
+var windowsZones = map[string]string{
+	"UTC":                            "UTC",
+	"GMT Standard Time":              "Europe/London",
+	"W. Europe Standard Time":        "Europe/Berlin",
+	"Romance Standard Time":          "Europe/Paris",
+	"Central Europe Standard Time":   "Europe/Budapest",
+	"Central European Standard Time": "Europe/Warsaw",
+	"E. Europe Standard Time":        "Europe/Chisinau",
+	"FLE Standard Time":              "Europe/Kiev",
+	"Russian Standard Time":          "Europe/Moscow",
+	"Eastern Standard Time":          "America/New_York",
+	"Central Standard Time":          "America/Chicago",
+	"Mountain Standard Time":         "America/Denver",
+	"US Mountain Standard Time":      "America/Phoenix",
+	"Pacific Standard Time":          "America/Los_Angeles",
+	"Alaskan Standard Time":          "America/Anchorage",
+	"Hawaiian Standard Time":         "Pacific/Honolulu",
+	"E. South America Standard Time": "America/Sao_Paulo",
+	"India Standard Time":            "Asia/Kolkata",
+	"China Standard Time":            "Asia/Shanghai",
+	"Tokyo Standard Time":            "Asia/Tokyo",
+	"Singapore Standard Time":        "Asia/Singapore",
+	"AUS Eastern Standard Time":      "Australia/Sydney",
+	"New Zealand Standard Time":      "Pacific/Auckland",
+}
+
+// LoadZone finds the IANA zone a TZID stands for: the ID itself, an IANA
+// name at its end (as in "/mozilla.org/20050126_1/Europe/Berlin") or a
+// Windows zone name. Returns nil when there is none.
+func LoadZone(tzid string) (*time.Location, string) {
+	candidates := []string{tzid}
+	if parts := strings.Split(strings.Trim(tzid, "/"), "/"); len(parts) >= 2 {
+		candidates = append(candidates, strings.Join(parts[len(parts)-2:], "/"))
+	}
+	if name, ok := windowsZones[tzid]; ok {
+		candidates = append(candidates, name)
+	}
+	for _, name := range candidates {
+		if name == "" || strings.EqualFold(name, "local") {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc, name
+		}
+	}
+	return nil, ""
+}
+
+var durationPattern = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// parseDuration reads a DURATION value such as "PT1H30M" or "P1D".
+func parseDuration(value string) (time.Duration, error) {
+	parts := durationPattern.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(value)))
+	if parts == nil || value == "P" || value == "PT" {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, unit := range units {
+		if parts[i+2] != "" {
+			n, _ := strconv.Atoi(parts[i+2])
+			d += time.Duration(n) * unit
+		}
+	}
+	if parts[1] == "-" {
+		d = -d
+	}
+	return d, nil
+}
+
+var textUnescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+// unescapeText undoes escapeText.
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
+
+func trimMailto(s string) string {
+	if len(s) >= 7 && strings.EqualFold(s[:7], "mailto:") {
+		return s[7:]
+	}
+	return s
+}
@@ -0,0 +1,229 @@
+// internal/ics/parse_test.go
+package ics
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// outlookExport is shaped like what Outlook writes: a VTIMEZONE under a
+// Windows name, a custom TZID only the stream describes, folded lines and
+// CRLFs.
+const outlookExport = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"X-WR-CALNAME:Team\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Custom Zone\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:16011104T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=1SU;BYMONTH=11\r\n" +
+	"TZOFFSETFROM:-0400\r\n" +
+	"TZOFFSETTO:-0500\r\n" +
+	"END:STANDARD\r\n" +
+	"BEGIN:DAYLIGHT\r\n" +
+	"DTSTART:16010311T020000\r\n" +
+	"RRULE:FREQ=YEARLY;BYDAY=2SU;BYMONTH=3\r\n" +
+	"TZOFFSETFROM:-0500\r\n" +
+	"TZOFFSETTO:-0400\r\n" +
+	"END:DAYLIGHT\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review-1\r\n" +
+	"SUMMARY:Design review\\, round 2\r\n" +
+	"DESCRIPTION:First line\\nsecond line that goes on for a while so that it has to be fol\r\n" +
+	" ded\r\n" +
+	"DTSTART;TZID=W. Europe Standard Time:20300304T090000\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4\r\n" +
+	"EXDATE;TZID=W. Europe Standard Time:20300311T090000\r\n" +
+	"ORGANIZER;CN=Alice:mailto:alice@example.com\r\n" +
+	"ATTENDEE;ROLE=OPT-PARTICIPANT;PARTSTAT=tentative;CN=\"Doe, Jane\":MAILTO:jane@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review-1\r\n" +
+	"RECURRENCE-ID;TZID=W. Europe Standard Time:20300318T090000\r\n" +
+	"DTSTART;TZID=W. Europe Standard Time:20300318T110000\r\n" +
+	"DTEND;TZID=W. Europe Standard Time:20300318T123000\r\n" +
+	"SUMMARY:Design review (moved)\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:call-1\r\n" +
+	"SUMMARY:Call\r\n" +
+	"DTSTART;TZID=Custom Zone:20300704T100000\r\n" +
+	"DTEND;TZID=Custom Zone:20300704T110000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday-1\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"DTSTART;VALUE=DATE:20300501\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"TRIGGER:-PT15M\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:broken-1\r\n" +
+	"SUMMARY:No start\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:broken-2\r\n" +
+	"SUMMARY:Unknown zone\r\n" +
+	"DTSTART;TZID=Atlantis:20300101T090000\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParse(t *testing.T) {
+	cal, invalid, err := Parse(strings.NewReader(outlookExport))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if cal.Name != "Team" {
+		t.Errorf("Name = %q; want Team", cal.Name)
+	}
+	if len(cal.Events) != 4 {
+		t.Fatalf("read %d events; want 4", len(cal.Events))
+	}
+
+	review := cal.Events[0]
+	if review.Summary != "Design review, round 2" || !strings.HasSuffix(review.Description, "has to be folded") || !strings.HasPrefix(review.Description, "First line\nsecond") {
+		t.Errorf("text = %q / %q; want it unescaped and unfolded", review.Summary, review.Description)
+	}
+	if want := time.Date(2030, 3, 4, 8, 0, 0, 0, time.UTC); !review.Start.Equal(want) || review.TimeZone != "Europe/Berlin" {
+		t.Errorf("start = %v in %q; want %v in Europe/Berlin", review.Start, review.TimeZone, want)
+	}
+	if d := review.End.Sub(review.Start); d != 90*time.Minute {
+		t.Errorf("lasts %v; want the DURATION of 1h30m", d)
+	}
+	if want := []string{"RRULE:FREQ=WEEKLY;COUNT=4", "EXDATE:20300311T080000Z"}; !slices.Equal(review.Recurrence, want) {
+		t.Errorf("recurrence = %q; want %q", review.Recurrence, want)
+	}
+	if want := []Attendee{{Email: "jane@example.com", Name: "Doe, Jane", Optional: true, PartStat: "TENTATIVE"}}; review.Organizer != "alice@example.com" || !slices.Equal(review.Attendees, want) {
+		t.Errorf("organizer %q, attendees %+v; want alice and %+v", review.Organizer, review.Attendees, want)
+	}
+
+	moved := cal.Events[1]
+	if moved.UID != "review-1" || moved.RecurrenceID == nil || !moved.RecurrenceID.Equal(time.Date(2030, 3, 18, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("exception = %q at %v; want review-1 for 08:00 UTC", moved.UID, moved.RecurrenceID)
+	}
+
+	// July is daylight time in the stream's own zone: -04:00.
+	if call := cal.Events[2]; !call.Start.Equal(time.Date(2030, 7, 4, 14, 0, 0, 0, time.UTC)) || call.TimeZone != "" {
+		t.Errorf("call starts %v in %q; want 14:00 UTC without an IANA zone", call.Start, call.TimeZone)
+	}
+
+	holiday := cal.Events[3]
+	if !holiday.AllDay || !holiday.Start.Equal(time.Date(2030, 5, 1, 0, 0, 0, 0, time.UTC)) || !holiday.End.Equal(time.Date(2030, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("holiday = %v–%v all day %v; want 1 May, one day", holiday.Start, holiday.End, holiday.AllDay)
+	}
+	if want := []string{"BEGIN:VALARM", "TRIGGER:-PT15M", "ACTION:DISPLAY", "END:VALARM"}; !slices.Equal(holiday.Extra, want) {
+		t.Errorf("holiday keeps %q; want its alarm %q", holiday.Extra, want)
+	}
+
+	var failed []string
+	for _, event := range invalid {
+		failed = append(failed, event.UID)
+	}
+	if !slices.Equal(failed, []string{"broken-1", "broken-2"}) {
+		t.Errorf("invalid events %q; want broken-1 and broken-2", failed)
+	}
+}
+
+func TestParseRejectsMalformedStreams(t *testing.T) {
+	for name, stream := range map[string]string{
+		"no calendar": "BEGIN:VEVENT\r\nUID:x\r\nEND:VEVENT\r\n",
+		"unclosed":    "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:x\r\n",
+		"mismatched":  "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n",
+		"no colon":    "BEGIN:VCALENDAR\r\nSUMMARY\r\nEND:VCALENDAR\r\n",
+	} {
+		if _, _, err := Parse(strings.NewReader(stream)); err == nil {
+			t.Errorf("Parse of a stream with %s succeeded; want an error", name)
+		}
+	}
+}
+
+// TestRoundTrip writes events and reads them back.
+func TestRoundTrip(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := time.Date(2030, 3, 11, 9, 0, 0, 0, berlin)
+	in := &Calendar{Name: "alice@example.com", Events: []Event{
+		{
+			UID:         "weekly@google.com",
+			Stamp:       time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+			Summary:     "Weekly; with, punctuation",
+			Description: "Agenda:\n1. Things\n2. " + strings.Repeat("More things ", 10),
+			Start:       time.Date(2030, 3, 4, 9, 0, 0, 0, berlin),
+			End:         time.Date(2030, 3, 4, 10, 0, 0, 0, berlin),
+			TimeZone:    "Europe/Berlin",
+			Recurrence:  []string{"RRULE:FREQ=WEEKLY;COUNT=10", "EXDATE:20300318T080000Z"},
+			Status:      "CONFIRMED",
+			Organizer:   "alice@example.com",
+			Attendees: []Attendee{
+				{Email: "bob@example.com", Name: "Bob", PartStat: "ACCEPTED"},
+				{Email: "carol@example.com", Name: "Carol: Ops", Optional: true, PartStat: "NEEDS-ACTION"},
+			},
+			URL:   "https://meet.example.com/abc",
+			Extra: []string{"X-APPLE-TRAVEL-ADVISORY-BEHAVIOR:AUTOMATIC", "BEGIN:VALARM", "TRIGGER:-PT5M", "ACTION:DISPLAY", "END:VALARM"},
+		},
+		{
+			UID:          "weekly@google.com",
+			Stamp:        time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC),
+			Summary:      "Weekly (later)",
+			Start:        moved.Add(2 * time.Hour),
+			End:          moved.Add(3 * time.Hour),
+			TimeZone:     "Europe/Berlin",
+			RecurrenceID: &moved,
+		},
+		{
+			UID:        "trip@google.com",
+			Stamp:      time.Date(2030, 1, 3, 0, 0, 0, 0, time.UTC),
+			Summary:    "Trip",
+			Start:      time.Date(2030, 4, 1, 0, 0, 0, 0, time.UTC),
+			End:        time.Date(2030, 4, 4, 0, 0, 0, 0, time.UTC),
+			AllDay:     true,
+			Recurrence: []string{"RRULE:FREQ=YEARLY"},
+		},
+		{
+			UID:     "call@google.com",
+			Stamp:   time.Date(2030, 1, 4, 0, 0, 0, 0, time.UTC),
+			Summary: "Call",
+			Start:   time.Date(2030, 3, 5, 15, 0, 0, 0, time.UTC),
+			End:     time.Date(2030, 3, 5, 15, 30, 0, 0, time.UTC),
+		},
+	}}
+	var b strings.Builder
+	if err := Write(&b, in); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out, invalid, err := Parse(strings.NewReader(b.String()))
+	if err != nil || len(invalid) != 0 {
+		t.Fatalf("Parse = %v, %+v", err, invalid)
+	}
+	if out.Name != in.Name || len(out.Events) != len(in.Events) {
+		t.Fatalf("read %q with %d events; want %q with %d", out.Name, len(out.Events), in.Name, len(in.Events))
+	}
+	for i, want := range in.Events {
+		got := out.Events[i]
+		if !got.Start.Equal(want.Start) || !got.End.Equal(want.End) || !got.Stamp.Equal(want.Stamp) {
+			t.Errorf("event %d runs %v–%v stamped %v; want %v–%v stamped %v", i, got.Start, got.End, got.Stamp, want.Start, want.End, want.Stamp)
+		}
+		if (got.RecurrenceID == nil) != (want.RecurrenceID == nil) || got.RecurrenceID != nil && !got.RecurrenceID.Equal(*want.RecurrenceID) {
+			t.Errorf("event %d has RECURRENCE-ID %v; want %v", i, got.RecurrenceID, want.RecurrenceID)
+		}
+		got.Start, got.End, got.Stamp, got.RecurrenceID = want.Start, want.End, want.Stamp, want.RecurrenceID
+		if !eventsEqual(got, want) {
+			t.Errorf("event %d = %+v; want %+v", i, got, want)
+		}
+	}
+}
+
+func eventsEqual(a, b Event) bool {
+	return a.UID == b.UID && a.Summary == b.Summary && a.Description == b.Description &&
+		a.AllDay == b.AllDay && a.TimeZone == b.TimeZone && slices.Equal(a.Recurrence, b.Recurrence) &&
+		a.Status == b.Status && a.Organizer == b.Organizer && slices.Equal(a.Attendees, b.Attendees) && a.URL == b.URL &&
+		slices.Equal(a.Extra, b.Extra)
+}
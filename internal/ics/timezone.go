@@ -0,0 +1,269 @@
+// internal/ics/timezone.go
+package ics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// observances describes loc as the STANDARD and DAYLIGHT components of a
+// VTIMEZONE. Go doesn't expose the zone rules, so the transitions of year
+// are found by probing and turned into yearly rules, which holds as long as
+// the zone's rules didn't change since.
+func observances(loc *time.Location, year int) []string {
+	transitions := findTransitions(loc, year)
+	if len(transitions) == 0 {
+		// No daylight saving: one observance since forever.
+		name, offset := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+		return observance("STANDARD", "19700101T000000", offset, offset, name, "")
+	}
+
+	var lines []string
+	for _, t := range transitions {
+		before := t.Add(-time.Minute)
+		_, fromOffset := before.Zone()
+		toName, toOffset := t.Zone()
+		kind := "STANDARD"
+		if t.IsDST() {
+			kind = "DAYLIGHT"
+		}
+
+		// DTSTART is the wall clock time just before the change.
+		local := t.In(time.FixedZone("", fromOffset))
+		if len(transitions) != 2 {
+			// Not a plain yearly switch; describe only this year.
+			lines = append(lines, observance(kind, local.Format(localFormat), fromOffset, toOffset, toName, "")...)
+			continue
+		}
+		week := (local.Day()-1)/7 + 1
+		if local.AddDate(0, 0, 7).Month() != local.Month() {
+			week = -1 // The last one of the month
+		}
+		rule := fmt.Sprintf("RRULE:FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", int(local.Month()), week, weekdays[local.Weekday()])
+		start := nthWeekday(1970, local.Month(), local.Weekday(), week)
+		start = start.Add(time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute)
+		lines = append(lines, observance(kind, start.Format(localFormat), fromOffset, toOffset, toName, rule)...)
+	}
+	return lines
+}
+
+// findTransitions returns the moments in year when loc changes its offset.
+func findTransitions(loc *time.Location, year int) []time.Time {
+	var transitions []time.Time
+	t := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).In(loc)
+	end := t.AddDate(1, 0, 0)
+	_, offset := t.Zone()
+	for t.Before(end) {
+		next := t.Add(time.Hour)
+		if _, nextOffset := next.Zone(); nextOffset != offset {
+			// Narrow it down to the minute.
+			lo, hi := t, next
+			for hi.Sub(lo) > time.Minute {
+				mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Minute)
+				if _, o := mid.Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			transitions = append(transitions, hi)
+			offset = nextOffset
+		}
+		t = next
+	}
+	return transitions
+}
+
+// nthWeekday returns the nth weekday of month at midnight UTC; n = -1 means
+// the last one.
+func nthWeekday(year int, month time.Month, weekday time.Weekday, n int) time.Time {
+	if n < 0 {
+		last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC)
+		return last.AddDate(0, 0, -((int(last.Weekday()) - int(weekday) + 7) % 7))
+	}
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	return first.AddDate(0, 0, (int(weekday)-int(first.Weekday())+7)%7+7*(n-1))
+}
+
+func observance(kind, start string, fromOffset, toOffset int, name, rule string) []string {
+	lines := []string{
+		"BEGIN:" + kind,
+		"DTSTART:" + start,
+		"TZOFFSETFROM:" + formatOffset(fromOffset),
+		"TZOFFSETTO:" + formatOffset(toOffset),
+	}
+	if rule != "" {
+		lines = append(lines, rule)
+	}
+	if name != "" {
+		lines = append(lines, "TZNAME:"+escapeText(name))
+	}
+	return append(lines, "END:"+kind)
+}
+
+// formatOffset formats a UTC offset in seconds as +HHMM, or +HHMMSS when
+// it isn't whole minutes.
+func formatOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	if offset%60 != 0 {
+		return fmt.Sprintf("%c%02d%02d%02d", sign, offset/3600, offset/60%60, offset%60)
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset/60%60)
+}
+
+var weekdays = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// vtimezone is a VTIMEZONE read from a stream, for TZIDs that don't name an
+// IANA zone.
+type vtimezone struct {
+	id          string
+	observances []zoneObservance
+	err         error // Set when a part could not be read
+}
+
+// zoneObservance is a STANDARD or DAYLIGHT part of a VTIMEZONE.
+type zoneObservance struct {
+	start      time.Time // Wall clock time of the first onset
+	offsetFrom int
+	offsetTo   int
+	month      time.Month // Yearly rule; zero when the observance happens once
+	weekday    time.Weekday
+	week       int   // nth weekday of the month; -1 is the last
+	monthDays  []int // BYMONTHDAY of older rules like "BYMONTHDAY=8,9,10,11,12,13,14;BYDAY=SU"
+}
+
+// set reads one property of the observance.
+func (o *zoneObservance) set(prop property) error {
+	var err error
+	switch prop.name {
+	case "DTSTART":
+		o.start, err = time.Parse(localFormat, prop.value)
+	case "TZOFFSETFROM":
+		o.offsetFrom, err = parseOffset(prop.value)
+	case "TZOFFSETTO":
+		o.offsetTo, err = parseOffset(prop.value)
+	case "RRULE":
+		err = o.setRule(prop.value)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid %s in VTIMEZONE: %w", prop.name, err)
+	}
+	return nil
+}
+
+// setRule reads the yearly rules zones use, e.g. FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU.
+func (o *zoneObservance) setRule(rule string) error {
+	parts := make(map[string]string)
+	for _, part := range strings.Split(rule, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		parts[strings.ToUpper(key)] = strings.ToUpper(value)
+	}
+	if parts["FREQ"] != "YEARLY" {
+		return fmt.Errorf("unsupported rule %q", rule)
+	}
+	month, err := strconv.Atoi(parts["BYMONTH"])
+	if err != nil || month < 1 || month > 12 {
+		return fmt.Errorf("unsupported rule %q", rule)
+	}
+	byDay := parts["BYDAY"]
+	if len(byDay) < 2 {
+		return fmt.Errorf("unsupported rule %q", rule)
+	}
+	weekday := -1
+	for i, name := range weekdays {
+		if strings.HasSuffix(byDay, name) {
+			weekday = i
+		}
+	}
+	if weekday < 0 {
+		return fmt.Errorf("unsupported rule %q", rule)
+	}
+	week := 1
+	if n := strings.TrimSuffix(byDay, weekdays[weekday]); n != "" && n != "+" {
+		if week, err = strconv.Atoi(n); err != nil {
+			return fmt.Errorf("unsupported rule %q", rule)
+		}
+	}
+	if days := parts["BYMONTHDAY"]; days != "" {
+		for _, day := range strings.Split(days, ",") {
+			n, err := strconv.Atoi(day)
+			if err != nil {
+				return fmt.Errorf("unsupported rule %q", rule)
+			}
+			o.monthDays = append(o.monthDays, n)
+		}
+	}
+	o.month, o.weekday, o.week = time.Month(month), time.Weekday(weekday), week
+	return nil
+}
+
+// onset returns the wall clock time the observance starts in year, if it
+// does.
+func (o *zoneObservance) onset(year int) (time.Time, bool) {
+	if o.month == 0 {
+		return o.start, o.start.Year() == year
+	}
+	day := nthWeekday(year, o.month, o.weekday, o.week)
+	if len(o.monthDays) > 0 {
+		// The weekday that falls on one of the listed days.
+		for _, d := range o.monthDays {
+			candidate := time.Date(year, o.month, d, 0, 0, 0, 0, time.UTC)
+			if candidate.Weekday() == o.weekday {
+				day = candidate
+				break
+			}
+		}
+	}
+	t := day.Add(time.Duration(o.start.Hour())*time.Hour + time.Duration(o.start.Minute())*time.Minute)
+	return t, !t.Before(o.start)
+}
+
+// offsetAt returns the UTC offset in seconds in effect at a wall clock time:
+// that of the observance with the latest onset before it.
+func (z *vtimezone) offsetAt(local time.Time) int {
+	var latest time.Time
+	offset, found := 0, false
+	for _, o := range z.observances {
+		for _, year := range []int{local.Year() - 1, local.Year()} {
+			onset, ok := o.onset(year)
+			if ok && !onset.After(local) && (!found || onset.After(latest)) {
+				latest, offset, found = onset, o.offsetTo, true
+			}
+		}
+	}
+	if !found && len(z.observances) > 0 {
+		// Before the first onset; take the earliest observance's prior offset.
+		earliest := z.observances[0]
+		for _, o := range z.observances[1:] {
+			if o.start.Before(earliest.start) {
+				earliest = o
+			}
+		}
+		offset = earliest.offsetFrom
+	}
+	return offset
+}
+
+// parseOffset reads a UTC offset such as "+0130" or "-053000" in seconds.
+func parseOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 || (value[0] != '+' && value[0] != '-') {
+		return 0, fmt.Errorf("malformed offset %q", value)
+	}
+	digits := value[1:] + "00"
+	hours, err1 := strconv.Atoi(digits[0:2])
+	minutes, err2 := strconv.Atoi(digits[2:4])
+	seconds, err3 := strconv.Atoi(digits[4:6])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("malformed offset %q", value)
+	}
+	offset := hours*3600 + minutes*60 + seconds
+	if value[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
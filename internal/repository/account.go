@@ -0,0 +1,56 @@
+// internal/repository/account.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"google-calendar-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type calendarAccountRepo struct {
+	db *gorm.DB
+}
+
+// NewCalendarAccountRepository creates a new CalendarAccountRepository instance.
+func NewCalendarAccountRepository(db *gorm.DB) CalendarAccountRepository {
+	return &calendarAccountRepo{db}
+}
+
+func (r *calendarAccountRepo) GetAccount(ctx context.Context, userEmail, provider string) (*domain.CalendarAccount, error) {
+	var account domain.CalendarAccount
+	result := r.db.WithContext(ctx).Where("user_email = ? AND provider = ?", userEmail, provider).First(&account)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &account, nil
+}
+
+func (r *calendarAccountRepo) GetAccountBySubject(ctx context.Context, provider, subject string) (*domain.CalendarAccount, error) {
+	if subject == "" {
+		return nil, nil
+	}
+	var account domain.CalendarAccount
+	result := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&account)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &account, nil
+}
+
+func (r *calendarAccountRepo) ListAccounts(ctx context.Context, userEmail string) ([]domain.CalendarAccount, error) {
+	var accounts []domain.CalendarAccount
+	err := r.db.WithContext(ctx).Where("user_email = ?", userEmail).Order("provider").Find(&accounts).Error
+	return accounts, err
+}
+
+func (r *calendarAccountRepo) SaveAccount(ctx context.Context, account *domain.CalendarAccount) error {
+	return r.db.WithContext(ctx).Save(account).Error
+}
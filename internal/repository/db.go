@@ -0,0 +1,78 @@
+// internal/repository/db.go
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// OpenDB connects to the database of driver at url, a file path for SQLite.
+func OpenDB(driver, url string) (*gorm.DB, error) {
+	switch driver {
+	case DriverPostgres:
+		return gorm.Open(postgres.Open(url), &gorm.Config{})
+	case DriverSQLite:
+		// Foreign keys are off by default. WAL lets readers go on while one
+		// writes, and taking the write lock when a transaction begins makes
+		// writers queue up behind the busy timeout instead of failing.
+		sep := "?"
+		if strings.Contains(url, "?") {
+			sep = "&"
+		}
+		return gorm.Open(sqlite.New(sqlite.Config{
+			DriverName: sqliteUTCDriver,
+			DSN:        url + sep + "_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate",
+		}), &gorm.Config{})
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+}
+
+// sqliteUTCDriver is SQLite with every time argument turned into UTC. SQLite
+// keeps times as text and compares them as such, which only orders them
+// right when they all have the same offset.
+const sqliteUTCDriver = "sqlite3_utc"
+
+func init() {
+	sql.Register(sqliteUTCDriver, utcDriver{})
+}
+
+type utcDriver struct {
+	sqlite3.SQLiteDriver
+}
+
+func (d utcDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.SQLiteDriver.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return utcConn{conn.(*sqlite3.SQLiteConn)}, nil
+}
+
+type utcConn struct {
+	*sqlite3.SQLiteConn
+}
+
+// CheckNamedValue converts times and leaves everything else to the default
+// conversion.
+func (c utcConn) CheckNamedValue(nv *driver.NamedValue) error {
+	switch v := nv.Value.(type) {
+	case time.Time:
+		nv.Value = v.UTC()
+		return nil
+	case *time.Time:
+		if v != nil {
+			nv.Value = v.UTC()
+			return nil
+		}
+	}
+	return driver.ErrSkip
+}
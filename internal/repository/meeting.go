@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"google-calendar-api/internal/domain"
 	"strings"
 	"time"
@@ -20,9 +21,7 @@ func NewMeetingRepository(db *gorm.DB) MeetingRepository {
 }
 
 func (r *meetingRepo) CreateMeeting(ctx context.Context, meeting *domain.Meeting) error {
-	// Convert attendees slice to a comma-separated string
-	attendeesString := strings.Join(meeting.Attendees, ",")
-	meeting.AttendeesString = attendeesString // Store in the database field
+	fillStorageColumns(meeting)
 
 	tx := r.db.WithContext(ctx).Begin() // Start a transaction
 	defer func() {
@@ -41,7 +40,7 @@ func (r *meetingRepo) CreateMeeting(ctx context.Context, meeting *domain.Meeting
 
 func (r *meetingRepo) ListMeetingsByUser(ctx context.Context, userEmail string, startTime, endTime time.Time) ([]domain.Meeting, error) {
 	var meetings []domain.Meeting
-	err := r.db.WithContext(ctx).
+	err := r.meetings(ctx).
 		Where("created_by = ? AND start_time >= ? AND end_time <= ?", userEmail, startTime, endTime).
 		Find(&meetings).Error
 	return meetings, err
@@ -49,11 +48,115 @@ func (r *meetingRepo) ListMeetingsByUser(ctx context.Context, userEmail string,
 
 func (r *meetingRepo) GetMeetingByID(ctx context.Context, id uint) (*domain.Meeting, error) {
 	var meeting domain.Meeting
-	err := r.db.WithContext(ctx).
+	err := r.meetings(ctx).
 		Where("id = ?", id).
 		First(&meeting).Error
 	if err != nil {
-		return nil, err // Handle not found appropriately
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, err
+	}
+	return &meeting, nil
+}
+
+func (r *meetingRepo) GetMeetingByEventID(ctx context.Context, createdBy, eventID string) (*domain.Meeting, error) {
+	var meeting domain.Meeting
+	result := r.meetings(ctx).Where("created_by = ? AND event_id = ?", createdBy, eventID).First(&meeting)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
 	}
 	return &meeting, nil
 }
+
+func (r *meetingRepo) GetMeetingByICalUID(ctx context.Context, createdBy, icalUID string) (*domain.Meeting, error) {
+	var meeting domain.Meeting
+	// Rows whose creation failed never made it to Google, so they don't count.
+	result := r.meetings(ctx).Where("created_by = ? AND ical_uid = ? AND commit_state <> 'failed'", createdBy, icalUID).Order("id").First(&meeting)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &meeting, nil
+}
+
+func (r *meetingRepo) UpdateMeeting(ctx context.Context, meeting *domain.Meeting) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return saveMeeting(tx, meeting)
+	})
+}
+
+func (r *meetingRepo) DeleteMeeting(ctx context.Context, meeting *domain.Meeting) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("meeting_id = ?", meeting.ID).Delete(&domain.Attendee{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(meeting).Error
+	})
+}
+
+func (r *meetingRepo) ListMeetingExceptions(ctx context.Context, createdBy, recurringEventID string) ([]domain.Meeting, error) {
+	var meetings []domain.Meeting
+	err := r.meetings(ctx).
+		Where("created_by = ? AND recurring_event_id = ?", createdBy, recurringEventID).
+		Order("original_start_time").
+		Find(&meetings).Error
+	return meetings, err
+}
+
+func (r *meetingRepo) ListMeetingsByCalendar(ctx context.Context, createdBy, provider, calendarID string) ([]domain.Meeting, error) {
+	var meetings []domain.Meeting
+	err := r.meetings(ctx).
+		Where("created_by = ? AND provider = ? AND calendar_id = ?", createdBy, provider, calendarID).
+		Find(&meetings).Error
+	return meetings, err
+}
+
+func (r *meetingRepo) ListMeetingsByOwner(ctx context.Context, createdBy string) ([]domain.Meeting, error) {
+	var meetings []domain.Meeting
+	err := r.meetings(ctx).Where("created_by = ?", createdBy).Order("id").Find(&meetings).Error
+	return meetings, err
+}
+
+func (r *meetingRepo) ListMeetingOwners(ctx context.Context) ([]string, error) {
+	var owners []string
+	err := r.db.WithContext(ctx).Model(&domain.Meeting{}).Distinct().Order("created_by").Pluck("created_by", &owners).Error
+	return owners, err
+}
+
+// meetings starts a meeting query that loads the attendees along.
+func (r *meetingRepo) meetings(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx).Preload("Attendees", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	})
+}
+
+// fillStorageColumns keeps the storage columns in sync with the slices.
+func fillStorageColumns(meeting *domain.Meeting) {
+	meeting.RecurrenceString = strings.Join(meeting.Recurrence, "\n")
+}
+
+// saveMeeting updates meeting inside tx and replaces its attendee rows with
+// meeting.Attendees.
+func saveMeeting(tx *gorm.DB, meeting *domain.Meeting) error {
+	fillStorageColumns(meeting)
+	if err := tx.Omit("Attendees").Save(meeting).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Where("meeting_id = ?", meeting.ID).Delete(&domain.Attendee{}).Error; err != nil {
+		return err
+	}
+	if len(meeting.Attendees) == 0 {
+		return nil
+	}
+	for i := range meeting.Attendees {
+		meeting.Attendees[i].Model = gorm.Model{}
+		meeting.Attendees[i].MeetingID = meeting.ID
+	}
+	return tx.Create(&meeting.Attendees).Error
+}
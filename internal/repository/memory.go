@@ -0,0 +1,371 @@
+// internal/repository/memory.go
+package repository
+
+import (
+	"context"
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The in-memory repositories behave like the GORM ones on an empty database,
+// down to the column defaults and unique indexes, so unit tests can use them
+// instead of a live database. Callers get copies and never share memory with
+// the store.
+
+type memoryUserRepo struct {
+	mu    sync.Mutex
+	users []domain.User
+}
+
+// NewMemoryUserRepository creates a UserRepository that keeps its users in
+// memory.
+func NewMemoryUserRepository() UserRepository {
+	return &memoryUserRepo{}
+}
+
+func (r *memoryUserRepo) CreateUser(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	if r.index(user.ID) >= 0 {
+		return fmt.Errorf("duplicate user id %s", user.ID)
+	}
+	if user.CalendarProvider == "" {
+		user.CalendarProvider = "google"
+	}
+	now := time.Now()
+	user.CreatedAt, user.UpdatedAt = now, now
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+	r.users = append(r.users, *user)
+	return nil
+}
+
+func (r *memoryUserRepo) GetUserByGoogleID(ctx context.Context, googleID string) (*domain.User, error) {
+	if googleID == "" {
+		return nil, nil // Users without a Google account have an empty ID
+	}
+	return r.find(func(u *domain.User) bool { return u.GoogleID == googleID }), nil
+}
+
+func (r *memoryUserRepo) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.find(func(u *domain.User) bool { return u.Email == email }), nil
+}
+
+func (r *memoryUserRepo) GetUserByFeedToken(ctx context.Context, token string) (*domain.User, error) {
+	if token == "" {
+		return nil, nil // Users without a feed have an empty token
+	}
+	return r.find(func(u *domain.User) bool { return u.FeedToken == token }), nil
+}
+
+func (r *memoryUserRepo) SetFeedToken(ctx context.Context, email, token string) error {
+	return r.update(email, func(u *domain.User) { u.FeedToken = token })
+}
+
+func (r *memoryUserRepo) SetTokens(ctx context.Context, email, accessToken, refreshToken string, expiresAt time.Time) error {
+	return r.update(email, func(u *domain.User) {
+		u.AccessToken, u.ExpiresAt = accessToken, expiresAt
+		if refreshToken != "" {
+			u.RefreshToken = refreshToken
+		}
+	})
+}
+
+func (r *memoryUserRepo) SetCalendarProvider(ctx context.Context, email, provider string) error {
+	return r.update(email, func(u *domain.User) { u.CalendarProvider = provider })
+}
+
+// UpdateUser writes every field, creating the user when the ID is new, as
+// GORM's Save does.
+func (r *memoryUserRepo) UpdateUser(ctx context.Context, user *domain.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	user.UpdatedAt = time.Now()
+	if err := r.checkUnique(user); err != nil {
+		return err
+	}
+	if i := r.index(user.ID); i >= 0 {
+		r.users[i] = *user
+	} else {
+		r.users = append(r.users, *user)
+	}
+	return nil
+}
+
+func (r *memoryUserRepo) ListUsers(ctx context.Context) ([]domain.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := append([]domain.User(nil), r.users...)
+	sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+	return users, nil
+}
+
+func (r *memoryUserRepo) find(match func(u *domain.User) bool) *domain.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if match(&r.users[i]) {
+			user := r.users[i]
+			return &user
+		}
+	}
+	return nil
+}
+
+// update changes the user with email, if there is one.
+func (r *memoryUserRepo) update(email string, change func(u *domain.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.users {
+		if r.users[i].Email != email {
+			continue
+		}
+		user := r.users[i]
+		change(&user)
+		user.UpdatedAt = time.Now()
+		if err := r.checkUnique(&user); err != nil {
+			return err
+		}
+		r.users[i] = user
+	}
+	return nil
+}
+
+// index returns where the user with id is stored, or -1. The caller holds
+// r.mu.
+func (r *memoryUserRepo) index(id uuid.UUID) int {
+	for i := range r.users {
+		if r.users[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// checkUnique enforces the unique indexes of the users table against every
+// other user. The caller holds r.mu.
+func (r *memoryUserRepo) checkUnique(user *domain.User) error {
+	for _, other := range r.users {
+		switch {
+		case other.ID == user.ID:
+		case other.Email == user.Email:
+			return fmt.Errorf("duplicate user email %q", user.Email)
+		case user.GoogleID != "" && other.GoogleID == user.GoogleID:
+			return fmt.Errorf("duplicate user google_id %q", user.GoogleID)
+		case user.FeedToken != "" && other.FeedToken == user.FeedToken:
+			return fmt.Errorf("duplicate user feed_token")
+		}
+	}
+	return nil
+}
+
+type memoryMeetingRepo struct {
+	mu             sync.Mutex
+	meetings       []domain.Meeting // By ID
+	lastMeetingID  uint
+	lastAttendeeID uint
+}
+
+// NewMemoryMeetingRepository creates a MeetingRepository that keeps its
+// meetings in memory.
+func NewMemoryMeetingRepository() MeetingRepository {
+	return &memoryMeetingRepo{}
+}
+
+func (r *memoryMeetingRepo) CreateMeeting(ctx context.Context, meeting *domain.Meeting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if meeting.ID != 0 && r.index(meeting.ID) >= 0 {
+		return fmt.Errorf("duplicate meeting id %d", meeting.ID)
+	}
+	r.store(meeting, time.Now())
+	return nil
+}
+
+func (r *memoryMeetingRepo) ListMeetingsByUser(ctx context.Context, userEmail string, startTime, endTime time.Time) ([]domain.Meeting, error) {
+	return r.filter(func(m *domain.Meeting) bool {
+		return m.CreatedBy == userEmail && !m.StartTime.Before(startTime) && !m.EndTime.After(endTime)
+	}), nil
+}
+
+func (r *memoryMeetingRepo) GetMeetingByID(ctx context.Context, id uint) (*domain.Meeting, error) {
+	return r.first(func(m *domain.Meeting) bool { return m.ID == id }), nil
+}
+
+func (r *memoryMeetingRepo) GetMeetingByEventID(ctx context.Context, createdBy, eventID string) (*domain.Meeting, error) {
+	return r.first(func(m *domain.Meeting) bool { return m.CreatedBy == createdBy && m.EventID == eventID }), nil
+}
+
+func (r *memoryMeetingRepo) GetMeetingByICalUID(ctx context.Context, createdBy, icalUID string) (*domain.Meeting, error) {
+	// Rows whose creation failed never made it to Google, so they don't count.
+	return r.first(func(m *domain.Meeting) bool {
+		return m.CreatedBy == createdBy && m.ICalUID == icalUID && m.CommitState != "failed"
+	}), nil
+}
+
+// UpdateMeeting writes every field and replaces the attendees, creating the
+// meeting when the ID is new, as the GORM repository does.
+func (r *memoryMeetingRepo) UpdateMeeting(ctx context.Context, meeting *domain.Meeting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.store(meeting, time.Now())
+	return nil
+}
+
+func (r *memoryMeetingRepo) DeleteMeeting(ctx context.Context, meeting *domain.Meeting) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i := r.index(meeting.ID); i >= 0 {
+		r.meetings = append(r.meetings[:i], r.meetings[i+1:]...)
+	}
+	return nil
+}
+
+func (r *memoryMeetingRepo) ListMeetingExceptions(ctx context.Context, createdBy, recurringEventID string) ([]domain.Meeting, error) {
+	meetings := r.filter(func(m *domain.Meeting) bool {
+		return m.CreatedBy == createdBy && m.RecurringEventID == recurringEventID
+	})
+	// Postgres puts rows without an original start last.
+	sort.SliceStable(meetings, func(i, j int) bool {
+		a, b := meetings[i].OriginalStartTime, meetings[j].OriginalStartTime
+		return a != nil && (b == nil || a.Before(*b))
+	})
+	return meetings, nil
+}
+
+func (r *memoryMeetingRepo) ListMeetingsByCalendar(ctx context.Context, createdBy, provider, calendarID string) ([]domain.Meeting, error) {
+	return r.filter(func(m *domain.Meeting) bool {
+		return m.CreatedBy == createdBy && m.Provider == provider && m.CalendarID == calendarID
+	}), nil
+}
+
+func (r *memoryMeetingRepo) ListMeetingsByOwner(ctx context.Context, createdBy string) ([]domain.Meeting, error) {
+	return r.filter(func(m *domain.Meeting) bool { return m.CreatedBy == createdBy }), nil
+}
+
+func (r *memoryMeetingRepo) ListMeetingOwners(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var owners []string
+	seen := make(map[string]bool)
+	for _, m := range r.meetings {
+		if !seen[m.CreatedBy] {
+			seen[m.CreatedBy] = true
+			owners = append(owners, m.CreatedBy)
+		}
+	}
+	sort.Strings(owners)
+	return owners, nil
+}
+
+// store fills in the IDs and, for a new row, the defaults the database
+// would, then saves a copy of meeting in place of the one with its ID. The
+// caller holds r.mu.
+func (r *memoryMeetingRepo) store(meeting *domain.Meeting, now time.Time) {
+	fillStorageColumns(meeting)
+	if meeting.ID == 0 {
+		meeting.ID = r.lastMeetingID + 1
+	}
+	r.lastMeetingID = max(r.lastMeetingID, meeting.ID)
+	if r.index(meeting.ID) < 0 {
+		if meeting.CreatedAt.IsZero() {
+			meeting.CreatedAt = now
+		}
+		setDefault(&meeting.CalendarID, "primary")
+		setDefault(&meeting.Provider, "google")
+		setDefault(&meeting.Status, "confirmed")
+		setDefault(&meeting.CommitState, "committed")
+	}
+	meeting.UpdatedAt = now
+	for i := range meeting.Attendees {
+		attendee := &meeting.Attendees[i]
+		r.lastAttendeeID++
+		attendee.Model.ID = r.lastAttendeeID
+		attendee.CreatedAt, attendee.UpdatedAt = now, now
+		attendee.MeetingID = meeting.ID
+		setDefault(&attendee.ResponseStatus, "needsAction")
+	}
+
+	stored := copyMeeting(meeting)
+	stored.Recurrence = nil // Like the gorm:"-" fields, these aren't kept
+	stored.Exceptions = nil
+	if i := r.index(meeting.ID); i >= 0 {
+		r.meetings[i] = stored
+		return
+	}
+	r.meetings = append(r.meetings, stored)
+	sort.Slice(r.meetings, func(i, j int) bool { return r.meetings[i].ID < r.meetings[j].ID })
+}
+
+func (r *memoryMeetingRepo) first(match func(m *domain.Meeting) bool) *domain.Meeting {
+	meetings := r.filter(match)
+	if len(meetings) == 0 {
+		return nil
+	}
+	return &meetings[0]
+}
+
+// filter returns copies of the matching meetings, oldest first.
+func (r *memoryMeetingRepo) filter(match func(m *domain.Meeting) bool) []domain.Meeting {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var meetings []domain.Meeting
+	for i := range r.meetings {
+		if match(&r.meetings[i]) {
+			meetings = append(meetings, copyMeeting(&r.meetings[i]))
+		}
+	}
+	return meetings
+}
+
+// index returns where the meeting with id is stored, or -1. The caller
+// holds r.mu.
+func (r *memoryMeetingRepo) index(id uint) int {
+	i := sort.Search(len(r.meetings), func(i int) bool { return r.meetings[i].ID >= id })
+	if i < len(r.meetings) && r.meetings[i].ID == id {
+		return i
+	}
+	return -1
+}
+
+// copyMeeting returns a copy of meeting that shares no memory with it.
+func copyMeeting(meeting *domain.Meeting) domain.Meeting {
+	c := *meeting
+	c.Attendees = append([]domain.Attendee(nil), meeting.Attendees...)
+	c.Recurrence = append([]string(nil), meeting.Recurrence...)
+	c.Exceptions = nil
+	if meeting.OriginalStartTime != nil {
+		t := *meeting.OriginalStartTime
+		c.OriginalStartTime = &t
+	}
+	return c
+}
+
+// setDefault applies a column default, which GORM only does for zero values.
+func setDefault(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
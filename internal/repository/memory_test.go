@@ -0,0 +1,14 @@
+// internal/repository/memory_test.go
+package repository_test
+
+import (
+	"testing"
+
+	"google-calendar-api/internal/repository/repotest"
+)
+
+// TestMemoryRepositories holds the in-memory repositories to the same
+// conformance suite as the database ones.
+func TestMemoryRepositories(t *testing.T) {
+	repotest.Run(t, repotest.Memory)
+}
@@ -0,0 +1,274 @@
+// internal/repository/migrate.go
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql migrations/sqlite/*.sql
+var migrationFiles embed.FS
+
+// Database drivers, each with its own set of migrations.
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+// migrationLockKey is the Postgres advisory lock held while migrating, so two
+// replicas starting at once don't both run the same step.
+const migrationLockKey int64 = 7_201_432_019
+
+// ErrSchemaBehind means the database lacks migrations this build needs.
+var ErrSchemaBehind = errors.New("database schema is behind, run the migrate up command")
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is one versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState tells whether a migration has been applied.
+type MigrationState struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"` // Nil while pending
+}
+
+// Migrations returns the embedded migrations for driver, oldest first.
+func Migrations(driver string) ([]Migration, error) {
+	dir := "migrations"
+	switch driver {
+	case DriverPostgres:
+	case DriverSQLite:
+		dir = "migrations/sqlite"
+	default:
+		return nil, fmt.Errorf("unknown database driver %q", driver)
+	}
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		parts := migrationName.FindStringSubmatch(entry.Name())
+		if parts == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(parts[1])
+		body, err := migrationFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: parts[2]}
+			byVersion[version] = m
+		} else if m.Name != parts[2] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, m.Name, parts[2])
+		}
+		if parts[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d needs both an up and a down file", m.Version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// MigrateUp applies every pending migration and returns the ones it applied.
+func MigrateUp(ctx context.Context, db *sql.DB, driver string) ([]Migration, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Migration
+	err = withMigrationLock(ctx, db, driver, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn, driver)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			err := runMigration(ctx, conn, m.Up, `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, CURRENT_TIMESTAMP)`, m.Version, m.Name)
+			if err != nil {
+				return fmt.Errorf("migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the latest steps applied migrations, newest first, and
+// returns the ones it reverted.
+func MigrateDown(ctx context.Context, db *sql.DB, driver string, steps int) ([]Migration, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = withMigrationLock(ctx, db, driver, func(conn *sql.Conn) error {
+		done, err := appliedVersions(ctx, conn, driver)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			err := runMigration(ctx, conn, m.Down, `DELETE FROM schema_migrations WHERE version = $1`, m.Version)
+			if err != nil {
+				return fmt.Errorf("reverting migration %d_%s failed: %w", m.Version, m.Name, err)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrationStatus lists every known migration and when it was applied.
+func MigrationStatus(ctx context.Context, db *sql.DB, driver string) ([]MigrationState, error) {
+	migrations, err := Migrations(driver)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	done, err := appliedVersions(ctx, conn, driver)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]MigrationState, 0, len(migrations))
+	for _, m := range migrations {
+		state := MigrationState{Version: m.Version, Name: m.Name}
+		if appliedAt, ok := done[m.Version]; ok {
+			state.AppliedAt = &appliedAt
+		}
+		states = append(states, state)
+	}
+	return states, nil
+}
+
+// CheckSchema returns ErrSchemaBehind when a known migration has not been
+// applied yet.
+func CheckSchema(ctx context.Context, db *sql.DB, driver string) error {
+	states, err := MigrationStatus(ctx, db, driver)
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		if state.AppliedAt == nil {
+			return fmt.Errorf("%w (missing %d_%s)", ErrSchemaBehind, state.Version, state.Name)
+		}
+	}
+	return nil
+}
+
+// withMigrationLock runs fn on one connection while holding the migration
+// advisory lock. Session locks belong to a connection, hence the pinning.
+// SQLite has no such lock; its transactions already exclude each other.
+func withMigrationLock(ctx context.Context, db *sql.DB, driver string, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if driver == DriverSQLite {
+		return fn(conn)
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	return fn(conn)
+}
+
+// appliedVersions returns when each applied migration ran, creating the
+// version table on first use.
+func appliedVersions(ctx context.Context, conn *sql.Conn, driver string) (map[int]time.Time, error) {
+	timeType := "timestamptz"
+	if driver == DriverSQLite {
+		timeType = "datetime" // Read back as time.Time
+	}
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at `+timeType+` NOT NULL
+	)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// runMigration runs body and the bookkeeping statement in one transaction.
+func runMigration(ctx context.Context, conn *sql.Conn, body, record string, args ...any) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, body); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
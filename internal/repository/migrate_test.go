@@ -0,0 +1,135 @@
+// internal/repository/migrate_test.go
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"google-calendar-api/internal/repository"
+)
+
+// The models as the first release had them, which AutoMigrate turned into
+// the schema of every database from before versioned migrations.
+type baselineUser struct {
+	gorm.Model
+	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	GoogleID     string    `gorm:"unique"`
+	Email        string    `gorm:"unique"`
+	Name         string
+	Picture      string
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineMeeting struct {
+	gorm.Model
+	ID              uint `gorm:"primaryKey"`
+	Title           string
+	Description     string
+	StartTime       time.Time
+	EndTime         time.Time
+	EventID         string
+	AttendeesString string `gorm:"column:attendees"`
+	CreatedBy       string
+}
+
+func (baselineMeeting) TableName() string { return "meetings" }
+
+type baselineAttendee struct {
+	gorm.Model
+	MeetingID uint
+	Email     string
+}
+
+func (baselineAttendee) TableName() string { return "attendees" }
+
+// TestMigrateUpFromBaseline upgrades a database AutoMigrate created with the
+// first release, in a schema of its own on the TEST_DB_URL server.
+func TestMigrateUpFromBaseline(t *testing.T) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	ctx := context.Background()
+
+	admin, err := repository.OpenDB(repository.DriverPostgres, url)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	adminDB, err := admin.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { adminDB.Close() })
+
+	schema := fmt.Sprintf("baseline_%d", time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("failed to create schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	sep := "?"
+	if strings.Contains(url, "?") {
+		sep = "&"
+	}
+	db, err := repository.OpenDB(repository.DriverPostgres, url+sep+"search_path="+schema)
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&baselineUser{}, &baselineMeeting{}, &baselineAttendee{}); err != nil {
+		t.Fatalf("failed to create baseline schema: %v", err)
+	}
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	legacy := baselineMeeting{
+		Title:           "Standup",
+		StartTime:       start,
+		EndTime:         start.Add(15 * time.Minute),
+		EventID:         "evt1",
+		AttendeesString: "a@example.com, b@example.com,",
+		CreatedBy:       "owner@example.com",
+	}
+	if err := db.Create(&legacy).Error; err != nil {
+		t.Fatalf("failed to insert baseline meeting: %v", err)
+	}
+
+	if _, err := repository.MigrateUp(ctx, sqlDB, repository.DriverPostgres); err != nil {
+		t.Fatalf("MigrateUp failed: %v", err)
+	}
+	if err := repository.CheckSchema(ctx, sqlDB, repository.DriverPostgres); err != nil {
+		t.Fatalf("schema is not current after upgrade: %v", err)
+	}
+
+	meeting, err := repository.NewMeetingRepository(db).GetMeetingByID(ctx, legacy.ID)
+	if err != nil || meeting == nil {
+		t.Fatalf("GetMeetingByID = %v, %v", meeting, err)
+	}
+	if meeting.CalendarID != "primary" || meeting.Status != "confirmed" || meeting.CommitState != "committed" {
+		t.Errorf("backfilled columns = %q, %q, %q", meeting.CalendarID, meeting.Status, meeting.CommitState)
+	}
+	var emails []string
+	for _, attendee := range meeting.Attendees {
+		emails = append(emails, attendee.Email)
+		if attendee.ResponseStatus != "needsAction" {
+			t.Errorf("attendee %s response status = %q", attendee.Email, attendee.ResponseStatus)
+		}
+	}
+	if strings.Join(emails, ",") != "a@example.com,b@example.com" {
+		t.Errorf("attendees = %v, want a@example.com and b@example.com", emails)
+	}
+}
@@ -0,0 +1,242 @@
+-- Schema as AutoMigrate left it by the time versioned migrations came in.
+-- Databases AutoMigrate created with an older build, down to the first one,
+-- lack tables and columns, so everything is added only where it is missing.
+
+CREATE TABLE IF NOT EXISTS users (
+    id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    google_id     text UNIQUE,
+    email         text UNIQUE,
+    name          text,
+    picture       text,
+    access_token  text,
+    refresh_token text,
+    expires_at    timestamptz
+);
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS created_at    timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at    timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at    timestamptz,
+    ADD COLUMN IF NOT EXISTS google_id     text UNIQUE,
+    ADD COLUMN IF NOT EXISTS email         text UNIQUE,
+    ADD COLUMN IF NOT EXISTS name          text,
+    ADD COLUMN IF NOT EXISTS picture       text,
+    ADD COLUMN IF NOT EXISTS access_token  text,
+    ADD COLUMN IF NOT EXISTS refresh_token text,
+    ADD COLUMN IF NOT EXISTS expires_at    timestamptz;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+
+CREATE TABLE IF NOT EXISTS meetings (
+    id                  bigserial PRIMARY KEY,
+    created_at          timestamptz,
+    updated_at          timestamptz,
+    deleted_at          timestamptz,
+    title               text,
+    description         text,
+    start_time          timestamptz,
+    end_time            timestamptz,
+    all_day             boolean,
+    event_id            text,
+    calendar_id         text DEFAULT 'primary',
+    attendees           text,
+    created_by          text,
+    conference_id       text,
+    conference_url      text,
+    recurrence          text,
+    recurring_event_id  text,
+    original_start_time timestamptz,
+    status              text DEFAULT 'confirmed',
+    commit_state        text DEFAULT 'committed'
+);
+ALTER TABLE meetings
+    ADD COLUMN IF NOT EXISTS created_at          timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at          timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at          timestamptz,
+    ADD COLUMN IF NOT EXISTS title               text,
+    ADD COLUMN IF NOT EXISTS description         text,
+    ADD COLUMN IF NOT EXISTS start_time          timestamptz,
+    ADD COLUMN IF NOT EXISTS end_time            timestamptz,
+    ADD COLUMN IF NOT EXISTS all_day             boolean,
+    ADD COLUMN IF NOT EXISTS event_id            text,
+    ADD COLUMN IF NOT EXISTS calendar_id         text DEFAULT 'primary',
+    ADD COLUMN IF NOT EXISTS attendees           text,
+    ADD COLUMN IF NOT EXISTS created_by          text,
+    ADD COLUMN IF NOT EXISTS conference_id       text,
+    ADD COLUMN IF NOT EXISTS conference_url      text,
+    ADD COLUMN IF NOT EXISTS recurrence          text,
+    ADD COLUMN IF NOT EXISTS recurring_event_id  text,
+    ADD COLUMN IF NOT EXISTS original_start_time timestamptz,
+    ADD COLUMN IF NOT EXISTS status              text DEFAULT 'confirmed',
+    ADD COLUMN IF NOT EXISTS commit_state        text DEFAULT 'committed';
+CREATE INDEX IF NOT EXISTS idx_meetings_deleted_at ON meetings (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_meetings_recurring_event_id ON meetings (recurring_event_id);
+
+CREATE TABLE IF NOT EXISTS attendees (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    meeting_id      bigint REFERENCES meetings (id),
+    email           text,
+    name            text,
+    optional        boolean,
+    organizer       boolean,
+    response_status text DEFAULT 'needsAction'
+);
+ALTER TABLE attendees
+    ADD COLUMN IF NOT EXISTS created_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS meeting_id      bigint REFERENCES meetings (id),
+    ADD COLUMN IF NOT EXISTS email           text,
+    ADD COLUMN IF NOT EXISTS name            text,
+    ADD COLUMN IF NOT EXISTS optional        boolean,
+    ADD COLUMN IF NOT EXISTS organizer       boolean,
+    ADD COLUMN IF NOT EXISTS response_status text DEFAULT 'needsAction';
+CREATE INDEX IF NOT EXISTS idx_attendees_deleted_at ON attendees (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_attendees_meeting_id ON attendees (meeting_id);
+
+CREATE TABLE IF NOT EXISTS calendar_syncs (
+    id             bigserial PRIMARY KEY,
+    created_at     timestamptz,
+    updated_at     timestamptz,
+    deleted_at     timestamptz,
+    user_email     text,
+    calendar_id    text,
+    sync_token     text,
+    last_synced_at timestamptz
+);
+ALTER TABLE calendar_syncs
+    ADD COLUMN IF NOT EXISTS created_at     timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at     timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at     timestamptz,
+    ADD COLUMN IF NOT EXISTS user_email     text,
+    ADD COLUMN IF NOT EXISTS calendar_id    text,
+    ADD COLUMN IF NOT EXISTS sync_token     text,
+    ADD COLUMN IF NOT EXISTS last_synced_at timestamptz;
+CREATE INDEX IF NOT EXISTS idx_calendar_syncs_deleted_at ON calendar_syncs (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_sync ON calendar_syncs (user_email, calendar_id);
+
+CREATE TABLE IF NOT EXISTS watch_channels (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz,
+    channel_id  text,
+    resource_id text,
+    user_email  text,
+    calendar_id text,
+    token       text,
+    expiration  timestamptz
+);
+ALTER TABLE watch_channels
+    ADD COLUMN IF NOT EXISTS created_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS channel_id  text,
+    ADD COLUMN IF NOT EXISTS resource_id text,
+    ADD COLUMN IF NOT EXISTS user_email  text,
+    ADD COLUMN IF NOT EXISTS calendar_id text,
+    ADD COLUMN IF NOT EXISTS token       text,
+    ADD COLUMN IF NOT EXISTS expiration  timestamptz;
+CREATE INDEX IF NOT EXISTS idx_watch_channels_deleted_at ON watch_channels (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_watch_channels_channel_id ON watch_channels (channel_id);
+CREATE INDEX IF NOT EXISTS idx_watch_channels_user_email ON watch_channels (user_email);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id          bigserial PRIMARY KEY,
+    created_at  timestamptz,
+    updated_at  timestamptz,
+    deleted_at  timestamptz,
+    user_email  text,
+    url         text,
+    secret      text,
+    event_types text
+);
+ALTER TABLE webhook_subscriptions
+    ADD COLUMN IF NOT EXISTS created_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at  timestamptz,
+    ADD COLUMN IF NOT EXISTS user_email  text,
+    ADD COLUMN IF NOT EXISTS url         text,
+    ADD COLUMN IF NOT EXISTS secret      text,
+    ADD COLUMN IF NOT EXISTS event_types text;
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_subscriptions_user_email ON webhook_subscriptions (user_email);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    subscription_id bigint,
+    message_id      text,
+    event_type      text,
+    payload         text,
+    status          text,
+    attempts        bigint,
+    next_attempt_at timestamptz,
+    last_error      text,
+    response_status bigint,
+    delivered_at    timestamptz,
+    replay_of       bigint
+);
+ALTER TABLE webhook_deliveries
+    ADD COLUMN IF NOT EXISTS created_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS subscription_id bigint,
+    ADD COLUMN IF NOT EXISTS message_id      text,
+    ADD COLUMN IF NOT EXISTS event_type      text,
+    ADD COLUMN IF NOT EXISTS payload         text,
+    ADD COLUMN IF NOT EXISTS status          text,
+    ADD COLUMN IF NOT EXISTS attempts        bigint,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz,
+    ADD COLUMN IF NOT EXISTS last_error      text,
+    ADD COLUMN IF NOT EXISTS response_status bigint,
+    ADD COLUMN IF NOT EXISTS delivered_at    timestamptz,
+    ADD COLUMN IF NOT EXISTS replay_of       bigint;
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_message_id ON webhook_deliveries (message_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE IF NOT EXISTS outbox_operations (
+    id              bigserial PRIMARY KEY,
+    created_at      timestamptz,
+    updated_at      timestamptz,
+    deleted_at      timestamptz,
+    kind            text,
+    user_email      text,
+    meeting_id      bigint,
+    calendar_id     text,
+    event_id        text,
+    payload         text,
+    status          text,
+    attempts        bigint,
+    next_attempt_at timestamptz,
+    last_error      text
+);
+ALTER TABLE outbox_operations
+    ADD COLUMN IF NOT EXISTS created_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS updated_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS deleted_at      timestamptz,
+    ADD COLUMN IF NOT EXISTS kind            text,
+    ADD COLUMN IF NOT EXISTS user_email      text,
+    ADD COLUMN IF NOT EXISTS meeting_id      bigint,
+    ADD COLUMN IF NOT EXISTS calendar_id     text,
+    ADD COLUMN IF NOT EXISTS event_id        text,
+    ADD COLUMN IF NOT EXISTS payload         text,
+    ADD COLUMN IF NOT EXISTS status          text,
+    ADD COLUMN IF NOT EXISTS attempts        bigint,
+    ADD COLUMN IF NOT EXISTS next_attempt_at timestamptz,
+    ADD COLUMN IF NOT EXISTS last_error      text;
+CREATE INDEX IF NOT EXISTS idx_outbox_operations_deleted_at ON outbox_operations (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_operations_kind ON outbox_operations (kind);
+CREATE INDEX IF NOT EXISTS idx_outbox_operations_user_email ON outbox_operations (user_email);
+CREATE INDEX IF NOT EXISTS idx_outbox_operations_meeting_id ON outbox_operations (meeting_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_operations_status ON outbox_operations (status);
+CREATE INDEX IF NOT EXISTS idx_outbox_operations_next_attempt_at ON outbox_operations (next_attempt_at);
@@ -0,0 +1,7 @@
+ALTER TABLE meetings ADD COLUMN attendees text;
+
+UPDATE meetings m SET attendees = (
+    SELECT string_agg(a.email, ',' ORDER BY a.id)
+    FROM attendees a
+    WHERE a.meeting_id = m.id AND a.deleted_at IS NULL
+);
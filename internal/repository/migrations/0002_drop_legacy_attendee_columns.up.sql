@@ -0,0 +1,10 @@
+-- Move attendees still kept in the comma-separated column into attendee
+-- rows, then drop it along with the RSVP column that came before them.
+
+INSERT INTO attendees (created_at, updated_at, meeting_id, email, response_status)
+SELECT now(), now(), m.id, trim(e.email), 'needsAction'
+FROM meetings m, unnest(string_to_array(m.attendees, ',')) AS e(email)
+WHERE m.attendees <> '' AND trim(e.email) <> '';
+
+ALTER TABLE meetings DROP COLUMN IF EXISTS attendees;
+ALTER TABLE meetings DROP COLUMN IF EXISTS responses;
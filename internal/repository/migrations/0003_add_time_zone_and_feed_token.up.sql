@@ -0,0 +1,4 @@
+ALTER TABLE meetings ADD COLUMN time_zone text NOT NULL DEFAULT '';
+
+ALTER TABLE users ADD COLUMN feed_token text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX idx_users_feed_token ON users (feed_token) WHERE feed_token <> '';
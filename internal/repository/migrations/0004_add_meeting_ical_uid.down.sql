@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_meetings_ical_uid;
+ALTER TABLE meetings DROP COLUMN IF EXISTS ical_uid;
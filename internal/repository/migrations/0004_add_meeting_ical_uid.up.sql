@@ -0,0 +1,2 @@
+ALTER TABLE meetings ADD COLUMN ical_uid text NOT NULL DEFAULT '';
+CREATE INDEX idx_meetings_ical_uid ON meetings (created_by, ical_uid) WHERE ical_uid <> '';
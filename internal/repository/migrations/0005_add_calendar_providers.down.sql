@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS calendar_accounts;
+
+DROP INDEX IF EXISTS idx_users_google_id;
+UPDATE users SET google_id = NULL WHERE google_id = '';
+ALTER TABLE users ADD CONSTRAINT users_google_id_key UNIQUE (google_id);
+
+ALTER TABLE meetings DROP COLUMN IF EXISTS provider;
+ALTER TABLE users DROP COLUMN IF EXISTS calendar_provider;
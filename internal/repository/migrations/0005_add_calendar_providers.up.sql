@@ -0,0 +1,26 @@
+ALTER TABLE users ADD COLUMN calendar_provider text NOT NULL DEFAULT 'google';
+ALTER TABLE meetings ADD COLUMN provider text NOT NULL DEFAULT 'google';
+
+-- Users who only signed in with Microsoft have no Google ID, so only set
+-- IDs have to be unique.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_google_id_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_google_id;
+DROP INDEX IF EXISTS idx_users_google_id;
+CREATE UNIQUE INDEX idx_users_google_id ON users (google_id) WHERE google_id <> '';
+
+CREATE TABLE calendar_accounts (
+    id            bigserial PRIMARY KEY,
+    created_at    timestamptz,
+    updated_at    timestamptz,
+    deleted_at    timestamptz,
+    user_email    text NOT NULL,
+    provider      text NOT NULL,
+    subject       text NOT NULL DEFAULT '',
+    email         text NOT NULL DEFAULT '',
+    access_token  text,
+    refresh_token text,
+    expires_at    timestamptz
+);
+CREATE INDEX idx_calendar_accounts_deleted_at ON calendar_accounts (deleted_at);
+CREATE UNIQUE INDEX idx_calendar_account ON calendar_accounts (user_email, provider);
+CREATE UNIQUE INDEX idx_calendar_accounts_subject ON calendar_accounts (provider, subject) WHERE subject <> '';
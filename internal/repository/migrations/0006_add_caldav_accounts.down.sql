@@ -0,0 +1,5 @@
+UPDATE users SET calendar_provider = 'google' WHERE calendar_provider = 'caldav';
+DELETE FROM calendar_accounts WHERE provider = 'caldav';
+ALTER TABLE calendar_accounts DROP COLUMN IF EXISTS encrypted_password;
+ALTER TABLE calendar_accounts DROP COLUMN IF EXISTS username;
+ALTER TABLE calendar_accounts DROP COLUMN IF EXISTS server_url;
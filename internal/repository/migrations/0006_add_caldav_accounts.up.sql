@@ -0,0 +1,3 @@
+ALTER TABLE calendar_accounts ADD COLUMN server_url text NOT NULL DEFAULT '';
+ALTER TABLE calendar_accounts ADD COLUMN username text NOT NULL DEFAULT '';
+ALTER TABLE calendar_accounts ADD COLUMN encrypted_password text NOT NULL DEFAULT '';
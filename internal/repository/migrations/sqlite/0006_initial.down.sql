@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS calendar_accounts;
+DROP TABLE IF EXISTS outbox_operations;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS watch_channels;
+DROP TABLE IF EXISTS calendar_syncs;
+DROP TABLE IF EXISTS attendees;
+DROP TABLE IF EXISTS meetings;
+DROP TABLE IF EXISTS users;
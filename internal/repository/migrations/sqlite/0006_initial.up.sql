@@ -0,0 +1,172 @@
+-- SQLite databases start at the schema the Postgres migrations reach with
+-- 0006. Later migrations need a SQLite version here as well.
+
+CREATE TABLE users (
+    id                text PRIMARY KEY NOT NULL DEFAULT (lower(hex(randomblob(16)))),
+    created_at        datetime,
+    updated_at        datetime,
+    deleted_at        datetime,
+    google_id         text,
+    email             text UNIQUE,
+    name              text,
+    picture           text,
+    access_token      text,
+    refresh_token     text,
+    expires_at        datetime,
+    feed_token        text NOT NULL DEFAULT '',
+    calendar_provider text NOT NULL DEFAULT 'google'
+);
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE UNIQUE INDEX idx_users_google_id ON users (google_id) WHERE google_id <> '';
+CREATE UNIQUE INDEX idx_users_feed_token ON users (feed_token) WHERE feed_token <> '';
+
+CREATE TABLE meetings (
+    id                  integer PRIMARY KEY AUTOINCREMENT,
+    created_at          datetime,
+    updated_at          datetime,
+    deleted_at          datetime,
+    title               text,
+    description         text,
+    start_time          datetime,
+    end_time            datetime,
+    all_day             boolean,
+    time_zone           text NOT NULL DEFAULT '',
+    event_id            text,
+    ical_uid            text NOT NULL DEFAULT '',
+    calendar_id         text DEFAULT 'primary',
+    provider            text NOT NULL DEFAULT 'google',
+    created_by          text,
+    conference_id       text,
+    conference_url      text,
+    recurrence          text,
+    recurring_event_id  text,
+    original_start_time datetime,
+    status              text DEFAULT 'confirmed',
+    commit_state        text DEFAULT 'committed'
+);
+CREATE INDEX idx_meetings_deleted_at ON meetings (deleted_at);
+CREATE INDEX idx_meetings_recurring_event_id ON meetings (recurring_event_id);
+CREATE INDEX idx_meetings_ical_uid ON meetings (created_by, ical_uid) WHERE ical_uid <> '';
+
+CREATE TABLE attendees (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    created_at      datetime,
+    updated_at      datetime,
+    deleted_at      datetime,
+    meeting_id      integer REFERENCES meetings (id),
+    email           text,
+    name            text,
+    optional        boolean,
+    organizer       boolean,
+    response_status text DEFAULT 'needsAction'
+);
+CREATE INDEX idx_attendees_deleted_at ON attendees (deleted_at);
+CREATE INDEX idx_attendees_meeting_id ON attendees (meeting_id);
+
+CREATE TABLE calendar_syncs (
+    id             integer PRIMARY KEY AUTOINCREMENT,
+    created_at     datetime,
+    updated_at     datetime,
+    deleted_at     datetime,
+    user_email     text,
+    calendar_id    text,
+    sync_token     text,
+    last_synced_at datetime
+);
+CREATE INDEX idx_calendar_syncs_deleted_at ON calendar_syncs (deleted_at);
+CREATE UNIQUE INDEX idx_calendar_sync ON calendar_syncs (user_email, calendar_id);
+
+CREATE TABLE watch_channels (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    created_at  datetime,
+    updated_at  datetime,
+    deleted_at  datetime,
+    channel_id  text,
+    resource_id text,
+    user_email  text,
+    calendar_id text,
+    token       text,
+    expiration  datetime
+);
+CREATE INDEX idx_watch_channels_deleted_at ON watch_channels (deleted_at);
+CREATE UNIQUE INDEX idx_watch_channels_channel_id ON watch_channels (channel_id);
+CREATE INDEX idx_watch_channels_user_email ON watch_channels (user_email);
+
+CREATE TABLE webhook_subscriptions (
+    id          integer PRIMARY KEY AUTOINCREMENT,
+    created_at  datetime,
+    updated_at  datetime,
+    deleted_at  datetime,
+    user_email  text,
+    url         text,
+    secret      text,
+    event_types text
+);
+CREATE INDEX idx_webhook_subscriptions_deleted_at ON webhook_subscriptions (deleted_at);
+CREATE INDEX idx_webhook_subscriptions_user_email ON webhook_subscriptions (user_email);
+
+CREATE TABLE webhook_deliveries (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    created_at      datetime,
+    updated_at      datetime,
+    deleted_at      datetime,
+    subscription_id integer,
+    message_id      text,
+    event_type      text,
+    payload         text,
+    status          text,
+    attempts        integer,
+    next_attempt_at datetime,
+    last_error      text,
+    response_status integer,
+    delivered_at    datetime,
+    replay_of       integer
+);
+CREATE INDEX idx_webhook_deliveries_deleted_at ON webhook_deliveries (deleted_at);
+CREATE INDEX idx_webhook_deliveries_subscription_id ON webhook_deliveries (subscription_id);
+CREATE INDEX idx_webhook_deliveries_message_id ON webhook_deliveries (message_id);
+CREATE INDEX idx_webhook_deliveries_status ON webhook_deliveries (status);
+CREATE INDEX idx_webhook_deliveries_next_attempt_at ON webhook_deliveries (next_attempt_at);
+
+CREATE TABLE outbox_operations (
+    id              integer PRIMARY KEY AUTOINCREMENT,
+    created_at      datetime,
+    updated_at      datetime,
+    deleted_at      datetime,
+    kind            text,
+    user_email      text,
+    meeting_id      integer,
+    calendar_id     text,
+    event_id        text,
+    payload         text,
+    status          text,
+    attempts        integer,
+    next_attempt_at datetime,
+    last_error      text
+);
+CREATE INDEX idx_outbox_operations_deleted_at ON outbox_operations (deleted_at);
+CREATE INDEX idx_outbox_operations_kind ON outbox_operations (kind);
+CREATE INDEX idx_outbox_operations_user_email ON outbox_operations (user_email);
+CREATE INDEX idx_outbox_operations_meeting_id ON outbox_operations (meeting_id);
+CREATE INDEX idx_outbox_operations_status ON outbox_operations (status);
+CREATE INDEX idx_outbox_operations_next_attempt_at ON outbox_operations (next_attempt_at);
+
+CREATE TABLE calendar_accounts (
+    id                 integer PRIMARY KEY AUTOINCREMENT,
+    created_at         datetime,
+    updated_at         datetime,
+    deleted_at         datetime,
+    user_email         text NOT NULL,
+    provider           text NOT NULL,
+    subject            text NOT NULL DEFAULT '',
+    email              text NOT NULL DEFAULT '',
+    access_token       text,
+    refresh_token      text,
+    expires_at         datetime,
+    server_url         text NOT NULL DEFAULT '',
+    username           text NOT NULL DEFAULT '',
+    encrypted_password text NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_calendar_accounts_deleted_at ON calendar_accounts (deleted_at);
+CREATE UNIQUE INDEX idx_calendar_account ON calendar_accounts (user_email, provider);
+CREATE UNIQUE INDEX idx_calendar_accounts_subject ON calendar_accounts (provider, subject) WHERE subject <> '';
@@ -0,0 +1,79 @@
+// internal/repository/outbox.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"google-calendar-api/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type outboxRepo struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository creates a new OutboxRepository instance.
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepo{db}
+}
+
+func (r *outboxRepo) CreateMeetingWithOperation(ctx context.Context, meeting *domain.Meeting, op *domain.OutboxOperation) error {
+	fillStorageColumns(meeting)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(meeting).Error; err != nil {
+			return err
+		}
+		op.MeetingID = meeting.ID
+		return tx.Create(op).Error
+	})
+}
+
+func (r *outboxRepo) CommitOperation(ctx context.Context, meeting *domain.Meeting, op *domain.OutboxOperation) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveMeeting(tx, meeting); err != nil {
+			return err
+		}
+		return tx.Save(op).Error
+	})
+}
+
+func (r *outboxRepo) GetOperation(ctx context.Context, id uint) (*domain.OutboxOperation, error) {
+	var op domain.OutboxOperation
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&op)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &op, nil
+}
+
+func (r *outboxRepo) UpdateOperation(ctx context.Context, op *domain.OutboxOperation) error {
+	return r.db.WithContext(ctx).Save(op).Error
+}
+
+func (r *outboxRepo) ListDueOperations(ctx context.Context, now time.Time, limit int) ([]domain.OutboxOperation, error) {
+	var ops []domain.OutboxOperation
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&ops).Error
+	return ops, err
+}
+
+func (r *outboxRepo) ClaimOperation(ctx context.Context, op *domain.OutboxOperation, now, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.OutboxOperation{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", op.ID, "pending", now).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		op.NextAttemptAt = until
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -5,8 +5,6 @@ import (
 	"context"
 	"google-calendar-api/internal/domain"
 	"time"
-
-	"gorm.io/gorm"
 )
 
 // UserRepository defines the interface for user data access.
@@ -14,17 +12,73 @@ type UserRepository interface {
 	CreateUser(ctx context.Context, user *domain.User) error
 	GetUserByGoogleID(ctx context.Context, googleID string) (*domain.User, error)
 	UpdateUser(ctx context.Context, user *domain.User) error
+	ListUsers(ctx context.Context) ([]domain.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	GetUserByFeedToken(ctx context.Context, token string) (*domain.User, error)
+	SetFeedToken(ctx context.Context, email, token string) error                                       // Empty token turns the feed off
+	SetTokens(ctx context.Context, email, accessToken, refreshToken string, expiresAt time.Time) error // Empty refresh token keeps the stored one
+	SetCalendarProvider(ctx context.Context, email, provider string) error
+}
+
+// CalendarAccountRepository defines the interface for the calendar accounts
+// users linked besides Google.
+type CalendarAccountRepository interface {
+	GetAccount(ctx context.Context, userEmail, provider string) (*domain.CalendarAccount, error)
+	GetAccountBySubject(ctx context.Context, provider, subject string) (*domain.CalendarAccount, error)
+	ListAccounts(ctx context.Context, userEmail string) ([]domain.CalendarAccount, error)
+	SaveAccount(ctx context.Context, account *domain.CalendarAccount) error // Creates the account when it has no ID yet
 }
 
 // MeetingRepository defines the interface for meeting data access.
 type MeetingRepository interface {
 	CreateMeeting(ctx context.Context, meeting *domain.Meeting) error
 	ListMeetingsByUser(ctx context.Context, userEmail string, startTime, endTime time.Time) ([]domain.Meeting, error)
-	GetMeetingByID(ctx context.Context, id uint) (*domain.Meeting, error) // Added GetMeetingByID
+	GetMeetingByID(ctx context.Context, id uint) (*domain.Meeting, error)                        // Added GetMeetingByID
+	GetMeetingByEventID(ctx context.Context, createdBy, eventID string) (*domain.Meeting, error) // Event IDs repeat across attendees' calendars
+	GetMeetingByICalUID(ctx context.Context, createdBy, icalUID string) (*domain.Meeting, error)
+	UpdateMeeting(ctx context.Context, meeting *domain.Meeting) error
+	DeleteMeeting(ctx context.Context, meeting *domain.Meeting) error
+	ListMeetingExceptions(ctx context.Context, createdBy, recurringEventID string) ([]domain.Meeting, error)      // Exceptions of a recurring series
+	ListMeetingsByCalendar(ctx context.Context, createdBy, provider, calendarID string) ([]domain.Meeting, error) // Calendar IDs like "primary" repeat across providers
+	ListMeetingsByOwner(ctx context.Context, createdBy string) ([]domain.Meeting, error)                          // Every stored row of the user, oldest first
+	ListMeetingOwners(ctx context.Context) ([]string, error)                                                      // Distinct created_by values
+}
+
+// SyncStateRepository defines the interface for calendar sync state.
+type SyncStateRepository interface {
+	GetSyncState(ctx context.Context, userEmail, calendarID string) (*domain.CalendarSync, error)
+	SaveSyncState(ctx context.Context, state *domain.CalendarSync) error
+}
+
+// WatchChannelRepository defines the interface for push notification channels.
+type WatchChannelRepository interface {
+	CreateChannel(ctx context.Context, channel *domain.WatchChannel) error
+	GetChannel(ctx context.Context, channelID string) (*domain.WatchChannel, error)
+	ListChannelsByUser(ctx context.Context, userEmail string) ([]domain.WatchChannel, error)
+	ListChannelsExpiringBefore(ctx context.Context, t time.Time) ([]domain.WatchChannel, error)
+	DeleteChannel(ctx context.Context, channel *domain.WatchChannel) error
+}
+
+// WebhookRepository defines the interface for webhook subscriptions and their deliveries.
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id uint) (*domain.WebhookSubscription, error)
+	ListSubscriptionsByUser(ctx context.Context, userEmail string) ([]domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uint) (*domain.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ListDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]domain.WebhookDelivery, error)    // Newest first
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error)       // Pending and due, oldest first
+	ClaimDelivery(ctx context.Context, delivery *domain.WebhookDelivery, now, until time.Time) (bool, error) // Pushes the next attempt to until unless another worker got there first
 }
 
-// MigrateDB performs database migrations.
-func MigrateDB(db *gorm.DB) error {
-	return db.AutoMigrate(&domain.User{}, &domain.Meeting{}, &domain.Attendee{})
+// OutboxRepository defines the interface for outbox operations.
+type OutboxRepository interface {
+	CreateMeetingWithOperation(ctx context.Context, meeting *domain.Meeting, op *domain.OutboxOperation) error // Stores both or neither
+	CommitOperation(ctx context.Context, meeting *domain.Meeting, op *domain.OutboxOperation) error            // Saves both or neither
+	GetOperation(ctx context.Context, id uint) (*domain.OutboxOperation, error)
+	UpdateOperation(ctx context.Context, op *domain.OutboxOperation) error
+	ListDueOperations(ctx context.Context, now time.Time, limit int) ([]domain.OutboxOperation, error)
+	ClaimOperation(ctx context.Context, op *domain.OutboxOperation, now, until time.Time) (bool, error) // Pushes the next attempt to until unless another worker got there first
 }
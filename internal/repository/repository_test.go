@@ -0,0 +1,14 @@
+// internal/repository/repository_test.go
+package repository_test
+
+import (
+	"testing"
+
+	"google-calendar-api/internal/repository/repotest"
+)
+
+// TestRepositories runs the conformance suite against the GORM repositories
+// on each database. Set TEST_DB_URL to include Postgres.
+func TestRepositories(t *testing.T) {
+	repotest.RunAll(t)
+}
@@ -0,0 +1,508 @@
+// internal/repository/repotest/repotest.go
+package repotest
+
+import (
+	"context"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Factory returns empty repositories of one storage backend. It registers
+// whatever needs closing with t.Cleanup.
+type Factory func(t *testing.T) (repository.UserRepository, repository.MeetingRepository)
+
+// Backends returns a factory for every database backend: SQLite in a
+// temporary file and Postgres, which skips its tests unless TEST_DB_URL is
+// set. The Postgres database is migrated and emptied, so never point it at
+// one you need. The in-memory repositories have no database and are run on
+// their own with Memory.
+func Backends() map[string]Factory {
+	return map[string]Factory{
+		"sqlite":   SQLite,
+		"postgres": Postgres,
+	}
+}
+
+// RunAll runs the conformance suite against every database backend.
+func RunAll(t *testing.T) {
+	for name, factory := range Backends() {
+		t.Run(name, func(t *testing.T) { Run(t, factory) })
+	}
+}
+
+// Memory is the factory of the in-memory repositories.
+func Memory(t *testing.T) (repository.UserRepository, repository.MeetingRepository) {
+	return repository.NewMemoryUserRepository(), repository.NewMemoryMeetingRepository()
+}
+
+// SQLite is the factory of the GORM repositories on a new SQLite database.
+func SQLite(t *testing.T) (repository.UserRepository, repository.MeetingRepository) {
+	return openGORM(t, repository.DriverSQLite, filepath.Join(t.TempDir(), "calendar.db"))
+}
+
+// Postgres is the factory of the GORM repositories on the database at
+// TEST_DB_URL, with every table emptied.
+func Postgres(t *testing.T) (repository.UserRepository, repository.MeetingRepository) {
+	url := os.Getenv("TEST_DB_URL")
+	if url == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	return openGORM(t, repository.DriverPostgres, url)
+}
+
+func openGORM(t *testing.T, driver, url string) (repository.UserRepository, repository.MeetingRepository) {
+	t.Helper()
+	db, err := repository.OpenDB(driver, url)
+	if err != nil {
+		t.Fatalf("failed to open %s database: %v", driver, err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := repository.MigrateUp(context.Background(), sqlDB, driver); err != nil {
+		t.Fatalf("failed to migrate %s database: %v", driver, err)
+	}
+	if driver == repository.DriverPostgres {
+		if _, err := sqlDB.Exec(`TRUNCATE users, meetings, attendees RESTART IDENTITY CASCADE`); err != nil {
+			t.Fatalf("failed to empty the database: %v", err)
+		}
+	}
+	return repository.NewUserRepository(db), repository.NewMeetingRepository(db)
+}
+
+// Run runs the conformance suite against the repositories newRepos makes,
+// each test on empty ones.
+func Run(t *testing.T, newRepos Factory) {
+	tests := []struct {
+		name string
+		run  func(t *testing.T, users repository.UserRepository, meetings repository.MeetingRepository)
+	}{
+		{"UserLookups", testUserLookups},
+		{"UserUniqueness", testUserUniqueness},
+		{"UserUpdates", testUserUpdates},
+		{"ListUsers", testListUsers},
+		{"MeetingCreate", testMeetingCreate},
+		{"MeetingLookups", testMeetingLookups},
+		{"MeetingUpdate", testMeetingUpdate},
+		{"MeetingDelete", testMeetingDelete},
+		{"MeetingLists", testMeetingLists},
+		{"MeetingExceptions", testMeetingExceptions},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, meetings := newRepos(t)
+			tt.run(t, users, meetings)
+		})
+	}
+}
+
+var ctx = context.Background()
+
+// base is a fixed time with an offset, so backends that keep times as text
+// have to get the zones right.
+var base = time.Date(2025, 3, 10, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+
+func newUser(email, googleID string) *domain.User {
+	return &domain.User{
+		ID:           uuid.New(),
+		GoogleID:     googleID,
+		Email:        email,
+		Name:         "Test User",
+		AccessToken:  "access",
+		RefreshToken: "refresh",
+		ExpiresAt:    base.Add(time.Hour),
+	}
+}
+
+func createUser(t *testing.T, users repository.UserRepository, user *domain.User) {
+	t.Helper()
+	if err := users.CreateUser(ctx, user); err != nil {
+		t.Fatalf("CreateUser(%s): %v", user.Email, err)
+	}
+}
+
+func testUserLookups(t *testing.T, users repository.UserRepository, _ repository.MeetingRepository) {
+	alice := newUser("alice@example.com", "g-alice")
+	createUser(t, users, alice)
+	createUser(t, users, newUser("bob@example.com", ""))
+
+	got, err := users.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil || got == nil {
+		t.Fatalf("GetUserByEmail = %v, %v; want alice", got, err)
+	}
+	if got.ID != alice.ID || got.GoogleID != "g-alice" || got.RefreshToken != "refresh" || !got.ExpiresAt.Equal(alice.ExpiresAt) {
+		t.Errorf("GetUserByEmail = %+v; want the stored fields of %+v", got, alice)
+	}
+	if got.CalendarProvider != "google" {
+		t.Errorf("CalendarProvider = %q; want the default google", got.CalendarProvider)
+	}
+
+	got, err = users.GetUserByGoogleID(ctx, "g-alice")
+	if err != nil || got == nil || got.Email != "alice@example.com" {
+		t.Errorf("GetUserByGoogleID = %v, %v; want alice", got, err)
+	}
+	for name, lookup := range map[string]func() (*domain.User, error){
+		"GetUserByEmail(unknown)":    func() (*domain.User, error) { return users.GetUserByEmail(ctx, "carol@example.com") },
+		"GetUserByGoogleID(unknown)": func() (*domain.User, error) { return users.GetUserByGoogleID(ctx, "g-carol") },
+		"GetUserByGoogleID(empty)":   func() (*domain.User, error) { return users.GetUserByGoogleID(ctx, "") },
+		"GetUserByFeedToken(empty)":  func() (*domain.User, error) { return users.GetUserByFeedToken(ctx, "") },
+	} {
+		if got, err := lookup(); got != nil || err != nil {
+			t.Errorf("%s = %v, %v; want nil, nil", name, got, err)
+		}
+	}
+}
+
+func testUserUniqueness(t *testing.T, users repository.UserRepository, _ repository.MeetingRepository) {
+	createUser(t, users, newUser("alice@example.com", "g-alice"))
+	// Users without a Google account all have an empty ID.
+	createUser(t, users, newUser("bob@example.com", ""))
+	createUser(t, users, newUser("carol@example.com", ""))
+
+	if err := users.CreateUser(ctx, newUser("alice@example.com", "g-other")); err == nil {
+		t.Error("CreateUser with a taken email succeeded")
+	}
+	if err := users.CreateUser(ctx, newUser("dave@example.com", "g-alice")); err == nil {
+		t.Error("CreateUser with a taken Google ID succeeded")
+	}
+
+	if err := users.SetFeedToken(ctx, "bob@example.com", "token-1"); err != nil {
+		t.Fatalf("SetFeedToken: %v", err)
+	}
+	if err := users.SetFeedToken(ctx, "carol@example.com", "token-1"); err == nil {
+		t.Error("SetFeedToken with a taken token succeeded")
+	}
+}
+
+func testUserUpdates(t *testing.T, users repository.UserRepository, _ repository.MeetingRepository) {
+	alice := newUser("alice@example.com", "g-alice")
+	createUser(t, users, alice)
+
+	alice.AccessToken = "new-access"
+	alice.ExpiresAt = base.Add(2 * time.Hour)
+	if err := users.UpdateUser(ctx, alice); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if err := users.SetCalendarProvider(ctx, "alice@example.com", "microsoft"); err != nil {
+		t.Fatalf("SetCalendarProvider: %v", err)
+	}
+	if err := users.SetFeedToken(ctx, "alice@example.com", "secret"); err != nil {
+		t.Fatalf("SetFeedToken: %v", err)
+	}
+
+	got, err := users.GetUserByFeedToken(ctx, "secret")
+	if err != nil || got == nil {
+		t.Fatalf("GetUserByFeedToken = %v, %v; want alice", got, err)
+	}
+	if got.AccessToken != "new-access" || !got.ExpiresAt.Equal(alice.ExpiresAt) || got.CalendarProvider != "microsoft" {
+		t.Errorf("after updates got %+v", got)
+	}
+
+	if err := users.SetFeedToken(ctx, "alice@example.com", ""); err != nil {
+		t.Fatalf("SetFeedToken(empty): %v", err)
+	}
+	if got, err := users.GetUserByFeedToken(ctx, "secret"); got != nil || err != nil {
+		t.Errorf("GetUserByFeedToken after clearing = %v, %v; want nil, nil", got, err)
+	}
+	// Unknown users are no error, as with an UPDATE that matches no row.
+	if err := users.SetFeedToken(ctx, "nobody@example.com", "x"); err != nil {
+		t.Errorf("SetFeedToken(unknown) = %v; want nil", err)
+	}
+
+	// A refresh only touches the tokens: the provider set above stays.
+	if err := users.SetTokens(ctx, "alice@example.com", "refreshed", "", base.Add(3*time.Hour)); err != nil {
+		t.Fatalf("SetTokens: %v", err)
+	}
+	got, err = users.GetUserByEmail(ctx, "alice@example.com")
+	if err != nil || got == nil {
+		t.Fatalf("GetUserByEmail = %v, %v; want alice", got, err)
+	}
+	if got.AccessToken != "refreshed" || got.RefreshToken != alice.RefreshToken || !got.ExpiresAt.Equal(base.Add(3*time.Hour)) || got.CalendarProvider != "microsoft" {
+		t.Errorf("after SetTokens got %+v", got)
+	}
+	if err := users.SetTokens(ctx, "alice@example.com", "rotated", "new-refresh", base.Add(4*time.Hour)); err != nil {
+		t.Fatalf("SetTokens with a refresh token: %v", err)
+	}
+	if got, err := users.GetUserByEmail(ctx, "alice@example.com"); err != nil || got.RefreshToken != "new-refresh" || got.AccessToken != "rotated" {
+		t.Errorf("after SetTokens with a refresh token got %+v, %v", got, err)
+	}
+}
+
+func testListUsers(t *testing.T, users repository.UserRepository, _ repository.MeetingRepository) {
+	list, err := users.ListUsers(ctx)
+	if err != nil || len(list) != 0 {
+		t.Fatalf("ListUsers on an empty store = %v, %v", list, err)
+	}
+	for _, email := range []string{"carol@example.com", "alice@example.com", "bob@example.com"} {
+		createUser(t, users, newUser(email, ""))
+	}
+	list, err = users.ListUsers(ctx)
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	var emails []string
+	for _, u := range list {
+		emails = append(emails, u.Email)
+	}
+	if want := []string{"alice@example.com", "bob@example.com", "carol@example.com"}; !slices.Equal(emails, want) {
+		t.Errorf("ListUsers emails = %v; want %v", emails, want)
+	}
+}
+
+func newMeeting(owner, eventID string, start time.Time) *domain.Meeting {
+	return &domain.Meeting{
+		Title:     "Meeting " + eventID,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		EventID:   eventID,
+		CreatedBy: owner,
+		Attendees: []domain.Attendee{
+			{Email: "x@example.com", Organizer: true, ResponseStatus: "accepted"},
+			{Email: "y@example.com", Optional: true},
+		},
+	}
+}
+
+func createMeeting(t *testing.T, meetings repository.MeetingRepository, meeting *domain.Meeting) {
+	t.Helper()
+	if err := meetings.CreateMeeting(ctx, meeting); err != nil {
+		t.Fatalf("CreateMeeting(%s): %v", meeting.EventID, err)
+	}
+}
+
+func testMeetingCreate(t *testing.T, _ repository.UserRepository, meetings repository.MeetingRepository) {
+	m := newMeeting("alice@example.com", "ev1", base)
+	m.Recurrence = []string{"RRULE:FREQ=WEEKLY", "EXDATE:20250317T080000Z"}
+	createMeeting(t, meetings, m)
+	if m.ID == 0 {
+		t.Fatal("CreateMeeting left the ID at zero")
+	}
+	if m.RecurrenceString != "RRULE:FREQ=WEEKLY\nEXDATE:20250317T080000Z" {
+		t.Errorf("RecurrenceString = %q", m.RecurrenceString)
+	}
+
+	got, err := meetings.GetMeetingByID(ctx, m.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetMeetingByID = %v, %v", got, err)
+	}
+	if got.Title != m.Title || !got.StartTime.Equal(m.StartTime) || !got.EndTime.Equal(m.EndTime) || got.RecurrenceString != m.RecurrenceString {
+		t.Errorf("GetMeetingByID = %+v; want the stored fields of %+v", got, m)
+	}
+	if got.CalendarID != "primary" || got.Provider != "google" || got.Status != "confirmed" || got.CommitState != "committed" {
+		t.Errorf("defaults = %q, %q, %q, %q; want primary, google, confirmed, committed", got.CalendarID, got.Provider, got.Status, got.CommitState)
+	}
+	if len(got.Attendees) != 2 {
+		t.Fatalf("got %d attendees; want 2", len(got.Attendees))
+	}
+	first, second := got.Attendees[0], got.Attendees[1]
+	if first.Email != "x@example.com" || !first.Organizer || first.ResponseStatus != "accepted" || first.MeetingID != m.ID {
+		t.Errorf("first attendee = %+v", first)
+	}
+	if second.Email != "y@example.com" || !second.Optional || second.ResponseStatus != "needsAction" || second.ID <= first.ID {
+		t.Errorf("second attendee = %+v", second)
+	}
+
+	other := newMeeting("alice@example.com", "ev2", base)
+	createMeeting(t, meetings, other)
+	if other.ID == m.ID {
+		t.Errorf("two meetings got ID %d", m.ID)
+	}
+}
+
+func testMeetingLookups(t *testing.T, _ repository.UserRepository, meetings repository.MeetingRepository) {
+	alice := newMeeting("alice@example.com", "shared", base)
+	bob := newMeeting("bob@example.com", "shared", base)
+	createMeeting(t, meetings, alice)
+	createMeeting(t, meetings, bob)
+
+	got, err := meetings.GetMeetingByEventID(ctx, "bob@example.com", "shared")
+	if err != nil || got == nil || got.ID != bob.ID {
+		t.Errorf("GetMeetingByEventID(bob) = %v, %v; want meeting %d", got, err, bob.ID)
+	}
+	if got, err := meetings.GetMeetingByEventID(ctx, "carol@example.com", "shared"); got != nil || err != nil {
+		t.Errorf("GetMeetingByEventID(carol) = %v, %v; want nil, nil", got, err)
+	}
+	if got, err := meetings.GetMeetingByID(ctx, bob.ID+100); got != nil || err != nil {
+		t.Errorf("GetMeetingByID(unknown) = %v, %v; want nil, nil", got, err)
+	}
+
+	// The oldest row of a UID wins, unless it never made it to the calendar.
+	failed := newMeeting("alice@example.com", "", base)
+	failed.ICalUID = "uid-1@example.com"
+	failed.CommitState = "failed"
+	createMeeting(t, meetings, failed)
+	first := newMeeting("alice@example.com", "ev-a", base)
+	first.ICalUID = "uid-1@example.com"
+	createMeeting(t, meetings, first)
+	second := newMeeting("alice@example.com", "ev-b", base)
+	second.ICalUID = "uid-1@example.com"
+	createMeeting(t, meetings, second)
+
+	got, err = meetings.GetMeetingByICalUID(ctx, "alice@example.com", "uid-1@example.com")
+	if err != nil || got == nil || got.ID != first.ID {
+		t.Errorf("GetMeetingByICalUID = %v, %v; want meeting %d", got, err, first.ID)
+	}
+	if got, err := meetings.GetMeetingByICalUID(ctx, "bob@example.com", "uid-1@example.com"); got != nil || err != nil {
+		t.Errorf("GetMeetingByICalUID(bob) = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func testMeetingUpdate(t *testing.T, _ repository.UserRepository, meetings repository.MeetingRepository) {
+	m := newMeeting("alice@example.com", "ev1", base)
+	createMeeting(t, meetings, m)
+
+	m.Title = "Moved"
+	m.StartTime = base.Add(24 * time.Hour)
+	m.EndTime = m.StartTime.Add(30 * time.Minute)
+	m.Recurrence = []string{"RRULE:FREQ=DAILY;COUNT=3"}
+	m.Status = "cancelled"
+	m.Attendees = []domain.Attendee{{Email: "z@example.com"}}
+	if err := meetings.UpdateMeeting(ctx, m); err != nil {
+		t.Fatalf("UpdateMeeting: %v", err)
+	}
+
+	got, err := meetings.GetMeetingByID(ctx, m.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetMeetingByID = %v, %v", got, err)
+	}
+	if got.Title != "Moved" || !got.StartTime.Equal(m.StartTime) || got.RecurrenceString != "RRULE:FREQ=DAILY;COUNT=3" || got.Status != "cancelled" {
+		t.Errorf("after UpdateMeeting got %+v", got)
+	}
+	if len(got.Attendees) != 1 || got.Attendees[0].Email != "z@example.com" || got.Attendees[0].ResponseStatus != "needsAction" {
+		t.Errorf("attendees after UpdateMeeting = %+v; want only z@example.com", got.Attendees)
+	}
+}
+
+func testMeetingDelete(t *testing.T, _ repository.UserRepository, meetings repository.MeetingRepository) {
+	m := newMeeting("alice@example.com", "ev1", base)
+	createMeeting(t, meetings, m)
+	keep := newMeeting("alice@example.com", "ev2", base)
+	createMeeting(t, meetings, keep)
+
+	if err := meetings.DeleteMeeting(ctx, m); err != nil {
+		t.Fatalf("DeleteMeeting: %v", err)
+	}
+	if got, err := meetings.GetMeetingByID(ctx, m.ID); got != nil || err != nil {
+		t.Errorf("GetMeetingByID after delete = %v, %v; want nil, nil", got, err)
+	}
+	if got, err := meetings.GetMeetingByEventID(ctx, "alice@example.com", "ev1"); got != nil || err != nil {
+		t.Errorf("GetMeetingByEventID after delete = %v, %v; want nil, nil", got, err)
+	}
+	list, err := meetings.ListMeetingsByOwner(ctx, "alice@example.com")
+	if err != nil || len(list) != 1 || list[0].ID != keep.ID {
+		t.Errorf("ListMeetingsByOwner after delete = %v, %v; want only meeting %d", list, err, keep.ID)
+	}
+}
+
+func testMeetingLists(t *testing.T, _ repository.UserRepository, meetings repository.MeetingRepository) {
+	early := newMeeting("alice@example.com", "early", base)
+	late := newMeeting("alice@example.com", "late", base.Add(48*time.Hour))
+	work := newMeeting("alice@example.com", "work", base.Add(2*time.Hour))
+	work.CalendarID = "work@example.com"
+	outlook := newMeeting("alice@example.com", "outlook", base.Add(-72*time.Hour))
+	outlook.Provider = "microsoft"
+	bob := newMeeting("bob@example.com", "bob", base)
+	for _, m := range []*domain.Meeting{late, early, work, outlook, bob} {
+		createMeeting(t, meetings, m)
+	}
+
+	// The window holds meetings that lie entirely within it; UTC bounds
+	// against zoned times check the comparison isn't textual.
+	inWindow, err := meetings.ListMeetingsByUser(ctx, "alice@example.com", base.UTC(), base.Add(24*time.Hour).UTC())
+	if err != nil {
+		t.Fatalf("ListMeetingsByUser: %v", err)
+	}
+	if got, want := sortedIDs(inWindow), []uint{early.ID, work.ID}; !slices.Equal(got, want) {
+		t.Errorf("ListMeetingsByUser IDs = %v; want %v", got, want)
+	}
+	if len(inWindow) > 0 && len(inWindow[0].Attendees) != 2 {
+		t.Errorf("ListMeetingsByUser left out the attendees: %+v", inWindow[0])
+	}
+	narrow, err := meetings.ListMeetingsByUser(ctx, "alice@example.com", base.Add(time.Minute).UTC(), base.Add(24*time.Hour).UTC())
+	if err != nil || len(narrow) != 1 || narrow[0].ID != work.ID {
+		t.Errorf("ListMeetingsByUser from 9:01 = %v, %v; want only meeting %d", narrow, err, work.ID)
+	}
+
+	byCalendar, err := meetings.ListMeetingsByCalendar(ctx, "alice@example.com", "google", "primary")
+	if err != nil {
+		t.Fatalf("ListMeetingsByCalendar: %v", err)
+	}
+	if got, want := sortedIDs(byCalendar), []uint{late.ID, early.ID}; !slices.Equal(got, want) {
+		t.Errorf("ListMeetingsByCalendar IDs = %v; want %v", got, want)
+	}
+
+	byOwner, err := meetings.ListMeetingsByOwner(ctx, "alice@example.com")
+	if err != nil {
+		t.Fatalf("ListMeetingsByOwner: %v", err)
+	}
+	var ids []uint
+	for _, m := range byOwner {
+		ids = append(ids, m.ID)
+	}
+	if want := []uint{late.ID, early.ID, work.ID, outlook.ID}; !slices.Equal(ids, want) {
+		t.Errorf("ListMeetingsByOwner IDs = %v; want %v, oldest first", ids, want)
+	}
+
+	owners, err := meetings.ListMeetingOwners(ctx)
+	if err != nil {
+		t.Fatalf("ListMeetingOwners: %v", err)
+	}
+	if want := []string{"alice@example.com", "bob@example.com"}; !slices.Equal(owners, want) {
+		t.Errorf("ListMeetingOwners = %v; want %v", owners, want)
+	}
+}
+
+func testMeetingExceptions(t *testing.T, _ repository.UserRepository, meetings repository.MeetingRepository) {
+	master := newMeeting("alice@example.com", "series", base)
+	master.Recurrence = []string{"RRULE:FREQ=DAILY"}
+	createMeeting(t, meetings, master)
+
+	var want []uint
+	for _, day := range []int{3, 1, 2} {
+		original := base.AddDate(0, 0, day)
+		exception := newMeeting("alice@example.com", "series_"+original.UTC().Format("20060102T150405Z"), original.Add(time.Hour))
+		exception.RecurringEventID = "series"
+		exception.OriginalStartTime = &original
+		createMeeting(t, meetings, exception)
+		want = append(want, exception.ID)
+	}
+	want = []uint{want[1], want[2], want[0]} // By original start
+	other := newMeeting("bob@example.com", "series_x", base)
+	other.RecurringEventID = "series"
+	createMeeting(t, meetings, other)
+
+	exceptions, err := meetings.ListMeetingExceptions(ctx, "alice@example.com", "series")
+	if err != nil {
+		t.Fatalf("ListMeetingExceptions: %v", err)
+	}
+	var ids []uint
+	for _, e := range exceptions {
+		ids = append(ids, e.ID)
+	}
+	if !slices.Equal(ids, want) {
+		t.Errorf("ListMeetingExceptions IDs = %v; want %v", ids, want)
+	}
+	if len(exceptions) > 0 && (exceptions[0].OriginalStartTime == nil || !exceptions[0].OriginalStartTime.Equal(base.AddDate(0, 0, 1))) {
+		t.Errorf("first exception's original start = %v; want %v", exceptions[0].OriginalStartTime, base.AddDate(0, 0, 1))
+	}
+}
+
+// sortedIDs returns the IDs of meetings, for lists in no particular order.
+func sortedIDs(meetings []domain.Meeting) []uint {
+	ids := make([]uint, 0, len(meetings))
+	for _, m := range meetings {
+		ids = append(ids, m.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -0,0 +1,35 @@
+// internal/repository/sync.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"google-calendar-api/internal/domain"
+
+	"gorm.io/gorm"
+)
+
+type syncStateRepo struct {
+	db *gorm.DB
+}
+
+// NewSyncStateRepository creates a new SyncStateRepository instance.
+func NewSyncStateRepository(db *gorm.DB) SyncStateRepository {
+	return &syncStateRepo{db}
+}
+
+func (r *syncStateRepo) GetSyncState(ctx context.Context, userEmail, calendarID string) (*domain.CalendarSync, error) {
+	var state domain.CalendarSync
+	result := r.db.WithContext(ctx).Where("user_email = ? AND calendar_id = ?", userEmail, calendarID).First(&state)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &state, nil
+}
+
+func (r *syncStateRepo) SaveSyncState(ctx context.Context, state *domain.CalendarSync) error {
+	return r.db.WithContext(ctx).Save(state).Error
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"google-calendar-api/internal/domain"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -23,6 +24,9 @@ func (r *userRepo) CreateUser(ctx context.Context, user *domain.User) error {
 }
 
 func (r *userRepo) GetUserByGoogleID(ctx context.Context, googleID string) (*domain.User, error) {
+	if googleID == "" {
+		return nil, nil // Users without a Google account have an empty ID
+	}
 	var user domain.User
 	result := r.db.WithContext(ctx).Where("google_id = ?", googleID).First(&user)
 	if result.Error != nil {
@@ -46,6 +50,47 @@ func (r *userRepo) GetUserByEmail(ctx context.Context, email string) (*domain.Us
 	return &user, nil
 }
 
+func (r *userRepo) GetUserByFeedToken(ctx context.Context, token string) (*domain.User, error) {
+	if token == "" {
+		return nil, nil // Users without a feed have an empty token
+	}
+	var user domain.User
+	result := r.db.WithContext(ctx).Where("feed_token = ?", token).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &user, nil
+}
+
+// SetFeedToken only writes the token column, so it can't undo a token
+// refresh saved at the same time.
+func (r *userRepo) SetFeedToken(ctx context.Context, email, token string) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("email = ?", email).Update("feed_token", token).Error
+}
+
+// SetTokens only writes the token columns, so a refresh saved from a copy of
+// the user loaded earlier can't undo other changes made since.
+func (r *userRepo) SetTokens(ctx context.Context, email, accessToken, refreshToken string, expiresAt time.Time) error {
+	columns := map[string]interface{}{"access_token": accessToken, "expires_at": expiresAt}
+	if refreshToken != "" {
+		columns["refresh_token"] = refreshToken
+	}
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("email = ?", email).Updates(columns).Error
+}
+
+func (r *userRepo) SetCalendarProvider(ctx context.Context, email, provider string) error {
+	return r.db.WithContext(ctx).Model(&domain.User{}).Where("email = ?", email).Update("calendar_provider", provider).Error
+}
+
 func (r *userRepo) UpdateUser(ctx context.Context, user *domain.User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
+
+func (r *userRepo) ListUsers(ctx context.Context) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).Order("email").Find(&users).Error
+	return users, err
+}
@@ -0,0 +1,52 @@
+// internal/repository/watch.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"google-calendar-api/internal/domain"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type watchChannelRepo struct {
+	db *gorm.DB
+}
+
+// NewWatchChannelRepository creates a new WatchChannelRepository instance.
+func NewWatchChannelRepository(db *gorm.DB) WatchChannelRepository {
+	return &watchChannelRepo{db}
+}
+
+func (r *watchChannelRepo) CreateChannel(ctx context.Context, channel *domain.WatchChannel) error {
+	return r.db.WithContext(ctx).Create(channel).Error
+}
+
+func (r *watchChannelRepo) GetChannel(ctx context.Context, channelID string) (*domain.WatchChannel, error) {
+	var channel domain.WatchChannel
+	result := r.db.WithContext(ctx).Where("channel_id = ?", channelID).First(&channel)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &channel, nil
+}
+
+func (r *watchChannelRepo) ListChannelsByUser(ctx context.Context, userEmail string) ([]domain.WatchChannel, error) {
+	var channels []domain.WatchChannel
+	err := r.db.WithContext(ctx).Where("user_email = ?", userEmail).Find(&channels).Error
+	return channels, err
+}
+
+func (r *watchChannelRepo) ListChannelsExpiringBefore(ctx context.Context, t time.Time) ([]domain.WatchChannel, error) {
+	var channels []domain.WatchChannel
+	err := r.db.WithContext(ctx).Where("expiration < ?", t).Order("expiration").Find(&channels).Error
+	return channels, err
+}
+
+func (r *watchChannelRepo) DeleteChannel(ctx context.Context, channel *domain.WatchChannel) error {
+	return r.db.WithContext(ctx).Delete(channel).Error
+}
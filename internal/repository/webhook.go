@@ -0,0 +1,101 @@
+// internal/repository/webhook.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"google-calendar-api/internal/domain"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type webhookRepo struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository creates a new WebhookRepository instance.
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepo{db}
+}
+
+func (r *webhookRepo) CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	subscription.EventTypesString = strings.Join(subscription.EventTypes, ",")
+	return r.db.WithContext(ctx).Create(subscription).Error
+}
+
+func (r *webhookRepo) GetSubscription(ctx context.Context, id uint) (*domain.WebhookSubscription, error) {
+	var subscription domain.WebhookSubscription
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&subscription)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &subscription, nil
+}
+
+func (r *webhookRepo) ListSubscriptionsByUser(ctx context.Context, userEmail string) ([]domain.WebhookSubscription, error) {
+	var subscriptions []domain.WebhookSubscription
+	err := r.db.WithContext(ctx).Where("user_email = ?", userEmail).Order("id").Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+func (r *webhookRepo) DeleteSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	return r.db.WithContext(ctx).Delete(subscription).Error
+}
+
+func (r *webhookRepo) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookRepo) GetDelivery(ctx context.Context, id uint) (*domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	result := r.db.WithContext(ctx).Where("id = ?", id).First(&delivery)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, nil // Return nil, nil for not found
+		}
+		return nil, result.Error
+	}
+	return &delivery, nil
+}
+
+func (r *webhookRepo) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
+
+func (r *webhookRepo) ListDeliveries(ctx context.Context, subscriptionID uint, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionID).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepo) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND next_attempt_at <= ?", "pending", now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepo) ClaimDelivery(ctx context.Context, delivery *domain.WebhookDelivery, now, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&domain.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND next_attempt_at <= ?", delivery.ID, "pending", now).
+		Update("next_attempt_at", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		delivery.NextAttemptAt = until
+	}
+	return result.RowsAffected == 1, nil
+}
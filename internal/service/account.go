@@ -0,0 +1,115 @@
+// internal/service/account.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"google-calendar-api/internal/repository"
+	"log"
+)
+
+type accountService struct {
+	userRepo    repository.UserRepository
+	accountRepo repository.CalendarAccountRepository
+	providers   CalendarProviders
+	caldav      *caldavProvider
+}
+
+// NewAccountService creates a new AccountService instance.
+func NewAccountService(userRepo repository.UserRepository, accountRepo repository.CalendarAccountRepository, providers CalendarProviders, caldav *caldavProvider) *accountService {
+	return &accountService{
+		userRepo:    userRepo,
+		accountRepo: accountRepo,
+		providers:   providers,
+		caldav:      caldav,
+	}
+}
+
+func (s *accountService) ListProviders(ctx context.Context, userEmail string) (*ProvidersOutput, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	accounts, err := s.accountRepo.ListAccounts(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar accounts: %w", err)
+	}
+
+	output := &ProvidersOutput{
+		Active:    user.CalendarProvider,
+		Linked:    []LinkedProvider{},
+		Available: s.providers.Names(),
+	}
+	if output.Active == "" {
+		output.Active = ProviderGoogle
+	}
+	if user.GoogleID != "" {
+		output.Linked = append(output.Linked, LinkedProvider{Provider: ProviderGoogle, Email: user.Email})
+	}
+	for _, account := range accounts {
+		output.Linked = append(output.Linked, LinkedProvider{Provider: account.Provider, Email: account.Email})
+	}
+	return output, nil
+}
+
+func (s *accountService) SetProvider(ctx context.Context, userEmail, provider string) error {
+	if _, err := s.providers.Get(provider); err != nil || provider == "" {
+		return fmt.Errorf("%w: unknown calendar provider %q", ErrProviderUnsupported, provider)
+	}
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil || user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	if provider == ProviderGoogle {
+		if user.GoogleID == "" {
+			return fmt.Errorf("%w: %s has no Google account", ErrProviderNotLinked, userEmail)
+		}
+	} else {
+		account, err := s.accountRepo.GetAccount(ctx, userEmail, provider)
+		if err != nil {
+			return fmt.Errorf("failed to load calendar account: %w", err)
+		}
+		if account == nil {
+			return fmt.Errorf("%w: %s has no %s account", ErrProviderNotLinked, userEmail, provider)
+		}
+	}
+
+	if err := s.userRepo.SetCalendarProvider(ctx, userEmail, provider); err != nil {
+		return fmt.Errorf("failed to save calendar provider: %w", err)
+	}
+	log.Printf("🔀 New events of %s now go to %s", userEmail, provider)
+	return nil
+}
+
+func (s *accountService) LinkCalDAV(ctx context.Context, input CalDAVAccountInput) (*LinkedProvider, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, input.UserEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	account, err := s.caldav.LinkAccount(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.accountRepo.GetAccountBySubject(ctx, ProviderCalDAV, account.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar account: %w", err)
+	}
+	if owner != nil && owner.UserEmail != user.Email {
+		return nil, fmt.Errorf("%w: the CalDAV account belongs to another user", ErrAccountConflict)
+	}
+	existing, err := s.accountRepo.GetAccount(ctx, user.Email, ProviderCalDAV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendar account: %w", err)
+	}
+	if existing != nil {
+		account.ID, account.CreatedAt = existing.ID, existing.CreatedAt
+	}
+	if err := s.accountRepo.SaveAccount(ctx, account); err != nil {
+		return nil, fmt.Errorf("failed to save calendar account: %w", err)
+	}
+
+	log.Printf("🔗 Linked CalDAV account %s to %s", account.Email, user.Email)
+	return &LinkedProvider{Provider: ProviderCalDAV, Email: account.Email}, nil
+}
@@ -13,34 +13,47 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 
-	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
 )
 
 type authService struct {
-	userRepo     repository.UserRepository
-	oauthConfig  *oauth2.Config // Use oauth2.Config
-	jwtSecret    []byte
-	oidcProvider *oidc.Provider // OIDC Provider
+	userRepo        repository.UserRepository
+	accountRepo     repository.CalendarAccountRepository
+	oauthConfig     *oauth2.Config // Use oauth2.Config
+	microsoftConfig *oauth2.Config // Nil when Microsoft is off
+	graphURL        string
+	jwtSecret       []byte
+	issuer          *oidcIssuer // Google's OpenID Connect issuer, discovered on first use
 }
 
-// NewAuthService creates a new AuthService instance.
-func NewAuthService(cfg *config.Config, userRepo repository.UserRepository) *authService {
-	provider, err := oidc.NewProvider(context.Background(), "https://accounts.google.com")
-	if err != nil {
-		//This should stop the execution of the app.
-		log.Fatalf("❌ Failed to create OIDC provider: %v\n", err)
-	}
+// NewAuthService creates a new AuthService instance. The OIDC issuer is not
+// contacted yet; see DiscoverIssuer.
+func NewAuthService(cfg *config.Config, userRepo repository.UserRepository, accountRepo repository.CalendarAccountRepository) *authService {
 	return &authService{
-		userRepo:     userRepo,
-		oauthConfig:  cfg.OAuthConfig, // Use directly from config
-		jwtSecret:    cfg.JWTSecret,
-		oidcProvider: provider,
+		userRepo:        userRepo,
+		accountRepo:     accountRepo,
+		oauthConfig:     cfg.OAuthConfig, // Use directly from config
+		microsoftConfig: cfg.MicrosoftOAuthConfig,
+		graphURL:        cfg.MicrosoftGraphURL,
+		jwtSecret:       cfg.JWTSecret,
+		issuer:          newOIDCIssuer(cfg.GoogleIssuerURL, cfg.OAuthConfig.ClientID),
 	}
 }
 
+// DiscoverIssuer discovers the OIDC issuer ahead of the first login,
+// retrying with backoff until it answers or ctx is done.
+func (s *authService) DiscoverIssuer(ctx context.Context) error {
+	return s.issuer.Discover(ctx)
+}
+
+// Ready reports whether logins can be verified.
+func (s *authService) Ready(ctx context.Context) error {
+	return s.issuer.Ready()
+}
+
 // HandleGoogleCallback handles the OAuth2 callback from Google, creates/updates the user, and generates a JWT.
-func (s *authService) HandleGoogleCallback(ctx context.Context, token *oauth2.Token) (string, error) {
+// With currentEmail set, the Google account is linked to that logged-in user instead.
+func (s *authService) HandleGoogleCallback(ctx context.Context, token *oauth2.Token, currentEmail string) (string, error) {
 	// Extract ID Token from the token response
 	rawIDToken, ok := token.Extra("id_token").(string)
 	if !ok {
@@ -48,7 +61,10 @@ func (s *authService) HandleGoogleCallback(ctx context.Context, token *oauth2.To
 	}
 
 	// Verify and decode the ID Token
-	verifier := s.oidcProvider.Verifier(&oidc.Config{ClientID: s.oauthConfig.ClientID})
+	verifier, err := s.issuer.Verifier(ctx)
+	if err != nil {
+		return "", err
+	}
 	idToken, err := verifier.Verify(ctx, rawIDToken)
 	if err != nil {
 		return "", fmt.Errorf("failed to verify ID token: %w", err)
@@ -76,9 +92,34 @@ func (s *authService) HandleGoogleCallback(ctx context.Context, token *oauth2.To
 		return "", fmt.Errorf("database error: %w", err)
 	}
 
+	if currentEmail != "" {
+		if user != nil && user.Email != currentEmail {
+			return "", fmt.Errorf("%w: Google account %s belongs to %s", ErrAccountConflict, claims.Email, user.Email)
+		}
+		user, err = s.userRepo.GetUserByEmail(ctx, currentEmail)
+		if err != nil || user == nil {
+			return "", fmt.Errorf("user not found")
+		}
+		if user.GoogleID != "" && user.GoogleID != claims.Sub {
+			return "", fmt.Errorf("%w: %s has another Google account linked", ErrAccountConflict, currentEmail)
+		}
+		user.GoogleID = claims.Sub
+		log.Printf("🔗 Linking Google account %s to %s", claims.Email, currentEmail)
+	}
+
 	if user == nil {
+		// The email alone doesn't prove the account is theirs, so an
+		// existing user has to log in their own way and link Google from there.
+		existing, err := s.userRepo.GetUserByEmail(ctx, claims.Email)
+		if err != nil {
+			return "", fmt.Errorf("database error: %w", err)
+		}
+		if existing != nil {
+			return "", fmt.Errorf("%w: %s signed up with another provider; log in with it to link Google", ErrAccountConflict, claims.Email)
+		}
+
 		// Create new user
-		newUser := &domain.User{
+		user = &domain.User{
 			ID:           uuid.New(), // Use UUID
 			GoogleID:     claims.Sub,
 			Email:        claims.Email,
@@ -88,7 +129,7 @@ func (s *authService) HandleGoogleCallback(ctx context.Context, token *oauth2.To
 			RefreshToken: token.RefreshToken,
 			ExpiresAt:    token.Expiry,
 		}
-		if err := s.userRepo.CreateUser(ctx, newUser); err != nil {
+		if err := s.userRepo.CreateUser(ctx, user); err != nil {
 			return "", fmt.Errorf("failed to create user: %w", err)
 		}
 	} else {
@@ -106,7 +147,7 @@ func (s *authService) HandleGoogleCallback(ctx context.Context, token *oauth2.To
 	}
 
 	// Generate JWT
-	jwtToken, err := s.generateJWT(claims.Email) // Generate JWT with email
+	jwtToken, err := s.generateJWT(user.Email) // Generate JWT with email
 	if err != nil {
 		return "", fmt.Errorf("failed to generate JWT: %w", err)
 	}
@@ -114,6 +155,85 @@ func (s *authService) HandleGoogleCallback(ctx context.Context, token *oauth2.To
 	return jwtToken, nil
 }
 
+// HandleMicrosoftCallback handles the OAuth2 callback from Microsoft. The
+// account is linked to the logged-in user when currentEmail is set; without
+// one, it logs in the user it belongs to or signs up a new one.
+func (s *authService) HandleMicrosoftCallback(ctx context.Context, token *oauth2.Token, currentEmail string) (string, error) {
+	if s.microsoftConfig == nil {
+		return "", fmt.Errorf("%w: %s is not configured", ErrProviderUnsupported, ProviderMicrosoft)
+	}
+	profile, err := fetchMicrosoftProfile(ctx, s.microsoftConfig.Client(ctx, token), s.graphURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Microsoft profile: %w", err)
+	}
+
+	account, err := s.accountRepo.GetAccountBySubject(ctx, ProviderMicrosoft, profile.ID)
+	if err != nil {
+		return "", fmt.Errorf("database error: %w", err)
+	}
+
+	userEmail := currentEmail
+	switch {
+	case currentEmail != "":
+		if account != nil && account.UserEmail != currentEmail {
+			return "", fmt.Errorf("%w: Microsoft account %s belongs to %s", ErrAccountConflict, profile.Mail, account.UserEmail)
+		}
+		user, err := s.userRepo.GetUserByEmail(ctx, currentEmail)
+		if err != nil || user == nil {
+			return "", fmt.Errorf("user not found")
+		}
+		if account == nil {
+			// Linking another Microsoft account replaces the one before.
+			if account, err = s.accountRepo.GetAccount(ctx, currentEmail, ProviderMicrosoft); err != nil {
+				return "", fmt.Errorf("database error: %w", err)
+			}
+		}
+		log.Printf("🔗 Linking Microsoft account %s to %s", profile.Mail, currentEmail)
+	case account != nil:
+		userEmail = account.UserEmail
+	default:
+		// Same rule as for Google: an existing user has to link from their
+		// own login.
+		existing, err := s.userRepo.GetUserByEmail(ctx, profile.Mail)
+		if err != nil {
+			return "", fmt.Errorf("database error: %w", err)
+		}
+		if existing != nil {
+			return "", fmt.Errorf("%w: %s signed up with another provider; log in with it to link Microsoft", ErrAccountConflict, profile.Mail)
+		}
+		newUser := &domain.User{
+			ID:               uuid.New(),
+			Email:            profile.Mail,
+			Name:             profile.DisplayName,
+			CalendarProvider: ProviderMicrosoft,
+		}
+		if err := s.userRepo.CreateUser(ctx, newUser); err != nil {
+			return "", fmt.Errorf("failed to create user: %w", err)
+		}
+		userEmail = newUser.Email
+	}
+
+	if account == nil {
+		account = &domain.CalendarAccount{UserEmail: userEmail, Provider: ProviderMicrosoft}
+	}
+	account.Subject = profile.ID
+	account.Email = profile.Mail
+	account.AccessToken = token.AccessToken
+	account.ExpiresAt = token.Expiry
+	if token.RefreshToken != "" {
+		account.RefreshToken = token.RefreshToken
+	}
+	if err := s.accountRepo.SaveAccount(ctx, account); err != nil {
+		return "", fmt.Errorf("failed to save Microsoft account: %w", err)
+	}
+
+	jwtToken, err := s.generateJWT(userEmail)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate JWT: %w", err)
+	}
+	return jwtToken, nil
+}
+
 // generateJWT creates a JWT for the user.
 func (s *authService) generateJWT(email string) (string, error) {
 	claims := jwt.MapClaims{
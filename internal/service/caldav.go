@@ -0,0 +1,824 @@
+// internal/service/caldav.go
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"google-calendar-api/internal/caldav"
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/ics"
+	"google-calendar-api/internal/repository"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// caldavProvider is the CalendarProvider for CalDAV servers such as
+// Nextcloud, Radicale or Fastmail. Every user links their own server; the
+// credentials live on their CalendarAccount.
+//
+// Passwords are stored encrypted with CALDAV_SECRET_KEY, bound to the user
+// they belong to.
+//
+// Event IDs are the names of the calendar object resources. Occurrences of
+// recurring events get "<name>_<original start>", as Google does it.
+// Updates change only the fields the API knows about; other properties and
+// components of a VEVENT, like alarms, are written back as they were.
+type caldavProvider struct {
+	enabled     bool
+	allowHTTP   bool
+	allowLocal  bool
+	passwords   cipher.AEAD // Nil without a valid CALDAV_SECRET_KEY
+	httpClient  *http.Client
+	accountRepo repository.CalendarAccountRepository
+}
+
+// NewCalDAVProvider creates the CalendarProvider for CalDAV. It is unused
+// unless cfg enables CalDAV. Users pick the server, so as with webhooks it
+// has to be HTTPS and may not be in the server's own network unless the
+// config says otherwise.
+func NewCalDAVProvider(cfg *config.Config, accountRepo repository.CalendarAccountRepository) *caldavProvider {
+	p := &caldavProvider{
+		enabled:     cfg.CalDAVEnabled,
+		allowHTTP:   cfg.CalDAVAllowHTTP,
+		allowLocal:  cfg.CalDAVAllowLocal,
+		accountRepo: accountRepo,
+	}
+	if block, err := aes.NewCipher(cfg.CalDAVSecretKey); err == nil {
+		p.passwords, _ = cipher.NewGCM(block)
+	} else if cfg.CalDAVEnabled {
+		log.Printf("⚠️ CalDAV accounts can't be linked or used: invalid CALDAV_SECRET_KEY: %v", err)
+	}
+	dialer := &net.Dialer{Timeout: cfg.CalDAVTimeout}
+	if !cfg.CalDAVAllowLocal {
+		dialer.Control = dialPublicOnly("CalDAV")
+	}
+	p.httpClient = &http.Client{
+		Timeout:   cfg.CalDAVTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext}, // No proxy, which would do the dialing instead
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			// Servers redirect from /.well-known/caldav; the target is held
+			// to the same rules as the URL the user gave.
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			if !p.allowedURL(req.URL.String()) {
+				return fmt.Errorf("redirect to %s is not allowed", req.URL.Redacted())
+			}
+			return nil
+		},
+	}
+	return p
+}
+
+func (p *caldavProvider) Name() string {
+	return ProviderCalDAV
+}
+
+func (p *caldavProvider) ListCalendars(ctx context.Context, user *domain.User) ([]CalendarOutput, error) {
+	session, err := p.open(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := session.client.ListCalendars(ctx, session.account.ServerURL)
+	if err != nil {
+		return nil, classifyCalDAVError(err)
+	}
+
+	outputs := []CalendarOutput{}
+	primary := defaultCalendar(calendars)
+	for _, c := range calendars {
+		if !c.SupportsEvents() {
+			continue
+		}
+		output := CalendarOutput{
+			ID:          calendarIDOf(c.Href),
+			Summary:     c.Name,
+			Description: c.Description,
+			AccessRole:  "reader",
+			Primary:     primary != nil && c.Href == primary.Href,
+			Color:       c.Color,
+		}
+		if output.Summary == "" {
+			output.Summary = path.Base(strings.TrimSuffix(output.ID, "/"))
+		}
+		if c.CanWrite {
+			output.AccessRole = "owner" // Everything in the home is the user's
+		}
+		if len(output.Color) == len("#RRGGBBAA") {
+			output.Color = output.Color[:len("#RRGGBB")] // Apple adds an alpha channel
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+func (p *caldavProvider) CreateEvent(ctx context.Context, user *domain.User, input CreateEventInput) (*ProviderEvent, error) {
+	if input.AddConference {
+		return nil, fmt.Errorf("%w: CalDAV calendars have no conferences", ErrProviderUnsupported)
+	}
+	session, err := p.open(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	calendarURL, err := session.calendarURL(ctx, input.CalendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	uid := input.ICalUID
+	if uid == "" {
+		uid = uuid.New().String()
+	}
+	item := ics.Event{UID: uid, Recurrence: input.Recurrence, Status: "CONFIRMED"}
+	applyOutputToICS(&item, EventOutput{
+		Title:       input.Title,
+		Description: input.Description,
+		StartTime:   input.StartTime,
+		EndTime:     input.EndTime,
+		AllDay:      input.AllDay,
+		Attendees:   attendeeOutputs(input.Attendees, nil),
+	})
+
+	name := uuid.New().String() + ".ics"
+	data, err := encodeCalDAVObject([]ics.Event{item})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := session.client.PutObject(ctx, calendarURL+url.PathEscape(name), data, ""); err != nil {
+		return nil, classifyCalDAVError(err)
+	}
+	return fromICSEvent(name, &item, user.Email), nil
+}
+
+func (p *caldavProvider) GetEvent(ctx context.Context, user *domain.User, calendarID, eventID string) (*ProviderEvent, error) {
+	session, err := p.open(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	calendarURL, err := session.calendarURL(ctx, calendarID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, original := splitCalDAVID(eventID)
+	object, err := session.client.GetObject(ctx, calendarURL+url.PathEscape(name))
+	if err != nil {
+		if errors.Is(err, caldav.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, classifyCalDAVError(err)
+	}
+	cal, _, err := ics.Parse(bytes.NewReader(object.Data))
+	if err != nil {
+		return nil, fmt.Errorf("unreadable CalDAV event %s: %w", name, err)
+	}
+
+	event := eventOf(cal.Events, name, original, user.Email)
+	if event == nil || event.Status == "cancelled" {
+		return nil, nil
+	}
+	return event, nil
+}
+
+// ListEvents asks the server for the occurrences within the query's bounds.
+// CalDAV has no paging, so every page queries again and the page token is
+// the offset of the page.
+func (p *caldavProvider) ListEvents(ctx context.Context, user *domain.User, query ProviderQuery) ([]EventOutput, string, error) {
+	offset := 0
+	if query.PageToken != "" {
+		n, err := strconv.Atoi(query.PageToken)
+		if err != nil || n < 0 {
+			return nil, "", ErrInvalidCursor
+		}
+		offset = n
+	}
+	session, err := p.open(ctx, user)
+	if err != nil {
+		return nil, "", err
+	}
+	calendarURL, err := session.calendarURL(ctx, query.CalendarID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	events, err := session.events(ctx, calendarURL, query.TimeMin, query.TimeMax, query.Query)
+	if err != nil {
+		return nil, "", err
+	}
+	if offset >= len(events) {
+		return []EventOutput{}, "", nil
+	}
+	end := min(offset+query.MaxResults, len(events))
+	next := ""
+	if end < len(events) {
+		next = strconv.Itoa(end)
+	}
+	return events[offset:end], next, nil
+}
+
+// UpdateEvent rewrites the VEVENT of the event. An occurrence gets its own
+// VEVENT next to the series, as RFC 5545 has it.
+func (p *caldavProvider) UpdateEvent(ctx context.Context, user *domain.User, calendarID string, event EventOutput) (*ProviderEvent, error) {
+	name, original := splitCalDAVID(event.EventId)
+	return p.modify(ctx, user, calendarID, name, func(events []ics.Event) ([]ics.Event, *ProviderEvent, error) {
+		master := findICSEvent(events, nil)
+		if original == nil {
+			if master == nil {
+				return nil, nil, ErrEventNotFound
+			}
+			applyOutputToICS(master, event)
+			master.Recurrence = event.Recurrence
+			return events, fromICSEvent(name, master, user.Email), nil
+		}
+
+		override := findICSEvent(events, original)
+		if override == nil {
+			if master == nil || len(master.Recurrence) == 0 {
+				return nil, nil, ErrEventNotFound
+			}
+			events = append(events, occurrenceOf(master, *original))
+			override = &events[len(events)-1]
+		}
+		applyOutputToICS(override, event)
+		return events, fromICSEvent(name, override, user.Email), nil
+	})
+}
+
+// DeleteEvent removes the resource of the event. An occurrence becomes an
+// exception date of its series instead.
+func (p *caldavProvider) DeleteEvent(ctx context.Context, user *domain.User, calendarID, eventID string) error {
+	name, original := splitCalDAVID(eventID)
+	if original == nil {
+		session, err := p.open(ctx, user)
+		if err != nil {
+			return err
+		}
+		calendarURL, err := session.calendarURL(ctx, calendarID)
+		if err != nil {
+			return err
+		}
+		err = session.client.DeleteObject(ctx, calendarURL+url.PathEscape(name), "")
+		if errors.Is(err, caldav.ErrNotFound) {
+			return nil
+		}
+		return classifyCalDAVError(err)
+	}
+
+	_, err := p.modify(ctx, user, calendarID, name, func(events []ics.Event) ([]ics.Event, *ProviderEvent, error) {
+		kept := events[:0]
+		for _, e := range events {
+			if e.RecurrenceID == nil || !e.RecurrenceID.Equal(*original) {
+				kept = append(kept, e)
+			}
+		}
+		if master := findICSEvent(kept, nil); master != nil {
+			master.Recurrence = append(master.Recurrence, exceptionDate(*original, master.AllDay))
+		}
+		return kept, nil, nil
+	})
+	if errors.Is(err, ErrEventNotFound) {
+		return nil
+	}
+	return err
+}
+
+// FreeBusy reads the user's own calendars, the only ones CalDAV lets a
+// client query without scheduling support. Others are reported as
+// "notSupported".
+func (p *caldavProvider) FreeBusy(ctx context.Context, user *domain.User, input FreeBusyInput) (*FreeBusyOutput, error) {
+	session, err := p.open(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	loc := time.UTC
+	if input.TimeZone != "" {
+		if l, err := time.LoadLocation(input.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	output := &FreeBusyOutput{
+		TimeMin:   input.TimeMin,
+		TimeMax:   input.TimeMax,
+		Calendars: make(map[string]CalendarAvailability, len(input.Calendars)),
+	}
+
+	for _, id := range input.Calendars {
+		availability := CalendarAvailability{Busy: []TimeRange{}}
+		calendarID := id
+		if strings.EqualFold(id, user.Email) || strings.EqualFold(id, session.account.Email) {
+			calendarID = "primary"
+		}
+		if calendarID != "primary" && !strings.HasPrefix(calendarID, "/") {
+			availability.Errors = append(availability.Errors, "notSupported")
+			output.Calendars[id] = availability
+			continue
+		}
+
+		calendarURL, err := session.calendarURL(ctx, calendarID)
+		if err != nil {
+			if !errors.Is(err, ErrCalendarNotFound) {
+				return nil, err
+			}
+			availability.Errors = append(availability.Errors, "notFound")
+			output.Calendars[id] = availability
+			continue
+		}
+		events, err := session.events(ctx, calendarURL, input.TimeMin, input.TimeMax, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if event.AllDay {
+				continue // Like Google, all-day events don't block time
+			}
+			start, end := maxTime(event.StartTime, input.TimeMin), minTime(event.EndTime, input.TimeMax)
+			if !start.Before(end) {
+				continue
+			}
+			if n := len(availability.Busy); n > 0 && !start.After(availability.Busy[n-1].End) {
+				availability.Busy[n-1].End = maxTime(availability.Busy[n-1].End, end.In(loc)) // Overlaps the one before
+				continue
+			}
+			availability.Busy = append(availability.Busy, TimeRange{Start: start.In(loc), End: end.In(loc)})
+		}
+		output.Calendars[id] = availability
+	}
+	return output, nil
+}
+
+// LinkAccount finds the calendars of the account at the server and returns
+// the account to store. Only the caller's own input is trusted, so the URL
+// has to be https unless plain http is allowed.
+func (p *caldavProvider) LinkAccount(ctx context.Context, input CalDAVAccountInput) (*domain.CalendarAccount, error) {
+	if !p.enabled {
+		return nil, fmt.Errorf("%w: %s is not configured", ErrProviderUnsupported, ProviderCalDAV)
+	}
+	if input.Username == "" || input.Password == "" {
+		return nil, fmt.Errorf("%w: username and password are required", ErrInvalidAccount)
+	}
+	if !p.allowedURL(input.ServerURL) {
+		return nil, fmt.Errorf("%w: server_url must be an https URL of a public host", ErrInvalidAccount)
+	}
+
+	client := caldav.NewClient(p.httpClient, input.Username, input.Password)
+	principal, home, err := client.FindCalendarHome(ctx, input.ServerURL)
+	if err == nil && !p.allowedURL(home) {
+		err = fmt.Errorf("calendar home %s is not an https URL of a public host", home)
+	}
+	if err == nil {
+		_, err = client.ListCalendars(ctx, home)
+	}
+	switch {
+	case caldav.IsStatus(err, http.StatusUnauthorized):
+		return nil, fmt.Errorf("%w: the server rejected the username or password", ErrInvalidAccount)
+	case err != nil:
+		return nil, fmt.Errorf("%w: no CalDAV calendars found at %s: %v", ErrInvalidAccount, input.ServerURL, err)
+	}
+	password, err := p.sealPassword(input.UserEmail, input.Password)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(home, "/") {
+		home += "/"
+	}
+	if principal == "" {
+		principal = home
+	}
+
+	return &domain.CalendarAccount{
+		UserEmail:         input.UserEmail,
+		Provider:          ProviderCalDAV,
+		Subject:           principal,
+		Email:             input.Username,
+		ServerURL:         home,
+		Username:          input.Username,
+		EncryptedPassword: password,
+	}, nil
+}
+
+// sealPassword encrypts the password of userEmail's account for storage.
+// The email is authenticated with it, so the value can't be moved to the
+// account of another user.
+func (p *caldavProvider) sealPassword(userEmail, password string) (string, error) {
+	if p.passwords == nil {
+		return "", errors.New("CalDAV passwords can't be stored without a valid CALDAV_SECRET_KEY")
+	}
+	nonce := make([]byte, p.passwords.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := p.passwords.Seal(nonce, nonce, []byte(password), []byte(userEmail))
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openPassword decrypts the stored password of account.
+func (p *caldavProvider) openPassword(account *domain.CalendarAccount) (string, error) {
+	if p.passwords == nil {
+		return "", errors.New("CalDAV passwords can't be read without a valid CALDAV_SECRET_KEY")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(account.EncryptedPassword)
+	if err != nil || len(sealed) < p.passwords.NonceSize() {
+		return "", fmt.Errorf("stored CalDAV password of %s is malformed", account.UserEmail)
+	}
+	nonce, ciphertext := sealed[:p.passwords.NonceSize()], sealed[p.passwords.NonceSize():]
+	password, err := p.passwords.Open(nil, nonce, ciphertext, []byte(account.UserEmail))
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt the CalDAV password of %s (was CALDAV_SECRET_KEY changed?): %w", account.UserEmail, err)
+	}
+	return string(password), nil
+}
+
+// allowedURL reports whether the server may be contacted at rawURL. Names
+// are checked again when they are dialed.
+func (p *caldavProvider) allowedURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || u.User != nil {
+		return false
+	}
+	if !p.allowLocal && localHost(u.Hostname()) {
+		return false
+	}
+	return u.Scheme == "https" || (u.Scheme == "http" && p.allowHTTP)
+}
+
+// modify changes the VEVENTs of the resource called name and writes them
+// back, as long as nobody else changed the resource in between. A lost race
+// is tried once more with what the other writer stored.
+func (p *caldavProvider) modify(ctx context.Context, user *domain.User, calendarID, name string, change func([]ics.Event) ([]ics.Event, *ProviderEvent, error)) (*ProviderEvent, error) {
+	session, err := p.open(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	calendarURL, err := session.calendarURL(ctx, calendarID)
+	if err != nil {
+		return nil, err
+	}
+	href := calendarURL + url.PathEscape(name)
+
+	for attempt := 0; ; attempt++ {
+		object, err := session.client.GetObject(ctx, href)
+		if err != nil {
+			if errors.Is(err, caldav.ErrNotFound) {
+				return nil, ErrEventNotFound
+			}
+			return nil, classifyCalDAVError(err)
+		}
+		cal, invalid, err := ics.Parse(bytes.NewReader(object.Data))
+		if err != nil || len(invalid) > 0 {
+			// Writing back only what could be read would lose the rest.
+			return nil, fmt.Errorf("CalDAV event %s can't be read, so it is left alone: %v", name, err)
+		}
+
+		events, result, err := change(cal.Events)
+		if err != nil {
+			return nil, err
+		}
+		data, err := encodeCalDAVObject(events)
+		if err != nil {
+			return nil, err
+		}
+		etag := object.ETag
+		if etag == "" {
+			etag = "*" // The server sent none; at least don't recreate a deleted event
+		}
+		_, err = session.client.PutObject(ctx, href, data, etag)
+		if errors.Is(err, caldav.ErrPreconditionFailed) && attempt == 0 {
+			log.Printf("🔄 CalDAV event %s changed while saving, trying again", name)
+			continue
+		}
+		if err != nil {
+			return nil, classifyCalDAVError(err)
+		}
+		if result != nil {
+			result.EventId = eventIDOf(name, result)
+		}
+		return result, nil
+	}
+}
+
+func (p *caldavProvider) open(ctx context.Context, user *domain.User) (*caldavSession, error) {
+	account, err := p.accountRepo.GetAccount(ctx, user.Email, ProviderCalDAV)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CalDAV account: %w", err)
+	}
+	if account == nil {
+		return nil, fmt.Errorf("%w: %s has no CalDAV account", ErrProviderNotLinked, user.Email)
+	}
+	password, err := p.openPassword(account)
+	if err != nil {
+		return nil, err
+	}
+	return &caldavSession{
+		account: account,
+		client:  caldav.NewClient(p.httpClient, account.Username, password),
+	}, nil
+}
+
+// caldavSession is a user's CalDAV account with a client for it.
+type caldavSession struct {
+	account *domain.CalendarAccount
+	client  *caldav.Client
+}
+
+// calendarURL resolves a calendar ID. "primary" is the first writable event
+// calendar; other IDs have to be inside the user's calendar home, so the
+// credentials are only ever sent to their own server.
+func (s *caldavSession) calendarURL(ctx context.Context, calendarID string) (string, error) {
+	if calendarID == "" || calendarID == "primary" {
+		calendars, err := s.client.ListCalendars(ctx, s.account.ServerURL)
+		if err != nil {
+			return "", classifyCalDAVError(err)
+		}
+		if c := defaultCalendar(calendars); c != nil {
+			return withSlash(c.Href), nil
+		}
+		return "", fmt.Errorf("%w: %s has no calendar for events", ErrCalendarNotFound, s.account.Email)
+	}
+
+	home, err := url.Parse(s.account.ServerURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid CalDAV home %q: %w", s.account.ServerURL, err)
+	}
+	target, err := home.Parse(calendarID)
+	if err != nil || target.Scheme != home.Scheme || target.Host != home.Host ||
+		!strings.HasPrefix(target.Path, home.Path) || strings.Contains(target.Path, "/../") {
+		return "", fmt.Errorf("%w: %s", ErrCalendarNotFound, calendarID)
+	}
+	target.RawQuery, target.Fragment = "", ""
+	return withSlash(target.String()), nil
+}
+
+// events lists the occurrences within [timeMin, timeMax) sorted by start,
+// optionally only those whose title contains q.
+func (s *caldavSession) events(ctx context.Context, calendarURL string, timeMin, timeMax time.Time, q string) ([]EventOutput, error) {
+	objects, err := s.client.QueryEvents(ctx, calendarURL, caldav.Query{Start: timeMin, End: timeMax, Expand: true, Summary: q})
+	if err != nil {
+		if errors.Is(err, caldav.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrCalendarNotFound, calendarIDOf(calendarURL))
+		}
+		return nil, classifyCalDAVError(err)
+	}
+
+	events := []EventOutput{}
+	for _, object := range objects {
+		name := objectName(object.Href)
+		cal, _, err := ics.Parse(bytes.NewReader(object.Data))
+		if err != nil {
+			log.Printf("⚠️ Skipping unreadable CalDAV event %s: %v", object.Href, err)
+			continue
+		}
+		for i := range cal.Events {
+			item := &cal.Events[i]
+			if item.Status == "CANCELLED" || !item.Start.Before(timeMax) || !item.End.After(timeMin) && !item.End.Equal(item.Start) {
+				continue
+			}
+			event := fromICSEvent(name, item, s.account.UserEmail)
+			if item.RecurrenceID != nil {
+				event.Recurrence = nil // Expanded occurrences may still carry the rules
+			}
+			events = append(events, event.EventOutput)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].StartTime.Before(events[j].StartTime) })
+	return events, nil
+}
+
+// eventOf picks the event or occurrence from the VEVENTs of a resource. An
+// occurrence without its own VEVENT is made from the series.
+func eventOf(events []ics.Event, name string, original *time.Time, userEmail string) *ProviderEvent {
+	if item := findICSEvent(events, original); item != nil {
+		return fromICSEvent(name, item, userEmail)
+	}
+	master := findICSEvent(events, nil)
+	if original == nil || master == nil || len(master.Recurrence) == 0 {
+		return nil
+	}
+	for _, line := range master.Recurrence {
+		if line == exceptionDate(*original, master.AllDay) {
+			return nil // Deleted occurrence
+		}
+	}
+	item := occurrenceOf(master, *original)
+	return fromICSEvent(name, &item, userEmail)
+}
+
+// findICSEvent returns the series or single event for a nil original, else
+// the changed occurrence that started at original.
+func findICSEvent(events []ics.Event, original *time.Time) *ics.Event {
+	for i := range events {
+		id := events[i].RecurrenceID
+		if (original == nil && id == nil) || (original != nil && id != nil && id.Equal(*original)) {
+			return &events[i]
+		}
+	}
+	return nil
+}
+
+// occurrenceOf returns the VEVENT of one occurrence of master that starts
+// at original.
+func occurrenceOf(master *ics.Event, original time.Time) ics.Event {
+	occurrence := *master
+	occurrence.Recurrence = nil
+	occurrence.RecurrenceID = &original
+	occurrence.Start = original
+	occurrence.End = original.Add(master.End.Sub(master.Start))
+	occurrence.Attendees = append([]ics.Attendee(nil), master.Attendees...)
+	occurrence.Extra = append([]string(nil), master.Extra...)
+	return occurrence
+}
+
+// fromICSEvent converts a VEVENT of the resource called name.
+func fromICSEvent(name string, item *ics.Event, userEmail string) *ProviderEvent {
+	event := &ProviderEvent{
+		EventOutput: EventOutput{
+			Title:       item.Summary,
+			Description: item.Description,
+			StartTime:   item.Start,
+			EndTime:     item.End,
+			AllDay:      item.AllDay,
+			Attendees:   []AttendeeOutput{},
+			CreatedBy:   userEmail,
+			Recurrence:  item.Recurrence,
+		},
+		ICalUID:  item.UID,
+		TimeZone: item.TimeZone,
+		Status:   strings.ToLower(item.Status),
+	}
+	if event.Status == "" {
+		event.Status = "confirmed"
+	}
+	if item.RecurrenceID != nil {
+		original := *item.RecurrenceID
+		event.RecurringEventID = name
+		event.OriginalStartTime = &original
+	}
+	event.EventId = eventIDOf(name, event)
+
+	for _, a := range item.Attendees {
+		event.Attendees = append(event.Attendees, AttendeeOutput{
+			Email:          a.Email,
+			Name:           a.Name,
+			Optional:       a.Optional,
+			Organizer:      item.Organizer != "" && strings.EqualFold(a.Email, item.Organizer),
+			ResponseStatus: responseStatus(a.PartStat),
+		})
+	}
+	return event
+}
+
+// applyOutputToICS writes the fields the API manages onto a VEVENT. Times
+// keep the zone they come in when it has an IANA name.
+func applyOutputToICS(item *ics.Event, event EventOutput) {
+	item.Stamp = time.Now().UTC()
+	item.Summary = event.Title
+	item.Description = event.Description
+	item.Start = event.StartTime
+	item.End = event.EndTime
+	item.AllDay = event.AllDay
+	item.TimeZone = ""
+	if zone := event.StartTime.Location().String(); !event.AllDay && zone != "UTC" && zone != "Local" {
+		if _, err := time.LoadLocation(zone); zone != "" && err == nil {
+			item.TimeZone = zone
+		}
+	}
+
+	known := make(map[string]ics.Attendee, len(item.Attendees))
+	for _, a := range item.Attendees {
+		known[strings.ToLower(a.Email)] = a
+	}
+	item.Attendees = item.Attendees[:0]
+	for _, a := range event.Attendees {
+		attendee, ok := known[strings.ToLower(a.Email)]
+		if !ok {
+			attendee = ics.Attendee{Email: a.Email, PartStat: partStat(a.ResponseStatus)}
+		}
+		attendee.Optional = a.Optional
+		if a.Name != "" {
+			attendee.Name = a.Name
+		}
+		item.Attendees = append(item.Attendees, attendee)
+	}
+}
+
+// encodeCalDAVObject writes the VEVENTs of one calendar object resource.
+func encodeCalDAVObject(events []ics.Event) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := ics.Write(&buf, &ics.Calendar{Events: events}); err != nil {
+		return nil, fmt.Errorf("failed to encode CalDAV event: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// eventIDOf returns the ID of event, an occurrence when it has an original
+// start.
+func eventIDOf(name string, event *ProviderEvent) string {
+	if event.OriginalStartTime == nil {
+		return name
+	}
+	if event.AllDay {
+		return name + "_" + event.OriginalStartTime.UTC().Format("20060102")
+	}
+	return name + "_" + event.OriginalStartTime.UTC().Format("20060102T150405Z")
+}
+
+// splitCalDAVID splits an event ID into the resource name and, for
+// occurrences, their original start.
+func splitCalDAVID(eventID string) (string, *time.Time) {
+	i := strings.LastIndex(eventID, "_")
+	if i < 0 {
+		return eventID, nil
+	}
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, eventID[i+1:]); err == nil {
+			return eventID[:i], &t
+		}
+	}
+	return eventID, nil
+}
+
+// defaultCalendar picks the calendar "primary" stands for.
+func defaultCalendar(calendars []caldav.Calendar) *caldav.Calendar {
+	for i := range calendars {
+		if calendars[i].SupportsEvents() && calendars[i].CanWrite {
+			return &calendars[i]
+		}
+	}
+	return nil
+}
+
+// calendarIDOf returns the ID the API uses for a calendar: its path.
+func calendarIDOf(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return u.EscapedPath()
+}
+
+// objectName returns the last segment of an object's href.
+func objectName(href string) string {
+	name := path.Base(strings.TrimSuffix(href, "/"))
+	if unescaped, err := url.PathUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+func withSlash(s string) string {
+	if strings.HasSuffix(s, "/") {
+		return s
+	}
+	return s + "/"
+}
+
+// responseStatus maps an iCalendar PARTSTAT to a Google response status.
+func responseStatus(partStat string) string {
+	switch partStat {
+	case "ACCEPTED":
+		return "accepted"
+	case "DECLINED":
+		return "declined"
+	case "TENTATIVE":
+		return "tentative"
+	default:
+		return "needsAction"
+	}
+}
+
+// classifyCalDAVError turns rejected credentials into ErrReauthRequired.
+func classifyCalDAVError(err error) error {
+	if caldav.IsStatus(err, http.StatusUnauthorized) {
+		return fmt.Errorf("%w: the CalDAV server rejected the credentials", ErrReauthRequired)
+	}
+	return err
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
@@ -3,261 +3,1060 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"google-calendar-api/internal/config"
 	"google-calendar-api/internal/domain"
 	"google-calendar-api/internal/repository"
 	"log"
+	"strconv"
 	"strings"
 	"time"
 
-	"golang.org/x/oauth2"
 	"google.golang.org/api/calendar/v3"
-	"google.golang.org/api/option"
 )
 
 type eventService struct {
 	meetingRepo repository.MeetingRepository
 	userRepo    repository.UserRepository
-	oauthConfig *oauth2.Config
+	clients     GoogleClientProvider
+	providers   CalendarProviders
+	webhooks    WebhookService
+	outboxRepo  repository.OutboxRepository
 }
 
-// NewEventService creates a new EventService instance.
-func NewEventService(meetingRepo repository.MeetingRepository, userRepo repository.UserRepository, cfg *config.Config) *eventService {
+// NewEventService creates a new EventService instance. Events of Google users
+// go through clients directly, since recurrence scopes and the outbox rely on
+// Google; other users' events go through their provider.
+func NewEventService(meetingRepo repository.MeetingRepository, userRepo repository.UserRepository, clients GoogleClientProvider, providers CalendarProviders, webhooks WebhookService, outboxRepo repository.OutboxRepository) *eventService {
 	return &eventService{
 		meetingRepo: meetingRepo,
 		userRepo:    userRepo,
-		oauthConfig: cfg.OAuthConfig,
+		clients:     clients,
+		providers:   providers,
+		webhooks:    webhooks,
+		outboxRepo:  outboxRepo,
 	}
 }
 
-func (s *eventService) CreateEvent(ctx context.Context, input CreateEventInput) (string, error) {
+func (s *eventService) CreateEvent(ctx context.Context, input CreateEventInput) (*OperationOutput, error) {
+	if err := validateRecurrence(input.Recurrence); err != nil {
+		return nil, err
+	}
+	calendarID := input.CalendarID
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+
 	//Retrieve User
 	user, err := s.userRepo.GetUserByEmail(ctx, input.CreatedBy) // Find user to get credentials
 	if err != nil || user == nil {
 		log.Printf("❌ User not found or error: %v\n", err)
-		return "", fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user not found")
 	}
-
-	//Create an oauth2 token
-	token := &oauth2.Token{
-		AccessToken:  user.AccessToken,
-		RefreshToken: user.RefreshToken,
-		Expiry:       user.ExpiresAt,
+	provider, err := s.providers.For(user)
+	if err != nil {
+		return nil, err
+	}
+	if !isGoogle(provider) {
+		input.CalendarID = calendarID
+		return s.createWithProvider(ctx, provider, user, input)
 	}
 
-	// Create a Google Calendar service client
-	client := s.oauthConfig.Client(ctx, token) // Use context here
-	service, err := calendar.NewService(ctx, option.WithHTTPClient(client))
+	// Record the intent before touching Google. The event ID is chosen here,
+	// so whoever applies the operation can never create a second event.
+	event := newGoogleEvent(input)
+	eventID := newGoogleEventID()
+	event.Id = eventID
+	payload, err := json.Marshal(event)
 	if err != nil {
-		return "", fmt.Errorf("failed to create calendar service: %w", err)
+		return nil, fmt.Errorf("failed to encode event: %w", err)
 	}
 
-	// Convert attendee emails into Google Calendar Attendee objects
-	var eventAttendees []*calendar.EventAttendee
-	for _, email := range input.Attendees {
-		eventAttendees = append(eventAttendees, &calendar.EventAttendee{Email: email})
+	meeting := &domain.Meeting{
+		Title:       input.Title,
+		Description: input.Description,
+		StartTime:   input.StartTime,
+		EndTime:     input.EndTime,
+		EventID:     eventID,
+		ICalUID:     input.ICalUID,
+		CalendarID:  calendarID,
+		AllDay:      input.AllDay,
+		Attendees:   toAttendees(event.Attendees),
+		Recurrence:  input.Recurrence,
+		CreatedBy:   input.CreatedBy,
+		Status:      "confirmed",
+		CommitState: "pending",
+	}
+	op := &domain.OutboxOperation{
+		Kind:          "create_event",
+		UserEmail:     input.CreatedBy,
+		CalendarID:    calendarID,
+		EventID:       eventID,
+		Payload:       string(payload),
+		Status:        "pending",
+		NextAttemptAt: time.Now().Add(outboxLease), // Keeps the worker away while we try right now
+	}
+	if err := s.outboxRepo.CreateMeetingWithOperation(ctx, meeting, op); err != nil {
+		return nil, fmt.Errorf("failed to store event in database: %w", err)
 	}
 
-	// Create the event object for Google Calendar
-	event := &calendar.Event{
-		Summary:     input.Title,
-		Description: input.Description,
-		Start: &calendar.EventDateTime{
-			DateTime: input.StartTime.Format(time.RFC3339),
-			TimeZone: input.StartTime.Location().String(), // Use the start time's location
-		},
-		End: &calendar.EventDateTime{
-			DateTime: input.EndTime.Format(time.RFC3339),
-			TimeZone: input.EndTime.Location().String(), // Use the end time's location
-		},
-		Attendees: eventAttendees, // Add attendees
-	}
-
-	// Insert event into Google Calendar
-	createdEvent, err := service.Events.Insert("primary", event).Do()
+	output, err := s.applyOperation(ctx, op)
 	if err != nil {
 		log.Printf("❌ Error creating event in Google Calendar %v\n", err)
-		// Check if the error is due to token expiry
-		if isTokenExpiredError(err) {
-			// Try to refresh the token
-			log.Println("🔄 Attempting to refresh token...")
-			newToken, refreshErr := s.refreshToken(ctx, user)
-			if refreshErr != nil {
-				return "", fmt.Errorf("failed to refresh token: %w", refreshErr)
-			}
+		if isNotFoundError(err) {
+			return output, fmt.Errorf("%w: %s", ErrCalendarNotFound, calendarID)
+		}
+		return output, fmt.Errorf("failed to create event: %w", err)
+	}
+	return output, nil
+}
 
-			// Update the user with the new token
-			user.AccessToken = newToken.AccessToken
-			user.RefreshToken = newToken.RefreshToken
-			user.ExpiresAt = newToken.Expiry
-			if updateErr := s.userRepo.UpdateUser(ctx, user); updateErr != nil {
-				return "", fmt.Errorf("failed to update user with new token: %w", updateErr)
-			}
+// createWithProvider creates the event in a provider other than Google and
+// then stores it. Without the outbox the event is created first, so it is
+// deleted again when it can't be stored.
+func (s *eventService) createWithProvider(ctx context.Context, provider CalendarProvider, user *domain.User, input CreateEventInput) (*OperationOutput, error) {
+	created, err := provider.CreateEvent(ctx, user, input)
+	if err != nil {
+		log.Printf("❌ Error creating event in %s: %v", provider.Name(), err)
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
 
-			// Retry creating the event with the new token
-			client = s.oauthConfig.Client(ctx, newToken)
-			service, err := calendar.NewService(ctx, option.WithHTTPClient(client)) // Use context here
+	meeting := &domain.Meeting{
+		CreatedBy:   user.Email,
+		CalendarID:  input.CalendarID,
+		Provider:    provider.Name(),
+		CommitState: "committed",
+	}
+	applyProviderEvent(meeting, created)
+	if input.ICalUID != "" {
+		meeting.ICalUID = input.ICalUID // Providers may pick their own; imports are found by the original
+	}
+	if err := s.meetingRepo.CreateMeeting(ctx, meeting); err != nil {
+		if delErr := provider.DeleteEvent(ctx, user, input.CalendarID, created.EventId); delErr != nil {
+			log.Printf("⚠️ Failed to remove event %s that could not be stored: %v", created.EventId, delErr)
+		}
+		return nil, fmt.Errorf("failed to store event in database: %w", err)
+	}
+	publishChanges(ctx, s.webhooks, nil, meeting)
 
-			if err != nil {
-				return "", fmt.Errorf("failed to create calendar service after refresh: %w", err)
-			}
-			createdEvent, err = service.Events.Insert("primary", event).Do()
-			if err != nil {
-				return "", fmt.Errorf("failed to create event after token refresh: %w", err)
-			}
+	return &OperationOutput{
+		Status:    "committed",
+		MeetingID: meeting.ID,
+		EventID:   meeting.EventID,
+		Event:     &created.EventOutput,
+	}, nil
+}
 
-		} else {
-			return "", fmt.Errorf("failed to create event: %w", err)
+func (s *eventService) ListEvents(ctx context.Context, input ListEventsInput) (*ListEventsOutput, error) {
+	// Retrieve User by Email.
+	user, err := s.userRepo.GetUserByEmail(ctx, input.UserEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	// A cursor carries the filters of the first request, so later pages
+	// always continue the same listing.
+	query := listQuery{
+		CalendarID: input.CalendarID,
+		TimeMin:    input.TimeMin,
+		TimeMax:    input.TimeMax,
+		Query:      input.Query,
+	}
+	if input.Cursor != "" {
+		if query, err = decodeCursor(input.Cursor); err != nil {
+			return nil, err
 		}
 	}
+	if query.CalendarID == "" {
+		query.CalendarID = "primary"
+	}
+	if query.TimeMin.IsZero() {
+		query.TimeMin = time.Now()
+	}
+	if query.TimeMax.IsZero() {
+		query.TimeMax = query.TimeMin.AddDate(0, 0, 7) // Next 7 days by default
+	}
+	if !query.TimeMin.Before(query.TimeMax) {
+		return nil, ErrInvalidTime
+	}
 
-	// Store event in the database
-	meeting := &domain.Meeting{
-		Title:       input.Title,
-		Description: input.Description,
-		StartTime:   input.StartTime,
-		EndTime:     input.EndTime,
-		EventID:     createdEvent.Id, // Store Google Calendar event ID
-		Attendees:   input.Attendees,
-		CreatedBy:   input.CreatedBy,
+	maxResults := input.MaxResults
+	if maxResults <= 0 {
+		maxResults = defaultPageSize
+	}
+	if maxResults > maxPageSize {
+		maxResults = maxPageSize
 	}
 
-	if err := s.meetingRepo.CreateMeeting(ctx, meeting); err != nil {
-		return "", fmt.Errorf("failed to store event in database: %w", err)
+	provider, err := s.providers.For(user)
+	if err != nil {
+		return nil, err
 	}
 
-	return createdEvent.Id, nil
+	// Providers may return short pages, so keep going until the page is
+	// full or there is nothing left.
+	output := &ListEventsOutput{Events: []EventOutput{}}
+	pageToken := query.PageToken
+	for {
+		events, next, err := provider.ListEvents(ctx, user, ProviderQuery{
+			CalendarID: query.CalendarID,
+			TimeMin:    query.TimeMin,
+			TimeMax:    query.TimeMax,
+			Query:      query.Query,
+			MaxResults: maxResults - len(output.Events),
+			PageToken:  pageToken,
+		})
+		if err != nil {
+			log.Printf("❌ Error fetching events from %s: %v", provider.Name(), err)
+			return nil, fmt.Errorf("failed to fetch events: %w", err)
+		}
+		output.Events = append(output.Events, events...)
+
+		pageToken = next
+		if pageToken == "" || len(output.Events) >= maxResults {
+			break
+		}
+	}
+
+	if pageToken != "" {
+		next := query
+		next.PageToken = pageToken
+		output.NextCursor = encodeCursor(next)
+	}
+	return output, nil
 }
 
-func (s *eventService) ListEvents(ctx context.Context, userEmail string) ([]EventOutput, error) {
-	// Retrieve User by Email.
+func (s *eventService) GetEvent(ctx context.Context, id, calendarID, userEmail string) (*EventDetail, error) {
 	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
 	if err != nil || user == nil {
 		return nil, fmt.Errorf("user not found")
 	}
 
-	// Create an oauth2 token.
-	token := &oauth2.Token{
-		AccessToken:  user.AccessToken,
-		RefreshToken: user.RefreshToken,
-		Expiry:       user.ExpiresAt,
+	meeting, err := s.findMeeting(ctx, id, userEmail)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create Google Calendar service client.
-	client := s.oauthConfig.Client(ctx, token)
-	service, err := calendar.NewService(ctx, option.WithHTTPClient(client)) // Use context here
+	// The stored calendar wins; calendarID only helps find events that are
+	// not stored locally.
+	eventID, providerName := id, user.CalendarProvider
+	if meeting != nil {
+		eventID, providerName = meeting.EventID, meeting.Provider
+		calendarID = meeting.CalendarID
+	}
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+	provider, err := s.providers.Get(providerName)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create calendar service: %w", err)
+		return nil, err
 	}
 
-	// Fetch upcoming meetings from Google Calendar for the next 7 days.
-	now := time.Now().Format(time.RFC3339)
-	weekLater := time.Now().AddDate(0, 0, 7).Format(time.RFC3339)
+	item, err := provider.GetEvent(ctx, user, calendarID, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch event: %w", err)
+	}
+	if meeting == nil && item == nil {
+		return nil, ErrEventNotFound
+	}
 
-	events, err := service.Events.List("primary").
-		ShowDeleted(false).
-		SingleEvents(true).
-		TimeMin(now).
-		TimeMax(weekLater).
-		OrderBy("startTime").
-		Do()
+	detail := &EventDetail{
+		Meeting:         meeting,
+		StoredLocally:   meeting != nil,
+		DeletedInGoogle: meeting != nil && item == nil,
+		Drift:           []FieldDrift{},
+	}
+	if item != nil {
+		detail.Event = &item.EventOutput
+	}
+	if meeting != nil && detail.Event != nil {
+		detail.Drift = compareMeeting(meeting, detail.Event)
+	}
+	if meeting != nil && len(meeting.Recurrence) > 0 {
+		meeting.Exceptions, err = s.meetingRepo.ListMeetingExceptions(ctx, meeting.CreatedBy, meeting.EventID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load exceptions from database: %w", err)
+		}
+	}
+	return detail, nil
+}
 
+func (s *eventService) ListCalendars(ctx context.Context, userEmail string) ([]CalendarOutput, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	provider, err := s.providers.For(user)
+	if err != nil {
+		return nil, err
+	}
+	calendars, err := provider.ListCalendars(ctx, user)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list calendars: %w", err)
+	}
+	return calendars, nil
+}
+
+// findMeeting looks one of the user's meetings up by local ID first and then
+// by Google event ID. It returns nil, nil when neither matches.
+func (s *eventService) findMeeting(ctx context.Context, id, userEmail string) (*domain.Meeting, error) {
+	if localID, err := strconv.ParseUint(id, 10, 64); err == nil {
+		meeting, err := s.meetingRepo.GetMeetingByID(ctx, uint(localID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load event from database: %w", err)
+		}
+		// Another user's row is as good as none: telling them apart would
+		// reveal which IDs exist, and event IDs can be all digits too.
+		if meeting != nil && strings.EqualFold(meeting.CreatedBy, userEmail) {
+			return meeting, nil
+		}
+	}
+
+	meeting, err := s.meetingRepo.GetMeetingByEventID(ctx, userEmail, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event from database: %w", err)
+	}
+	return meeting, nil
+}
+
+// toEventOutput converts a Google Calendar event into an EventOutput.
+func toEventOutput(item *calendar.Event, userEmail string) EventOutput {
+	startTime, allDay, err := parseEventDateTime(item.Start)
+	if err != nil {
+		log.Printf("⚠️ Event %s has an unreadable start: %v", item.Id, err)
+	}
+	endTime, _, err := parseEventDateTime(item.End)
+	if err != nil {
+		log.Printf("⚠️ Event %s has an unreadable end: %v", item.Id, err)
+	}
+
+	output := EventOutput{
+		Title:            item.Summary,
+		Description:      item.Description,
+		StartTime:        startTime,
+		EndTime:          endTime,
+		AllDay:           allDay,
+		Attendees:        toAttendeeOutputs(toAttendees(item.Attendees)),
+		EventId:          item.Id,
+		CreatedBy:        userEmail,
+		Recurrence:       item.Recurrence,
+		RecurringEventID: item.RecurringEventId,
+	}
+	if item.OriginalStartTime != nil {
+		if originalStart, _, err := parseEventDateTime(item.OriginalStartTime); err == nil {
+			output.OriginalStartTime = &originalStart
+		}
+	}
+	output.Conference = toConferenceOutput(item.ConferenceData)
+	return output
+}
+
+// toConferenceOutput picks the video link out of Google's conference data.
+func toConferenceOutput(data *calendar.ConferenceData) *ConferenceOutput {
+	if data == nil {
+		return nil
+	}
+	conference := &ConferenceOutput{ConferenceID: data.ConferenceId}
+	if data.ConferenceSolution != nil {
+		conference.Solution = data.ConferenceSolution.Name
+	}
+	if data.CreateRequest != nil && data.CreateRequest.Status != nil {
+		conference.Status = data.CreateRequest.Status.StatusCode
+	}
+	for _, entry := range data.EntryPoints {
+		if entry.EntryPointType == "video" {
+			conference.JoinURL = entry.Uri
+			break
+		}
+	}
+	return conference
+}
+
+// compareMeeting lists the fields where the stored meeting and the Google event disagree.
+func compareMeeting(meeting *domain.Meeting, event *EventOutput) []FieldDrift {
+	drift := []FieldDrift{}
+	if meeting.Title != event.Title {
+		drift = append(drift, FieldDrift{Field: "title", Stored: meeting.Title, Google: event.Title})
+	}
+	if meeting.Description != event.Description {
+		drift = append(drift, FieldDrift{Field: "description", Stored: meeting.Description, Google: event.Description})
+	}
+	if !meeting.StartTime.Equal(event.StartTime) {
+		drift = append(drift, FieldDrift{Field: "start_time", Stored: meeting.StartTime, Google: event.StartTime})
+	}
+	if !meeting.EndTime.Equal(event.EndTime) {
+		drift = append(drift, FieldDrift{Field: "end_time", Stored: meeting.EndTime, Google: event.EndTime})
+	}
+
+	// Attendee emails are compared case-insensitively, order does not matter.
+	inGoogle := make(map[string]AttendeeOutput)
+	for _, a := range event.Attendees {
+		inGoogle[strings.ToLower(a.Email)] = a
+	}
+	stored := make(map[string]bool)
+	for _, a := range meeting.Attendees {
+		stored[strings.ToLower(a.Email)] = true
+		google, ok := inGoogle[strings.ToLower(a.Email)]
+		if !ok {
+			drift = append(drift, FieldDrift{Field: "attendees", Stored: a.Email, Google: nil})
+		} else if google.ResponseStatus != a.ResponseStatus {
+			drift = append(drift, FieldDrift{Field: "response_status", Stored: a.Email + ": " + a.ResponseStatus, Google: a.Email + ": " + google.ResponseStatus})
+		}
+	}
+	for _, a := range event.Attendees {
+		if !stored[strings.ToLower(a.Email)] {
+			drift = append(drift, FieldDrift{Field: "attendees", Stored: nil, Google: a.Email})
+		}
+	}
+	return drift
+}
+
+func (s *eventService) UpdateEvent(ctx context.Context, input UpdateEventInput) error {
+	change := eventChange{
+		title:       &input.Title,
+		description: &input.Description,
+		start:       &input.StartTime,
+		end:         &input.EndTime,
+		allDay:      &input.AllDay,
+		attendees:   &input.Attendees,
+		replace:     true,
+	}
+	if input.Recurrence != nil {
+		change.recurrence = &input.Recurrence
+	}
+	if err := s.changeEvent(ctx, input.EventID, input.UpdatedBy, input.Scope, change); err != nil {
+		return fmt.Errorf("failed to update event: %w", err)
+	}
+	return nil
+}
+
+func (s *eventService) PatchEvent(ctx context.Context, input PatchEventInput) error {
+	change := eventChange{
+		title:       input.Title,
+		description: input.Description,
+		start:       input.StartTime,
+		end:         input.EndTime,
+		allDay:      input.AllDay,
+		attendees:   input.Attendees,
+		recurrence:  input.Recurrence,
+	}
+	if err := s.changeEvent(ctx, input.EventID, input.UpdatedBy, input.Scope, change); err != nil {
+		return fmt.Errorf("failed to patch event: %w", err)
+	}
+	return nil
+}
+
+// changeEvent applies an update or patch to the event and the occurrences
+// selected by scope, then brings the stored meetings in line with Google.
+func (s *eventService) changeEvent(ctx context.Context, eventID, userEmail string, scope RecurrenceScope, change eventChange) error {
+	if change.recurrence != nil {
+		if err := validateRecurrence(*change.recurrence); err != nil {
+			return err
+		}
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil || user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	owner, err := s.findOwner(ctx, user, eventID)
 	if err != nil {
-		log.Printf("❌ Error fetching from Google Calendar: %v", err)
-		// Check if the error is due to token expiry
-		if isTokenExpiredError(err) {
-			// Try to refresh the token
-			log.Println("🔄 Attempting to refresh token...")
-			newToken, refreshErr := s.refreshToken(ctx, user)
-			if refreshErr != nil {
-				return nil, fmt.Errorf("failed to refresh token: %w", refreshErr)
+		return err
+	}
+	provider, err := s.providers.Get(owner.Provider)
+	if err != nil {
+		return err
+	}
+	if !isGoogle(provider) {
+		return s.changeWithProvider(ctx, provider, user, owner, eventID, scope, change)
+	}
+
+	return s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		calendarID := owner.CalendarID
+		target, err := getGoogleEvent(ctx, service, calendarID, eventID)
+		if err != nil {
+			return err
+		}
+		if target == nil {
+			return ErrEventNotFound
+		}
+
+		scope, err := resolveScope(scope, target.RecurringEventId, target.Recurrence)
+		if err != nil {
+			return err
+		}
+		if change.recurrence != nil && scope == ScopeInstance {
+			return fmt.Errorf("%w: recurrence can only be changed on the whole series", ErrInvalidScope)
+		}
+		if err := change.check(toEventOutput(target, userEmail)); err != nil {
+			return err
+		}
+
+		switch scope {
+		case ScopeInstance:
+			// Changing an occurrence makes Google store it as an exception of the series.
+			body := &calendar.Event{}
+			change.applyTo(body)
+			keepAttendeeDetails(body.Attendees, target.Attendees)
+			body.ForceSendFields = change.forceSendFields()
+			updated, err := service.Events.Patch(calendarID, target.Id, body).Do()
+			if err != nil {
+				return err
 			}
+			return s.saveMeeting(ctx, owner, updated)
 
-			// Update the user with the new token
-			user.AccessToken = newToken.AccessToken
-			user.RefreshToken = newToken.RefreshToken
-			user.ExpiresAt = newToken.Expiry
+		case ScopeFollowing:
+			master, err := service.Events.Get(calendarID, target.RecurringEventId).Do()
+			if err != nil {
+				return err
+			}
+			return s.splitSeries(ctx, service, owner, master, target, change)
 
-			if updateErr := s.userRepo.UpdateUser(ctx, user); updateErr != nil {
-				return nil, fmt.Errorf("failed to update user with refreshed token, %w", updateErr)
+		default:
+			// A plain event, or the whole series. Time changes made on an
+			// occurrence move every occurrence by the same amount.
+			master := target
+			if target.RecurringEventId != "" {
+				master, err = service.Events.Get(calendarID, target.RecurringEventId).Do()
+				if err != nil {
+					return err
+				}
+				change = change.relativeTo(toEventOutput(target, ""), toEventOutput(master, ""))
+			}
+			updated, err := writeEvent(service, calendarID, master, change)
+			if err != nil {
+				return err
 			}
+			return s.saveMeeting(ctx, owner, updated)
+		}
+	})
+}
+
+// changeWithProvider applies an update or patch through a provider other
+// than Google. Occurrences can be changed on their own or with their series,
+// but a series can't be split.
+func (s *eventService) changeWithProvider(ctx context.Context, provider CalendarProvider, user *domain.User, owner *domain.Meeting, eventID string, scope RecurrenceScope, change eventChange) error {
+	target, err := provider.GetEvent(ctx, user, owner.CalendarID, eventID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return ErrEventNotFound
+	}
+
+	scope, err = resolveScope(scope, target.RecurringEventID, target.Recurrence)
+	if err != nil {
+		return err
+	}
+	if scope == ScopeFollowing {
+		return fmt.Errorf("%w: %s can't change following occurrences", ErrProviderUnsupported, provider.Name())
+	}
+	if change.recurrence != nil && scope == ScopeInstance {
+		return fmt.Errorf("%w: recurrence can only be changed on the whole series", ErrInvalidScope)
+	}
+	if err := change.check(target.EventOutput); err != nil {
+		return err
+	}
+
+	if scope == ScopeAll && target.RecurringEventID != "" {
+		master, err := provider.GetEvent(ctx, user, owner.CalendarID, target.RecurringEventID)
+		if err != nil {
+			return err
+		}
+		if master == nil {
+			return ErrEventNotFound
+		}
+		change = change.relativeTo(target.EventOutput, master.EventOutput)
+		target = master
+	}
+
+	updated, err := provider.UpdateEvent(ctx, user, owner.CalendarID, change.applyToOutput(target.EventOutput))
+	if err != nil {
+		return err
+	}
+	return s.storeEvent(ctx, owner, updated)
+}
+
+func (s *eventService) DeleteEvent(ctx context.Context, eventID, userEmail string, scope RecurrenceScope) error {
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil || user == nil {
+		return fmt.Errorf("user not found")
+	}
+
+	owner, err := s.findOwner(ctx, user, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	provider, err := s.providers.Get(owner.Provider)
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	if !isGoogle(provider) {
+		if err := s.deleteWithProvider(ctx, provider, user, owner, eventID, scope); err != nil {
+			return fmt.Errorf("failed to delete event: %w", err)
+		}
+		return nil
+	}
+
+	err = s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		calendarID := owner.CalendarID
+		target, err := getGoogleEvent(ctx, service, calendarID, eventID)
+		if err != nil {
+			return err
+		}
+		// An event that is already gone from Google only needs the local row removed.
+		if target == nil {
+			return s.deleteMeetings(ctx, owner)
+		}
 
-			// Retry creating the event with the new token
-			client = s.oauthConfig.Client(ctx, newToken)
-			service, err = calendar.NewService(ctx, option.WithHTTPClient(client)) // Use context here
+		scope, err := resolveScope(scope, target.RecurringEventId, target.Recurrence)
+		if err != nil {
+			return err
+		}
+
+		switch scope {
+		case ScopeInstance:
+			if err := service.Events.Delete(calendarID, target.Id).Do(); err != nil && !isNotFoundError(err) {
+				return err
+			}
+			target.Status = "cancelled"
+			return s.saveMeeting(ctx, owner, target)
 
+		case ScopeFollowing:
+			master, err := service.Events.Get(calendarID, target.RecurringEventId).Do()
 			if err != nil {
-				return nil, fmt.Errorf("failed to create calendar service after refresh: %w", err)
+				return err
 			}
+			splitAt := originalStart(target)
+			before, err := countInstancesBefore(ctx, service, calendarID, master, splitAt)
+			if err != nil {
+				return err
+			}
+			if before > 0 {
+				// Ending the series just before this occurrence removes it and
+				// everything after it.
+				updated, err := service.Events.Patch(calendarID, master.Id, &calendar.Event{
+					Recurrence: truncateRecurrence(master.Recurrence, splitAt, master.Start.Date != ""),
+				}).Do()
+				if err != nil {
+					return err
+				}
+				if err := s.saveMeeting(ctx, owner, updated); err != nil {
+					return err
+				}
+				return s.deleteExceptionsFrom(ctx, owner.CreatedBy, master.Id, splitAt)
+			}
+			// Deleting from the first occurrence on is deleting the whole series.
+			target = master
+
+		default:
+			if target.RecurringEventId != "" {
+				target, err = service.Events.Get(calendarID, target.RecurringEventId).Do()
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := service.Events.Delete(calendarID, target.Id).Do(); err != nil && !isNotFoundError(err) {
+			return err
+		}
+		meeting, err := s.meetingRepo.GetMeetingByEventID(ctx, owner.CreatedBy, target.Id)
+		if err != nil {
+			return err
+		}
+		return s.deleteMeetings(ctx, meeting)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+	return nil
+}
+
+// deleteWithProvider deletes through a provider other than Google, which can
+// delete an occurrence or a whole series but can't split one.
+func (s *eventService) deleteWithProvider(ctx context.Context, provider CalendarProvider, user *domain.User, owner *domain.Meeting, eventID string, scope RecurrenceScope) error {
+	target, err := provider.GetEvent(ctx, user, owner.CalendarID, eventID)
+	if err != nil {
+		return err
+	}
+	if target == nil {
+		return s.deleteMeetings(ctx, owner)
+	}
 
-			events, err = service.Events.List("primary").
-				ShowDeleted(false).
-				SingleEvents(true).
-				TimeMin(now).
-				TimeMax(weekLater).
-				OrderBy("startTime").
-				Do()
+	scope, err = resolveScope(scope, target.RecurringEventID, target.Recurrence)
+	if err != nil {
+		return err
+	}
+	switch scope {
+	case ScopeFollowing:
+		return fmt.Errorf("%w: %s can't delete following occurrences", ErrProviderUnsupported, provider.Name())
+	case ScopeInstance:
+		if err := provider.DeleteEvent(ctx, user, owner.CalendarID, target.EventId); err != nil {
+			return err
+		}
+		target.Status = "cancelled"
+		return s.storeEvent(ctx, owner, target)
+	}
+
+	id := target.EventId
+	if target.RecurringEventID != "" {
+		id = target.RecurringEventID
+	}
+	if err := provider.DeleteEvent(ctx, user, owner.CalendarID, id); err != nil {
+		return err
+	}
+	meeting, err := s.meetingRepo.GetMeetingByEventID(ctx, owner.CreatedBy, id)
+	if err != nil {
+		return err
+	}
+	return s.deleteMeetings(ctx, meeting)
+}
+
+// findOwner finds the stored meeting that proves user owns eventID.
+// Occurrences without their own row are owned through the series master.
+func (s *eventService) findOwner(ctx context.Context, user *domain.User, eventID string) (*domain.Meeting, error) {
+	owner, err := s.meetingRepo.GetMeetingByEventID(ctx, user.Email, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load event from database: %w", err)
+	}
+	// Occurrence IDs are "<series ID>_<original start>"; the series ID
+	// itself may contain "_" at CalDAV servers.
+	if i := strings.LastIndex(eventID, "_"); owner == nil && i > 0 {
+		owner, err = s.meetingRepo.GetMeetingByEventID(ctx, user.Email, eventID[:i])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load event from database: %w", err)
+		}
+	}
+	if owner == nil {
+		masterID, err := s.seriesOf(ctx, user, eventID)
+		if err != nil {
+			return nil, err
+		}
+		if masterID != "" {
+			owner, err = s.meetingRepo.GetMeetingByEventID(ctx, user.Email, masterID)
 			if err != nil {
-				return nil, fmt.Errorf("failed to list events after token refresh: %w", err)
+				return nil, fmt.Errorf("failed to load event from database: %w", err)
 			}
-		} else {
-			return nil, fmt.Errorf("failed to fetch events from Google Calendar: %w", err)
 		}
 	}
+	if owner == nil {
+		return nil, ErrEventNotFound
+	}
+	if !strings.EqualFold(owner.CreatedBy, user.Email) {
+		return nil, ErrNotEventOwner
+	}
+	if owner.CalendarID == "" {
+		owner.CalendarID = "primary" // Rows stored before calendars were tracked
+	}
+	return owner, nil
+}
 
-	//Convert to EventOutput
-	var eventOutputs []EventOutput
-	for _, item := range events.Items {
-		startTime, _ := time.Parse(time.RFC3339, item.Start.DateTime)
-		endTime, _ := time.Parse(time.RFC3339, item.End.DateTime)
+// seriesOf asks the user's provider for the series eventID is an occurrence
+// of. It returns "" when it isn't one, or for Google, whose occurrence IDs
+// tell already.
+func (s *eventService) seriesOf(ctx context.Context, user *domain.User, eventID string) (string, error) {
+	provider, err := s.providers.For(user)
+	if err != nil || isGoogle(provider) {
+		return "", err
+	}
+	event, err := provider.GetEvent(ctx, user, "primary", eventID)
+	if err != nil || event == nil {
+		return "", err
+	}
+	return event.RecurringEventID, nil
+}
 
-		var attendees []string
-		if item.Attendees != nil {
-			for _, a := range item.Attendees {
-				attendees = append(attendees, a.Email)
+// saveMeeting stores the state Google returned for an event, see storeEvent.
+func (s *eventService) saveMeeting(ctx context.Context, owner *domain.Meeting, event *calendar.Event) error {
+	stored := toProviderEvent(event, owner.CreatedBy)
+	return s.storeEvent(ctx, owner, &stored)
+}
+
+// storeEvent stores the state the provider returned for an event, creating
+// the row when the event is an occurrence that did not have one yet.
+// The new row takes its creator, calendar and provider from owner.
+func (s *eventService) storeEvent(ctx context.Context, owner *domain.Meeting, event *ProviderEvent) error {
+	meeting, err := s.meetingRepo.GetMeetingByEventID(ctx, owner.CreatedBy, event.EventId)
+	if err != nil {
+		return fmt.Errorf("failed to load event from database: %w", err)
+	}
+	if meeting == nil {
+		meeting = &domain.Meeting{CreatedBy: owner.CreatedBy, CalendarID: owner.CalendarID, Provider: owner.Provider}
+		applyProviderEvent(meeting, event)
+		if err := s.meetingRepo.CreateMeeting(ctx, meeting); err != nil {
+			return fmt.Errorf("failed to store event in database: %w", err)
+		}
+		publishChanges(ctx, s.webhooks, nil, meeting)
+		return nil
+	}
+
+	before := *meeting
+	applyProviderEvent(meeting, event)
+	if err := s.meetingRepo.UpdateMeeting(ctx, meeting); err != nil {
+		return fmt.Errorf("failed to update event in database: %w", err)
+	}
+	publishChanges(ctx, s.webhooks, &before, meeting)
+	return nil
+}
+
+// deleteMeetings removes a stored meeting and, for a series master, its exceptions.
+func (s *eventService) deleteMeetings(ctx context.Context, meeting *domain.Meeting) error {
+	if meeting == nil {
+		return nil
+	}
+	if len(meeting.Recurrence) > 0 {
+		exceptions, err := s.meetingRepo.ListMeetingExceptions(ctx, meeting.CreatedBy, meeting.EventID)
+		if err != nil {
+			return fmt.Errorf("failed to load exceptions from database: %w", err)
+		}
+		for i := range exceptions {
+			if err := s.meetingRepo.DeleteMeeting(ctx, &exceptions[i]); err != nil {
+				return fmt.Errorf("failed to delete exception from database: %w", err)
+			}
+		}
+	}
+	if err := s.meetingRepo.DeleteMeeting(ctx, meeting); err != nil {
+		return fmt.Errorf("failed to delete event from database: %w", err)
+	}
+	before := *meeting
+	meeting.Status = "cancelled"
+	publishChanges(ctx, s.webhooks, &before, meeting)
+	return nil
+}
+
+// deleteExceptionsFrom removes stored exceptions of a series from splitAt on,
+// once the series has been ended before them.
+func (s *eventService) deleteExceptionsFrom(ctx context.Context, createdBy, recurringEventID string, splitAt time.Time) error {
+	exceptions, err := s.meetingRepo.ListMeetingExceptions(ctx, createdBy, recurringEventID)
+	if err != nil {
+		return fmt.Errorf("failed to load exceptions from database: %w", err)
+	}
+	for i := range exceptions {
+		if exceptions[i].OriginalStartTime != nil && !exceptions[i].OriginalStartTime.Before(splitAt) {
+			if err := s.meetingRepo.DeleteMeeting(ctx, &exceptions[i]); err != nil {
+				return fmt.Errorf("failed to delete exception from database: %w", err)
 			}
 		}
+	}
+	return nil
+}
+
+// eventDateTime converts a time into Google's representation. All-day events
+// carry only a date; timed events carry the time and its zone name.
+func eventDateTime(t time.Time, allDay bool) *calendar.EventDateTime {
+	if allDay {
+		return &calendar.EventDateTime{Date: t.Format("2006-01-02")}
+	}
+	return &calendar.EventDateTime{
+		DateTime: t.Format(time.RFC3339),
+		TimeZone: t.Location().String(),
+	}
+}
+
+// parseEventDateTime reads a Google start or end. All-day values are returned
+// as midnight UTC of their date.
+func parseEventDateTime(dt *calendar.EventDateTime) (time.Time, bool, error) {
+	switch {
+	case dt == nil:
+		return time.Time{}, false, fmt.Errorf("missing date")
+	case dt.DateTime != "":
+		t, err := time.Parse(time.RFC3339, dt.DateTime)
+		return t, false, err
+	case dt.Date != "":
+		t, err := time.Parse("2006-01-02", dt.Date)
+		return t, true, err
+	default:
+		return time.Time{}, false, fmt.Errorf("neither date nor dateTime is set")
+	}
+}
+
+// eventChange is the set of fields an update or patch changes. Nil fields are
+// left as they are.
+type eventChange struct {
+	title       *string
+	description *string
+	start       *time.Time
+	end         *time.Time
+	allDay      *bool // Decides whether start and end are sent as dates; set from the event when nil
+	attendees   *[]string
+	recurrence  *[]string
+	replace     bool // PUT: send the whole event with Events.Update
+}
+
+// applyTo copies the changed fields onto event.
+func (c eventChange) applyTo(event *calendar.Event) {
+	if c.title != nil {
+		event.Summary = *c.title
+	}
+	if c.description != nil {
+		event.Description = *c.description
+	}
+	if c.start != nil {
+		event.Start = eventDateTime(*c.start, c.allDay != nil && *c.allDay)
+	}
+	if c.end != nil {
+		event.End = eventDateTime(*c.end, c.allDay != nil && *c.allDay)
+	}
+	if c.attendees != nil {
+		event.Attendees = []*calendar.EventAttendee{}
+		for _, email := range *c.attendees {
+			event.Attendees = append(event.Attendees, &calendar.EventAttendee{Email: email})
+		}
+	}
+	if c.recurrence != nil {
+		event.Recurrence = *c.recurrence
+	}
+}
+
+// forceSendFields lists the changed fields that may be empty, since empty
+// values are otherwise dropped from a patch body.
+func (c eventChange) forceSendFields() []string {
+	var fields []string
+	if c.title != nil {
+		fields = append(fields, "Summary")
+	}
+	if c.description != nil {
+		fields = append(fields, "Description")
+	}
+	if c.attendees != nil {
+		fields = append(fields, "Attendees")
+	}
+	if c.recurrence != nil {
+		fields = append(fields, "Recurrence")
+	}
+	return fields
+}
+
+// check fills in whether the event stays all-day and makes sure the
+// changed event, current before the change, still ends after it starts.
+func (c *eventChange) check(current EventOutput) error {
+	if c.allDay == nil {
+		c.allDay = &current.AllDay
+	} else if *c.allDay != current.AllDay && (c.start == nil || c.end == nil) {
+		return fmt.Errorf("%w: both start and end are needed to switch all_day", ErrInvalidTime)
+	}
+	start, end := current.StartTime, current.EndTime
+	if c.start != nil {
+		start = *c.start
+	}
+	if c.end != nil {
+		end = *c.end
+	}
+	if !start.Before(end) {
+		return ErrInvalidTime
+	}
+	return nil
+}
 
-		eventOutputs = append(eventOutputs, EventOutput{
-			Title:       item.Summary,
-			Description: item.Description,
-			StartTime:   startTime,
-			EndTime:     endTime,
-			Attendees:   attendees,
-			EventId:     item.Id,
-			CreatedBy:   userEmail, // The user who is listing the events
+// applyToOutput returns event with the changed fields replaced.
+func (c eventChange) applyToOutput(event EventOutput) EventOutput {
+	if c.title != nil {
+		event.Title = *c.title
+	}
+	if c.description != nil {
+		event.Description = *c.description
+	}
+	if c.start != nil {
+		event.StartTime = *c.start
+	}
+	if c.end != nil {
+		event.EndTime = *c.end
+	}
+	if c.allDay != nil {
+		event.AllDay = *c.allDay
+	}
+	if c.attendees != nil {
+		event.Attendees = attendeeOutputs(*c.attendees, event.Attendees)
+	}
+	if c.recurrence != nil {
+		event.Recurrence = *c.recurrence
+	}
+	return event
+}
+
+// relativeTo turns times given for an occurrence into times for the series
+// master, moving each end of the master by as much as the occurrence moved.
+func (c eventChange) relativeTo(occurrence, series EventOutput) eventChange {
+	if c.start != nil {
+		start := series.StartTime.Add(c.start.Sub(occurrence.StartTime)).In(c.start.Location())
+		c.start = &start
+	}
+	if c.end != nil {
+		end := series.EndTime.Add(c.end.Sub(occurrence.EndTime)).In(c.end.Location())
+		c.end = &end
+	}
+	return c
+}
+
+// writeEvent sends the change to Google, as a full Events.Update for PUT and
+// as an Events.Patch otherwise.
+func writeEvent(service *calendar.Service, calendarID string, existing *calendar.Event, change eventChange) (*calendar.Event, error) {
+	if change.replace {
+		// Start from the current event so fields this API does not manage survive.
+		event := *existing
+		change.applyTo(&event)
+		keepAttendeeDetails(event.Attendees, existing.Attendees)
+		return service.Events.Update(calendarID, existing.Id, &event).Do()
+	}
+	body := &calendar.Event{}
+	change.applyTo(body)
+	keepAttendeeDetails(body.Attendees, existing.Attendees)
+	body.ForceSendFields = change.forceSendFields()
+	return service.Events.Patch(calendarID, existing.Id, body).Do()
+}
+
+// applyEventToMeeting copies the Google state of an event onto a stored meeting.
+func applyEventToMeeting(meeting *domain.Meeting, event *calendar.Event) {
+	output := toProviderEvent(event, meeting.CreatedBy)
+	applyProviderEvent(meeting, &output)
+}
+
+// toAttendees converts Google attendees into attendee rows.
+func toAttendees(items []*calendar.EventAttendee) []domain.Attendee {
+	attendees := make([]domain.Attendee, 0, len(items))
+	for _, a := range items {
+		status := a.ResponseStatus
+		if status == "" {
+			status = "needsAction"
+		}
+		attendees = append(attendees, domain.Attendee{
+			Email:          a.Email,
+			Name:           a.DisplayName,
+			Optional:       a.Optional,
+			Organizer:      a.Organizer,
+			ResponseStatus: status,
 		})
 	}
+	return attendees
+}
 
-	return eventOutputs, nil
+func toAttendeeOutputs(attendees []domain.Attendee) []AttendeeOutput {
+	outputs := make([]AttendeeOutput, 0, len(attendees))
+	for _, a := range attendees {
+		outputs = append(outputs, AttendeeOutput{
+			Email:          a.Email,
+			Name:           a.Name,
+			Optional:       a.Optional,
+			Organizer:      a.Organizer,
+			ResponseStatus: a.ResponseStatus,
+		})
+	}
+	return outputs
 }
 
-// refreshToken refreshes the access token using the refresh token.
-func (s *eventService) refreshToken(ctx context.Context, user *domain.User) (*oauth2.Token, error) {
-	tokenSource := s.oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: user.RefreshToken})
-	newToken, err := tokenSource.Token()
-	if err != nil {
-		return nil, err
+// keepAttendeeDetails fills attendees that are only given by email with what
+// Google already knows about them, so rewriting the list keeps their answers.
+func keepAttendeeDetails(attendees, existing []*calendar.EventAttendee) {
+	known := make(map[string]*calendar.EventAttendee, len(existing))
+	for _, a := range existing {
+		known[strings.ToLower(a.Email)] = a
+	}
+	for i, a := range attendees {
+		if previous, ok := known[strings.ToLower(a.Email)]; ok {
+			attendees[i] = previous
+		}
 	}
-	return newToken, nil
-}
-
-// isTokenExpiredError checks if the error is due to an expired or invalid token.
-func isTokenExpiredError(err error) bool {
-	// This is a simplified check.  In a real application, you'd need to inspect
-	// the error more thoroughly, possibly checking for specific error codes
-	// returned by the Google API.
-	return err != nil && (
-	// Google uses specific error structures; check for those
-	// This is a placeholder; you *must* adapt this to the *actual* error
-	// structure returned by the Google API.
-	// Example:  strings.Contains(err.Error(), "Token expired") ||
-	//           strings.Contains(err.Error(), "Invalid Credentials")
-	//
-	//  You might need to use `googleapi.CheckResponse` to properly inspect the error:
-	//  https://pkg.go.dev/google.golang.org/api/googleapi#CheckResponse
-	strings.Contains(err.Error(), "invalid_grant") || // Common OAuth2 error
-		strings.Contains(err.Error(), "expired") || //Possible expired
-		strings.Contains(err.Error(), "Invalid Credentials")) //Possible invalid
 }
@@ -0,0 +1,99 @@
+// internal/service/event_test.go
+package service
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// TestFindMeetingIsScopedToUser checks that local IDs only find the user's
+// own rows, so probing them reveals nothing, and that an event ID made of
+// digits still finds its event.
+func TestFindMeetingIsScopedToUser(t *testing.T) {
+	meetings := repository.NewMemoryMeetingRepository()
+	s := &eventService{meetingRepo: meetings}
+	ctx := context.Background()
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	bobs := &domain.Meeting{Title: "Bob's", EventID: "bobevent", CreatedBy: "bob@example.com", StartTime: start, EndTime: start.Add(time.Hour)}
+	alices := &domain.Meeting{Title: "Alice's", CreatedBy: "alice@example.com", StartTime: start, EndTime: start.Add(time.Hour)}
+	for _, m := range []*domain.Meeting{bobs, alices} {
+		if err := meetings.CreateMeeting(ctx, m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Alice's event ID is the local ID of Bob's row.
+	alices.EventID = strconv.FormatUint(uint64(bobs.ID), 10)
+	if err := meetings.UpdateMeeting(ctx, alices); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := s.findMeeting(ctx, alices.EventID, "alice@example.com")
+	if err != nil || got == nil || got.ID != alices.ID {
+		t.Errorf("findMeeting(%s) for alice = %+v, %v; want her event", alices.EventID, got, err)
+	}
+	got, err = s.findMeeting(ctx, strconv.FormatUint(uint64(bobs.ID), 10), "carol@example.com")
+	if err != nil || got != nil {
+		t.Errorf("findMeeting of Bob's local ID for carol = %+v, %v; want nil", got, err)
+	}
+	got, err = s.findMeeting(ctx, strconv.FormatUint(uint64(alices.ID), 10), "alice@example.com")
+	if err != nil || got == nil || got.ID != alices.ID {
+		t.Errorf("findMeeting of her local ID = %+v, %v; want her event", got, err)
+	}
+}
+
+// TestPatchInstanceKeepsResponses checks that changing the attendees of one
+// occurrence keeps the answers of the attendees who stay.
+func TestPatchInstanceKeepsResponses(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	user := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	start := time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)
+	series := f.insert(t, user, &calendar.Event{
+		Summary:    "Weekly",
+		Start:      &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:        &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+		Recurrence: []string{"RRULE:FREQ=WEEKLY;COUNT=4"},
+		Attendees: []*calendar.EventAttendee{
+			{Email: "bob@example.com", ResponseStatus: "accepted"},
+			{Email: "carol@example.com", ResponseStatus: "declined"},
+		},
+	})
+
+	second := f.instances(t, user, series.Id)[1]
+
+	attendees := []string{"bob@example.com", "carol@example.com", "dave@example.com"}
+	err := f.events.PatchEvent(ctx, PatchEventInput{
+		EventID:   second.Id,
+		Attendees: &attendees,
+		Scope:     ScopeInstance,
+		UpdatedBy: user.Email,
+	})
+	if err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+
+	want := map[string]string{"bob@example.com": "accepted", "carol@example.com": "declined", "dave@example.com": "needsAction"}
+	for _, event := range f.fake.Events(user.Email) {
+		if event.Id != second.Id {
+			continue
+		}
+		if len(event.Attendees) != len(want) {
+			t.Fatalf("attendees = %d; want %d", len(event.Attendees), len(want))
+		}
+		for _, a := range event.Attendees {
+			if a.ResponseStatus != want[a.Email] {
+				t.Errorf("%s answered %q; want %q", a.Email, a.ResponseStatus, want[a.Email])
+			}
+		}
+		return
+	}
+	t.Fatal("the occurrence was not stored as an exception")
+}
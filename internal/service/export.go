@@ -0,0 +1,281 @@
+// internal/service/export.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/ics"
+	"google-calendar-api/internal/repository"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// Window of Google events rendered when the caller gives no bounds.
+const (
+	feedPast   = 30 * 24 * time.Hour
+	feedFuture = 365 * 24 * time.Hour
+)
+
+type exportService struct {
+	meetingRepo repository.MeetingRepository
+	userRepo    repository.UserRepository
+	clients     GoogleClientProvider
+}
+
+// NewExportService creates a new ExportService instance.
+func NewExportService(meetingRepo repository.MeetingRepository, userRepo repository.UserRepository, clients GoogleClientProvider) *exportService {
+	return &exportService{
+		meetingRepo: meetingRepo,
+		userRepo:    userRepo,
+		clients:     clients,
+	}
+}
+
+func (s *exportService) ExportICS(ctx context.Context, w io.Writer, input ExportInput) error {
+	if input.IncludeGoogle {
+		now := time.Now()
+		if input.TimeMin.IsZero() {
+			input.TimeMin = now.Add(-feedPast)
+		}
+		if input.TimeMax.IsZero() {
+			input.TimeMax = now.Add(feedFuture)
+		}
+	}
+	meetings, err := s.selectMeetings(ctx, input)
+	if err != nil {
+		return err
+	}
+
+	cal := &ics.Calendar{Name: input.UserEmail, Method: "PUBLISH"}
+	stored := make(map[string]bool)
+	for i := range meetings {
+		cal.Events = append(cal.Events, meetingToICS(&meetings[i]))
+		stored[meetings[i].EventID] = true
+	}
+	if input.IncludeGoogle {
+		events, err := s.googleEvents(ctx, input, stored)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Still serve what is stored; a feed that errors gets dropped by clients.
+			log.Printf("⚠️ Failed to load Google events of %s for export: %v", input.UserEmail, err)
+		}
+		cal.Events = append(cal.Events, events...)
+	}
+	return ics.Write(w, cal)
+}
+
+func (s *exportService) ExportEvents(ctx context.Context, input ExportInput) ([]EventOutput, error) {
+	meetings, err := s.selectMeetings(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]EventOutput, 0, len(meetings))
+	for i := range meetings {
+		event := meetingToEventOutput(&meetings[i])
+		event.OriginalStartTime = meetings[i].OriginalStartTime
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+func (s *exportService) FeedToken(ctx context.Context, userEmail string) (string, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil {
+		return "", fmt.Errorf("failed to load user: %w", err)
+	}
+	if user == nil || user.FeedToken == "" {
+		return "", ErrFeedNotFound
+	}
+	return user.FeedToken, nil
+}
+
+func (s *exportService) RotateFeedToken(ctx context.Context, userEmail string) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	if err := s.userRepo.SetFeedToken(ctx, userEmail, token); err != nil {
+		return "", fmt.Errorf("failed to store feed token: %w", err)
+	}
+	return token, nil
+}
+
+func (s *exportService) RevokeFeedToken(ctx context.Context, userEmail string) error {
+	if err := s.userRepo.SetFeedToken(ctx, userEmail, ""); err != nil {
+		return fmt.Errorf("failed to remove feed token: %w", err)
+	}
+	return nil
+}
+
+func (s *exportService) ExportFeed(ctx context.Context, w io.Writer, token string) error {
+	user, err := s.userRepo.GetUserByFeedToken(ctx, token)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	if user == nil {
+		return ErrFeedNotFound
+	}
+	return s.ExportICS(ctx, w, ExportInput{UserEmail: user.Email, IncludeGoogle: true})
+}
+
+// googleEvents lists the Google events of the calendar within the input's
+// bounds, leaving out the ones in skip. Recurring events come as one series
+// with its rules plus its changed occurrences.
+func (s *exportService) googleEvents(ctx context.Context, input ExportInput, skip map[string]bool) ([]ics.Event, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, input.UserEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+	if user.GoogleID == "" {
+		return nil, nil // Nothing to add for users without a Google account
+	}
+	calendarID := input.CalendarID
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+
+	var events []ics.Event
+	err = s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		events = events[:0] // The call is retried after a token refresh
+		return service.Events.List(calendarID).
+			TimeMin(input.TimeMin.Format(time.RFC3339)).
+			TimeMax(input.TimeMax.Format(time.RFC3339)).
+			MaxResults(syncPageSize).
+			Pages(ctx, func(page *calendar.Events) error {
+				for _, item := range page.Items {
+					if skip[item.Id] || item.Status == "cancelled" {
+						continue
+					}
+					events = append(events, googleEventToICS(item, user.Email))
+				}
+				return nil
+			})
+	})
+	return events, err
+}
+
+// selectMeetings loads the user's committed meetings that match input,
+// ordered by start. A series is kept whole when it starts before TimeMax,
+// since its occurrences can't be told apart without expanding the rules.
+func (s *exportService) selectMeetings(ctx context.Context, input ExportInput) ([]domain.Meeting, error) {
+	stored, err := s.meetingRepo.ListMeetingsByOwner(ctx, input.UserEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load events from database: %w", err)
+	}
+
+	inCalendar := func(m *domain.Meeting) bool {
+		return m.CommitState != "pending" && (input.CalendarID == "" || m.CalendarID == input.CalendarID)
+	}
+	series := make(map[string]bool) // Event IDs of the exported series masters
+	var meetings []domain.Meeting
+	for _, m := range stored {
+		if !inCalendar(&m) || len(m.Recurrence) == 0 || m.RecurringEventID != "" {
+			continue
+		}
+		if input.TimeMax.IsZero() || m.StartTime.Before(input.TimeMax) {
+			series[m.EventID] = true
+			meetings = append(meetings, m)
+		}
+	}
+	for _, m := range stored {
+		if !inCalendar(&m) || (len(m.Recurrence) > 0 && m.RecurringEventID == "") {
+			continue
+		}
+		if m.RecurringEventID != "" && series[m.RecurringEventID] {
+			meetings = append(meetings, m) // Exceptions go along with their series
+			continue
+		}
+		if m.Status == "cancelled" {
+			continue
+		}
+		if (input.TimeMax.IsZero() || m.StartTime.Before(input.TimeMax)) && (input.TimeMin.IsZero() || m.EndTime.After(input.TimeMin)) {
+			meetings = append(meetings, m)
+		}
+	}
+
+	sort.SliceStable(meetings, func(i, j int) bool { return meetings[i].StartTime.Before(meetings[j].StartTime) })
+	return meetings, nil
+}
+
+// meetingToICS converts a stored meeting into a VEVENT.
+func meetingToICS(meeting *domain.Meeting) ics.Event {
+	event := ics.Event{
+		UID:          icalUID(meeting),
+		Stamp:        meeting.UpdatedAt,
+		Summary:      meeting.Title,
+		Description:  meeting.Description,
+		Start:        meeting.StartTime,
+		End:          meeting.EndTime,
+		AllDay:       meeting.AllDay,
+		TimeZone:     meeting.TimeZone,
+		Recurrence:   meeting.Recurrence,
+		RecurrenceID: meeting.OriginalStartTime,
+		Status:       strings.ToUpper(meeting.Status),
+		Organizer:    meeting.CreatedBy,
+		URL:          meeting.ConferenceURL,
+	}
+	for _, attendee := range meeting.Attendees {
+		event.Attendees = append(event.Attendees, ics.Attendee{
+			Email:    attendee.Email,
+			Name:     attendee.Name,
+			Optional: attendee.Optional,
+			PartStat: partStat(attendee.ResponseStatus),
+		})
+	}
+	return event
+}
+
+// googleEventToICS converts a Google event that isn't stored into a VEVENT.
+func googleEventToICS(item *calendar.Event, userEmail string) ics.Event {
+	meeting := &domain.Meeting{CreatedBy: userEmail}
+	applyEventToMeeting(meeting, item)
+	if updated, err := time.Parse(time.RFC3339, item.Updated); err == nil {
+		meeting.UpdatedAt = updated
+	}
+	event := meetingToICS(meeting)
+	if item.ICalUID != "" {
+		event.UID = item.ICalUID
+	}
+	if item.Organizer != nil && item.Organizer.Email != "" {
+		event.Organizer = item.Organizer.Email
+	}
+	return event
+}
+
+// icalUID returns the UID Google gives the event in its own iCalendar feeds,
+// so clients see the same event whichever feed they read. Exceptions share
+// the UID of their series.
+func icalUID(meeting *domain.Meeting) string {
+	switch {
+	case meeting.ICalUID != "":
+		return meeting.ICalUID
+	case meeting.RecurringEventID != "":
+		return meeting.RecurringEventID + "@google.com"
+	case meeting.EventID != "":
+		return meeting.EventID + "@google.com"
+	default:
+		return fmt.Sprintf("meeting-%d@google-calendar-api", meeting.ID)
+	}
+}
+
+// partStat maps a Google response status to an iCalendar PARTSTAT.
+func partStat(responseStatus string) string {
+	switch responseStatus {
+	case "accepted":
+		return "ACCEPTED"
+	case "declined":
+		return "DECLINED"
+	case "tentative":
+		return "TENTATIVE"
+	default:
+		return "NEEDS-ACTION"
+	}
+}
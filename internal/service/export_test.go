@@ -0,0 +1,153 @@
+// internal/service/export_test.go
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// TestExportEventsSelection checks which stored meetings a bounded export
+// picks: a series is kept whole with its exceptions, and meetings still
+// pending in the outbox, cancelled or outside the window are left out.
+func TestExportEventsSelection(t *testing.T) {
+	db := newTestDB(t)
+	meetings := repository.NewMeetingRepository(db)
+	exporter := NewExportService(meetings, repository.NewUserRepository(db), nil)
+	ctx := context.Background()
+	day := time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC)
+	moved := day.AddDate(0, 0, -14).Add(9 * time.Hour)
+	for _, m := range []domain.Meeting{
+		{EventID: "early", Title: "Before", StartTime: day.AddDate(0, 0, -2), EndTime: day.AddDate(0, 0, -1)},
+		{EventID: "inside", Title: "Inside", StartTime: day.Add(9 * time.Hour), EndTime: day.Add(10 * time.Hour)},
+		{EventID: "allday", Title: "Offsite", StartTime: day.AddDate(0, 0, 1), EndTime: day.AddDate(0, 0, 2), AllDay: true},
+		{EventID: "late", Title: "After", StartTime: day.AddDate(0, 0, 9), EndTime: day.AddDate(0, 0, 10)},
+		{EventID: "queued", Title: "Queued", StartTime: day.Add(11 * time.Hour), EndTime: day.Add(12 * time.Hour), CommitState: "pending"},
+		{EventID: "gone", Title: "Cancelled", StartTime: day.Add(13 * time.Hour), EndTime: day.Add(14 * time.Hour), Status: "cancelled"},
+		{EventID: "series", Title: "Weekly", StartTime: moved.AddDate(0, 0, -7), EndTime: moved.AddDate(0, 0, -7).Add(time.Hour), Recurrence: []string{"RRULE:FREQ=WEEKLY"}},
+		{EventID: "series_1", Title: "Weekly, moved", StartTime: moved.Add(time.Hour), EndTime: moved.Add(2 * time.Hour), RecurringEventID: "series", OriginalStartTime: &moved},
+		{EventID: "other", Title: "Elsewhere", CalendarID: "team", StartTime: day.Add(15 * time.Hour), EndTime: day.Add(16 * time.Hour)},
+	} {
+		m.CreatedBy = "alice@example.com"
+		if m.CalendarID == "" {
+			m.CalendarID = "primary"
+		}
+		if err := meetings.CreateMeeting(ctx, &m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	titles := func(input ExportInput) []string {
+		t.Helper()
+		input.UserEmail = "alice@example.com"
+		events, err := exporter.ExportEvents(ctx, input)
+		if err != nil {
+			t.Fatalf("ExportEvents: %v", err)
+		}
+		var titles []string
+		for _, event := range events {
+			titles = append(titles, event.Title)
+		}
+		return titles
+	}
+
+	// Ordered by start; the series starts before the window but may recur in it.
+	got := titles(ExportInput{CalendarID: "primary", TimeMin: day, TimeMax: day.AddDate(0, 0, 7)})
+	if want := []string{"Weekly", "Weekly, moved", "Inside", "Offsite"}; !slices.Equal(got, want) {
+		t.Errorf("exported %q; want %q", got, want)
+	}
+	got = titles(ExportInput{})
+	if want := []string{"Weekly", "Weekly, moved", "Before", "Inside", "Elsewhere", "Offsite", "After"}; !slices.Equal(got, want) {
+		t.Errorf("exported without bounds %q; want %q", got, want)
+	}
+	if got := titles(ExportInput{TimeMax: moved.AddDate(0, 0, -8)}); len(got) != 0 {
+		t.Errorf("exported %q before the series starts; want nothing", got)
+	}
+}
+
+// TestFeedToken turns a user's feed on, reads it, moves it to a new URL and
+// turns it off again.
+func TestFeedToken(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	exporter := NewExportService(f.meetings, f.users, f.clients)
+	alice := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	addGoogleUser(t, f.fake, f.users, "bob@example.com")
+	f.insert(t, alice, &calendar.Event{
+		Summary: "Stored",
+		Start:   &calendar.EventDateTime{DateTime: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)},
+	})
+	// Made in Google directly, so only the feed's Google half knows it.
+	err := f.clients.WithCalendar(ctx, alice, func(service *calendar.Service) error {
+		_, err := service.Events.Insert("primary", &calendar.Event{
+			Summary: "Only in Google",
+			Start:   &calendar.EventDateTime{Date: time.Now().AddDate(0, 0, 2).Format("2006-01-02")},
+			End:     &calendar.EventDateTime{Date: time.Now().AddDate(0, 0, 3).Format("2006-01-02")},
+		}).Do()
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := exporter.FeedToken(ctx, alice.Email); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("FeedToken before the feed is on = %v; want ErrFeedNotFound", err)
+	}
+	if err := exporter.ExportFeed(ctx, io.Discard, ""); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("ExportFeed with no token = %v; want ErrFeedNotFound", err)
+	}
+
+	token, err := exporter.RotateFeedToken(ctx, alice.Email)
+	if err != nil || len(token) < 32 {
+		t.Fatalf("RotateFeedToken = %q, %v; want a long token", token, err)
+	}
+	if got, err := exporter.FeedToken(ctx, alice.Email); err != nil || got != token {
+		t.Errorf("FeedToken = %q, %v; want %q", got, err, token)
+	}
+	var feed strings.Builder
+	if err := exporter.ExportFeed(ctx, &feed, token); err != nil {
+		t.Fatalf("ExportFeed: %v", err)
+	}
+	for _, want := range []string{"X-WR-CALNAME:alice@example.com", "SUMMARY:Stored", "SUMMARY:Only in Google", "DTSTART;VALUE=DATE:"} {
+		if !strings.Contains(feed.String(), want) {
+			t.Errorf("feed lacks %q:\n%s", want, feed.String())
+		}
+	}
+	if n := strings.Count(feed.String(), "SUMMARY:Stored"); n != 1 {
+		t.Errorf("the stored event is in the feed %d times; want once", n)
+	}
+	if err := exporter.ExportFeed(ctx, io.Discard, token+"x"); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("ExportFeed with a wrong token = %v; want ErrFeedNotFound", err)
+	}
+
+	rotated, err := exporter.RotateFeedToken(ctx, alice.Email)
+	if err != nil || rotated == token {
+		t.Fatalf("RotateFeedToken again = %q, %v; want a new token", rotated, err)
+	}
+	if err := exporter.ExportFeed(ctx, io.Discard, token); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("ExportFeed with the old token = %v; want ErrFeedNotFound", err)
+	}
+	if _, err := exporter.FeedToken(ctx, "bob@example.com"); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("bob's FeedToken = %v; want ErrFeedNotFound", err)
+	}
+
+	if err := exporter.RevokeFeedToken(ctx, alice.Email); err != nil {
+		t.Fatalf("RevokeFeedToken: %v", err)
+	}
+	if err := exporter.ExportFeed(ctx, io.Discard, rotated); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("ExportFeed after revoking = %v; want ErrFeedNotFound", err)
+	}
+	if _, err := exporter.FeedToken(ctx, alice.Email); !errors.Is(err, ErrFeedNotFound) {
+		t.Errorf("FeedToken after revoking = %v; want ErrFeedNotFound", err)
+	}
+}
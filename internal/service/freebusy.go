@@ -0,0 +1,123 @@
+// internal/service/freebusy.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"strings"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// freeBusyBatchSize is the most calendars Google accepts in one Freebusy.Query.
+const freeBusyBatchSize = 50
+
+func (s *eventService) FreeBusy(ctx context.Context, input FreeBusyInput) (*FreeBusyOutput, error) {
+	if !input.TimeMin.Before(input.TimeMax) {
+		return nil, ErrInvalidTime
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, input.UserEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	// Drop duplicates but keep the order the caller asked in.
+	var ids []string
+	seen := make(map[string]bool)
+	for _, id := range input.Calendars {
+		id = strings.TrimSpace(id)
+		if id != "" && !seen[strings.ToLower(id)] {
+			seen[strings.ToLower(id)] = true
+			ids = append(ids, id)
+		}
+	}
+
+	provider, err := s.providers.For(user)
+	if err != nil {
+		return nil, err
+	}
+	input.Calendars = ids
+	output, err := provider.FreeBusy(ctx, user, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query free/busy: %w", err)
+	}
+	return output, nil
+}
+
+func (p *googleCalendarProvider) FreeBusy(ctx context.Context, user *domain.User, input FreeBusyInput) (*FreeBusyOutput, error) {
+	output := &FreeBusyOutput{
+		TimeMin:   input.TimeMin,
+		TimeMax:   input.TimeMax,
+		Calendars: make(map[string]CalendarAvailability, len(input.Calendars)),
+	}
+	err := p.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		for start := 0; start < len(input.Calendars); start += freeBusyBatchSize {
+			end := min(start+freeBusyBatchSize, len(input.Calendars))
+			if err := queryFreeBusy(ctx, service, input, input.Calendars[start:end], output); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// queryFreeBusy runs one Freebusy.Query and adds the answer for ids to output.
+func queryFreeBusy(ctx context.Context, service *calendar.Service, input FreeBusyInput, ids []string, output *FreeBusyOutput) error {
+	req := &calendar.FreeBusyRequest{
+		TimeMin:  input.TimeMin.Format(time.RFC3339),
+		TimeMax:  input.TimeMax.Format(time.RFC3339),
+		TimeZone: input.TimeZone,
+	}
+	for _, id := range ids {
+		req.Items = append(req.Items, &calendar.FreeBusyRequestItem{Id: id})
+	}
+
+	resp, err := service.Freebusy.Query(req).Context(ctx).Do()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		availability := CalendarAvailability{Busy: []TimeRange{}}
+
+		// Google echoes the IDs back, but not always with the same casing.
+		result, ok := resp.Calendars[id]
+		if !ok {
+			for key, value := range resp.Calendars {
+				if strings.EqualFold(key, id) {
+					result, ok = value, true
+					break
+				}
+			}
+		}
+		if !ok {
+			availability.Errors = append(availability.Errors, "notFound")
+			output.Calendars[id] = availability
+			continue
+		}
+
+		for _, e := range result.Errors {
+			availability.Errors = append(availability.Errors, e.Reason)
+		}
+		for _, period := range result.Busy {
+			start, err := time.Parse(time.RFC3339, period.Start)
+			if err != nil {
+				return fmt.Errorf("unreadable busy start %q for %s: %w", period.Start, id, err)
+			}
+			end, err := time.Parse(time.RFC3339, period.End)
+			if err != nil {
+				return fmt.Errorf("unreadable busy end %q for %s: %w", period.End, id, err)
+			}
+			availability.Busy = append(availability.Busy, TimeRange{Start: start, End: end})
+		}
+		output.Calendars[id] = availability
+	}
+	return nil
+}
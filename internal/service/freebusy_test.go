@@ -0,0 +1,73 @@
+// internal/service/freebusy_test.go
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestFreeBusy(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	alice := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	bob := addGoogleUser(t, f.fake, f.users, "bob@example.com")
+	f.insert(t, alice, &calendar.Event{
+		Summary: "Dentist",
+		Start:   &calendar.EventDateTime{DateTime: "2030-03-04T09:00:00+01:00", TimeZone: "Europe/Berlin"},
+		End:     &calendar.EventDateTime{DateTime: "2030-03-04T10:00:00+01:00", TimeZone: "Europe/Berlin"},
+	})
+	f.insert(t, alice, &calendar.Event{
+		Summary:      "Reminder to self",
+		Start:        &calendar.EventDateTime{DateTime: "2030-03-04T11:00:00+01:00"},
+		End:          &calendar.EventDateTime{DateTime: "2030-03-04T12:00:00+01:00"},
+		Transparency: "transparent", // Shown as free
+	})
+	f.insert(t, bob, &calendar.Event{
+		Summary: "Holiday",
+		Start:   &calendar.EventDateTime{Date: "2030-03-04"},
+		End:     &calendar.EventDateTime{Date: "2030-03-05"},
+	})
+
+	timeMin := time.Date(2030, 3, 4, 7, 0, 0, 0, time.UTC)
+	timeMax := time.Date(2030, 3, 4, 17, 0, 0, 0, time.UTC)
+	output, err := f.events.FreeBusy(ctx, FreeBusyInput{
+		UserEmail: alice.Email,
+		Calendars: []string{"alice@example.com", "Bob@example.com", " alice@example.com ", "nobody@example.com"},
+		TimeMin:   timeMin,
+		TimeMax:   timeMax,
+		TimeZone:  "Europe/Berlin",
+	})
+	if err != nil {
+		t.Fatalf("FreeBusy: %v", err)
+	}
+	if len(output.Calendars) != 3 {
+		t.Errorf("answers for %d calendars; want 3 without the duplicate", len(output.Calendars))
+	}
+
+	busy := output.Calendars["alice@example.com"].Busy
+	dentist := TimeRange{Start: time.Date(2030, 3, 4, 8, 0, 0, 0, time.UTC), End: time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC)}
+	if len(busy) != 1 || !busy[0].Start.Equal(dentist.Start) || !busy[0].End.Equal(dentist.End) {
+		t.Errorf("alice is busy %v; want only %v", busy, dentist)
+	} else if _, offset := busy[0].Start.Zone(); offset != 3600 {
+		t.Errorf("busy times are at offset %d; want Berlin's 3600", offset)
+	}
+
+	// The all-day event fills the whole window.
+	busy = output.Calendars["Bob@example.com"].Busy
+	if len(busy) != 1 || !busy[0].Start.Equal(timeMin) || !busy[0].End.Equal(timeMax) {
+		t.Errorf("bob is busy %v; want the whole window", busy)
+	}
+
+	if nobody := output.Calendars["nobody@example.com"]; len(nobody.Busy) != 0 || !slices.Equal(nobody.Errors, []string{"notFound"}) {
+		t.Errorf("unknown calendar = %+v; want a notFound error", nobody)
+	}
+
+	if _, err := f.events.FreeBusy(ctx, FreeBusyInput{UserEmail: alice.Email, TimeMin: timeMax, TimeMax: timeMin}); !errors.Is(err, ErrInvalidTime) {
+		t.Errorf("FreeBusy with an empty window = %v; want ErrInvalidTime", err)
+	}
+}
@@ -0,0 +1,400 @@
+// internal/service/google.go
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+type googleClientProvider struct {
+	oauthConfig *oauth2.Config
+	calendarURL string // Empty for the library's default
+	userRepo    repository.UserRepository
+}
+
+// NewGoogleClientProvider creates a GoogleClientProvider that saves refreshed
+// tokens through userRepo.
+func NewGoogleClientProvider(cfg *config.Config, userRepo repository.UserRepository) *googleClientProvider {
+	return &googleClientProvider{
+		oauthConfig: cfg.OAuthConfig,
+		calendarURL: cfg.GoogleCalendarURL,
+		userRepo:    userRepo,
+	}
+}
+
+// HTTPClient returns an HTTP client that authorizes requests as user. Tokens
+// are refreshed when they expire and the new token is stored on the user.
+func (p *googleClientProvider) HTTPClient(ctx context.Context, user *domain.User) *http.Client {
+	return p.httpClient(ctx, user, false)
+}
+
+func (p *googleClientProvider) httpClient(ctx context.Context, user *domain.User, forceRefresh bool) *http.Client {
+	token := &oauth2.Token{
+		AccessToken:  user.AccessToken,
+		RefreshToken: user.RefreshToken,
+		Expiry:       user.ExpiresAt,
+	}
+	if forceRefresh {
+		// An expired token makes the next request fetch a new one.
+		token.AccessToken = ""
+		token.Expiry = time.Now().Add(-time.Minute)
+	}
+
+	source := &persistingTokenSource{
+		ctx:      context.WithoutCancel(ctx), // Saving must not depend on the request finishing first
+		base:     p.oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: user.RefreshToken}),
+		user:     user,
+		userRepo: p.userRepo,
+	}
+	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(token, source))
+}
+
+func (p *googleClientProvider) WithCalendar(ctx context.Context, user *domain.User, call func(*calendar.Service) error) error {
+	if user.GoogleID == "" {
+		return fmt.Errorf("%w: %s has no Google account", ErrProviderNotLinked, user.Email)
+	}
+	service, err := p.calendarService(ctx, user, false)
+	if err != nil {
+		return fmt.Errorf("failed to create calendar service: %w", err)
+	}
+
+	err = call(service)
+	if !isUnauthorizedError(err) {
+		return classifyGoogleError(err)
+	}
+
+	// The token looked valid but Google rejected it, e.g. after the user
+	// changed their password. Get a new one and try once more.
+	log.Printf("🔄 Google rejected the access token of %s, refreshing...", user.Email)
+	service, err = p.calendarService(ctx, user, true)
+	if err != nil {
+		return fmt.Errorf("failed to create calendar service after refresh: %w", err)
+	}
+	err = call(service)
+	if isUnauthorizedError(err) {
+		return fmt.Errorf("%w: %v", ErrReauthRequired, err)
+	}
+	return classifyGoogleError(err)
+}
+
+func (p *googleClientProvider) calendarService(ctx context.Context, user *domain.User, forceRefresh bool) (*calendar.Service, error) {
+	opts := []option.ClientOption{option.WithHTTPClient(p.httpClient(ctx, user, forceRefresh))}
+	if p.calendarURL != "" {
+		opts = append(opts, option.WithEndpoint(p.calendarURL))
+	}
+	return calendar.NewService(ctx, opts...)
+}
+
+// googleCalendarProvider is the CalendarProvider for Google Calendar.
+type googleCalendarProvider struct {
+	clients GoogleClientProvider
+}
+
+// NewGoogleCalendarProvider creates the CalendarProvider for Google Calendar.
+func NewGoogleCalendarProvider(clients GoogleClientProvider) *googleCalendarProvider {
+	return &googleCalendarProvider{clients: clients}
+}
+
+func (p *googleCalendarProvider) Name() string {
+	return ProviderGoogle
+}
+
+func (p *googleCalendarProvider) ListCalendars(ctx context.Context, user *domain.User) ([]CalendarOutput, error) {
+	calendars := []CalendarOutput{}
+	err := p.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		calendars = calendars[:0]
+		return service.CalendarList.List().Pages(ctx, func(page *calendar.CalendarList) error {
+			for _, item := range page.Items {
+				calendars = append(calendars, CalendarOutput{
+					ID:          item.Id,
+					Summary:     item.Summary,
+					Description: item.Description,
+					TimeZone:    item.TimeZone,
+					AccessRole:  item.AccessRole,
+					Primary:     item.Primary,
+					Color:       item.BackgroundColor,
+				})
+			}
+			return nil
+		})
+	})
+	return calendars, err
+}
+
+// CreateEvent inserts the event right away. The event service goes through
+// the outbox instead, which builds the same event.
+func (p *googleCalendarProvider) CreateEvent(ctx context.Context, user *domain.User, input CreateEventInput) (*ProviderEvent, error) {
+	calendarID := input.CalendarID
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+	event := newGoogleEvent(input)
+	event.Id = newGoogleEventID()
+
+	var created *calendar.Event
+	err := p.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		var err error
+		created, err = service.Events.Insert(calendarID, event).ConferenceDataVersion(1).Context(ctx).Do()
+		if isConflictError(err) {
+			// The first try got through before the token was refreshed.
+			created, err = service.Events.Get(calendarID, event.Id).Context(ctx).Do()
+		}
+		return err
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, fmt.Errorf("%w: %s", ErrCalendarNotFound, calendarID)
+		}
+		return nil, err
+	}
+	output := toProviderEvent(created, user.Email)
+	return &output, nil
+}
+
+func (p *googleCalendarProvider) GetEvent(ctx context.Context, user *domain.User, calendarID, eventID string) (*ProviderEvent, error) {
+	var item *calendar.Event
+	err := p.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		var err error
+		item, err = getGoogleEvent(ctx, service, calendarID, eventID)
+		return err
+	})
+	if err != nil || item == nil {
+		return nil, err
+	}
+	output := toProviderEvent(item, user.Email)
+	return &output, nil
+}
+
+func (p *googleCalendarProvider) ListEvents(ctx context.Context, user *domain.User, query ProviderQuery) ([]EventOutput, string, error) {
+	var events []EventOutput
+	var nextPageToken string
+	err := p.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		call := service.Events.List(query.CalendarID).
+			ShowDeleted(false).
+			SingleEvents(true).
+			TimeMin(query.TimeMin.Format(time.RFC3339)).
+			TimeMax(query.TimeMax.Format(time.RFC3339)).
+			OrderBy("startTime").
+			MaxResults(int64(query.MaxResults))
+		if query.Query != "" {
+			call = call.Q(query.Query)
+		}
+		if query.PageToken != "" {
+			call = call.PageToken(query.PageToken)
+		}
+
+		page, err := call.Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		events = events[:0]
+		for _, item := range page.Items {
+			events = append(events, toEventOutput(item, user.Email)) // The user who is listing the events
+		}
+		nextPageToken = page.NextPageToken
+		return nil
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, "", fmt.Errorf("%w: %s", ErrCalendarNotFound, query.CalendarID)
+		}
+		return nil, "", err
+	}
+	return events, nextPageToken, nil
+}
+
+// UpdateEvent replaces the fields the API manages and keeps everything else
+// Google knows about the event.
+func (p *googleCalendarProvider) UpdateEvent(ctx context.Context, user *domain.User, calendarID string, event EventOutput) (*ProviderEvent, error) {
+	attendees := make([]string, 0, len(event.Attendees))
+	for _, a := range event.Attendees {
+		attendees = append(attendees, a.Email)
+	}
+	change := eventChange{
+		title:       &event.Title,
+		description: &event.Description,
+		start:       &event.StartTime,
+		end:         &event.EndTime,
+		allDay:      &event.AllDay,
+		attendees:   &attendees,
+		replace:     true,
+	}
+	if event.Recurrence != nil {
+		change.recurrence = &event.Recurrence
+	}
+
+	var updated *calendar.Event
+	err := p.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		existing, err := getGoogleEvent(ctx, service, calendarID, event.EventId)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			return ErrEventNotFound
+		}
+		updated, err = writeEvent(service, calendarID, existing, change)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	output := toProviderEvent(updated, user.Email)
+	return &output, nil
+}
+
+func (p *googleCalendarProvider) DeleteEvent(ctx context.Context, user *domain.User, calendarID, eventID string) error {
+	err := p.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		return service.Events.Delete(calendarID, eventID).Context(ctx).Do()
+	})
+	if isNotFoundError(err) {
+		return nil
+	}
+	return err
+}
+
+// getGoogleEvent fetches an event. It returns nil, nil when Google no longer
+// has it, which for deleted events may also show as a cancelled event.
+func getGoogleEvent(ctx context.Context, service *calendar.Service, calendarID, eventID string) (*calendar.Event, error) {
+	event, err := service.Events.Get(calendarID, eventID).Context(ctx).Do()
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if event.Status == "cancelled" {
+		return nil, nil
+	}
+	return event, nil
+}
+
+// newGoogleEvent builds the Google Calendar event for input.
+func newGoogleEvent(input CreateEventInput) *calendar.Event {
+	var attendees []*calendar.EventAttendee
+	for _, email := range input.Attendees {
+		attendees = append(attendees, &calendar.EventAttendee{Email: email})
+	}
+	event := &calendar.Event{
+		Summary:     input.Title,
+		Description: input.Description,
+		Start:       eventDateTime(input.StartTime, input.AllDay), // Uses the start time's location
+		End:         eventDateTime(input.EndTime, input.AllDay),
+		Attendees:   attendees,
+		Recurrence:  input.Recurrence,
+		ICalUID:     input.ICalUID,
+	}
+	if input.AddConference {
+		// Google creates the Meet link; the request ID is part of the stored
+		// payload, so retries of the operation reuse it.
+		event.ConferenceData = &calendar.ConferenceData{
+			CreateRequest: &calendar.CreateConferenceRequest{
+				RequestId:             uuid.New().String(),
+				ConferenceSolutionKey: &calendar.ConferenceSolutionKey{Type: "hangoutsMeet"},
+			},
+		}
+	}
+	return event
+}
+
+// newGoogleEventID picks an event ID, so creating an event can be retried
+// without creating a second one.
+func newGoogleEventID() string {
+	return strings.ReplaceAll(uuid.New().String(), "-", "") // Google wants base32hex; hex is a subset
+}
+
+// toProviderEvent converts a Google Calendar event into a ProviderEvent.
+func toProviderEvent(item *calendar.Event, userEmail string) ProviderEvent {
+	event := ProviderEvent{
+		EventOutput: toEventOutput(item, userEmail),
+		ICalUID:     item.ICalUID,
+		Status:      item.Status,
+	}
+	if item.Start != nil {
+		event.TimeZone = item.Start.TimeZone
+	}
+	return event
+}
+
+// persistingTokenSource stores every token it hands out that differs from the
+// one saved on the user, so refreshed tokens survive the request.
+type persistingTokenSource struct {
+	ctx      context.Context
+	base     oauth2.TokenSource
+	user     *domain.User
+	userRepo repository.UserRepository
+
+	mu sync.Mutex
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == s.user.AccessToken {
+		return token, nil
+	}
+
+	s.user.AccessToken = token.AccessToken
+	s.user.ExpiresAt = token.Expiry
+	if token.RefreshToken != "" {
+		s.user.RefreshToken = token.RefreshToken // Google only sometimes rotates it
+	}
+	// Only the token columns: s.user was loaded with the request and may be
+	// older than the row by now.
+	if err := s.userRepo.SetTokens(s.ctx, s.user.Email, token.AccessToken, token.RefreshToken, token.Expiry); err != nil {
+		// The token still works for this request, so only log it.
+		log.Printf("⚠️ Failed to save refreshed token for %s: %v", s.user.Email, err)
+	}
+	return token, nil
+}
+
+// isUnauthorizedError reports whether Google answered 401 Unauthorized.
+func isUnauthorizedError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusUnauthorized
+}
+
+// classifyGoogleError turns a refresh token that Google no longer accepts
+// into ErrReauthRequired. Other errors are returned as they are.
+func classifyGoogleError(err error) error {
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) && retrieveErr.ErrorCode == "invalid_grant" {
+		return fmt.Errorf("%w: %v", ErrReauthRequired, err)
+	}
+	return err
+}
+
+// isNotFoundError reports whether Google answered 404 Not Found or 410 Gone.
+func isNotFoundError(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone
+	}
+	return false
+}
+
+// isGoneError reports whether Google answered 410 Gone, which for a list call
+// means the sync token expired.
+func isGoneError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusGone
+}
@@ -0,0 +1,45 @@
+// internal/service/google_test.go
+package service
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// TestRefreshKeepsOtherColumns refreshes the token of a user loaded before
+// other columns changed, and checks that saving the new token leaves them be.
+func TestRefreshKeepsOtherColumns(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	stale := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+
+	// Meanwhile, another request turns the feed on and moves alice to CalDAV.
+	if err := f.users.SetFeedToken(ctx, stale.Email, "feed-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.users.SetCalendarProvider(ctx, stale.Email, ProviderCalDAV); err != nil {
+		t.Fatal(err)
+	}
+
+	f.fake.ExpireAccessTokens(stale.Email)
+	err := f.clients.WithCalendar(ctx, stale, func(service *calendar.Service) error {
+		_, err := service.Events.List("primary").Do()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithCalendar with an expired access token: %v", err)
+	}
+
+	got, err := f.users.GetUserByEmail(ctx, stale.Email)
+	if err != nil || got == nil {
+		t.Fatalf("GetUserByEmail = %v, %v", got, err)
+	}
+	if got.AccessToken == "" || got.AccessToken != stale.AccessToken {
+		t.Errorf("stored access token %q; want the refreshed %q", got.AccessToken, stale.AccessToken)
+	}
+	if got.FeedToken != "feed-secret" || got.CalendarProvider != ProviderCalDAV {
+		t.Errorf("after the refresh feed token = %q, provider = %q; want both kept", got.FeedToken, got.CalendarProvider)
+	}
+}
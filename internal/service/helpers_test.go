@@ -0,0 +1,192 @@
+// internal/service/helpers_test.go
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/fakegoogle"
+	"google-calendar-api/internal/repository"
+
+	"google.golang.org/api/calendar/v3"
+	"gorm.io/gorm"
+)
+
+// newTestDB returns a migrated SQLite database that lives as long as the test.
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := repository.OpenDB(repository.DriverSQLite, filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sql.DB: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := repository.MigrateUp(context.Background(), sqlDB, repository.DriverSQLite); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	return db
+}
+
+// newFakeGoogle starts a fake Google and returns it with a config pointing
+// at it.
+func newFakeGoogle(t *testing.T) (*fakegoogle.Server, *config.Config) {
+	t.Helper()
+	fake := fakegoogle.NewServer()
+	t.Cleanup(fake.Close)
+	cfg := &config.Config{GoogleRedirectURL: "http://localhost:8080/auth/google/callback"}
+	fake.Configure(cfg)
+	return fake, cfg
+}
+
+// addGoogleUser creates an account on fake and stores the user as a login
+// would have.
+func addGoogleUser(t *testing.T, fake *fakegoogle.Server, users repository.UserRepository, email string) *domain.User {
+	t.Helper()
+	account := fake.AddUser(fakegoogle.User{Email: email})
+	token, err := fake.Token(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &domain.User{
+		GoogleID:     account.Subject,
+		Email:        email,
+		Name:         account.Name,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		ExpiresAt:    token.Expiry,
+	}
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+// googleFixture is an event service on a fresh database, with Google played
+// by a fake.
+type googleFixture struct {
+	fake     *fakegoogle.Server
+	clients  GoogleClientProvider
+	users    repository.UserRepository
+	meetings repository.MeetingRepository
+	events   *eventService
+}
+
+func newGoogleFixture(t *testing.T) *googleFixture {
+	t.Helper()
+	fake, cfg := newFakeGoogle(t)
+	db := newTestDB(t)
+	f := &googleFixture{
+		fake:     fake,
+		users:    repository.NewUserRepository(db),
+		meetings: repository.NewMeetingRepository(db),
+	}
+	f.clients = NewGoogleClientProvider(cfg, f.users)
+	providers := CalendarProviders{ProviderGoogle: NewGoogleCalendarProvider(f.clients)}
+	webhooks := NewWebhookService(&config.Config{}, repository.NewWebhookRepository(db))
+	f.events = NewEventService(f.meetings, f.users, f.clients, providers, webhooks, repository.NewOutboxRepository(db))
+	return f
+}
+
+// insert creates event on the user's primary Google calendar directly, and
+// stores it as if it had been created through the API.
+func (f *googleFixture) insert(t *testing.T, user *domain.User, event *calendar.Event) *calendar.Event {
+	t.Helper()
+	var created *calendar.Event
+	err := f.clients.WithCalendar(context.Background(), user, func(service *calendar.Service) error {
+		var err error
+		created, err = service.Events.Insert("primary", event).Do()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create Google event: %v", err)
+	}
+	meeting := &domain.Meeting{CreatedBy: user.Email, CalendarID: "primary"}
+	applyEventToMeeting(meeting, created)
+	if err := f.meetings.CreateMeeting(context.Background(), meeting); err != nil {
+		t.Fatalf("failed to store meeting: %v", err)
+	}
+	return created
+}
+
+// instances returns the occurrences of a series on the user's primary
+// Google calendar.
+func (f *googleFixture) instances(t *testing.T, user *domain.User, seriesID string) []*calendar.Event {
+	t.Helper()
+	var items []*calendar.Event
+	err := f.clients.WithCalendar(context.Background(), user, func(service *calendar.Service) error {
+		instances, err := service.Events.Instances("primary", seriesID).Do()
+		if err == nil {
+			items = instances.Items
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to list occurrences: %v", err)
+	}
+	return items
+}
+
+// accountStore is a CalendarAccountRepository in memory, for tests of the
+// providers that need no other table.
+type accountStore struct {
+	mu       sync.Mutex
+	accounts []domain.CalendarAccount
+}
+
+func (s *accountStore) GetAccount(ctx context.Context, userEmail, provider string) (*domain.CalendarAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, account := range s.accounts {
+		if account.UserEmail == userEmail && account.Provider == provider {
+			return &account, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *accountStore) GetAccountBySubject(ctx context.Context, provider, subject string) (*domain.CalendarAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, account := range s.accounts {
+		if subject != "" && account.Provider == provider && account.Subject == subject {
+			return &account, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *accountStore) ListAccounts(ctx context.Context, userEmail string) ([]domain.CalendarAccount, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var accounts []domain.CalendarAccount
+	for _, account := range s.accounts {
+		if account.UserEmail == userEmail {
+			accounts = append(accounts, account)
+		}
+	}
+	slices.SortFunc(accounts, func(a, b domain.CalendarAccount) int { return strings.Compare(a.Provider, b.Provider) })
+	return accounts, nil
+}
+
+func (s *accountStore) SaveAccount(ctx context.Context, account *domain.CalendarAccount) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.accounts {
+		if s.accounts[i].ID == account.ID {
+			s.accounts[i] = *account
+			return nil
+		}
+	}
+	account.ID = uint(len(s.accounts) + 1)
+	s.accounts = append(s.accounts, *account)
+	return nil
+}
@@ -0,0 +1,220 @@
+// internal/service/import.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/ics"
+	"google-calendar-api/internal/repository"
+	"io"
+	"log"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// importMaxEvents caps the events of one import, since each one is created
+// while the request waits.
+const importMaxEvents = 500
+
+type importService struct {
+	events      EventService
+	meetingRepo repository.MeetingRepository
+	userRepo    repository.UserRepository
+	clients     GoogleClientProvider
+}
+
+// NewImportService creates a new ImportService instance.
+func NewImportService(events EventService, meetingRepo repository.MeetingRepository, userRepo repository.UserRepository, clients GoogleClientProvider) *importService {
+	return &importService{
+		events:      events,
+		meetingRepo: meetingRepo,
+		userRepo:    userRepo,
+		clients:     clients,
+	}
+}
+
+func (s *importService) ImportICS(ctx context.Context, r io.Reader, input ImportInput) (*ImportReport, error) {
+	if input.CalendarID == "" {
+		input.CalendarID = "primary"
+	}
+	user, err := s.userRepo.GetUserByEmail(ctx, input.UserEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	cal, invalid, err := ics.Parse(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCalendar, err)
+	}
+	if len(cal.Events)+len(invalid) > importMaxEvents {
+		return nil, fmt.Errorf("%w: more than %d events", ErrInvalidCalendar, importMaxEvents)
+	}
+
+	report := &ImportReport{DryRun: input.DryRun, Total: len(cal.Events) + len(invalid)}
+	add := func(result ImportResult) {
+		switch result.Status {
+		case "created", "pending", "would_create":
+			report.Created++
+		case "duplicate":
+			report.Duplicates++
+		case "skipped":
+			report.Skipped++
+		case "failed":
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+	for _, event := range invalid {
+		add(ImportResult{UID: event.UID, Title: event.Summary, Status: "failed", Reason: event.Err.Error()})
+	}
+
+	// Changed occurrences can't be created on their own. Cancelled ones
+	// become exception dates of their series; the rest are reported.
+	series := make(map[string]*ics.Event)
+	for i := range cal.Events {
+		if cal.Events[i].RecurrenceID == nil {
+			if _, ok := series[cal.Events[i].UID]; !ok {
+				series[cal.Events[i].UID] = &cal.Events[i]
+			}
+		}
+	}
+	for _, event := range cal.Events {
+		if event.RecurrenceID == nil {
+			continue
+		}
+		master := series[event.UID]
+		if event.Status == "CANCELLED" && master != nil {
+			master.Recurrence = append(master.Recurrence, exceptionDate(*event.RecurrenceID, master.AllDay))
+			report.Total-- // Counted as part of its series
+			continue
+		}
+		add(ImportResult{UID: event.UID, Title: event.Summary, Start: event.Start, Status: "skipped", Reason: "changed occurrences of recurring events are not imported"})
+	}
+
+	seen := make(map[string]bool)
+	for _, event := range cal.Events {
+		if event.RecurrenceID != nil {
+			continue
+		}
+		result := ImportResult{UID: event.UID, Title: event.Summary, Start: event.Start}
+		if seen[event.UID] {
+			result.Status, result.Reason = "duplicate", "UID appears more than once in the file"
+			add(result)
+			continue
+		}
+		seen[event.UID] = true
+		s.importEvent(ctx, user, event, input, &result)
+		add(result)
+	}
+	return report, nil
+}
+
+// importEvent checks one event and, unless it is a dry run or a duplicate,
+// creates it the way CreateEvent does for the API.
+func (s *importService) importEvent(ctx context.Context, user *domain.User, event ics.Event, input ImportInput, result *ImportResult) {
+	if event.Status == "CANCELLED" {
+		result.Status, result.Reason = "skipped", "event is cancelled"
+		return
+	}
+	if event.End.Before(event.Start) {
+		result.Status, result.Reason = "failed", ErrInvalidTime.Error()
+		return
+	}
+	if err := validateRecurrence(event.Recurrence); err != nil {
+		result.Status, result.Reason = "failed", err.Error()
+		return
+	}
+	if len(event.Recurrence) > 0 && event.TimeZone == "" && !event.AllDay {
+		result.Warning = "time zone not recognised; occurrences repeat at the same UTC time"
+	}
+
+	duplicate, err := s.findDuplicate(ctx, user, input.CalendarID, event.UID)
+	if err != nil {
+		result.Status, result.Reason = "failed", err.Error()
+		return
+	}
+	if duplicate != "" {
+		result.Status, result.EventID, result.Reason = "duplicate", duplicate, "an event with this UID exists already"
+		return
+	}
+	if input.DryRun {
+		result.Status = "would_create"
+		return
+	}
+
+	attendees := make([]string, 0, len(event.Attendees))
+	for _, attendee := range event.Attendees {
+		if attendee.Email != "" && attendee.Email != user.Email { // The importer becomes the organizer
+			attendees = append(attendees, attendee.Email)
+		}
+	}
+	op, err := s.events.CreateEvent(ctx, CreateEventInput{
+		Title:       event.Summary,
+		Description: event.Description,
+		StartTime:   event.Start, // In the event's zone when it has a known one
+		EndTime:     event.End,
+		AllDay:      event.AllDay,
+		Attendees:   attendees,
+		Recurrence:  event.Recurrence,
+		CalendarID:  input.CalendarID,
+		ICalUID:     event.UID,
+		CreatedBy:   user.Email,
+	})
+	if op != nil {
+		result.OperationID, result.EventID = op.OperationID, op.EventID
+	}
+	switch {
+	case err != nil:
+		log.Printf("❌ Failed to import event %s: %v", event.UID, err)
+		result.Status, result.Reason = "failed", err.Error()
+	case op.Status == "pending":
+		result.Status = "pending" // The outbox worker finishes it
+	default:
+		result.Status = "created"
+	}
+}
+
+// findDuplicate returns the ID of an event with the UID, stored or, for
+// Google users, in the Google calendar, or "" when there is none.
+func (s *importService) findDuplicate(ctx context.Context, user *domain.User, calendarID, uid string) (string, error) {
+	meeting, err := s.meetingRepo.GetMeetingByICalUID(ctx, user.Email, uid)
+	if err != nil {
+		return "", fmt.Errorf("failed to load event from database: %w", err)
+	}
+	if meeting != nil {
+		return meeting.EventID, nil
+	}
+	if user.CalendarProvider != "" && user.CalendarProvider != ProviderGoogle {
+		return "", nil // Only stored events can be checked
+	}
+
+	var eventID string
+	err = s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		events, err := service.Events.List(calendarID).ICalUID(uid).MaxResults(1).Context(ctx).Do()
+		if err != nil {
+			return err
+		}
+		if len(events.Items) > 0 {
+			eventID = events.Items[0].Id
+		}
+		return nil
+	})
+	if err != nil {
+		if isNotFoundError(err) {
+			return "", fmt.Errorf("%w: %s", ErrCalendarNotFound, calendarID)
+		}
+		return "", fmt.Errorf("failed to look up UID in Google Calendar: %w", err)
+	}
+	return eventID, nil
+}
+
+// exceptionDate returns the EXDATE line that cancels the occurrence starting
+// at t.
+func exceptionDate(t time.Time, allDay bool) string {
+	if allDay {
+		return "EXDATE;VALUE=DATE:" + t.UTC().Format("20060102")
+	}
+	return "EXDATE:" + t.UTC().Format("20060102T150405Z")
+}
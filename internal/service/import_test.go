@@ -0,0 +1,208 @@
+// internal/service/import_test.go
+package service
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const teamCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review-1\r\n" +
+	"SUMMARY:Design review\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20300304T090000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20300304T100000\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4\r\n" +
+	"ATTENDEE;CN=Alice:mailto:alice@example.com\r\n" +
+	"ATTENDEE;CN=Bob:mailto:bob@example.com\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review-1\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20300311T090000\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20300311T090000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20300311T100000\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:review-1\r\n" +
+	"RECURRENCE-ID;TZID=Europe/Berlin:20300318T090000\r\n" +
+	"DTSTART;TZID=Europe/Berlin:20300318T140000\r\n" +
+	"DTEND;TZID=Europe/Berlin:20300318T150000\r\n" +
+	"SUMMARY:Design review (moved)\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday-1\r\n" +
+	"SUMMARY:Holiday\r\n" +
+	"DTSTART;VALUE=DATE:20300501\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:holiday-1\r\n" +
+	"SUMMARY:Holiday again\r\n" +
+	"DTSTART;VALUE=DATE:20300501\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:cancelled-1\r\n" +
+	"SUMMARY:Called off\r\n" +
+	"DTSTART:20300305T120000Z\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:backwards-1\r\n" +
+	"SUMMARY:Backwards\r\n" +
+	"DTSTART:20300305T120000Z\r\n" +
+	"DTEND:20300305T110000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:broken-1\r\n" +
+	"SUMMARY:No start\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+// statuses returns the status of every result by UID and title.
+func statuses(report *ImportReport) map[string]string {
+	got := make(map[string]string)
+	for _, result := range report.Results {
+		got[result.UID+" "+result.Title] = result.Status
+	}
+	return got
+}
+
+func TestImportICS(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	alice := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	importer := NewImportService(f.events, f.meetings, f.users, f.clients)
+
+	// A dry run reports what would happen and writes nothing.
+	report, err := importer.ImportICS(ctx, strings.NewReader(teamCalendar), ImportInput{UserEmail: alice.Email, DryRun: true})
+	if err != nil {
+		t.Fatalf("ImportICS dry run: %v", err)
+	}
+	want := map[string]string{
+		"review-1 Design review":         "would_create",
+		"review-1 Design review (moved)": "skipped",
+		"holiday-1 Holiday":              "would_create",
+		"holiday-1 Holiday again":        "duplicate",
+		"cancelled-1 Called off":         "skipped",
+		"backwards-1 Backwards":          "failed",
+		"broken-1 No start":              "failed",
+	}
+	if got := statuses(report); !maps.Equal(got, want) {
+		t.Errorf("dry run results = %v; want %v", got, want)
+	}
+	if !report.DryRun || report.Total != 7 || report.Created != 2 || report.Duplicates != 1 || report.Skipped != 2 || report.Failed != 2 {
+		t.Errorf("dry run report = %+v", report)
+	}
+	if events := f.fake.Events(alice.Email); len(events) != 0 {
+		t.Fatalf("dry run created %d Google events", len(events))
+	}
+
+	report, err = importer.ImportICS(ctx, strings.NewReader(teamCalendar), ImportInput{UserEmail: alice.Email})
+	if err != nil {
+		t.Fatalf("ImportICS: %v", err)
+	}
+	if report.Created != 2 || report.Failed != 2 {
+		t.Errorf("import report = %+v; want 2 created and 2 failed", report)
+	}
+	for _, result := range report.Results {
+		if (result.Status == "created" || result.Status == "pending") && result.EventID == "" {
+			t.Errorf("result %+v has no event ID", result)
+		}
+	}
+
+	byTitle := make(map[string]int)
+	for _, event := range f.fake.Events(alice.Email) {
+		byTitle[event.Summary]++
+		switch event.Summary {
+		case "Design review":
+			if event.Start.TimeZone != "Europe/Berlin" || !strings.HasPrefix(event.Start.DateTime, "2030-03-04T09:00:00") {
+				t.Errorf("review starts %+v; want 09:00 Berlin time", event.Start)
+			}
+			if want := []string{"RRULE:FREQ=WEEKLY;COUNT=4", "EXDATE:20300311T080000Z"}; !slices.Equal(event.Recurrence, want) {
+				t.Errorf("review recurrence = %q; want the cancelled occurrence excluded: %q", event.Recurrence, want)
+			}
+			if event.ICalUID != "review-1" {
+				t.Errorf("review iCalUID = %q; want review-1", event.ICalUID)
+			}
+			var attendees []string
+			for _, attendee := range event.Attendees {
+				attendees = append(attendees, attendee.Email)
+			}
+			if slices.Contains(attendees, alice.Email) || !slices.Contains(attendees, "bob@example.com") {
+				t.Errorf("review attendees = %q; want bob without the importer", attendees)
+			}
+		case "Holiday":
+			if event.Start.Date != "2030-05-01" || event.End.Date != "2030-05-02" {
+				t.Errorf("holiday = %+v–%+v; want the all-day 1 May", event.Start, event.End)
+			}
+		}
+	}
+	if byTitle["Design review"] != 1 || byTitle["Holiday"] != 1 || len(byTitle) != 2 {
+		t.Errorf("Google has %v; want one review and one holiday", byTitle)
+	}
+
+	// Importing the file again finds every event by its UID.
+	report, err = importer.ImportICS(ctx, strings.NewReader(teamCalendar), ImportInput{UserEmail: alice.Email})
+	if err != nil {
+		t.Fatalf("ImportICS again: %v", err)
+	}
+	if report.Created != 0 || report.Duplicates != 3 {
+		t.Errorf("second import report = %+v; want 3 duplicates and nothing created", report)
+	}
+	if events := f.fake.Events(alice.Email); len(events) != 2 {
+		t.Errorf("Google has %d events after the second import; want 2", len(events))
+	}
+
+	if _, err := importer.ImportICS(ctx, strings.NewReader("not a calendar"), ImportInput{UserEmail: alice.Email}); err == nil {
+		t.Error("ImportICS of garbage succeeded; want ErrInvalidCalendar")
+	}
+}
+
+// TestExportImportRoundTrip exports one user's meetings and imports them for
+// another, which should give the same events.
+func TestExportImportRoundTrip(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	alice := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	bob := addGoogleUser(t, f.fake, f.users, "bob@example.com")
+	importer := NewImportService(f.events, f.meetings, f.users, f.clients)
+	exporter := NewExportService(f.meetings, f.users, f.clients)
+
+	_, err := importer.ImportICS(ctx, strings.NewReader(teamCalendar), ImportInput{UserEmail: alice.Email})
+	if err != nil {
+		t.Fatalf("ImportICS: %v", err)
+	}
+	var exported strings.Builder
+	if err := exporter.ExportICS(ctx, &exported, ExportInput{UserEmail: alice.Email}); err != nil {
+		t.Fatalf("ExportICS: %v", err)
+	}
+	report, err := importer.ImportICS(ctx, strings.NewReader(exported.String()), ImportInput{UserEmail: bob.Email})
+	if err != nil {
+		t.Fatalf("ImportICS of the export: %v", err)
+	}
+	if report.Created != 2 || report.Failed != 0 {
+		t.Errorf("import of the export = %+v; want both events created", report)
+	}
+
+	summary := func(email string) []string {
+		var events []string
+		for _, event := range f.fake.Events(email) {
+			start := event.Start.Date
+			if start == "" {
+				at, _ := time.Parse(time.RFC3339, event.Start.DateTime)
+				start = at.UTC().Format(time.RFC3339) + " " + event.Start.TimeZone
+			}
+			events = append(events, event.Summary+" "+start+" "+strings.Join(event.Recurrence, " "))
+		}
+		slices.Sort(events)
+		return events
+	}
+	if got, want := summary(bob.Email), summary(alice.Email); !slices.Equal(got, want) {
+		t.Errorf("bob's events = %q; want alice's %q", got, want)
+	}
+}
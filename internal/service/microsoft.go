@@ -0,0 +1,949 @@
+// internal/service/microsoft.go
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/ics"
+	"google-calendar-api/internal/repository"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+)
+
+// graphScheduleBatchSize is the most schedules sent in one getSchedule call.
+const graphScheduleBatchSize = 20
+
+// graphTimeLayout is how Graph writes a dateTimeTimeZone; the zone comes
+// separately.
+const graphTimeLayout = "2006-01-02T15:04:05"
+
+// microsoftProvider is the CalendarProvider for Microsoft 365 and Outlook
+// calendars, through Microsoft Graph. Tokens live on the user's
+// CalendarAccount.
+type microsoftProvider struct {
+	oauthConfig *oauth2.Config
+	graphURL    string
+	accountRepo repository.CalendarAccountRepository
+}
+
+// NewMicrosoftProvider creates the CalendarProvider for Microsoft Graph. It
+// is unused unless cfg has a Microsoft OAuth client.
+func NewMicrosoftProvider(cfg *config.Config, accountRepo repository.CalendarAccountRepository) *microsoftProvider {
+	return &microsoftProvider{
+		oauthConfig: cfg.MicrosoftOAuthConfig,
+		graphURL:    cfg.MicrosoftGraphURL,
+		accountRepo: accountRepo,
+	}
+}
+
+func (p *microsoftProvider) Name() string {
+	return ProviderMicrosoft
+}
+
+func (p *microsoftProvider) ListCalendars(ctx context.Context, user *domain.User) ([]CalendarOutput, error) {
+	calendars := []CalendarOutput{}
+	next := p.graphURL + "/me/calendars?$top=100"
+	for next != "" {
+		var page struct {
+			Value    []graphCalendar `json:"value"`
+			NextLink string          `json:"@odata.nextLink"`
+		}
+		if err := p.call(ctx, user, http.MethodGet, next, nil, &page); err != nil {
+			return nil, err
+		}
+		for _, item := range page.Value {
+			role := "reader"
+			switch {
+			case item.CanShare:
+				role = "owner" // Only the owner may share a calendar
+			case item.CanEdit:
+				role = "writer"
+			}
+			calendars = append(calendars, CalendarOutput{
+				ID:         item.ID,
+				Summary:    item.Name,
+				AccessRole: role,
+				Primary:    item.IsDefaultCalendar,
+				Color:      item.HexColor,
+			})
+		}
+		next = page.NextLink
+		if next != "" && !p.isGraphURL(next) {
+			return nil, fmt.Errorf("unexpected next link %q from Microsoft Graph", next)
+		}
+	}
+	return calendars, nil
+}
+
+func (p *microsoftProvider) CreateEvent(ctx context.Context, user *domain.User, input CreateEventInput) (*ProviderEvent, error) {
+	event, err := newGraphEvent(EventOutput{
+		Title:       input.Title,
+		Description: input.Description,
+		StartTime:   input.StartTime,
+		EndTime:     input.EndTime,
+		AllDay:      input.AllDay,
+		Attendees:   attendeeOutputs(input.Attendees, nil),
+		Recurrence:  input.Recurrence,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Graph drops a second create with the same transaction ID, so the retry
+	// after a token refresh can't create the event twice.
+	event.TransactionID = uuid.New().String()
+	if input.AddConference {
+		event.IsOnlineMeeting = true
+		event.OnlineMeetingProvider = "teamsForBusiness"
+	}
+
+	var created graphEvent
+	err = p.call(ctx, user, http.MethodPost, p.graphURL+calendarPath(input.CalendarID)+"/events", event, &created)
+	if err != nil {
+		if isGraphStatus(err, http.StatusNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrCalendarNotFound, input.CalendarID)
+		}
+		return nil, err
+	}
+	return fromGraphEvent(&created, user.Email)
+}
+
+// GetEvent looks the event up by ID alone; Graph event IDs are unique across
+// the user's calendars.
+func (p *microsoftProvider) GetEvent(ctx context.Context, user *domain.User, calendarID, eventID string) (*ProviderEvent, error) {
+	var item graphEvent
+	err := p.call(ctx, user, http.MethodGet, p.graphURL+"/me/events/"+url.PathEscape(eventID), nil, &item)
+	if err != nil {
+		if isGraphNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if item.IsCancelled {
+		return nil, nil
+	}
+	return fromGraphEvent(&item, user.Email)
+}
+
+// ListEvents reads the calendar view, which has recurring events expanded.
+// The page token is the next link Graph returned for the previous page.
+func (p *microsoftProvider) ListEvents(ctx context.Context, user *domain.User, query ProviderQuery) ([]EventOutput, string, error) {
+	next := query.PageToken
+	if next == "" {
+		params := url.Values{}
+		params.Set("startDateTime", query.TimeMin.UTC().Format(time.RFC3339))
+		params.Set("endDateTime", query.TimeMax.UTC().Format(time.RFC3339))
+		params.Set("$top", strconv.Itoa(query.MaxResults))
+		params.Set("$orderby", "start/dateTime")
+		if query.Query != "" {
+			params.Set("$filter", "contains(subject,'"+strings.ReplaceAll(query.Query, "'", "''")+"')")
+		}
+		next = p.graphURL + calendarPath(query.CalendarID) + "/calendarView?" + params.Encode()
+	} else if !p.isGraphURL(next) {
+		// The token is sent along with the user's access token, so it must
+		// not point anywhere else.
+		return nil, "", ErrInvalidCursor
+	}
+
+	var page struct {
+		Value    []graphEvent `json:"value"`
+		NextLink string       `json:"@odata.nextLink"`
+	}
+	if err := p.call(ctx, user, http.MethodGet, next, nil, &page); err != nil {
+		if isGraphStatus(err, http.StatusNotFound) {
+			return nil, "", fmt.Errorf("%w: %s", ErrCalendarNotFound, query.CalendarID)
+		}
+		return nil, "", err
+	}
+
+	events := make([]EventOutput, 0, len(page.Value))
+	for i := range page.Value {
+		if page.Value[i].IsCancelled {
+			continue
+		}
+		event, err := fromGraphEvent(&page.Value[i], user.Email)
+		if err != nil {
+			return nil, "", err
+		}
+		events = append(events, event.EventOutput)
+	}
+	return events, page.NextLink, nil
+}
+
+// UpdateEvent writes the fields the API manages and leaves everything else
+// on the event as it is. calendarID is not needed, see GetEvent.
+func (p *microsoftProvider) UpdateEvent(ctx context.Context, user *domain.User, calendarID string, event EventOutput) (*ProviderEvent, error) {
+	patch, err := newGraphEvent(event)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Recurrence == nil && event.RecurringEventID == "" {
+		patch.Recurrence = json.RawMessage("null") // Ends the series, if it was one
+	}
+
+	var updated graphEvent
+	err = p.call(ctx, user, http.MethodPatch, p.graphURL+"/me/events/"+url.PathEscape(event.EventId), patch, &updated)
+	if err != nil {
+		if isGraphNotFound(err) {
+			return nil, ErrEventNotFound
+		}
+		return nil, err
+	}
+	return fromGraphEvent(&updated, user.Email)
+}
+
+func (p *microsoftProvider) DeleteEvent(ctx context.Context, user *domain.User, calendarID, eventID string) error {
+	err := p.call(ctx, user, http.MethodDelete, p.graphURL+"/me/events/"+url.PathEscape(eventID), nil, nil)
+	if isGraphNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// FreeBusy asks getSchedule for the calendars, which have to be email
+// addresses of people or rooms.
+func (p *microsoftProvider) FreeBusy(ctx context.Context, user *domain.User, input FreeBusyInput) (*FreeBusyOutput, error) {
+	loc := time.UTC
+	if input.TimeZone != "" {
+		if l, err := time.LoadLocation(input.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	output := &FreeBusyOutput{
+		TimeMin:   input.TimeMin,
+		TimeMax:   input.TimeMax,
+		Calendars: make(map[string]CalendarAvailability, len(input.Calendars)),
+	}
+
+	for start := 0; start < len(input.Calendars); start += graphScheduleBatchSize {
+		ids := input.Calendars[start:min(start+graphScheduleBatchSize, len(input.Calendars))]
+		req := map[string]interface{}{
+			"schedules": ids,
+			"startTime": toGraphDateTime(input.TimeMin.UTC(), false),
+			"endTime":   toGraphDateTime(input.TimeMax.UTC(), false),
+		}
+		var resp struct {
+			Value []graphSchedule `json:"value"`
+		}
+		if err := p.call(ctx, user, http.MethodPost, p.graphURL+"/me/calendar/getSchedule", req, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, id := range ids {
+			availability := CalendarAvailability{Busy: []TimeRange{}}
+			var schedule *graphSchedule
+			for i := range resp.Value {
+				if strings.EqualFold(resp.Value[i].ScheduleID, id) {
+					schedule = &resp.Value[i]
+					break
+				}
+			}
+			switch {
+			case schedule == nil:
+				availability.Errors = append(availability.Errors, "notFound")
+			case schedule.Error != nil:
+				reason := schedule.Error.ResponseCode
+				if reason == "" {
+					reason = "internalError"
+				}
+				availability.Errors = append(availability.Errors, reason)
+			default:
+				for _, item := range schedule.ScheduleItems {
+					if item.Status == "free" || item.Status == "workingElsewhere" {
+						continue
+					}
+					start, err := fromGraphDateTime(item.Start, false)
+					if err != nil {
+						return nil, fmt.Errorf("unreadable busy start for %s: %w", id, err)
+					}
+					end, err := fromGraphDateTime(item.End, false)
+					if err != nil {
+						return nil, fmt.Errorf("unreadable busy end for %s: %w", id, err)
+					}
+					availability.Busy = append(availability.Busy, TimeRange{Start: start.In(loc), End: end.In(loc)})
+				}
+			}
+			output.Calendars[id] = availability
+		}
+	}
+	return output, nil
+}
+
+// call sends a Graph request as user and decodes the answer into out, which
+// may be nil. When Graph rejects the access token, it is refreshed and the
+// request sent once more.
+func (p *microsoftProvider) call(ctx context.Context, user *domain.User, method, target string, body, out interface{}) error {
+	account, err := p.accountRepo.GetAccount(ctx, user.Email, ProviderMicrosoft)
+	if err != nil {
+		return fmt.Errorf("failed to load Microsoft account: %w", err)
+	}
+	if account == nil {
+		return fmt.Errorf("%w: %s has no Microsoft account", ErrProviderNotLinked, user.Email)
+	}
+
+	err = sendGraphRequest(ctx, p.httpClient(ctx, account, false), method, target, body, out)
+	if !isGraphStatus(err, http.StatusUnauthorized) {
+		return classifyGoogleError(err) // Microsoft also answers invalid_grant
+	}
+
+	log.Printf("🔄 Microsoft rejected the access token of %s, refreshing...", user.Email)
+	err = sendGraphRequest(ctx, p.httpClient(ctx, account, true), method, target, body, out)
+	if isGraphStatus(err, http.StatusUnauthorized) {
+		return fmt.Errorf("%w: %v", ErrReauthRequired, err)
+	}
+	return classifyGoogleError(err)
+}
+
+func (p *microsoftProvider) httpClient(ctx context.Context, account *domain.CalendarAccount, forceRefresh bool) *http.Client {
+	token := &oauth2.Token{
+		AccessToken:  account.AccessToken,
+		RefreshToken: account.RefreshToken,
+		Expiry:       account.ExpiresAt,
+	}
+	if forceRefresh {
+		token.AccessToken = ""
+		token.Expiry = time.Now().Add(-time.Minute)
+	}
+
+	source := &accountTokenSource{
+		ctx:         context.WithoutCancel(ctx),
+		base:        p.oauthConfig.TokenSource(ctx, &oauth2.Token{RefreshToken: account.RefreshToken}),
+		account:     account,
+		accountRepo: p.accountRepo,
+	}
+	return oauth2.NewClient(ctx, oauth2.ReuseTokenSource(token, source))
+}
+
+// isGraphURL reports whether target is on the configured Graph API.
+func (p *microsoftProvider) isGraphURL(target string) bool {
+	return strings.HasPrefix(target, p.graphURL+"/")
+}
+
+// sendGraphRequest sends body as JSON and decodes a successful answer into out.
+func sendGraphRequest(ctx context.Context, client *http.Client, method, target string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode Graph request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Prefer", `outlook.body-content-type="text"`)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		apiErr := &graphError{StatusCode: resp.StatusCode}
+		var payload struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&payload) == nil {
+			apiErr.Code, apiErr.Message = payload.Error.Code, payload.Error.Message
+		}
+		return apiErr
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode Graph response: %w", err)
+	}
+	return nil
+}
+
+// fetchMicrosoftProfile reads the signed-in user from Graph.
+func fetchMicrosoftProfile(ctx context.Context, client *http.Client, graphURL string) (*microsoftProfile, error) {
+	var profile microsoftProfile
+	if err := sendGraphRequest(ctx, client, http.MethodGet, graphURL+"/me", nil, &profile); err != nil {
+		return nil, err
+	}
+	if profile.Mail == "" {
+		profile.Mail = profile.UserPrincipalName // Personal accounts often have no mail set
+	}
+	if profile.ID == "" || profile.Mail == "" {
+		return nil, fmt.Errorf("Microsoft profile has no ID or email")
+	}
+	return &profile, nil
+}
+
+// calendarPath is the Graph path of a calendar; "primary" and "" stand for
+// the user's default calendar, as with Google.
+func calendarPath(calendarID string) string {
+	if calendarID == "" || calendarID == "primary" {
+		return "/me/calendar"
+	}
+	return "/me/calendars/" + url.PathEscape(calendarID)
+}
+
+// newGraphEvent builds the Graph event for the fields the API manages.
+func newGraphEvent(event EventOutput) (*graphEvent, error) {
+	recurrence, err := toGraphRecurrence(event.Recurrence, event.StartTime)
+	if err != nil {
+		return nil, err
+	}
+	item := &graphEvent{
+		Subject:   event.Title,
+		Body:      &graphBody{ContentType: "text", Content: event.Description},
+		Start:     toGraphDateTime(event.StartTime, event.AllDay),
+		End:       toGraphDateTime(event.EndTime, event.AllDay),
+		IsAllDay:  event.AllDay,
+		Attendees: []graphAttendee{},
+	}
+	if recurrence != nil {
+		item.Recurrence, _ = json.Marshal(recurrence)
+	}
+	for _, a := range event.Attendees {
+		attendee := graphAttendee{
+			EmailAddress: graphEmailAddress{Address: a.Email, Name: a.Name},
+			Type:         "required",
+		}
+		if a.Optional {
+			attendee.Type = "optional"
+		}
+		item.Attendees = append(item.Attendees, attendee)
+	}
+	return item, nil
+}
+
+// fromGraphEvent converts a Graph event into a ProviderEvent.
+func fromGraphEvent(item *graphEvent, userEmail string) (*ProviderEvent, error) {
+	event := &ProviderEvent{
+		EventOutput: EventOutput{
+			Title:            item.Subject,
+			EventId:          item.ID,
+			CreatedBy:        userEmail,
+			AllDay:           item.IsAllDay,
+			Attendees:        []AttendeeOutput{},
+			RecurringEventID: item.SeriesMasterID,
+		},
+		ICalUID: item.ICalUID,
+		Status:  "confirmed",
+	}
+	if item.IsCancelled {
+		event.Status = "cancelled"
+	}
+	if item.Body != nil {
+		event.Description = item.Body.Content
+	}
+
+	var err error
+	if event.StartTime, err = fromGraphDateTime(item.Start, item.IsAllDay); err != nil {
+		return nil, fmt.Errorf("unreadable start of event %s: %w", item.ID, err)
+	}
+	if event.EndTime, err = fromGraphDateTime(item.End, item.IsAllDay); err != nil {
+		return nil, fmt.Errorf("unreadable end of event %s: %w", item.ID, err)
+	}
+	if !item.IsAllDay {
+		if loc, _ := ics.LoadZone(item.OriginalStartTimeZone); loc != nil {
+			event.TimeZone = loc.String()
+			event.StartTime, event.EndTime = event.StartTime.In(loc), event.EndTime.In(loc)
+		}
+	}
+	if item.OriginalStart != nil && item.SeriesMasterID != "" {
+		original := *item.OriginalStart
+		event.OriginalStartTime = &original
+	}
+
+	if len(item.Recurrence) > 0 && string(item.Recurrence) != "null" {
+		var recurrence graphRecurrence
+		if err := json.Unmarshal(item.Recurrence, &recurrence); err != nil {
+			return nil, fmt.Errorf("unreadable recurrence of event %s: %w", item.ID, err)
+		}
+		event.Recurrence = fromGraphRecurrence(&recurrence, item.IsAllDay)
+	}
+
+	organizer := ""
+	if item.Organizer != nil {
+		organizer = item.Organizer.EmailAddress.Address
+	}
+	for _, a := range item.Attendees {
+		status := "needsAction"
+		if a.Status != nil {
+			status = graphResponses[a.Status.Response]
+			if status == "" {
+				status = "needsAction"
+			}
+		}
+		event.Attendees = append(event.Attendees, AttendeeOutput{
+			Email:          a.EmailAddress.Address,
+			Name:           a.EmailAddress.Name,
+			Optional:       a.Type == "optional",
+			Organizer:      organizer != "" && strings.EqualFold(a.EmailAddress.Address, organizer),
+			ResponseStatus: status,
+		})
+	}
+
+	if item.OnlineMeeting != nil && item.OnlineMeeting.JoinURL != "" {
+		event.Conference = &ConferenceOutput{JoinURL: item.OnlineMeeting.JoinURL, Solution: "Microsoft Teams"}
+	} else if item.IsOnlineMeeting {
+		event.Conference = &ConferenceOutput{Solution: "Microsoft Teams", Status: "pending"}
+	}
+	return event, nil
+}
+
+// graphResponses maps Graph attendee responses to Google response statuses.
+var graphResponses = map[string]string{
+	"accepted":            "accepted",
+	"organizer":           "accepted",
+	"declined":            "declined",
+	"tentativelyAccepted": "tentative",
+}
+
+// toGraphDateTime writes t in its own zone when that has an IANA name, and
+// in UTC otherwise. All-day events are written as midnight of their dates.
+func toGraphDateTime(t time.Time, allDay bool) *graphDateTime {
+	if allDay {
+		return &graphDateTime{DateTime: t.UTC().Format("2006-01-02") + "T00:00:00", TimeZone: "UTC"}
+	}
+	zone := t.Location().String()
+	if _, err := time.LoadLocation(zone); zone == "" || zone == "Local" || err != nil {
+		t, zone = t.UTC(), "UTC"
+	}
+	return &graphDateTime{DateTime: t.Format(graphTimeLayout), TimeZone: zone}
+}
+
+// fromGraphDateTime reads a dateTimeTimeZone. Zones Graph may use that Go
+// doesn't know, like Windows names, are looked up the way the iCalendar
+// import does.
+func fromGraphDateTime(dt *graphDateTime, allDay bool) (time.Time, error) {
+	if dt == nil || len(dt.DateTime) < len("2006-01-02") {
+		return time.Time{}, fmt.Errorf("missing date")
+	}
+	if allDay {
+		return time.Parse("2006-01-02", dt.DateTime[:len("2006-01-02")])
+	}
+	loc := time.UTC
+	if l, _ := ics.LoadZone(dt.TimeZone); l != nil {
+		loc = l
+	}
+	// Graph writes up to seven fractional digits.
+	return time.ParseInLocation(graphTimeLayout+".9999999", dt.DateTime, loc)
+}
+
+// graphWeekdays maps iCalendar weekdays to Graph's.
+var graphWeekdays = map[string]string{
+	"MO": "monday",
+	"TU": "tuesday",
+	"WE": "wednesday",
+	"TH": "thursday",
+	"FR": "friday",
+	"SA": "saturday",
+	"SU": "sunday",
+}
+
+// graphIndexes maps the position of a weekday in the month to Graph's week
+// index.
+var graphIndexes = map[int]string{1: "first", 2: "second", 3: "third", 4: "fourth", -1: "last"}
+
+// toGraphRecurrence converts a single RRULE into a Graph recurrence. Graph
+// has no exception or extra dates, so rules that need them are refused.
+func toGraphRecurrence(lines []string, start time.Time) (*graphRecurrence, error) {
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	if len(lines) > 1 || !strings.HasPrefix(strings.ToUpper(lines[0]), "RRULE:") {
+		return nil, fmt.Errorf("%w: Microsoft calendars take a single RRULE", ErrProviderUnsupported)
+	}
+	parts := ruleParts(lines[0])
+	for key := range parts {
+		switch key {
+		case "FREQ", "INTERVAL", "COUNT", "UNTIL", "BYDAY", "BYMONTHDAY", "BYMONTH", "BYSETPOS", "WKST":
+		default:
+			return nil, fmt.Errorf("%w: %s in recurrence rules for Microsoft calendars", ErrProviderUnsupported, key)
+		}
+	}
+
+	pattern := graphPattern{Interval: 1}
+	if v := parts["INTERVAL"]; v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: invalid INTERVAL %q", ErrInvalidRule, v)
+		}
+		pattern.Interval = n
+	}
+	days, index, err := graphDays(parts["BYDAY"], parts["BYSETPOS"])
+	if err != nil {
+		return nil, err
+	}
+	dayOfMonth := start.Day()
+	if v := parts["BYMONTHDAY"]; v != "" {
+		if dayOfMonth, err = strconv.Atoi(v); err != nil || dayOfMonth < 1 || dayOfMonth > 31 {
+			return nil, fmt.Errorf("%w: BYMONTHDAY %q for Microsoft calendars", ErrProviderUnsupported, v)
+		}
+	}
+	month := int(start.Month())
+	if v := parts["BYMONTH"]; v != "" {
+		if month, err = strconv.Atoi(v); err != nil || month < 1 || month > 12 {
+			return nil, fmt.Errorf("%w: BYMONTH %q for Microsoft calendars", ErrProviderUnsupported, v)
+		}
+	}
+
+	switch strings.ToUpper(parts["FREQ"]) {
+	case "DAILY":
+		if len(days) > 0 {
+			return nil, fmt.Errorf("%w: BYDAY on daily rules for Microsoft calendars", ErrProviderUnsupported)
+		}
+		pattern.Type = "daily"
+	case "WEEKLY":
+		if index != "" {
+			return nil, fmt.Errorf("%w: numbered weekdays on weekly rules", ErrInvalidRule)
+		}
+		pattern.Type = "weekly"
+		pattern.DaysOfWeek = days
+		if len(days) == 0 {
+			pattern.DaysOfWeek = []string{strings.ToLower(start.Weekday().String())}
+		}
+	case "MONTHLY", "YEARLY":
+		relative := len(days) > 0
+		if relative && index == "" {
+			return nil, fmt.Errorf("%w: every weekday of a month for Microsoft calendars", ErrProviderUnsupported)
+		}
+		if strings.ToUpper(parts["FREQ"]) == "MONTHLY" {
+			pattern.Type = "absoluteMonthly"
+			if relative {
+				pattern.Type = "relativeMonthly"
+			}
+		} else {
+			pattern.Type = "absoluteYearly"
+			if relative {
+				pattern.Type = "relativeYearly"
+			}
+			pattern.Month = month
+		}
+		if relative {
+			pattern.DaysOfWeek, pattern.Index = days, index
+		} else {
+			pattern.DayOfMonth = dayOfMonth
+		}
+	default:
+		return nil, fmt.Errorf("%w: FREQ %q for Microsoft calendars", ErrProviderUnsupported, parts["FREQ"])
+	}
+
+	rng := graphRange{Type: "noEnd", StartDate: start.Format("2006-01-02")}
+	switch {
+	case parts["COUNT"] != "":
+		n, err := strconv.Atoi(parts["COUNT"])
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("%w: invalid COUNT %q", ErrInvalidRule, parts["COUNT"])
+		}
+		rng.Type, rng.NumberOfOccurrences = "numbered", n
+	case parts["UNTIL"] != "":
+		until, err := time.Parse("20060102", parts["UNTIL"][:min(8, len(parts["UNTIL"]))])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid UNTIL %q", ErrInvalidRule, parts["UNTIL"])
+		}
+		rng.Type, rng.EndDate = "endDate", until.Format("2006-01-02")
+	}
+	return &graphRecurrence{Pattern: pattern, Range: rng}, nil
+}
+
+// graphDays reads BYDAY, e.g. "MO,WE" or "-1FR", into Graph weekdays and the
+// week index they share, which may also come from BYSETPOS.
+func graphDays(byDay, bySetPos string) ([]string, string, error) {
+	if byDay == "" {
+		return nil, "", nil
+	}
+	position := 0
+	if bySetPos != "" {
+		n, err := strconv.Atoi(bySetPos)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: BYSETPOS %q for Microsoft calendars", ErrProviderUnsupported, bySetPos)
+		}
+		position = n
+	}
+
+	var days []string
+	for _, value := range strings.Split(strings.ToUpper(byDay), ",") {
+		value = strings.TrimSpace(value)
+		if len(value) < 2 {
+			return nil, "", fmt.Errorf("%w: invalid BYDAY %q", ErrInvalidRule, byDay)
+		}
+		day, ok := graphWeekdays[value[len(value)-2:]]
+		if !ok {
+			return nil, "", fmt.Errorf("%w: invalid BYDAY %q", ErrInvalidRule, byDay)
+		}
+		if ordinal := value[:len(value)-2]; ordinal != "" {
+			n, err := strconv.Atoi(ordinal)
+			if err != nil || (position != 0 && n != position) {
+				return nil, "", fmt.Errorf("%w: BYDAY %q for Microsoft calendars", ErrProviderUnsupported, byDay)
+			}
+			position = n
+		}
+		days = append(days, day)
+	}
+
+	if position == 0 {
+		return days, "", nil
+	}
+	index, ok := graphIndexes[position]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: week %d of a month for Microsoft calendars", ErrProviderUnsupported, position)
+	}
+	return days, index, nil
+}
+
+// fromGraphRecurrence converts a Graph recurrence into an RRULE.
+func fromGraphRecurrence(r *graphRecurrence, allDay bool) []string {
+	byDay := func() string {
+		ordinal := ""
+		for n, index := range graphIndexes {
+			if index == r.Pattern.Index {
+				ordinal = strconv.Itoa(n)
+			}
+		}
+		var days []string
+		for _, day := range r.Pattern.DaysOfWeek {
+			for short, long := range graphWeekdays {
+				if strings.EqualFold(day, long) {
+					days = append(days, short)
+				}
+			}
+		}
+		switch len(days) {
+		case 0:
+			return ""
+		case 1:
+			return "BYDAY=" + ordinal + days[0]
+		}
+		rule := "BYDAY=" + strings.Join(days, ",")
+		if ordinal != "" {
+			rule += ";BYSETPOS=" + ordinal
+		}
+		return rule
+	}
+
+	var parts []string
+	switch r.Pattern.Type {
+	case "daily":
+		parts = append(parts, "FREQ=DAILY")
+	case "weekly":
+		parts = append(parts, "FREQ=WEEKLY", byDay())
+	case "absoluteMonthly":
+		parts = append(parts, "FREQ=MONTHLY", "BYMONTHDAY="+strconv.Itoa(r.Pattern.DayOfMonth))
+	case "relativeMonthly":
+		parts = append(parts, "FREQ=MONTHLY", byDay())
+	case "absoluteYearly":
+		parts = append(parts, "FREQ=YEARLY", "BYMONTH="+strconv.Itoa(r.Pattern.Month), "BYMONTHDAY="+strconv.Itoa(r.Pattern.DayOfMonth))
+	case "relativeYearly":
+		parts = append(parts, "FREQ=YEARLY", "BYMONTH="+strconv.Itoa(r.Pattern.Month), byDay())
+	default:
+		return nil
+	}
+	if r.Pattern.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Pattern.Interval))
+	}
+
+	switch r.Range.Type {
+	case "numbered":
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Range.NumberOfOccurrences))
+	case "endDate":
+		if until, err := time.Parse("2006-01-02", r.Range.EndDate); err == nil {
+			if allDay {
+				parts = append(parts, "UNTIL="+until.Format("20060102"))
+			} else {
+				parts = append(parts, "UNTIL="+until.Format("20060102")+"T235959Z")
+			}
+		}
+	}
+	rule := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			rule = append(rule, part)
+		}
+	}
+	return []string{"RRULE:" + strings.Join(rule, ";")}
+}
+
+// accountTokenSource is persistingTokenSource for calendar accounts.
+type accountTokenSource struct {
+	ctx         context.Context
+	base        oauth2.TokenSource
+	account     *domain.CalendarAccount
+	accountRepo repository.CalendarAccountRepository
+
+	mu sync.Mutex
+}
+
+func (s *accountTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, err := s.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token.AccessToken == s.account.AccessToken {
+		return token, nil
+	}
+
+	s.account.AccessToken = token.AccessToken
+	s.account.ExpiresAt = token.Expiry
+	if token.RefreshToken != "" {
+		s.account.RefreshToken = token.RefreshToken // Microsoft rotates it on every refresh
+	}
+	if err := s.accountRepo.SaveAccount(s.ctx, s.account); err != nil {
+		log.Printf("⚠️ Failed to save refreshed %s token for %s: %v", s.account.Provider, s.account.UserEmail, err)
+	}
+	return token, nil
+}
+
+// graphError is an error answer from Microsoft Graph.
+type graphError struct {
+	StatusCode int
+	Code       string
+	Message    string
+}
+
+func (e *graphError) Error() string {
+	return fmt.Sprintf("graph: %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// isGraphStatus reports whether Graph answered with status.
+func isGraphStatus(err error, status int) bool {
+	var apiErr *graphError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+// isGraphNotFound reports whether Graph has no event with the ID, which for
+// IDs of another provider shows as a malformed ID.
+func isGraphNotFound(err error) bool {
+	var apiErr *graphError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusNotFound ||
+		(apiErr.StatusCode == http.StatusBadRequest && apiErr.Code == "ErrorInvalidIdMalformed")
+}
+
+// microsoftProfile is the part of the Graph /me resource used for login.
+type microsoftProfile struct {
+	ID                string `json:"id"`
+	DisplayName       string `json:"displayName"`
+	Mail              string `json:"mail"`
+	UserPrincipalName string `json:"userPrincipalName"`
+}
+
+type graphCalendar struct {
+	ID                string `json:"id"`
+	Name              string `json:"name"`
+	HexColor          string `json:"hexColor"`
+	CanEdit           bool   `json:"canEdit"`
+	CanShare          bool   `json:"canShare"`
+	IsDefaultCalendar bool   `json:"isDefaultCalendar"`
+}
+
+type graphEvent struct {
+	ID                    string              `json:"id,omitempty"`
+	ICalUID               string              `json:"iCalUId,omitempty"`
+	TransactionID         string              `json:"transactionId,omitempty"`
+	Subject               string              `json:"subject"`
+	Body                  *graphBody          `json:"body,omitempty"`
+	Start                 *graphDateTime      `json:"start,omitempty"`
+	End                   *graphDateTime      `json:"end,omitempty"`
+	IsAllDay              bool                `json:"isAllDay"`
+	IsCancelled           bool                `json:"isCancelled,omitempty"`
+	Organizer             *graphRecipient     `json:"organizer,omitempty"`
+	Attendees             []graphAttendee     `json:"attendees"`
+	Recurrence            json.RawMessage     `json:"recurrence,omitempty"` // A graphRecurrence; "null" ends a series
+	SeriesMasterID        string              `json:"seriesMasterId,omitempty"`
+	OriginalStart         *time.Time          `json:"originalStart,omitempty"`
+	OriginalStartTimeZone string              `json:"originalStartTimeZone,omitempty"`
+	IsOnlineMeeting       bool                `json:"isOnlineMeeting,omitempty"`
+	OnlineMeetingProvider string              `json:"onlineMeetingProvider,omitempty"`
+	OnlineMeeting         *graphOnlineMeeting `json:"onlineMeeting,omitempty"`
+}
+
+type graphBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type graphDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type graphRecipient struct {
+	EmailAddress graphEmailAddress `json:"emailAddress"`
+}
+
+type graphEmailAddress struct {
+	Address string `json:"address"`
+	Name    string `json:"name,omitempty"`
+}
+
+type graphAttendee struct {
+	EmailAddress graphEmailAddress `json:"emailAddress"`
+	Type         string            `json:"type,omitempty"`   // required, optional or resource
+	Status       *graphResponse    `json:"status,omitempty"` // Read only
+}
+
+type graphResponse struct {
+	Response string `json:"response"`
+}
+
+type graphOnlineMeeting struct {
+	JoinURL string `json:"joinUrl"`
+}
+
+type graphRecurrence struct {
+	Pattern graphPattern `json:"pattern"`
+	Range   graphRange   `json:"range"`
+}
+
+type graphPattern struct {
+	Type       string   `json:"type"`
+	Interval   int      `json:"interval"`
+	Month      int      `json:"month,omitempty"`
+	DayOfMonth int      `json:"dayOfMonth,omitempty"`
+	DaysOfWeek []string `json:"daysOfWeek,omitempty"`
+	Index      string   `json:"index,omitempty"`
+}
+
+type graphRange struct {
+	Type                string `json:"type"` // endDate, noEnd or numbered
+	StartDate           string `json:"startDate"`
+	EndDate             string `json:"endDate,omitempty"`
+	NumberOfOccurrences int    `json:"numberOfOccurrences,omitempty"`
+}
+
+type graphSchedule struct {
+	ScheduleID    string `json:"scheduleId"`
+	ScheduleItems []struct {
+		Status string         `json:"status"` // free, tentative, busy, oof, workingElsewhere or unknown
+		Start  *graphDateTime `json:"start"`
+		End    *graphDateTime `json:"end"`
+	} `json:"scheduleItems"`
+	Error *struct {
+		Message      string `json:"message"`
+		ResponseCode string `json:"responseCode"`
+	} `json:"error"`
+}
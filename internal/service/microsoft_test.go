@@ -0,0 +1,465 @@
+// internal/service/microsoft_test.go
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+
+	"golang.org/x/oauth2"
+)
+
+// fakeGraph is a stand-in for the Microsoft identity platform token endpoint
+// and the parts of Graph the provider uses, for one user. Access tokens it
+// didn't issue are rejected, and refresh tokens are rotated on every use as
+// Microsoft does.
+type fakeGraph struct {
+	*httptest.Server
+	user string
+
+	mu        sync.Mutex
+	access    map[string]bool
+	refresh   map[string]bool
+	refreshes int
+	events    map[string]*graphEvent
+	received  []graphEvent // Bodies of creates and updates, as sent
+	seq       int
+}
+
+func newFakeGraph(t *testing.T, user string) *fakeGraph {
+	g := &fakeGraph{
+		user:    user,
+		access:  map[string]bool{"access-0": true},
+		refresh: map[string]bool{"refresh-0": true},
+		events:  make(map[string]*graphEvent),
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", g.token)
+	mux.HandleFunc("GET /v1.0/me/calendars", g.authorized(g.listCalendars))
+	mux.HandleFunc("POST /v1.0/me/calendar/events", g.authorized(g.createEvent))
+	mux.HandleFunc("GET /v1.0/me/calendar/calendarView", g.authorized(g.calendarView))
+	mux.HandleFunc("GET /v1.0/me/events/{id}", g.authorized(g.getEvent))
+	mux.HandleFunc("PATCH /v1.0/me/events/{id}", g.authorized(g.updateEvent))
+	mux.HandleFunc("DELETE /v1.0/me/events/{id}", g.authorized(g.deleteEvent))
+	g.Server = httptest.NewServer(mux)
+	t.Cleanup(g.Close)
+	return g
+}
+
+// provider returns a Microsoft provider pointed at the fake, with the user's
+// account stored in accounts.
+func (g *fakeGraph) provider(t *testing.T, accounts repository.CalendarAccountRepository) *microsoftProvider {
+	cfg := &config.Config{
+		MicrosoftGraphURL: g.URL + "/v1.0",
+		MicrosoftOAuthConfig: &oauth2.Config{
+			ClientID:     "client",
+			ClientSecret: "secret",
+			Endpoint:     oauth2.Endpoint{TokenURL: g.URL + "/token", AuthStyle: oauth2.AuthStyleInParams},
+		},
+	}
+	account := &domain.CalendarAccount{
+		UserEmail:    g.user,
+		Provider:     ProviderMicrosoft,
+		Subject:      "00000000-0000-0000-0000-000000000001",
+		Email:        g.user,
+		AccessToken:  "access-0",
+		RefreshToken: "refresh-0",
+		ExpiresAt:    time.Now().Add(time.Hour),
+	}
+	if err := accounts.SaveAccount(context.Background(), account); err != nil {
+		t.Fatalf("failed to save account: %v", err)
+	}
+	return NewMicrosoftProvider(cfg, accounts)
+}
+
+// expireAccessTokens makes the fake reject every access token issued so far.
+func (g *fakeGraph) expireAccessTokens() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	clear(g.access)
+}
+
+// revokeTokens makes the fake reject every token issued so far.
+func (g *fakeGraph) revokeTokens() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	clear(g.access)
+	clear(g.refresh)
+}
+
+func (g *fakeGraph) token(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if r.FormValue("grant_type") != "refresh_token" || !g.refresh[r.FormValue("refresh_token")] {
+		writeFakeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+	delete(g.refresh, r.FormValue("refresh_token"))
+	g.refreshes++
+	access, refresh := fmt.Sprintf("access-%d", g.refreshes), fmt.Sprintf("refresh-%d", g.refreshes)
+	g.access[access], g.refresh[refresh] = true, true
+	writeFakeJSON(w, http.StatusOK, map[string]any{
+		"token_type":    "Bearer",
+		"access_token":  access,
+		"refresh_token": refresh,
+		"expires_in":    3600,
+	})
+}
+
+func (g *fakeGraph) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		g.mu.Lock()
+		ok := g.access[strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")]
+		g.mu.Unlock()
+		if !ok {
+			writeGraphError(w, http.StatusUnauthorized, "InvalidAuthenticationToken", "Access token has expired")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (g *fakeGraph) listCalendars(w http.ResponseWriter, r *http.Request) {
+	writeFakeJSON(w, http.StatusOK, map[string]any{"value": []graphCalendar{
+		{ID: "AAMkCal", Name: "Calendar", CanEdit: true, CanShare: true, IsDefaultCalendar: true},
+	}})
+}
+
+func (g *fakeGraph) createEvent(w http.ResponseWriter, r *http.Request) {
+	var event graphEvent
+	if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+		writeGraphError(w, http.StatusBadRequest, "RequestBodyRead", err.Error())
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.received = append(g.received, event)
+	g.seq++
+	event.ID = fmt.Sprintf("AAMkEvent%d", g.seq)
+	event.ICalUID = fmt.Sprintf("040000008200E00074C5B7101A82E008%d", g.seq)
+	event.OriginalStartTimeZone = event.Start.TimeZone
+	event.Organizer = &graphRecipient{EmailAddress: graphEmailAddress{Address: g.user}}
+	g.answerFor(&event)
+	g.events[event.ID] = &event
+	writeFakeJSON(w, http.StatusCreated, event)
+}
+
+// answerFor makes the attendees respond: optional ones tentatively, the
+// organizer as such and everyone else not at all.
+func (g *fakeGraph) answerFor(event *graphEvent) {
+	for i := range event.Attendees {
+		a := &event.Attendees[i]
+		switch {
+		case strings.EqualFold(a.EmailAddress.Address, g.user):
+			a.Status = &graphResponse{Response: "organizer"}
+		case a.Type == "optional":
+			a.Status = &graphResponse{Response: "tentativelyAccepted"}
+		default:
+			a.Status = &graphResponse{Response: "none"}
+		}
+	}
+}
+
+func (g *fakeGraph) calendarView(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	events := []graphEvent{}
+	for _, event := range g.events {
+		events = append(events, *event)
+	}
+	slices.SortFunc(events, func(a, b graphEvent) int { return strings.Compare(a.Start.DateTime, b.Start.DateTime) })
+	writeFakeJSON(w, http.StatusOK, map[string]any{"value": events})
+}
+
+func (g *fakeGraph) getEvent(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	event, ok := g.events[r.PathValue("id")]
+	if !ok {
+		writeGraphError(w, http.StatusNotFound, "ErrorItemNotFound", "The specified object was not found in the store.")
+		return
+	}
+	writeFakeJSON(w, http.StatusOK, event)
+}
+
+// updateEvent applies the fields that are in the body, as a PATCH does.
+func (g *fakeGraph) updateEvent(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	event, ok := g.events[r.PathValue("id")]
+	if !ok {
+		writeGraphError(w, http.StatusNotFound, "ErrorItemNotFound", "The specified object was not found in the store.")
+		return
+	}
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeGraphError(w, http.StatusBadRequest, "RequestBodyRead", err.Error())
+		return
+	}
+	var sent graphEvent
+	if err := json.Unmarshal(body, &sent); err != nil {
+		writeGraphError(w, http.StatusBadRequest, "RequestBodyRead", err.Error())
+		return
+	}
+	g.received = append(g.received, sent)
+	if err := json.Unmarshal(body, event); err != nil {
+		writeGraphError(w, http.StatusBadRequest, "RequestBodyRead", err.Error())
+		return
+	}
+	event.OriginalStartTimeZone = event.Start.TimeZone
+	g.answerFor(event)
+	writeFakeJSON(w, http.StatusOK, event)
+}
+
+func (g *fakeGraph) deleteEvent(w http.ResponseWriter, r *http.Request) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.events[r.PathValue("id")]; !ok {
+		writeGraphError(w, http.StatusNotFound, "ErrorItemNotFound", "The specified object was not found in the store.")
+		return
+	}
+	delete(g.events, r.PathValue("id"))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// lastReceived returns the body of the last create or update.
+func (g *fakeGraph) lastReceived() graphEvent {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.received[len(g.received)-1]
+}
+
+func writeGraphError(w http.ResponseWriter, status int, code, message string) {
+	writeFakeJSON(w, status, map[string]any{"error": map[string]string{"code": code, "message": message}})
+}
+
+func writeFakeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestMicrosoftEventLifecycle(t *testing.T) {
+	const alice = "alice@contoso.com"
+	graph := newFakeGraph(t, alice)
+	p := graph.provider(t, &accountStore{})
+	user := &domain.User{Email: alice}
+	ctx := context.Background()
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2025, 3, 10, 9, 30, 0, 0, berlin)
+	created, err := p.CreateEvent(ctx, user, CreateEventInput{
+		Title:       "Planning",
+		Description: "Quarterly planning",
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Attendees:   []string{alice, "bob@contoso.com"},
+		Recurrence:  []string{"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10"},
+		CalendarID:  "primary",
+	})
+	if err != nil {
+		t.Fatalf("CreateEvent: %v", err)
+	}
+
+	sent := graph.lastReceived()
+	if sent.Start.DateTime != "2025-03-10T09:30:00" || sent.Start.TimeZone != "Europe/Berlin" {
+		t.Errorf("start sent as %+v; want 09:30 in Europe/Berlin", *sent.Start)
+	}
+	if sent.TransactionID == "" {
+		t.Errorf("create was sent without a transaction ID")
+	}
+	if len(sent.Attendees) != 2 || sent.Attendees[1].EmailAddress.Address != "bob@contoso.com" || sent.Attendees[1].Type != "required" {
+		t.Errorf("attendees sent as %+v", sent.Attendees)
+	}
+	var recurrence graphRecurrence
+	if err := json.Unmarshal(sent.Recurrence, &recurrence); err != nil {
+		t.Fatalf("recurrence sent as %s: %v", sent.Recurrence, err)
+	}
+	want := graphRecurrence{
+		Pattern: graphPattern{Type: "weekly", Interval: 1, DaysOfWeek: []string{"monday", "wednesday"}},
+		Range:   graphRange{Type: "numbered", StartDate: "2025-03-10", NumberOfOccurrences: 10},
+	}
+	if fmt.Sprint(recurrence) != fmt.Sprint(want) {
+		t.Errorf("recurrence sent as %+v; want %+v", recurrence, want)
+	}
+
+	if !created.StartTime.Equal(start) || created.TimeZone != "Europe/Berlin" || created.StartTime.Location().String() != "Europe/Berlin" {
+		t.Errorf("created start = %v in %q; want %v", created.StartTime, created.TimeZone, start)
+	}
+	if created.Description != "Quarterly planning" || created.ICalUID == "" || created.Status != "confirmed" {
+		t.Errorf("created = %+v", created)
+	}
+	if got := strings.Join(created.Recurrence, "\n"); got != "RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10" {
+		t.Errorf("created recurrence = %q", got)
+	}
+	wantAttendees := []AttendeeOutput{
+		{Email: alice, Organizer: true, ResponseStatus: "accepted"},
+		{Email: "bob@contoso.com", ResponseStatus: "needsAction"},
+	}
+	if !slices.Equal(created.Attendees, wantAttendees) {
+		t.Errorf("created attendees = %+v; want %+v", created.Attendees, wantAttendees)
+	}
+
+	// Replacing the event without a recurrence ends the series; optional
+	// attendees go over as such.
+	update := created.EventOutput
+	update.Title = "Planning (moved)"
+	update.StartTime, update.EndTime = start.Add(time.Hour), start.Add(2*time.Hour)
+	update.Recurrence = nil
+	update.Attendees = append(update.Attendees, AttendeeOutput{Email: "carol@contoso.com", Optional: true})
+	updated, err := p.UpdateEvent(ctx, user, "primary", update)
+	if err != nil {
+		t.Fatalf("UpdateEvent: %v", err)
+	}
+	if sent := graph.lastReceived(); string(sent.Recurrence) != "null" || sent.Attendees[2].Type != "optional" {
+		t.Errorf("update sent recurrence %s and attendees %+v", sent.Recurrence, sent.Attendees)
+	}
+	if updated.Title != "Planning (moved)" || !updated.StartTime.Equal(update.StartTime) || updated.Recurrence != nil {
+		t.Errorf("updated = %+v", updated)
+	}
+	if carol := updated.Attendees[2]; !carol.Optional || carol.ResponseStatus != "tentative" {
+		t.Errorf("optional attendee = %+v", carol)
+	}
+
+	events, _, err := p.ListEvents(ctx, user, ProviderQuery{CalendarID: "primary", TimeMin: start, TimeMax: start.Add(24 * time.Hour), MaxResults: 50})
+	if err != nil || len(events) != 1 || events[0].EventId != created.EventId {
+		t.Errorf("ListEvents = %+v, %v; want the event", events, err)
+	}
+
+	if err := p.DeleteEvent(ctx, user, "primary", created.EventId); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if got, err := p.GetEvent(ctx, user, "primary", created.EventId); got != nil || err != nil {
+		t.Errorf("GetEvent after delete = %+v, %v; want nil, nil", got, err)
+	}
+	if err := p.DeleteEvent(ctx, user, "primary", created.EventId); err != nil {
+		t.Errorf("second DeleteEvent = %v; want nil", err)
+	}
+	if _, err := p.UpdateEvent(ctx, user, "primary", update); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("UpdateEvent after delete = %v; want ErrEventNotFound", err)
+	}
+}
+
+func TestMicrosoftTokenRefresh(t *testing.T) {
+	const alice = "alice@contoso.com"
+	graph := newFakeGraph(t, alice)
+	accounts := &accountStore{}
+	p := graph.provider(t, accounts)
+	user := &domain.User{Email: alice}
+	ctx := context.Background()
+
+	graph.expireAccessTokens()
+	calendars, err := p.ListCalendars(ctx, user)
+	if err != nil {
+		t.Fatalf("ListCalendars with an expired access token: %v", err)
+	}
+	if len(calendars) != 1 || !calendars[0].Primary || calendars[0].AccessRole != "owner" {
+		t.Errorf("calendars = %+v", calendars)
+	}
+
+	// The rotated refresh token must be stored, or the next refresh fails.
+	account, err := accounts.GetAccount(ctx, alice, ProviderMicrosoft)
+	if err != nil || account == nil {
+		t.Fatalf("GetAccount = %v, %v", account, err)
+	}
+	if account.AccessToken != "access-1" || account.RefreshToken != "refresh-1" {
+		t.Errorf("stored tokens = %q, %q; want the refreshed ones", account.AccessToken, account.RefreshToken)
+	}
+	graph.expireAccessTokens()
+	if _, err := p.ListCalendars(ctx, user); err != nil {
+		t.Fatalf("ListCalendars after a second expiry: %v", err)
+	}
+
+	graph.revokeTokens()
+	if _, err := p.ListCalendars(ctx, user); !errors.Is(err, ErrReauthRequired) {
+		t.Errorf("ListCalendars with revoked tokens = %v; want ErrReauthRequired", err)
+	}
+}
+
+func TestGraphRecurrence(t *testing.T) {
+	start := time.Date(2025, 3, 28, 9, 0, 0, 0, time.UTC) // The last Friday of March
+	for _, tt := range []struct {
+		rule    string
+		pattern graphPattern
+		rng     graphRange
+		back    string // The rule read back, when it is written differently
+	}{
+		{
+			rule:    "RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20250430T000000Z",
+			pattern: graphPattern{Type: "daily", Interval: 2},
+			rng:     graphRange{Type: "endDate", StartDate: "2025-03-28", EndDate: "2025-04-30"},
+			back:    "RRULE:FREQ=DAILY;INTERVAL=2;UNTIL=20250430T235959Z",
+		},
+		{
+			rule:    "RRULE:FREQ=WEEKLY",
+			pattern: graphPattern{Type: "weekly", Interval: 1, DaysOfWeek: []string{"friday"}},
+			rng:     graphRange{Type: "noEnd", StartDate: "2025-03-28"},
+			back:    "RRULE:FREQ=WEEKLY;BYDAY=FR",
+		},
+		{
+			rule:    "RRULE:FREQ=MONTHLY;BYDAY=-1FR;COUNT=6",
+			pattern: graphPattern{Type: "relativeMonthly", Interval: 1, DaysOfWeek: []string{"friday"}, Index: "last"},
+			rng:     graphRange{Type: "numbered", StartDate: "2025-03-28", NumberOfOccurrences: 6},
+		},
+		{
+			rule:    "RRULE:FREQ=MONTHLY;BYDAY=MO,TU;BYSETPOS=2",
+			pattern: graphPattern{Type: "relativeMonthly", Interval: 1, DaysOfWeek: []string{"monday", "tuesday"}, Index: "second"},
+			rng:     graphRange{Type: "noEnd", StartDate: "2025-03-28"},
+		},
+		{
+			rule:    "RRULE:FREQ=YEARLY",
+			pattern: graphPattern{Type: "absoluteYearly", Interval: 1, Month: 3, DayOfMonth: 28},
+			rng:     graphRange{Type: "noEnd", StartDate: "2025-03-28"},
+			back:    "RRULE:FREQ=YEARLY;BYMONTH=3;BYMONTHDAY=28",
+		},
+	} {
+		got, err := toGraphRecurrence([]string{tt.rule}, start)
+		if err != nil {
+			t.Errorf("toGraphRecurrence(%s): %v", tt.rule, err)
+			continue
+		}
+		want := graphRecurrence{Pattern: tt.pattern, Range: tt.rng}
+		if fmt.Sprint(*got) != fmt.Sprint(want) {
+			t.Errorf("toGraphRecurrence(%s) = %+v; want %+v", tt.rule, *got, want)
+		}
+		back := tt.back
+		if back == "" {
+			back = tt.rule
+		}
+		if rule := fromGraphRecurrence(got, false); len(rule) != 1 || rule[0] != back {
+			t.Errorf("fromGraphRecurrence(%+v) = %v; want %s", *got, rule, back)
+		}
+	}
+
+	for _, lines := range [][]string{
+		{"RRULE:FREQ=WEEKLY", "EXDATE:20250404T090000Z"},
+		{"RRULE:FREQ=MONTHLY;BYDAY=FR"},
+		{"RRULE:FREQ=HOURLY"},
+		{"RRULE:FREQ=WEEKLY;BYHOUR=9"},
+	} {
+		if _, err := toGraphRecurrence(lines, start); !errors.Is(err, ErrProviderUnsupported) {
+			t.Errorf("toGraphRecurrence(%v) = %v; want ErrProviderUnsupported", lines, err)
+		}
+	}
+}
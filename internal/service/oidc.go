@@ -0,0 +1,138 @@
+// internal/service/oidc.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+const (
+	oidcDiscoveryTimeout = 10 * time.Second
+	oidcBaseBackoff      = time.Second     // Wait after the first failed discovery, doubled after each one
+	oidcMaxBackoff       = 5 * time.Minute // Longest wait between discoveries
+)
+
+// oidcIssuer verifies ID tokens of an OpenID Connect issuer. Discovery
+// happens on first use instead of at startup, so the API comes up while the
+// issuer is unreachable; failed discoveries are retried with backoff. The
+// verifier caches the issuer's keys and fetches them again when a token is
+// signed with one it doesn't know, which is how key rotation gets picked up.
+type oidcIssuer struct {
+	url      string
+	clientID string
+
+	mu          sync.Mutex            // Never held across network I/O, so Ready answers at once
+	verifier    *oidc.IDTokenVerifier // Nil until discovery succeeded
+	lastErr     error                 // Why the last discovery failed
+	failures    int
+	retryAt     time.Time     // No discovery before then
+	discovering chan struct{} // Closed when the discovery in flight is done; nil when there is none
+}
+
+func newOIDCIssuer(url, clientID string) *oidcIssuer {
+	return &oidcIssuer{url: url, clientID: clientID}
+}
+
+// Verifier returns the verifier, discovering the issuer first if needed.
+// Callers arriving during a discovery wait for its result instead of
+// starting another. While discovery is backing off it fails right away with
+// ErrAuthUnavailable.
+func (i *oidcIssuer) Verifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	for {
+		i.mu.Lock()
+		if i.verifier != nil {
+			verifier := i.verifier
+			i.mu.Unlock()
+			return verifier, nil
+		}
+		if time.Now().Before(i.retryAt) {
+			err := fmt.Errorf("%w: %v", ErrAuthUnavailable, i.lastErr)
+			i.mu.Unlock()
+			return nil, err
+		}
+		if done := i.discovering; done != nil {
+			i.mu.Unlock()
+			select {
+			case <-done:
+				continue
+			case <-ctx.Done():
+				return nil, fmt.Errorf("%w: %v", ErrAuthUnavailable, ctx.Err())
+			}
+		}
+		done := make(chan struct{})
+		i.discovering = done
+		i.mu.Unlock()
+		return i.discover(ctx, done)
+	}
+}
+
+// discover fetches the issuer's configuration and publishes the outcome,
+// then closes done. Other callers wait on it, so the request that started
+// it going away doesn't cancel it.
+func (i *oidcIssuer) discover(ctx context.Context, done chan struct{}) (*oidc.IDTokenVerifier, error) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), oidcDiscoveryTimeout)
+	defer cancel()
+	provider, err := oidc.NewProvider(ctx, i.url)
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.discovering = nil
+	close(done)
+	if err != nil {
+		i.failures++
+		i.lastErr = err
+		i.retryAt = time.Now().Add(min(oidcBaseBackoff<<min(i.failures-1, 20), oidcMaxBackoff))
+		return nil, fmt.Errorf("%w: %v", ErrAuthUnavailable, err)
+	}
+	if i.failures > 0 {
+		log.Printf("✅ Discovered OIDC issuer %s after %d failed attempts", i.url, i.failures)
+	}
+	i.verifier = provider.Verifier(&oidc.Config{ClientID: i.clientID})
+	i.failures, i.lastErr, i.retryAt = 0, nil, time.Time{}
+	return i.verifier, nil
+}
+
+// Discover keeps trying to discover the issuer, waiting out the backoff
+// between attempts, until it succeeds or ctx is done.
+func (i *oidcIssuer) Discover(ctx context.Context) error {
+	for {
+		_, err := i.Verifier(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		i.mu.Lock()
+		wait, cause := time.Until(i.retryAt), i.lastErr
+		i.mu.Unlock()
+		log.Printf("⚠️ OIDC issuer %s is unavailable, retrying in %s: %v", i.url, wait.Round(time.Second), cause)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// Ready returns nil once the issuer has been discovered, and the reason it
+// hasn't been otherwise. It never starts a discovery itself.
+func (i *oidcIssuer) Ready() error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	switch {
+	case i.verifier != nil:
+		return nil
+	case i.lastErr != nil:
+		return fmt.Errorf("%w: %v", ErrAuthUnavailable, i.lastErr)
+	default:
+		return fmt.Errorf("%w: issuer not discovered yet", ErrAuthUnavailable)
+	}
+}
@@ -0,0 +1,89 @@
+// internal/service/oidc_test.go
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestOIDCDiscoveryDoesNotBlockReady holds the issuer's discovery document
+// back and checks that Ready answers meanwhile, and that the callers waiting
+// for the verifier share one discovery.
+func TestOIDCDiscoveryDoesNotBlockReady(t *testing.T) {
+	requested := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var discoveries atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" {
+			http.NotFound(w, r)
+			return
+		}
+		discoveries.Add(1)
+		requested <- struct{}{}
+		<-release
+		json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                                server.URL,
+			"authorization_endpoint":                server.URL + "/auth",
+			"token_endpoint":                        server.URL + "/token",
+			"jwks_uri":                              server.URL + "/certs",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	}))
+	t.Cleanup(server.Close)
+	finish := sync.OnceFunc(func() { close(release) })
+	t.Cleanup(finish) // Runs first, so a failing test doesn't hang in Close
+	issuer := newOIDCIssuer(server.URL, "client")
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := issuer.Verifier(context.Background())
+			errs <- err
+		}()
+	}
+	<-requested
+
+	ready := make(chan error, 1)
+	go func() { ready <- issuer.Ready() }()
+	select {
+	case err := <-ready:
+		if !errors.Is(err, ErrAuthUnavailable) {
+			t.Errorf("Ready during discovery = %v; want ErrAuthUnavailable", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Ready waited for the discovery in flight")
+	}
+
+	// A caller giving up doesn't wait for the discovery either.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := issuer.Verifier(ctx); !errors.Is(err, ErrAuthUnavailable) {
+		t.Errorf("Verifier with an expired context = %v; want ErrAuthUnavailable", err)
+	}
+
+	finish()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Verifier = %v", err)
+		}
+	}
+	if n := discoveries.Load(); n != 1 {
+		t.Errorf("discovered %d times; want once", n)
+	}
+	if err := issuer.Ready(); err != nil {
+		t.Errorf("Ready after discovery = %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+// internal/service/outbox.go
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+)
+
+const (
+	outboxLease       = 2 * time.Minute  // How long a claimed operation is left alone by other workers
+	outboxMaxAttempts = 6                // 10s, 20s, 40s, ... between attempts; undone after about 5 minutes
+	outboxBaseBackoff = 10 * time.Second // Wait after the first failed attempt, doubled after each one
+	outboxBatchSize   = 20               // Operations handled per ProcessOutbox run
+)
+
+func (s *eventService) GetOperation(ctx context.Context, id uint, userEmail string) (*OperationOutput, error) {
+	op, err := s.outboxRepo.GetOperation(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load operation: %w", err)
+	}
+	if op == nil || op.UserEmail != userEmail {
+		return nil, ErrOperationNotFound
+	}
+
+	output := toOperationOutput(op)
+	if op.Status == "committed" {
+		if meeting, err := s.meetingRepo.GetMeetingByID(ctx, op.MeetingID); err == nil && meeting != nil {
+			output.Event = meetingToEventOutput(meeting)
+		}
+	}
+	return output, nil
+}
+
+func (s *eventService) ProcessOutbox(ctx context.Context) error {
+	now := time.Now()
+	ops, err := s.outboxRepo.ListDueOperations(ctx, now, outboxBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load due operations: %w", err)
+	}
+	for i := range ops {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		claimed, err := s.outboxRepo.ClaimOperation(ctx, &ops[i], now, time.Now().Add(outboxLease))
+		if err != nil {
+			return fmt.Errorf("failed to claim operation: %w", err)
+		}
+		if !claimed {
+			continue // Another worker has it
+		}
+		if _, err := s.applyOperation(ctx, &ops[i]); err != nil {
+			log.Printf("❌ Outbox operation %d failed: %v", ops[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// applyOperation makes the change recorded in op in Google and then marks the
+// meeting and op committed in one transaction. A transient failure leaves op
+// pending for the worker; anything else, or too many attempts, undoes the
+// Google side and marks both failed, returning the cause.
+func (s *eventService) applyOperation(ctx context.Context, op *domain.OutboxOperation) (*OperationOutput, error) {
+	if op.Kind != "create_event" {
+		return s.failOperation(ctx, op, fmt.Errorf("unknown operation kind %q", op.Kind))
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, op.UserEmail)
+	if err != nil {
+		return s.retryOperation(ctx, op, fmt.Errorf("failed to load user: %w", err))
+	}
+	if user == nil {
+		return s.failOperation(ctx, op, fmt.Errorf("user not found"))
+	}
+
+	var event calendar.Event
+	if err := json.Unmarshal([]byte(op.Payload), &event); err != nil {
+		return s.failOperation(ctx, op, fmt.Errorf("failed to decode event: %w", err))
+	}
+
+	var created *calendar.Event
+	err = s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		created, err = service.Events.Insert(op.CalendarID, &event).ConferenceDataVersion(1).Do()
+		if isConflictError(err) {
+			// An earlier attempt got through before it failed; pick that up.
+			created, err = service.Events.Get(op.CalendarID, op.EventID).Do()
+		}
+		return err
+	})
+	if err != nil {
+		if isTransientError(err) {
+			return s.retryOperation(ctx, op, err)
+		}
+		return s.failOperation(ctx, op, err)
+	}
+
+	meeting, err := s.meetingRepo.GetMeetingByID(ctx, op.MeetingID)
+	if err != nil {
+		return s.retryOperation(ctx, op, fmt.Errorf("failed to load event from database: %w", err))
+	}
+	if meeting == nil {
+		return s.failOperation(ctx, op, fmt.Errorf("event was removed from the database before it was committed"))
+	}
+
+	applyEventToMeeting(meeting, created)
+	meeting.CommitState = "committed"
+	committed := *op
+	committed.Status = "committed"
+	committed.Attempts++
+	committed.LastError = ""
+	if err := s.outboxRepo.CommitOperation(ctx, meeting, &committed); err != nil {
+		// Google has the event; the retry only has to find it and commit.
+		return s.retryOperation(ctx, op, fmt.Errorf("failed to commit event in database: %w", err))
+	}
+	*op = committed
+	publishChanges(ctx, s.webhooks, nil, meeting)
+
+	output := toOperationOutput(op)
+	eventOutput := toEventOutput(created, op.UserEmail)
+	output.Event = &eventOutput
+	return output, nil
+}
+
+// retryOperation schedules another attempt, or gives up once op has used
+// all of them.
+func (s *eventService) retryOperation(ctx context.Context, op *domain.OutboxOperation, cause error) (*OperationOutput, error) {
+	op.Attempts++
+	if op.Attempts >= outboxMaxAttempts {
+		op.Attempts-- // failOperation counts it
+		return s.failOperation(ctx, op, cause)
+	}
+
+	op.LastError = cause.Error()
+	op.NextAttemptAt = time.Now().Add(outboxBaseBackoff << (op.Attempts - 1))
+	if err := s.outboxRepo.UpdateOperation(ctx, op); err != nil {
+		// The lease runs out and the worker tries again anyway.
+		log.Printf("⚠️ Failed to reschedule operation %d: %v", op.ID, err)
+	}
+	log.Printf("⚠️ Operation %d failed (attempt %d), retrying: %v", op.ID, op.Attempts, cause)
+	return toOperationOutput(op), nil
+}
+
+// failOperation undoes whatever op may have done in Google and marks it and
+// its meeting failed.
+func (s *eventService) failOperation(ctx context.Context, op *domain.OutboxOperation, cause error) (*OperationOutput, error) {
+	op.Attempts++
+	op.Status = "failed"
+	op.LastError = cause.Error()
+
+	// A timed out insert may still have gone through, so always try to
+	// remove the event. Its ID is known, and 404 means there is nothing to undo.
+	if user, err := s.userRepo.GetUserByEmail(ctx, op.UserEmail); err == nil && user != nil {
+		err := s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+			return service.Events.Delete(op.CalendarID, op.EventID).Do()
+		})
+		if err != nil && !isNotFoundError(err) {
+			log.Printf("⚠️ Failed to undo event %s of operation %d: %v", op.EventID, op.ID, err)
+		}
+	}
+
+	meeting, err := s.meetingRepo.GetMeetingByID(ctx, op.MeetingID)
+	if err == nil && meeting != nil {
+		meeting.CommitState = "failed"
+		err = s.outboxRepo.CommitOperation(ctx, meeting, op)
+	} else {
+		err = s.outboxRepo.UpdateOperation(ctx, op)
+	}
+	if err != nil {
+		log.Printf("⚠️ Failed to mark operation %d failed: %v", op.ID, err)
+	}
+	return toOperationOutput(op), cause
+}
+
+func toOperationOutput(op *domain.OutboxOperation) *OperationOutput {
+	return &OperationOutput{
+		OperationID: op.ID,
+		Status:      op.Status,
+		MeetingID:   op.MeetingID,
+		EventID:     op.EventID,
+		Attempts:    op.Attempts,
+		Error:       op.LastError,
+	}
+}
+
+// meetingToEventOutput describes a stored meeting the way the API describes
+// Google events.
+func meetingToEventOutput(meeting *domain.Meeting) *EventOutput {
+	output := &EventOutput{
+		Title:            meeting.Title,
+		Description:      meeting.Description,
+		StartTime:        meeting.StartTime,
+		EndTime:          meeting.EndTime,
+		AllDay:           meeting.AllDay,
+		Attendees:        toAttendeeOutputs(meeting.Attendees),
+		EventId:          meeting.EventID,
+		CreatedBy:        meeting.CreatedBy,
+		Recurrence:       meeting.Recurrence,
+		RecurringEventID: meeting.RecurringEventID,
+	}
+	if meeting.ConferenceURL != "" {
+		output.Conference = &ConferenceOutput{ConferenceID: meeting.ConferenceID, JoinURL: meeting.ConferenceURL}
+	}
+	return output
+}
+
+// isConflictError reports whether Google answered 409 Conflict, which an
+// insert gets when the event ID is taken.
+func isConflictError(err error) bool {
+	var apiErr *googleapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict
+}
+
+// isTransientError reports whether retrying err later may work: network
+// failures, rate limits and server errors.
+func isTransientError(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,40 @@
+// internal/service/pagination.go
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 250 // Largest page Google Calendar returns
+)
+
+// listQuery is the state behind an opaque list cursor.
+type listQuery struct {
+	CalendarID string    `json:"c"`
+	TimeMin    time.Time `json:"min"`
+	TimeMax    time.Time `json:"max"`
+	Query      string    `json:"q,omitempty"`
+	PageToken  string    `json:"p"` // Google's nextPageToken
+}
+
+func encodeCursor(q listQuery) string {
+	data, _ := json.Marshal(q) // Cannot fail for this struct
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) (listQuery, error) {
+	var q listQuery
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return q, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
+	}
+	if err := json.Unmarshal(data, &q); err != nil || q.PageToken == "" {
+		return q, ErrInvalidCursor
+	}
+	return q, nil
+}
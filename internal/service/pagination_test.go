@@ -0,0 +1,111 @@
+// internal/service/pagination_test.go
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	q := listQuery{
+		CalendarID: "team@group.calendar.google.com",
+		TimeMin:    time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC),
+		TimeMax:    time.Date(2030, 3, 11, 0, 0, 0, 0, time.UTC),
+		Query:      "standup",
+		PageToken:  "token",
+	}
+	got, err := decodeCursor(encodeCursor(q))
+	if err != nil || got != q {
+		t.Errorf("decodeCursor(encodeCursor(q)) = %+v, %v; want %+v", got, err, q)
+	}
+
+	for name, cursor := range map[string]string{
+		"not base64":    "not a cursor!",
+		"not JSON":      base64.RawURLEncoding.EncodeToString([]byte("page 2")),
+		"no page token": encodeCursor(listQuery{CalendarID: "primary"}),
+	} {
+		if _, err := decodeCursor(cursor); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decodeCursor of %s = %v; want ErrInvalidCursor", name, err)
+		}
+	}
+}
+
+// TestListEventsPages pages through a week holding a plain event, an
+// all-day event and a series, two events at a time.
+func TestListEventsPages(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	user := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	monday := time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC)
+	f.insert(t, user, &calendar.Event{
+		Summary: "Planning",
+		Start:   &calendar.EventDateTime{DateTime: monday.Add(9 * time.Hour).Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: monday.Add(10 * time.Hour).Format(time.RFC3339)},
+	})
+	f.insert(t, user, &calendar.Event{
+		Summary: "Offsite",
+		Start:   &calendar.EventDateTime{Date: "2030-03-06"},
+		End:     &calendar.EventDateTime{Date: "2030-03-08"},
+	})
+	f.insert(t, user, &calendar.Event{
+		Summary:    "Standup",
+		Start:      &calendar.EventDateTime{DateTime: "2030-03-04T08:30:00+01:00", TimeZone: "Europe/Berlin"},
+		End:        &calendar.EventDateTime{DateTime: "2030-03-04T08:45:00+01:00", TimeZone: "Europe/Berlin"},
+		Recurrence: []string{"RRULE:FREQ=DAILY;COUNT=3"},
+	})
+
+	input := ListEventsInput{UserEmail: user.Email, TimeMin: monday, TimeMax: monday.AddDate(0, 0, 7), MaxResults: 2}
+	var titles []string
+	var allDay []string
+	for page := 1; ; page++ {
+		output, err := f.events.ListEvents(ctx, input)
+		if err != nil {
+			t.Fatalf("ListEvents page %d: %v", page, err)
+		}
+		if len(output.Events) > 2 {
+			t.Errorf("page %d has %d events; want at most 2", page, len(output.Events))
+		}
+		for _, event := range output.Events {
+			titles = append(titles, event.Title)
+			if event.AllDay {
+				allDay = append(allDay, event.Title)
+			}
+		}
+		if output.NextCursor == "" {
+			break
+		}
+		if page > 5 {
+			t.Fatal("the listing doesn't end")
+		}
+		// The cursor carries the filters; these must not matter.
+		input = ListEventsInput{UserEmail: user.Email, TimeMin: monday.AddDate(1, 0, 0), Query: "nothing", Cursor: output.NextCursor, MaxResults: 2}
+	}
+
+	slices.Sort(titles)
+	if want := []string{"Offsite", "Planning", "Standup", "Standup", "Standup"}; !slices.Equal(titles, want) {
+		t.Errorf("listed %q; want %q", titles, want)
+	}
+	if !slices.Equal(allDay, []string{"Offsite"}) {
+		t.Errorf("all-day events = %q; want Offsite", allDay)
+	}
+}
+
+func TestListEventsRejectsBadInput(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	user := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+
+	if _, err := f.events.ListEvents(ctx, ListEventsInput{UserEmail: user.Email, Cursor: "garbage"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("ListEvents with a garbage cursor = %v; want ErrInvalidCursor", err)
+	}
+	now := time.Now()
+	if _, err := f.events.ListEvents(ctx, ListEventsInput{UserEmail: user.Email, TimeMin: now, TimeMax: now.Add(-time.Hour)}); !errors.Is(err, ErrInvalidTime) {
+		t.Errorf("ListEvents with time_max before time_min = %v; want ErrInvalidTime", err)
+	}
+}
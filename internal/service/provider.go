@@ -0,0 +1,123 @@
+// internal/service/provider.go
+package service
+
+import (
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"sort"
+	"strings"
+)
+
+// CalendarProviders holds the calendar providers this server is set up for,
+// by name.
+type CalendarProviders map[string]CalendarProvider
+
+// NewCalendarProviders collects the configured providers. Microsoft is left
+// out unless its OAuth client is configured, CalDAV unless it is enabled.
+func NewCalendarProviders(google *googleCalendarProvider, microsoft *microsoftProvider, caldav *caldavProvider) CalendarProviders {
+	providers := CalendarProviders{ProviderGoogle: google}
+	if microsoft.oauthConfig != nil {
+		providers[ProviderMicrosoft] = microsoft
+	}
+	if caldav.enabled {
+		providers[ProviderCalDAV] = caldav
+	}
+	return providers
+}
+
+// For returns the provider new events of user go to.
+func (p CalendarProviders) For(user *domain.User) (CalendarProvider, error) {
+	return p.Get(user.CalendarProvider)
+}
+
+// Get returns the provider called name; empty means Google, as for rows
+// stored before there was a choice.
+func (p CalendarProviders) Get(name string) (CalendarProvider, error) {
+	if name == "" {
+		name = ProviderGoogle
+	}
+	provider, ok := p[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not configured", ErrProviderUnsupported, name)
+	}
+	return provider, nil
+}
+
+// Names returns the names of the configured providers, sorted.
+func (p CalendarProviders) Names() []string {
+	names := make([]string, 0, len(p))
+	for name := range p {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// isGoogle reports whether provider is Google, the only one with sync, push
+// notifications and the outbox.
+func isGoogle(provider CalendarProvider) bool {
+	return provider.Name() == ProviderGoogle
+}
+
+// applyProviderEvent copies the provider's state of an event onto a stored
+// meeting.
+func applyProviderEvent(meeting *domain.Meeting, event *ProviderEvent) {
+	meeting.EventID = event.EventId
+	meeting.ICalUID = event.ICalUID
+	meeting.Title = event.Title
+	meeting.Description = event.Description
+	meeting.StartTime = event.StartTime
+	meeting.EndTime = event.EndTime
+	meeting.AllDay = event.AllDay
+	meeting.TimeZone = event.TimeZone
+	meeting.Attendees = fromAttendeeOutputs(event.Attendees)
+	meeting.Recurrence = event.Recurrence
+	meeting.RecurringEventID = event.RecurringEventID
+	meeting.OriginalStartTime = event.OriginalStartTime
+	meeting.Status = event.Status
+	meeting.ConferenceID, meeting.ConferenceURL = "", ""
+	if event.Conference != nil {
+		meeting.ConferenceID = event.Conference.ConferenceID
+		meeting.ConferenceURL = event.Conference.JoinURL
+	}
+	if meeting.Status == "" {
+		meeting.Status = "confirmed"
+	}
+}
+
+// fromAttendeeOutputs turns API attendees back into attendee rows.
+func fromAttendeeOutputs(outputs []AttendeeOutput) []domain.Attendee {
+	attendees := make([]domain.Attendee, 0, len(outputs))
+	for _, a := range outputs {
+		status := a.ResponseStatus
+		if status == "" {
+			status = "needsAction"
+		}
+		attendees = append(attendees, domain.Attendee{
+			Email:          a.Email,
+			Name:           a.Name,
+			Optional:       a.Optional,
+			Organizer:      a.Organizer,
+			ResponseStatus: status,
+		})
+	}
+	return attendees
+}
+
+// attendeeOutputs turns a list of emails into attendees that haven't
+// answered yet, keeping what current knows about the ones it has.
+func attendeeOutputs(emails []string, current []AttendeeOutput) []AttendeeOutput {
+	known := make(map[string]AttendeeOutput, len(current))
+	for _, a := range current {
+		known[strings.ToLower(a.Email)] = a
+	}
+	outputs := make([]AttendeeOutput, 0, len(emails))
+	for _, email := range emails {
+		if a, ok := known[strings.ToLower(email)]; ok {
+			outputs = append(outputs, a)
+			continue
+		}
+		outputs = append(outputs, AttendeeOutput{Email: email, ResponseStatus: "needsAction"})
+	}
+	return outputs
+}
@@ -0,0 +1,194 @@
+// internal/service/reconcile.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+	"log"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+type reconcileService struct {
+	meetingRepo repository.MeetingRepository
+	userRepo    repository.UserRepository
+	clients     GoogleClientProvider
+	webhooks    WebhookService
+}
+
+// NewReconcileService creates a new ReconcileService instance.
+func NewReconcileService(meetingRepo repository.MeetingRepository, userRepo repository.UserRepository, clients GoogleClientProvider, webhooks WebhookService) *reconcileService {
+	return &reconcileService{
+		meetingRepo: meetingRepo,
+		userRepo:    userRepo,
+		clients:     clients,
+		webhooks:    webhooks,
+	}
+}
+
+// Reconcile looks up every stored meeting in Google, which is taken to be
+// right. Unless it is a dry run, rows of deleted events and orphans are
+// removed and modified rows are overwritten with what Google has.
+func (s *reconcileService) Reconcile(ctx context.Context, input ReconcileInput) (*ReconcileReport, error) {
+	report := &ReconcileReport{StartedAt: time.Now().UTC(), DryRun: input.DryRun, Items: []ReconcileItem{}}
+
+	owners := []string{input.UserEmail}
+	if input.UserEmail == "" {
+		var err error
+		if owners, err = s.meetingRepo.ListMeetingOwners(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load meeting owners: %w", err)
+		}
+	}
+
+	for _, owner := range owners {
+		if err := s.reconcileUser(ctx, owner, input.DryRun, report); err != nil {
+			return nil, err
+		}
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	return report, nil
+}
+
+func (s *reconcileService) reconcileUser(ctx context.Context, userEmail string, dryRun bool, report *ReconcileReport) error {
+	meetings, err := s.meetingRepo.ListMeetingsByOwner(ctx, userEmail)
+	if err != nil {
+		return fmt.Errorf("failed to load meetings of %s: %w", userEmail, err)
+	}
+	if len(meetings) == 0 {
+		return nil
+	}
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil {
+		return fmt.Errorf("failed to load user %s: %w", userEmail, err)
+	}
+
+	for i := range meetings {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		meeting := &meetings[i]
+		if meeting.CommitState == "pending" {
+			continue // The outbox is still working on it
+		}
+		if meeting.Provider != "" && meeting.Provider != ProviderGoogle {
+			continue // Only Google events are reconciled
+		}
+		report.Checked++
+
+		item := ReconcileItem{
+			MeetingID:  meeting.ID,
+			EventID:    meeting.EventID,
+			CalendarID: meeting.CalendarID,
+			UserEmail:  userEmail,
+			Action:     "none",
+		}
+		switch {
+		case user == nil:
+			item.Kind, item.Reason = "orphaned", "user no longer exists"
+		case meeting.EventID == "":
+			item.Kind, item.Reason = "orphaned", "no Google event ID"
+		case meeting.CommitState == "failed":
+			item.Kind, item.Reason = "orphaned", "creating the Google event failed"
+		}
+		if item.Kind != "" {
+			s.fix(ctx, meeting, nil, dryRun, &item)
+			report.add(item)
+			continue
+		}
+
+		event, err := s.lookup(ctx, user, meeting)
+		if err != nil {
+			item.Kind, item.Error = "error", err.Error()
+			report.add(item)
+			continue
+		}
+		if event == nil || (event.Status == "cancelled" && meeting.RecurringEventID == "") {
+			item.Kind = "deleted"
+			s.fix(ctx, meeting, nil, dryRun, &item)
+			report.add(item)
+			continue
+		}
+
+		output := toEventOutput(event, userEmail)
+		drift := compareMeeting(meeting, &output)
+		if meeting.Status != event.Status && event.Status != "" {
+			drift = append(drift, FieldDrift{Field: "status", Stored: meeting.Status, Google: event.Status})
+		}
+		if len(drift) > 0 {
+			item.Kind, item.Drift = "modified", drift
+			s.fix(ctx, meeting, event, dryRun, &item)
+			report.add(item)
+		}
+	}
+	return nil
+}
+
+// lookup fetches the Google event of meeting. A nil event means Google no
+// longer has it.
+func (s *reconcileService) lookup(ctx context.Context, user *domain.User, meeting *domain.Meeting) (*calendar.Event, error) {
+	calendarID := meeting.CalendarID
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+	var event *calendar.Event
+	err := s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		var err error
+		event, err = service.Events.Get(calendarID, meeting.EventID).Context(ctx).Do()
+		return err
+	})
+	if isNotFoundError(err) {
+		return nil, nil
+	}
+	return event, err
+}
+
+// fix applies the outcome of item unless this is a dry run: an event from
+// Google overwrites the row, without one the row is removed.
+func (s *reconcileService) fix(ctx context.Context, meeting *domain.Meeting, event *calendar.Event, dryRun bool, item *ReconcileItem) {
+	if dryRun {
+		return
+	}
+
+	before := *meeting
+	if event != nil {
+		applyEventToMeeting(meeting, event)
+		if err := s.meetingRepo.UpdateMeeting(ctx, meeting); err != nil {
+			item.Error = fmt.Sprintf("failed to update row: %v", err)
+			return
+		}
+		item.Action = "updated_row"
+		publishChanges(ctx, s.webhooks, &before, meeting)
+		return
+	}
+
+	if err := s.meetingRepo.DeleteMeeting(ctx, meeting); err != nil {
+		item.Error = fmt.Sprintf("failed to delete row: %v", err)
+		return
+	}
+	item.Action = "deleted_row"
+	if item.Kind == "deleted" {
+		meeting.Status = "cancelled"
+		publishChanges(ctx, s.webhooks, &before, meeting)
+	}
+}
+
+// add records item and counts it.
+func (r *ReconcileReport) add(item ReconcileItem) {
+	switch item.Kind {
+	case "deleted":
+		r.Deleted++
+	case "modified":
+		r.Modified++
+	case "orphaned":
+		r.Orphaned++
+	}
+	if item.Error != "" {
+		r.Errors++
+		log.Printf("⚠️ Reconcile of meeting %d (%s) failed: %s", item.MeetingID, item.EventID, item.Error)
+	}
+	r.Items = append(r.Items, item)
+}
@@ -0,0 +1,223 @@
+// internal/service/recurrence.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google-calendar-api/internal/domain"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// resolveScope checks the requested scope against the kind of event the ID
+// points to, an occurrence of recurringEventID or a series with recurrence
+// rules, and fills in the default. Plain events always resolve to ScopeAll.
+func resolveScope(scope RecurrenceScope, recurringEventID string, recurrence []string) (RecurrenceScope, error) {
+	switch scope {
+	case "", ScopeInstance, ScopeFollowing, ScopeAll:
+	default:
+		return "", fmt.Errorf("%w: unknown scope %q", ErrInvalidScope, scope)
+	}
+
+	isInstance := recurringEventID != ""
+	isMaster := len(recurrence) > 0
+
+	switch {
+	case isInstance:
+		if scope == "" {
+			return ScopeInstance, nil
+		}
+		return scope, nil
+	case isMaster:
+		if scope == ScopeInstance || scope == ScopeFollowing {
+			return "", fmt.Errorf("%w: use the ID of an occurrence to change part of a series", ErrInvalidScope)
+		}
+		return ScopeAll, nil
+	default:
+		if scope != "" && scope != ScopeAll {
+			return "", ErrInvalidScope
+		}
+		return ScopeAll, nil
+	}
+}
+
+// validateRecurrence checks that every line is an RRULE, EXRULE, RDATE or EXDATE
+// property as Google Calendar expects them.
+func validateRecurrence(lines []string) error {
+	for _, line := range lines {
+		upper := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(upper, "RRULE:"), strings.HasPrefix(upper, "EXRULE:"):
+			parts := ruleParts(line)
+			if parts["FREQ"] == "" {
+				return fmt.Errorf("%w: %q has no FREQ", ErrInvalidRule, line)
+			}
+			if parts["COUNT"] != "" && parts["UNTIL"] != "" {
+				return fmt.Errorf("%w: %q has both COUNT and UNTIL", ErrInvalidRule, line)
+			}
+			if parts["COUNT"] != "" {
+				if n, err := strconv.Atoi(parts["COUNT"]); err != nil || n < 1 {
+					return fmt.Errorf("%w: %q has an invalid COUNT", ErrInvalidRule, line)
+				}
+			}
+		case strings.HasPrefix(upper, "RDATE"), strings.HasPrefix(upper, "EXDATE"):
+			if !strings.Contains(line, ":") {
+				return fmt.Errorf("%w: %q has no value", ErrInvalidRule, line)
+			}
+		default:
+			return fmt.Errorf("%w: %q must start with RRULE, EXRULE, RDATE or EXDATE", ErrInvalidRule, line)
+		}
+	}
+	return nil
+}
+
+// ruleParts splits "RRULE:FREQ=WEEKLY;COUNT=3" into its upper-cased keys.
+func ruleParts(line string) map[string]string {
+	parts := make(map[string]string)
+	_, body, _ := strings.Cut(line, ":")
+	for _, part := range strings.Split(body, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if ok {
+			parts[strings.ToUpper(key)] = value
+		}
+	}
+	return parts
+}
+
+// replaceRuleParts rewrites the RRULE lines with fn, leaving other lines as they are.
+func replaceRuleParts(lines []string, fn func(parts []string) []string) []string {
+	var out []string
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.ToUpper(line), "RRULE:") {
+			out = append(out, line)
+			continue
+		}
+		_, body, _ := strings.Cut(line, ":")
+		out = append(out, "RRULE:"+strings.Join(fn(strings.Split(body, ";")), ";"))
+	}
+	return out
+}
+
+// truncateRecurrence ends the series just before splitAt. All-day series need
+// UNTIL as a plain date.
+func truncateRecurrence(lines []string, splitAt time.Time, allDay bool) []string {
+	until := "UNTIL=" + splitAt.Add(-time.Second).UTC().Format("20060102T150405Z")
+	if allDay {
+		until = "UNTIL=" + splitAt.AddDate(0, 0, -1).Format("20060102")
+	}
+	return replaceRuleParts(lines, func(parts []string) []string {
+		var kept []string
+		for _, part := range parts {
+			key, _, _ := strings.Cut(part, "=")
+			if !strings.EqualFold(key, "COUNT") && !strings.EqualFold(key, "UNTIL") {
+				kept = append(kept, part)
+			}
+		}
+		return append(kept, until)
+	})
+}
+
+// continueRecurrence returns the rules for a series that carries on after the
+// first `before` occurrences of the original, so a COUNT still adds up.
+func continueRecurrence(lines []string, before int) []string {
+	return replaceRuleParts(lines, func(parts []string) []string {
+		for i, part := range parts {
+			key, value, _ := strings.Cut(part, "=")
+			if strings.EqualFold(key, "COUNT") {
+				if n, err := strconv.Atoi(value); err == nil {
+					parts[i] = "COUNT=" + strconv.Itoa(n-before)
+				}
+			}
+		}
+		return parts
+	})
+}
+
+// originalStart is the start an occurrence has according to the series rules.
+func originalStart(instance *calendar.Event) time.Time {
+	if instance.OriginalStartTime != nil {
+		if t, _, err := parseEventDateTime(instance.OriginalStartTime); err == nil {
+			return t
+		}
+	}
+	t, _, _ := parseEventDateTime(instance.Start)
+	return t
+}
+
+// countInstancesBefore counts the occurrences of a series, cancelled ones
+// included, that originally start before splitAt.
+func countInstancesBefore(ctx context.Context, service *calendar.Service, calendarID string, master *calendar.Event, splitAt time.Time) (int, error) {
+	count := 0
+	err := service.Events.Instances(calendarID, master.Id).
+		ShowDeleted(true).
+		TimeMax(splitAt.Add(time.Second).Format(time.RFC3339)).
+		Pages(ctx, func(page *calendar.Events) error {
+			for _, item := range page.Items {
+				if originalStart(item).Before(splitAt) {
+					count++
+				}
+			}
+			return nil
+		})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list occurrences: %w", err)
+	}
+	return count, nil
+}
+
+// splitSeries applies change to instance and every later occurrence. The
+// original series is ended before instance and a new series carrying the
+// change takes over from there.
+func (s *eventService) splitSeries(ctx context.Context, service *calendar.Service, owner *domain.Meeting, master, instance *calendar.Event, change eventChange) error {
+	splitAt := originalStart(instance)
+	before, err := countInstancesBefore(ctx, service, owner.CalendarID, master, splitAt)
+	if err != nil {
+		return err
+	}
+	// From the first occurrence on, "this and following" is the whole series.
+	if before == 0 {
+		updated, err := writeEvent(service, owner.CalendarID, master, change.relativeTo(toEventOutput(instance, ""), toEventOutput(master, "")))
+		if err != nil {
+			return err
+		}
+		return s.saveMeeting(ctx, owner, updated)
+	}
+
+	following := *master
+	following.Id = ""
+	following.ICalUID = ""
+	following.Etag = ""
+	following.Start = instance.Start
+	following.End = instance.End
+	following.Recurrence = continueRecurrence(master.Recurrence, before)
+	change.applyTo(&following)
+
+	// Version 1 carries the series' Meet link over to the new series.
+	created, err := service.Events.Insert(owner.CalendarID, &following).ConferenceDataVersion(1).Do()
+	if err != nil {
+		return fmt.Errorf("failed to create following series: %w", err)
+	}
+
+	truncated, err := service.Events.Patch(owner.CalendarID, master.Id, &calendar.Event{
+		Recurrence: truncateRecurrence(master.Recurrence, splitAt, master.Start.Date != ""),
+	}).Do()
+	if err != nil {
+		// Undo the new series so the occurrences are not there twice.
+		if delErr := service.Events.Delete(owner.CalendarID, created.Id).Do(); delErr != nil {
+			return fmt.Errorf("failed to end original series: %w (cleanup of %s also failed: %v)", err, created.Id, delErr)
+		}
+		return fmt.Errorf("failed to end original series: %w", err)
+	}
+
+	if err := s.saveMeeting(ctx, owner, truncated); err != nil {
+		return err
+	}
+	if err := s.deleteExceptionsFrom(ctx, owner.CreatedBy, master.Id, splitAt); err != nil {
+		return err
+	}
+	return s.saveMeeting(ctx, owner, created)
+}
@@ -0,0 +1,173 @@
+// internal/service/recurrence_test.go
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestResolveScope(t *testing.T) {
+	rules := []string{"RRULE:FREQ=DAILY"}
+	for _, tt := range []struct {
+		name       string
+		scope      RecurrenceScope
+		recurring  string
+		recurrence []string
+		want       RecurrenceScope
+		wantErr    bool
+	}{
+		{"occurrence defaults to itself", "", "series", nil, ScopeInstance, false},
+		{"occurrence and following", ScopeFollowing, "series", nil, ScopeFollowing, false},
+		{"occurrence and its series", ScopeAll, "series", nil, ScopeAll, false},
+		{"series", "", "", rules, ScopeAll, false},
+		{"series by instance", ScopeInstance, "", rules, "", true},
+		{"series by following", ScopeFollowing, "", rules, "", true},
+		{"plain event", "", "", nil, ScopeAll, false},
+		{"plain event by instance", ScopeInstance, "", nil, "", true},
+		{"unknown", "some", "series", nil, "", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveScope(tt.scope, tt.recurring, tt.recurrence)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidScope) {
+					t.Errorf("resolveScope = %q, %v; want ErrInvalidScope", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("resolveScope = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateRecurrence(t *testing.T) {
+	splitAt := time.Date(2030, 3, 18, 9, 0, 0, 0, time.FixedZone("CET", 3600))
+	for _, tt := range []struct {
+		name   string
+		lines  []string
+		allDay bool
+		want   []string
+	}{
+		{
+			name:  "count",
+			lines: []string{"RRULE:FREQ=WEEKLY;COUNT=10;BYDAY=MO", "EXDATE;TZID=Europe/Berlin:20300311T090000"},
+			want:  []string{"RRULE:FREQ=WEEKLY;BYDAY=MO;UNTIL=20300318T075959Z", "EXDATE;TZID=Europe/Berlin:20300311T090000"},
+		},
+		{
+			name:  "until",
+			lines: []string{"RRULE:FREQ=DAILY;UNTIL=20301231T000000Z"},
+			want:  []string{"RRULE:FREQ=DAILY;UNTIL=20300318T075959Z"},
+		},
+		{
+			name:   "all day",
+			lines:  []string{"RRULE:FREQ=DAILY;COUNT=5"},
+			allDay: true,
+			want:   []string{"RRULE:FREQ=DAILY;UNTIL=20300317"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateRecurrence(tt.lines, splitAt, tt.allDay); !slices.Equal(got, tt.want) {
+				t.Errorf("truncateRecurrence = %q; want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContinueRecurrence(t *testing.T) {
+	got := continueRecurrence([]string{"RRULE:FREQ=WEEKLY;COUNT=10", "RDATE:20300401T090000Z"}, 3)
+	if want := []string{"RRULE:FREQ=WEEKLY;COUNT=7", "RDATE:20300401T090000Z"}; !slices.Equal(got, want) {
+		t.Errorf("continueRecurrence with COUNT = %q; want %q", got, want)
+	}
+	until := []string{"RRULE:FREQ=WEEKLY;UNTIL=20301231T000000Z"}
+	if got := continueRecurrence(until, 3); !slices.Equal(got, until) {
+		t.Errorf("continueRecurrence with UNTIL = %q; want it unchanged", got)
+	}
+}
+
+// TestPatchFollowingSplitsSeries changes an occurrence "and following" of a
+// series kept in Berlin time, which ends the series before it and starts a
+// new one carrying the rest of the COUNT.
+func TestPatchFollowingSplitsSeries(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	user := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	series := f.insert(t, user, &calendar.Event{
+		Summary:    "Weekly",
+		Start:      &calendar.EventDateTime{DateTime: "2030-03-04T09:00:00+01:00", TimeZone: "Europe/Berlin"},
+		End:        &calendar.EventDateTime{DateTime: "2030-03-04T10:00:00+01:00", TimeZone: "Europe/Berlin"},
+		Recurrence: []string{"RRULE:FREQ=WEEKLY;COUNT=4"},
+	})
+	third := f.instances(t, user, series.Id)[2]
+
+	title := "Later"
+	err := f.events.PatchEvent(ctx, PatchEventInput{EventID: third.Id, Title: &title, Scope: ScopeFollowing, UpdatedBy: user.Email})
+	if err != nil {
+		t.Fatalf("PatchEvent: %v", err)
+	}
+
+	var master, following *calendar.Event
+	for _, event := range f.fake.Events(user.Email) {
+		switch {
+		case event.Id == series.Id:
+			master = event
+		case len(event.Recurrence) > 0:
+			following = event
+		}
+	}
+	if master == nil || following == nil {
+		t.Fatalf("Google has %+v, %+v; want the series and its continuation", master, following)
+	}
+	if want := []string{"RRULE:FREQ=WEEKLY;UNTIL=20300318T075959Z"}; !slices.Equal(master.Recurrence, want) || master.Summary != "Weekly" {
+		t.Errorf("series = %q %q; want %q ending before the split", master.Summary, master.Recurrence, want)
+	}
+	if want := []string{"RRULE:FREQ=WEEKLY;COUNT=2"}; !slices.Equal(following.Recurrence, want) || following.Summary != "Later" {
+		t.Errorf("continuation = %q %q; want \"Later\" %q", following.Summary, following.Recurrence, want)
+	}
+	if following.Start.DateTime != third.Start.DateTime || following.Start.TimeZone != "Europe/Berlin" {
+		t.Errorf("continuation starts %+v; want %s in Europe/Berlin", following.Start, third.Start.DateTime)
+	}
+
+	stored, err := f.meetings.ListMeetingsByOwner(ctx, user.Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, meeting := range stored {
+		titles = append(titles, meeting.Title)
+	}
+	if slices.Sort(titles); !slices.Equal(titles, []string{"Later", "Weekly"}) {
+		t.Errorf("stored meetings = %q; want both series", titles)
+	}
+}
+
+// TestDeleteFollowingAllDay ends an all-day series before an occurrence,
+// which needs UNTIL as a date.
+func TestDeleteFollowingAllDay(t *testing.T) {
+	f := newGoogleFixture(t)
+	user := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	series := f.insert(t, user, &calendar.Event{
+		Summary:    "Offsite",
+		Start:      &calendar.EventDateTime{Date: "2030-03-04"},
+		End:        &calendar.EventDateTime{Date: "2030-03-05"},
+		Recurrence: []string{"RRULE:FREQ=DAILY;COUNT=5"},
+	})
+	third := f.instances(t, user, series.Id)[2]
+
+	if err := f.events.DeleteEvent(context.Background(), third.Id, user.Email, ScopeFollowing); err != nil {
+		t.Fatalf("DeleteEvent: %v", err)
+	}
+	if got := f.instances(t, user, series.Id); len(got) != 2 {
+		t.Errorf("%d occurrences left; want the first two", len(got))
+	}
+	for _, event := range f.fake.Events(user.Email) {
+		if want := []string{"RRULE:FREQ=DAILY;UNTIL=20300305"}; event.Id == series.Id && !slices.Equal(event.Recurrence, want) {
+			t.Errorf("series recurrence = %q; want %q", event.Recurrence, want)
+		}
+	}
+}
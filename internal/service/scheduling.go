@@ -0,0 +1,212 @@
+// internal/service/scheduling.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	slotStep          = 15 * time.Minute    // Candidate slots start on quarter hours
+	maxSearchWindow   = 62 * 24 * time.Hour // Longest window Google answers free/busy for
+	proximityHorizon  = 2 * time.Hour       // Meetings further away than this earn no bonus
+	lunchStart        = 12 * time.Hour      // Lunch hour in the requested time zone
+	lunchEnd          = 13 * time.Hour
+	defaultSuggestion = 10
+)
+
+// Score weights. A slot everyone can attend starts at 100.
+const (
+	availabilityWeight = 100.0
+	proximityWeight    = 20.0 // Added when the slot sits right next to existing meetings
+	lunchPenalty       = 30.0 // Taken off when the slot fully covers lunch
+)
+
+func (s *eventService) SuggestTimes(ctx context.Context, input SuggestTimesInput) (*SuggestTimesOutput, error) {
+	if input.Duration <= 0 {
+		return nil, fmt.Errorf("%w: duration must be positive", ErrInvalidTime)
+	}
+	if !input.TimeMin.Before(input.TimeMax) {
+		return nil, ErrInvalidTime
+	}
+	if input.TimeMax.Sub(input.TimeMin) > maxSearchWindow {
+		return nil, fmt.Errorf("%w: search window is longer than %d days", ErrInvalidTime, int(maxSearchWindow.Hours()/24))
+	}
+	if input.WorkdayStart >= input.WorkdayEnd || input.WorkdayEnd > 24*time.Hour {
+		return nil, fmt.Errorf("%w: working hours must start before they end", ErrInvalidTime)
+	}
+	loc := time.UTC
+	if input.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(input.TimeZone); err != nil {
+			return nil, fmt.Errorf("%w: unknown time zone %q", ErrInvalidTime, input.TimeZone)
+		}
+	}
+
+	// The organizer has to be there too.
+	participants := append([]string{input.UserEmail}, input.Attendees...)
+	freeBusy, err := s.FreeBusy(ctx, FreeBusyInput{
+		UserEmail: input.UserEmail,
+		Calendars: participants,
+		TimeMin:   input.TimeMin,
+		TimeMax:   input.TimeMax,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// People whose calendar could not be read cannot be counted either way.
+	output := &SuggestTimesOutput{Suggestions: []TimeSuggestion{}}
+	busy := make(map[string][]TimeRange)
+	for id, availability := range freeBusy.Calendars {
+		if len(availability.Errors) > 0 {
+			output.Unreadable = append(output.Unreadable, id)
+			continue
+		}
+		busy[id] = availability.Busy
+	}
+	sort.Strings(output.Unreadable)
+	if len(busy) == 0 {
+		return output, nil
+	}
+
+	quorum := input.Quorum
+	if quorum <= 0 || quorum > len(busy) {
+		quorum = len(busy)
+	}
+
+	for _, slot := range candidateSlots(input, loc) {
+		suggestion := TimeSuggestion{Start: slot.Start, End: slot.End, Available: []string{}, Unavailable: []string{}}
+		for id, intervals := range busy {
+			if overlapsAny(slot, intervals) {
+				suggestion.Unavailable = append(suggestion.Unavailable, id)
+			} else {
+				suggestion.Available = append(suggestion.Available, id)
+			}
+		}
+		if len(suggestion.Available) < quorum {
+			continue
+		}
+		sort.Strings(suggestion.Available)
+		sort.Strings(suggestion.Unavailable)
+		suggestion.Score = scoreSlot(slot, suggestion.Available, busy, loc)
+		output.Suggestions = append(output.Suggestions, suggestion)
+	}
+
+	sort.SliceStable(output.Suggestions, func(i, j int) bool {
+		a, b := output.Suggestions[i], output.Suggestions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Start.Before(b.Start)
+	})
+	limit := input.MaxResults
+	if limit <= 0 {
+		limit = defaultSuggestion
+	}
+	if len(output.Suggestions) > limit {
+		output.Suggestions = output.Suggestions[:limit]
+	}
+	return output, nil
+}
+
+// candidateSlots lists every slot of the requested length that lies inside
+// both the search window and the working hours of a day.
+func candidateSlots(input SuggestTimesInput, loc *time.Location) []TimeRange {
+	var slots []TimeRange
+	first := input.TimeMin.In(loc)
+	day := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, loc)
+	for ; day.Before(input.TimeMax); day = day.AddDate(0, 0, 1) {
+		if !input.IncludeWeekends && (day.Weekday() == time.Saturday || day.Weekday() == time.Sunday) {
+			continue
+		}
+		workStart := atOffset(day, input.WorkdayStart)
+		workEnd := atOffset(day, input.WorkdayEnd)
+		for start := workStart; !start.Add(input.Duration).After(workEnd); start = start.Add(slotStep) {
+			end := start.Add(input.Duration)
+			if start.Before(input.TimeMin) || end.After(input.TimeMax) {
+				continue
+			}
+			slots = append(slots, TimeRange{Start: start, End: end})
+		}
+	}
+	return slots
+}
+
+// scoreSlot rates a slot by how many people can come, how close it sits to
+// their existing meetings and how much of the lunch hour it takes.
+func scoreSlot(slot TimeRange, available []string, busy map[string][]TimeRange, loc *time.Location) float64 {
+	score := availabilityWeight * float64(len(available)) / float64(len(busy))
+
+	// Clustering meetings leaves longer blocks of focus time.
+	var closeness float64
+	for _, id := range available {
+		gap := nearestGap(slot, busy[id])
+		if gap < proximityHorizon {
+			closeness += 1 - float64(gap)/float64(proximityHorizon)
+		}
+	}
+	if len(available) > 0 {
+		score += proximityWeight * closeness / float64(len(available))
+	}
+
+	local := slot.Start.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	lunch := TimeRange{Start: atOffset(day, lunchStart), End: atOffset(day, lunchEnd)}
+	if overlap := overlapDuration(slot, lunch); overlap > 0 {
+		score -= lunchPenalty * float64(overlap) / float64(lunch.End.Sub(lunch.Start))
+	}
+	return score
+}
+
+// nearestGap is the time between slot and the closest busy interval. It is
+// proximityHorizon when there is none nearby.
+func nearestGap(slot TimeRange, intervals []TimeRange) time.Duration {
+	gap := proximityHorizon
+	for _, b := range intervals {
+		var d time.Duration
+		switch {
+		case !b.End.After(slot.Start):
+			d = slot.Start.Sub(b.End)
+		case !b.Start.Before(slot.End):
+			d = b.Start.Sub(slot.End)
+		}
+		if d < gap {
+			gap = d
+		}
+	}
+	return gap
+}
+
+func overlapsAny(slot TimeRange, intervals []TimeRange) bool {
+	for _, b := range intervals {
+		if overlapDuration(slot, b) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func overlapDuration(a, b TimeRange) time.Duration {
+	start, end := a.Start, a.End
+	if b.Start.After(start) {
+		start = b.Start
+	}
+	if b.End.Before(end) {
+		end = b.End
+	}
+	if end.After(start) {
+		return end.Sub(start)
+	}
+	return 0
+}
+
+// atOffset is the wall-clock time offset after midnight of day, which stays
+// correct on days with a DST change.
+func atOffset(day time.Time, offset time.Duration) time.Time {
+	hours := int(offset / time.Hour)
+	minutes := int(offset % time.Hour / time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), hours, minutes, 0, 0, day.Location())
+}
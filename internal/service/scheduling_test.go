@@ -0,0 +1,174 @@
+// internal/service/scheduling_test.go
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// TestCandidateSlots checks that working hours follow the wall clock in the
+// requested zone across the start of summer time, and that weekends are
+// skipped unless asked for.
+func TestCandidateSlots(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := SuggestTimesInput{
+		Duration:     time.Hour,
+		TimeMin:      time.Date(2030, 3, 29, 0, 0, 0, 0, berlin), // Friday; clocks go forward on Sunday
+		TimeMax:      time.Date(2030, 4, 2, 0, 0, 0, 0, berlin),
+		WorkdayStart: 9 * time.Hour,
+		WorkdayEnd:   11 * time.Hour,
+	}
+
+	var days []string
+	for _, slot := range candidateSlots(input, berlin) {
+		local := slot.Start.In(berlin)
+		if local.Minute() == 0 && local.Hour() == 9 {
+			days = append(days, local.Format("Mon"))
+		}
+		if local.Hour() < 9 || slot.End.In(berlin).After(time.Date(local.Year(), local.Month(), local.Day(), 11, 0, 0, 0, berlin)) {
+			t.Errorf("slot %s–%s lies outside working hours", slot.Start.In(berlin), slot.End.In(berlin))
+		}
+	}
+	if want := []string{"Fri", "Mon"}; !slices.Equal(days, want) {
+		t.Errorf("first slots on %q; want %q", days, want)
+	}
+	if n := len(candidateSlots(input, berlin)); n != 2*5 {
+		t.Errorf("%d slots; want 5 a day", n)
+	}
+
+	input.IncludeWeekends = true
+	var sunday []time.Time
+	for _, slot := range candidateSlots(input, berlin) {
+		if slot.Start.In(berlin).Weekday() == time.Sunday {
+			sunday = append(sunday, slot.Start)
+		}
+	}
+	if len(sunday) != 5 || !sunday[0].Equal(time.Date(2030, 3, 31, 7, 0, 0, 0, time.UTC)) {
+		t.Errorf("Sunday slots start %v; want five from 09:00 summer time", sunday)
+	}
+}
+
+func TestScoreSlot(t *testing.T) {
+	day := time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	busy := map[string][]TimeRange{
+		"alice": {{Start: at(9, 0), End: at(10, 0)}},
+		"bob":   {},
+	}
+	available := []string{"alice", "bob"}
+
+	free := scoreSlot(TimeRange{Start: at(15, 0), End: at(16, 0)}, available, busy, time.UTC)
+	lunch := scoreSlot(TimeRange{Start: at(12, 0), End: at(13, 0)}, available, busy, time.UTC)
+	next := scoreSlot(TimeRange{Start: at(10, 0), End: at(11, 0)}, available, busy, time.UTC)
+	if free != availabilityWeight {
+		t.Errorf("score of a slot far from anything = %v; want %v", free, availabilityWeight)
+	}
+	if lunch != availabilityWeight-lunchPenalty {
+		t.Errorf("score over lunch = %v; want %v", lunch, availabilityWeight-lunchPenalty)
+	}
+	// Right after alice's meeting: half of the people get the full bonus.
+	if want := availabilityWeight + proximityWeight/2; next != want {
+		t.Errorf("score next to a meeting = %v; want %v", next, want)
+	}
+	if half := scoreSlot(TimeRange{Start: at(15, 0), End: at(16, 0)}, []string{"bob"}, busy, time.UTC); half != availabilityWeight/2 {
+		t.Errorf("score with half of the people = %v; want %v", half, availabilityWeight/2)
+	}
+}
+
+func TestSuggestTimes(t *testing.T) {
+	f := newGoogleFixture(t)
+	ctx := context.Background()
+	alice := addGoogleUser(t, f.fake, f.users, "alice@example.com")
+	bob := addGoogleUser(t, f.fake, f.users, "bob@example.com")
+	f.insert(t, alice, &calendar.Event{
+		Summary: "Workshop",
+		Start:   &calendar.EventDateTime{DateTime: "2030-03-04T09:00:00Z"},
+		End:     &calendar.EventDateTime{DateTime: "2030-03-04T11:00:00Z"},
+	})
+	f.insert(t, bob, &calendar.Event{
+		Summary: "Holiday",
+		Start:   &calendar.EventDateTime{Date: "2030-03-05"},
+		End:     &calendar.EventDateTime{Date: "2030-03-06"},
+	})
+	input := SuggestTimesInput{
+		UserEmail:    alice.Email,
+		Attendees:    []string{bob.Email},
+		Duration:     time.Hour,
+		TimeMin:      time.Date(2030, 3, 4, 9, 0, 0, 0, time.UTC),
+		TimeMax:      time.Date(2030, 3, 4, 11, 0, 0, 0, time.UTC),
+		WorkdayStart: 9 * time.Hour,
+		WorkdayEnd:   17 * time.Hour,
+	}
+
+	// The window is taken by alice's workshop.
+	output, err := f.events.SuggestTimes(ctx, input)
+	if err != nil {
+		t.Fatalf("SuggestTimes: %v", err)
+	}
+	if len(output.Suggestions) != 0 {
+		t.Errorf("suggestions in a window with no free slot = %+v; want none", output.Suggestions)
+	}
+
+	// Right after the workshop is best; bob's calendar is free.
+	input.TimeMax = time.Date(2030, 3, 4, 17, 0, 0, 0, time.UTC)
+	output, err = f.events.SuggestTimes(ctx, input)
+	if err != nil {
+		t.Fatalf("SuggestTimes: %v", err)
+	}
+	if len(output.Suggestions) == 0 || !output.Suggestions[0].Start.Equal(time.Date(2030, 3, 4, 11, 0, 0, 0, time.UTC)) {
+		t.Fatalf("suggestions = %+v; want 11:00 first", output.Suggestions)
+	}
+	for _, s := range output.Suggestions {
+		if s.Start.Before(input.TimeMin.Add(2 * time.Hour)) {
+			t.Errorf("suggested %s during the workshop", s.Start)
+		}
+	}
+
+	// Bob is away all day, so only a quorum of one finds anything.
+	input.TimeMin = time.Date(2030, 3, 5, 0, 0, 0, 0, time.UTC)
+	input.TimeMax = input.TimeMin.Add(24 * time.Hour)
+	if output, err = f.events.SuggestTimes(ctx, input); err != nil || len(output.Suggestions) != 0 {
+		t.Errorf("SuggestTimes on bob's holiday = %+v, %v; want nothing", output, err)
+	}
+	input.Quorum = 1
+	input.Attendees = append(input.Attendees, "nobody@example.com")
+	output, err = f.events.SuggestTimes(ctx, input)
+	if err != nil || len(output.Suggestions) == 0 {
+		t.Fatalf("SuggestTimes with a quorum of one = %+v, %v", output, err)
+	}
+	if s := output.Suggestions[0]; !slices.Equal(s.Available, []string{alice.Email}) || !slices.Equal(s.Unavailable, []string{bob.Email}) {
+		t.Errorf("suggestion = %+v; want alice available and bob not", s)
+	}
+	if !slices.Equal(output.Unreadable, []string{"nobody@example.com"}) {
+		t.Errorf("unreadable = %q; want nobody@example.com", output.Unreadable)
+	}
+}
+
+func TestSuggestTimesRejectsBadInput(t *testing.T) {
+	s := &eventService{}
+	start := time.Date(2030, 3, 4, 0, 0, 0, 0, time.UTC)
+	valid := SuggestTimesInput{Duration: time.Hour, TimeMin: start, TimeMax: start.Add(24 * time.Hour), WorkdayStart: 9 * time.Hour, WorkdayEnd: 17 * time.Hour}
+	for name, change := range map[string]func(*SuggestTimesInput){
+		"no duration":      func(in *SuggestTimesInput) { in.Duration = 0 },
+		"empty window":     func(in *SuggestTimesInput) { in.TimeMax = in.TimeMin },
+		"window too long":  func(in *SuggestTimesInput) { in.TimeMax = in.TimeMin.AddDate(0, 3, 0) },
+		"inverted workday": func(in *SuggestTimesInput) { in.WorkdayStart, in.WorkdayEnd = in.WorkdayEnd, in.WorkdayStart },
+		"unknown zone":     func(in *SuggestTimesInput) { in.TimeZone = "Mars/Olympus_Mons" },
+	} {
+		input := valid
+		change(&input)
+		if _, err := s.SuggestTimes(context.Background(), input); !errors.Is(err, ErrInvalidTime) {
+			t.Errorf("SuggestTimes with %s = %v; want ErrInvalidTime", name, err)
+		}
+	}
+}
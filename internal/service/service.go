@@ -3,15 +3,60 @@ package service
 
 import (
 	"context"
-	// "google-calendar-api/internal/domain"
+	"errors"
+	"google-calendar-api/internal/domain"
+	"io"
+	"net/http"
 	"time"
 
 	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
 )
 
-// AuthService defines the interface for authentication operations.
+// AuthService defines the interface for authentication operations. With the
+// email of a logged in user the callbacks link the account to that user
+// instead of logging in.
 type AuthService interface {
-	HandleGoogleCallback(ctx context.Context, token *oauth2.Token) (string, error) // Returns JWT
+	HandleGoogleCallback(ctx context.Context, token *oauth2.Token, currentEmail string) (string, error)    // Returns JWT
+	HandleMicrosoftCallback(ctx context.Context, token *oauth2.Token, currentEmail string) (string, error) // Returns JWT
+	DiscoverIssuer(ctx context.Context) error                                                              // Retries until Google's OIDC issuer answers or ctx is done
+	Ready(ctx context.Context) error                                                                       // ErrAuthUnavailable until the issuer has been discovered
+}
+
+// AccountService defines the interface for the calendar providers of a user.
+type AccountService interface {
+	ListProviders(ctx context.Context, userEmail string) (*ProvidersOutput, error)
+	SetProvider(ctx context.Context, userEmail, provider string) error // ErrProviderNotLinked unless the user linked it
+	LinkCalDAV(ctx context.Context, input CalDAVAccountInput) (*LinkedProvider, error)
+}
+
+// CalendarProvider is a calendar backend events are kept in. Events go in and
+// out as the API describes them, so callers don't depend on the backend.
+type CalendarProvider interface {
+	Name() string // Stored as the provider of users and meetings
+	ListCalendars(ctx context.Context, user *domain.User) ([]CalendarOutput, error)
+	CreateEvent(ctx context.Context, user *domain.User, input CreateEventInput) (*ProviderEvent, error)
+	GetEvent(ctx context.Context, user *domain.User, calendarID, eventID string) (*ProviderEvent, error) // Nil when the event is gone or cancelled
+	ListEvents(ctx context.Context, user *domain.User, query ProviderQuery) (events []EventOutput, nextPageToken string, err error)
+	UpdateEvent(ctx context.Context, user *domain.User, calendarID string, event EventOutput) (*ProviderEvent, error) // Writes the fields of the event with event.EventId
+	DeleteEvent(ctx context.Context, user *domain.User, calendarID, eventID string) error                             // Nil when the event is gone already
+	FreeBusy(ctx context.Context, user *domain.User, input FreeBusyInput) (*FreeBusyOutput, error)
+}
+
+// Names of the calendar providers.
+const (
+	ProviderGoogle    = "google"
+	ProviderMicrosoft = "microsoft"
+	ProviderCalDAV    = "caldav"
+)
+
+// GoogleClientProvider hands out Google API clients that act for a user. Refreshed
+// tokens are saved on the user automatically.
+type GoogleClientProvider interface {
+	HTTPClient(ctx context.Context, user *domain.User) *http.Client
+	// WithCalendar runs call with a Calendar client. When Google rejects the
+	// access token the token is refreshed and call runs once more.
+	WithCalendar(ctx context.Context, user *domain.User, call func(*calendar.Service) error) error
 }
 
 // UserInfo represents user information extracted from a token
@@ -21,30 +66,430 @@ type UserInfo struct {
 
 // EventService defines the interface for event-related operations.
 type EventService interface {
-	CreateEvent(ctx context.Context, input CreateEventInput) (string, error) // Returns event ID
-	ListEvents(ctx context.Context, userEmail string) ([]EventOutput, error)
+	CreateEvent(ctx context.Context, input CreateEventInput) (*OperationOutput, error) // Recorded first, then applied to Google; may still be pending
+	ListEvents(ctx context.Context, input ListEventsInput) (*ListEventsOutput, error)
+	GetEvent(ctx context.Context, id, calendarID, userEmail string) (*EventDetail, error) // id is the local meeting ID or the Google event ID
+	UpdateEvent(ctx context.Context, input UpdateEventInput) error                        // Replaces the whole event
+	PatchEvent(ctx context.Context, input PatchEventInput) error                          // Changes only the fields that are set
+	DeleteEvent(ctx context.Context, eventID, userEmail string, scope RecurrenceScope) error
+	ListCalendars(ctx context.Context, userEmail string) ([]CalendarOutput, error)
+	FreeBusy(ctx context.Context, input FreeBusyInput) (*FreeBusyOutput, error)
+	SuggestTimes(ctx context.Context, input SuggestTimesInput) (*SuggestTimesOutput, error)
+	GetOperation(ctx context.Context, id uint, userEmail string) (*OperationOutput, error)
+	ProcessOutbox(ctx context.Context) error // Retries pending operations that are due
+}
+
+// SyncService defines the interface for keeping the local store in step with Google.
+type SyncService interface {
+	SyncCalendar(ctx context.Context, userEmail, calendarID string) (*SyncResult, error) // Full sync the first time, then only changes
 }
 
+// WatchService defines the interface for Google push notifications.
+type WatchService interface {
+	WatchCalendars(ctx context.Context, userEmail string) ([]domain.WatchChannel, error) // Watches every calendar of the user that has no channel yet
+	HandleNotification(ctx context.Context, notification Notification) error             // Starts a sync for the channel's calendar
+	RenewChannels(ctx context.Context) error                                             // Watches new calendars and replaces channels that expire soon
+}
+
+// WebhookService defines the interface for outbound webhooks about meeting changes.
+type WebhookService interface {
+	CreateSubscription(ctx context.Context, input CreateSubscriptionInput) (*domain.WebhookSubscription, error) // The secret is generated when none is given
+	ListSubscriptions(ctx context.Context, userEmail string) ([]domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id uint, userEmail string) error
+	ListDeliveries(ctx context.Context, subscriptionID uint, userEmail string) ([]domain.WebhookDelivery, error)
+	ReplayDelivery(ctx context.Context, deliveryID uint, userEmail string) (*domain.WebhookDelivery, error) // Queues the payload again as a new delivery
+	Publish(ctx context.Context, eventType string, meeting *domain.Meeting) error                           // Queues a delivery for every matching subscription of the meeting's owner
+	DeliverPending(ctx context.Context) error                                                               // Sends deliveries that are due
+}
+
+// Meeting lifecycle events webhooks can subscribe to.
+const (
+	EventMeetingCreated     = "meeting.created"
+	EventMeetingUpdated     = "meeting.updated"
+	EventMeetingCancelled   = "meeting.cancelled"
+	EventMeetingRSVPChanged = "meeting.rsvp_changed" // An attendee answered differently
+)
+
+// ReconcileService defines the interface for finding and fixing stored
+// meetings that no longer match Google.
+type ReconcileService interface {
+	Reconcile(ctx context.Context, input ReconcileInput) (*ReconcileReport, error)
+}
+
+// ExportService defines the interface for exporting meetings, including the
+// iCalendar feed calendar clients subscribe to.
+type ExportService interface {
+	ExportICS(ctx context.Context, w io.Writer, input ExportInput) error
+	ExportEvents(ctx context.Context, input ExportInput) ([]EventOutput, error)
+	FeedToken(ctx context.Context, userEmail string) (string, error)       // ErrFeedNotFound while the feed is off
+	RotateFeedToken(ctx context.Context, userEmail string) (string, error) // Turns the feed on, or gives it a new URL
+	RevokeFeedToken(ctx context.Context, userEmail string) error
+	ExportFeed(ctx context.Context, w io.Writer, token string) error // Renders the feed the token belongs to
+}
+
+// ImportService defines the interface for loading iCalendar files into Google Calendar.
+type ImportService interface {
+	ImportICS(ctx context.Context, r io.Reader, input ImportInput) (*ImportReport, error) // Events whose UID exists already are left alone
+}
+
+// RecurrenceScope selects which occurrences of a recurring event a change applies to.
+type RecurrenceScope string
+
+const (
+	ScopeInstance  RecurrenceScope = "instance"  // Only the given occurrence
+	ScopeFollowing RecurrenceScope = "following" // The given occurrence and every later one
+	ScopeAll       RecurrenceScope = "all"       // The whole series
+)
+
+// Errors returned by EventService so handlers can pick the right status code.
+var (
+	ErrEventNotFound     = errors.New("event not found")
+	ErrNotEventOwner     = errors.New("event belongs to another user")
+	ErrInvalidTime       = errors.New("start time must be before end time")
+	ErrInvalidRule       = errors.New("invalid recurrence rule")
+	ErrInvalidScope      = errors.New("scope is only valid for recurring events")
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrCalendarNotFound  = errors.New("calendar not found")
+	ErrReauthRequired    = errors.New("calendar authorization expired, please log in again")
+	ErrInvalidChannel    = errors.New("unknown channel or invalid channel token")
+	ErrInvalidWebhook    = errors.New("invalid webhook subscription")
+	ErrWebhookNotFound   = errors.New("webhook subscription or delivery not found")
+	ErrOperationNotFound = errors.New("operation not found")
+	ErrFeedNotFound      = errors.New("calendar feed not found")
+	ErrInvalidCalendar   = errors.New("invalid iCalendar file")
+
+	ErrProviderNotLinked   = errors.New("calendar provider is not linked")
+	ErrProviderUnsupported = errors.New("not supported by the calendar provider")
+	ErrAccountConflict     = errors.New("account is linked to another user")
+	ErrInvalidAccount      = errors.New("invalid calendar account")
+	ErrAuthUnavailable     = errors.New("identity provider is unavailable, try again shortly")
+)
+
 // CreateEventInput represents the input for creating an event.
 type CreateEventInput struct {
-	Title       string
-	Description string
-	StartTime   time.Time
-	EndTime     time.Time
-	Attendees   []string // Use a slice of strings
-	CreatedBy   string
+	Title         string
+	Description   string
+	StartTime     time.Time
+	EndTime       time.Time
+	AllDay        bool     // Start and end are dates; EndTime is exclusive, as in Google Calendar
+	Attendees     []string // Use a slice of strings
+	Recurrence    []string // RRULE, EXDATE and RDATE lines, e.g. "RRULE:FREQ=WEEKLY;COUNT=10"
+	CalendarID    string   // Defaults to "primary"
+	AddConference bool     // Attach a Google Meet link
+	ICalUID       string   // iCalendar UID to keep, e.g. of an imported event; Google picks one when empty
+	CreatedBy     string
 }
 
-type EventOutput struct {
+// ListEventsInput represents the filters for listing events. Zero values
+// fall back to the primary calendar and the next 7 days.
+type ListEventsInput struct {
+	UserEmail  string
+	CalendarID string
+	TimeMin    time.Time
+	TimeMax    time.Time
+	Query      string // Free text search, as in the Google Calendar search box
+	MaxResults int    // Page size, 50 by default and at most 250
+	Cursor     string // NextCursor of the previous page; its filters replace the ones above
+}
+
+// ListEventsOutput is one page of events.
+type ListEventsOutput struct {
+	Events     []EventOutput `json:"events"`
+	NextCursor string        `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// UpdateEventInput represents the input for replacing an existing event.
+type UpdateEventInput struct {
+	EventID     string // Google Calendar event ID.
 	Title       string
 	Description string
 	StartTime   time.Time
 	EndTime     time.Time
+	AllDay      bool
 	Attendees   []string
-	EventId     string // Google Calendar event ID.
-	CreatedBy   string
+	Recurrence  []string // Only used when the series master is changed; nil keeps the current rules
+	Scope       RecurrenceScope
+	UpdatedBy   string // Email of the user making the change
 }
 
+// PatchEventInput represents a partial update. Nil fields are left untouched.
+type PatchEventInput struct {
+	EventID     string // Google Calendar event ID.
+	Title       *string
+	Description *string
+	StartTime   *time.Time
+	EndTime     *time.Time
+	AllDay      *bool // Nil keeps the current kind; switching needs both times
+	Attendees   *[]string
+	Recurrence  *[]string
+	Scope       RecurrenceScope
+	UpdatedBy   string // Email of the user making the change
+}
+
+type EventOutput struct {
+	Title             string            `json:"title"`
+	Description       string            `json:"description"`
+	StartTime         time.Time         `json:"start_time"`
+	EndTime           time.Time         `json:"end_time"`
+	AllDay            bool              `json:"all_day"` // Start and end are midnight UTC of their dates; end is exclusive
+	Attendees         []AttendeeOutput  `json:"attendees"`
+	EventId           string            `json:"event_id"` // Google Calendar event ID.
+	CreatedBy         string            `json:"created_by"`
+	Recurrence        []string          `json:"recurrence,omitempty"`          // Set on series masters
+	RecurringEventID  string            `json:"recurring_event_id,omitempty"`  // Series this occurrence belongs to
+	OriginalStartTime *time.Time        `json:"original_start_time,omitempty"` // Start of the occurrence before it was moved
+	Conference        *ConferenceOutput `json:"conference,omitempty"`
+}
+
+// ConferenceOutput describes the video call attached to an event.
+type ConferenceOutput struct {
+	ConferenceID string `json:"conference_id"`
+	JoinURL      string `json:"join_url"`           // Empty until Google has finished creating the conference
+	Solution     string `json:"solution,omitempty"` // e.g. "Google Meet"
+	Status       string `json:"status,omitempty"`   // pending, success or failure while a conference is being created
+}
+
+// EventDetail is the stored meeting merged with the live Google Calendar event.
+type EventDetail struct {
+	Meeting         *domain.Meeting `json:"meeting"`           // Nil when the event is not stored locally
+	Event           *EventOutput    `json:"google_event"`      // Nil when the event no longer exists in Google
+	StoredLocally   bool            `json:"stored_locally"`    // Whether a meetings row exists
+	DeletedInGoogle bool            `json:"deleted_in_google"` // The row exists but Google returned 404/410
+	Drift           []FieldDrift    `json:"drift"`             // Fields where the two disagree
+}
+
+// FieldDrift describes one field whose stored value differs from Google.
+// For attendees there is one entry per attendee that is missing on either side.
+type FieldDrift struct {
+	Field  string      `json:"field"`
+	Stored interface{} `json:"stored"`
+	Google interface{} `json:"google"`
+}
+
+// CalendarOutput is an entry of the user's calendar list.
+type CalendarOutput struct {
+	ID          string `json:"id"` // Pass as calendar_id to target this calendar
+	Summary     string `json:"summary"`
+	Description string `json:"description,omitempty"`
+	TimeZone    string `json:"time_zone"`
+	AccessRole  string `json:"access_role"` // owner, writer, reader or freeBusyReader
+	Primary     bool   `json:"primary"`
+	Color       string `json:"color,omitempty"`
+}
+
+// FreeBusyInput represents a free/busy lookup for several people.
+type FreeBusyInput struct {
+	UserEmail string   // Whose Google credentials are used
+	Calendars []string // Attendee emails or calendar IDs
+	TimeMin   time.Time
+	TimeMax   time.Time
+	TimeZone  string // Zone of the returned intervals; UTC when empty
+}
+
+// FreeBusyOutput holds the busy intervals of every requested calendar.
+type FreeBusyOutput struct {
+	TimeMin   time.Time                       `json:"time_min"`
+	TimeMax   time.Time                       `json:"time_max"`
+	Calendars map[string]CalendarAvailability `json:"calendars"` // Keyed by the requested email or calendar ID
+}
+
+// CalendarAvailability is the answer for one person or calendar.
+type CalendarAvailability struct {
+	Busy   []TimeRange `json:"busy"`
+	Errors []string    `json:"errors,omitempty"` // Why availability could not be read, e.g. "notFound" or "internalError"
+}
+
+// TimeRange is a half-open interval [Start, End).
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// SuggestTimesInput represents a search for meeting times.
+type SuggestTimesInput struct {
+	UserEmail       string // The organizer, who always has to be free
+	Attendees       []string
+	Duration        time.Duration
+	TimeMin         time.Time
+	TimeMax         time.Time
+	WorkdayStart    time.Duration // Offset from local midnight, e.g. 9h
+	WorkdayEnd      time.Duration // Offset from local midnight, e.g. 17h
+	TimeZone        string        // Zone of the working hours; UTC when empty
+	Quorum          int           // Minimum number of people who must be free; 0 means everyone
+	IncludeWeekends bool
+	MaxResults      int // 10 by default
+}
+
+// SuggestTimesOutput holds the candidate slots, best first.
+type SuggestTimesOutput struct {
+	Suggestions []TimeSuggestion `json:"suggestions"`
+	Unreadable  []string         `json:"unreadable,omitempty"` // People whose availability is unknown and not counted
+}
+
+// TimeSuggestion is one candidate slot.
+type TimeSuggestion struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Score       float64   `json:"score"`
+	Available   []string  `json:"available"`
+	Unavailable []string  `json:"unavailable"`
+}
+
+// AttendeeOutput is an attendee of an event and their answer.
 type AttendeeOutput struct {
-	Email string
+	Email          string `json:"email"`
+	Name           string `json:"name,omitempty"`
+	Optional       bool   `json:"optional"`
+	Organizer      bool   `json:"organizer"`
+	ResponseStatus string `json:"response_status"` // needsAction, accepted, declined or tentative
+}
+
+// SyncResult describes one sync run of a calendar.
+type SyncResult struct {
+	CalendarID string    `json:"calendar_id"`
+	FullSync   bool      `json:"full_sync"`   // Everything was fetched, not only changes
+	TokenReset bool      `json:"token_reset"` // Google expired the sync token (410 Gone), so a full sync ran instead
+	Upserted   int       `json:"upserted"`
+	Deleted    int       `json:"deleted"`
+	SyncedAt   time.Time `json:"synced_at"`
+}
+
+// Notification is a push message Google sends to the webhook receiver.
+type Notification struct {
+	ChannelID     string // X-Goog-Channel-ID
+	Token         string // X-Goog-Channel-Token
+	ResourceID    string // X-Goog-Resource-ID
+	ResourceState string // X-Goog-Resource-State: "sync", "exists" or "not_exists"
+}
+
+// CreateSubscriptionInput holds the input for registering a webhook.
+type CreateSubscriptionInput struct {
+	URL        string
+	Secret     string
+	EventTypes []string // Empty means every event type
+	UserEmail  string
+}
+
+// OperationOutput reports how far a change recorded in the outbox got.
+type OperationOutput struct {
+	OperationID uint         `json:"operation_id"`
+	Status      string       `json:"status"` // "pending", "committed" or "failed"
+	MeetingID   uint         `json:"meeting_id"`
+	EventID     string       `json:"event_id"`
+	Attempts    int          `json:"attempts"`
+	Error       string       `json:"error,omitempty"` // Last failure, also set while a retry is pending
+	Event       *EventOutput `json:"event,omitempty"` // Set once committed
+}
+
+// ProviderEvent is an event as a CalendarProvider returns it.
+type ProviderEvent struct {
+	EventOutput
+	ICalUID  string
+	TimeZone string // IANA zone the event is scheduled in; empty when unknown
+	Status   string // "confirmed", "tentative" or "cancelled"
+}
+
+// ProviderQuery asks a CalendarProvider for one page of events, with
+// recurring events expanded into their occurrences.
+type ProviderQuery struct {
+	CalendarID string
+	TimeMin    time.Time
+	TimeMax    time.Time
+	Query      string // Free text search
+	MaxResults int
+	PageToken  string // Next page token of the previous page
+}
+
+// ProvidersOutput lists the calendar providers of a user.
+type ProvidersOutput struct {
+	Active    string           `json:"active"` // Where new events go
+	Linked    []LinkedProvider `json:"linked"`
+	Available []string         `json:"available"` // Providers this server is set up for
+}
+
+// LinkedProvider is a calendar account the user connected.
+type LinkedProvider struct {
+	Provider string `json:"provider"`
+	Email    string `json:"email"`
+}
+
+// CalDAVAccountInput links a CalDAV server to a user.
+type CalDAVAccountInput struct {
+	UserEmail string
+	ServerURL string // The server's root, the user's principal or their calendar home
+	Username  string
+	Password  string // Often an app password
+}
+
+// ReconcileInput selects what to reconcile.
+type ReconcileInput struct {
+	UserEmail string // Empty reconciles every user
+	DryRun    bool   // Only report, change nothing
+}
+
+// ReconcileReport is the machine-readable result of a reconcile run.
+type ReconcileReport struct {
+	StartedAt  time.Time       `json:"started_at"`
+	FinishedAt time.Time       `json:"finished_at"`
+	DryRun     bool            `json:"dry_run"`
+	Checked    int             `json:"checked"` // Stored meetings looked at
+	Deleted    int             `json:"deleted"`
+	Modified   int             `json:"modified"`
+	Orphaned   int             `json:"orphaned"`
+	Errors     int             `json:"errors"`
+	Items      []ReconcileItem `json:"items"` // Only meetings that differ or could not be checked
+}
+
+// ReconcileItem is one stored meeting that differs from Google.
+type ReconcileItem struct {
+	MeetingID  uint         `json:"meeting_id"`
+	EventID    string       `json:"event_id"`
+	CalendarID string       `json:"calendar_id"`
+	UserEmail  string       `json:"user_email"`
+	Kind       string       `json:"kind"`             // "deleted" (gone from Google), "modified", "orphaned" (can't be matched to a Google event) or "error"
+	Reason     string       `json:"reason,omitempty"` // Why an orphan is one
+	Drift      []FieldDrift `json:"drift,omitempty"`  // Set for "modified"
+	Action     string       `json:"action"`           // "none" on dry runs, else "deleted_row" or "updated_row"
+	Error      string       `json:"error,omitempty"`  // Set when the check or the fix failed
+}
+
+// ExportInput selects which stored meetings to export.
+type ExportInput struct {
+	UserEmail  string
+	CalendarID string    // Empty exports every calendar
+	TimeMin    time.Time // Zero means no lower bound
+	TimeMax    time.Time // Zero means no upper bound
+	// Also render the Google events of the calendar that aren't stored.
+	// Zero bounds then default to the last 30 and the next 365 days.
+	IncludeGoogle bool
+}
+
+// ImportInput holds the options of an iCalendar import.
+type ImportInput struct {
+	UserEmail  string
+	CalendarID string // Defaults to "primary"
+	DryRun     bool   // Only report what would happen
+}
+
+// ImportReport lists what happened to every event of an imported file.
+type ImportReport struct {
+	DryRun     bool           `json:"dry_run"`
+	Total      int            `json:"total"`
+	Created    int            `json:"created"` // Including pending ones, or the ones that would be created on a dry run
+	Duplicates int            `json:"duplicates"`
+	Skipped    int            `json:"skipped"`
+	Failed     int            `json:"failed"`
+	Results    []ImportResult `json:"results"`
+}
+
+// ImportResult is the outcome for one VEVENT.
+type ImportResult struct {
+	UID         string    `json:"uid"`
+	Title       string    `json:"title"`
+	Start       time.Time `json:"start"`
+	Status      string    `json:"status"` // "created", "pending", "would_create", "duplicate", "skipped" or "failed"
+	EventID     string    `json:"event_id,omitempty"`
+	OperationID uint      `json:"operation_id,omitempty"` // Set when created through the outbox
+	Reason      string    `json:"reason,omitempty"`       // Why it was not created
+	Warning     string    `json:"warning,omitempty"`
 }
@@ -0,0 +1,205 @@
+// internal/service/sync.go
+package service
+
+import (
+	"context"
+	"fmt"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// syncPageSize is the most events Google returns per page of a sync.
+const syncPageSize = 250
+
+type syncService struct {
+	meetingRepo repository.MeetingRepository
+	userRepo    repository.UserRepository
+	syncRepo    repository.SyncStateRepository
+	clients     GoogleClientProvider
+	webhooks    WebhookService
+
+	locks sync.Map // "user|calendar" -> *sync.Mutex, so one calendar never syncs twice at once
+}
+
+// NewSyncService creates a new SyncService instance.
+func NewSyncService(meetingRepo repository.MeetingRepository, userRepo repository.UserRepository, syncRepo repository.SyncStateRepository, clients GoogleClientProvider, webhooks WebhookService) *syncService {
+	return &syncService{
+		meetingRepo: meetingRepo,
+		userRepo:    userRepo,
+		syncRepo:    syncRepo,
+		clients:     clients,
+		webhooks:    webhooks,
+	}
+}
+
+func (s *syncService) SyncCalendar(ctx context.Context, userEmail, calendarID string) (*SyncResult, error) {
+	if calendarID == "" {
+		calendarID = "primary"
+	}
+
+	lock, _ := s.locks.LoadOrStore(userEmail+"|"+calendarID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	state, err := s.syncRepo.GetSyncState(ctx, userEmail, calendarID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sync state: %w", err)
+	}
+	if state == nil {
+		state = &domain.CalendarSync{UserEmail: userEmail, CalendarID: calendarID}
+	}
+
+	result := &SyncResult{CalendarID: calendarID}
+	nextToken, err := s.pull(ctx, user, calendarID, state.SyncToken, result)
+	if err != nil && isGoneError(err) {
+		// The token is too old or was invalidated; start over with everything.
+		log.Printf("🔄 Sync token of %s/%s expired, running a full sync", userEmail, calendarID)
+		*result = SyncResult{CalendarID: calendarID, TokenReset: true}
+		nextToken, err = s.pull(ctx, user, calendarID, "", result)
+	}
+	if err != nil {
+		if isNotFoundError(err) {
+			return nil, ErrCalendarNotFound
+		}
+		return nil, fmt.Errorf("failed to sync calendar: %w", err)
+	}
+
+	result.SyncedAt = time.Now()
+	state.SyncToken = nextToken
+	state.LastSyncedAt = result.SyncedAt
+	if err := s.syncRepo.SaveSyncState(ctx, state); err != nil {
+		return nil, fmt.Errorf("failed to save sync state: %w", err)
+	}
+	return result, nil
+}
+
+// pull fetches the events changed since syncToken, or every event when it is
+// empty, and applies them to the local store. It returns the token for the
+// next run.
+func (s *syncService) pull(ctx context.Context, user *domain.User, calendarID, syncToken string, result *SyncResult) (string, error) {
+	result.FullSync = syncToken == ""
+	seen := make(map[string]bool)
+	var nextToken string
+
+	err := s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		result.Upserted, result.Deleted = 0, 0 // The call is retried after a token refresh
+		call := service.Events.List(calendarID).
+			ShowDeleted(true). // Deletions only show up as cancelled events
+			MaxResults(syncPageSize)
+		if syncToken != "" {
+			call = call.SyncToken(syncToken)
+		}
+		return call.Pages(ctx, func(page *calendar.Events) error {
+			for _, item := range page.Items {
+				seen[item.Id] = true
+				if err := s.apply(ctx, user.Email, calendarID, item, result); err != nil {
+					return err
+				}
+			}
+			nextToken = page.NextSyncToken // Only set on the last page
+			return nil
+		})
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if result.FullSync {
+		// Anything stored that Google no longer lists was deleted while we
+		// were not looking.
+		meetings, err := s.meetingRepo.ListMeetingsByCalendar(ctx, user.Email, ProviderGoogle, calendarID)
+		if err != nil {
+			return "", fmt.Errorf("failed to load events from database: %w", err)
+		}
+		for i := range meetings {
+			if meetings[i].EventID == "" || seen[meetings[i].EventID] || meetings[i].CommitState == "pending" {
+				continue
+			}
+			if err := s.meetingRepo.DeleteMeeting(ctx, &meetings[i]); err != nil {
+				return "", fmt.Errorf("failed to delete event from database: %w", err)
+			}
+			result.Deleted++
+			before := meetings[i]
+			meetings[i].Status = "cancelled"
+			publishChanges(ctx, s.webhooks, &before, &meetings[i])
+		}
+	}
+	return nextToken, nil
+}
+
+// apply stores one event from a sync page.
+func (s *syncService) apply(ctx context.Context, userEmail, calendarID string, item *calendar.Event, result *SyncResult) error {
+	meeting, err := s.meetingRepo.GetMeetingByEventID(ctx, userEmail, item.Id)
+	if err != nil {
+		return fmt.Errorf("failed to load event from database: %w", err)
+	}
+
+	if item.Status == "cancelled" && item.RecurringEventId == "" {
+		// A deleted event or series; drop it along with its exceptions.
+		if meeting == nil {
+			return nil
+		}
+		exceptions, err := s.meetingRepo.ListMeetingExceptions(ctx, userEmail, meeting.EventID)
+		if err != nil {
+			return fmt.Errorf("failed to load exceptions from database: %w", err)
+		}
+		for i := range exceptions {
+			if err := s.meetingRepo.DeleteMeeting(ctx, &exceptions[i]); err != nil {
+				return fmt.Errorf("failed to delete exception from database: %w", err)
+			}
+		}
+		if err := s.meetingRepo.DeleteMeeting(ctx, meeting); err != nil {
+			return fmt.Errorf("failed to delete event from database: %w", err)
+		}
+		result.Deleted += len(exceptions) + 1
+		before := *meeting
+		meeting.Status = "cancelled"
+		publishChanges(ctx, s.webhooks, &before, meeting)
+		return nil
+	}
+
+	var before *domain.Meeting
+	if meeting == nil {
+		meeting = &domain.Meeting{CreatedBy: userEmail, CalendarID: calendarID}
+	} else {
+		previous := *meeting
+		before = &previous
+	}
+	if item.Status == "cancelled" {
+		// A cancelled occurrence only carries its ID, series and original
+		// start, so keep whatever else is already stored.
+		meeting.EventID = item.Id
+		meeting.RecurringEventID = item.RecurringEventId
+		meeting.Status = item.Status
+		if originalStart, _, err := parseEventDateTime(item.OriginalStartTime); err == nil {
+			meeting.OriginalStartTime = &originalStart
+			if meeting.StartTime.IsZero() {
+				meeting.StartTime, meeting.EndTime = originalStart, originalStart
+			}
+		}
+	} else {
+		applyEventToMeeting(meeting, item)
+	}
+
+	if meeting.ID == 0 {
+		err = s.meetingRepo.CreateMeeting(ctx, meeting)
+	} else {
+		err = s.meetingRepo.UpdateMeeting(ctx, meeting)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store event in database: %w", err)
+	}
+	publishChanges(ctx, s.webhooks, before, meeting)
+	result.Upserted++
+	return nil
+}
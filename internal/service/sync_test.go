@@ -0,0 +1,79 @@
+// internal/service/sync_test.go
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+
+	"google.golang.org/api/calendar/v3"
+)
+
+// TestFullSyncKeepsOtherProviders checks that a full sync of the Google
+// primary calendar only purges Google rows, even though Microsoft and CalDAV
+// events are stored under the same calendar ID.
+func TestFullSyncKeepsOtherProviders(t *testing.T) {
+	fake, cfg := newFakeGoogle(t)
+	db := newTestDB(t)
+	users := repository.NewUserRepository(db)
+	meetings := repository.NewMeetingRepository(db)
+	clients := NewGoogleClientProvider(cfg, users)
+	webhooks := NewWebhookService(&config.Config{}, repository.NewWebhookRepository(db))
+	sync := NewSyncService(meetings, users, repository.NewSyncStateRepository(db), clients, webhooks)
+	ctx := context.Background()
+
+	user := addGoogleUser(t, fake, users, "alice@example.com")
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour)
+	var listed *calendar.Event
+	err := clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		var err error
+		listed, err = service.Events.Insert("primary", &calendar.Event{
+			Summary: "Still on Google",
+			Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+			End:     &calendar.EventDateTime{DateTime: start.Add(time.Hour).Format(time.RFC3339)},
+		}).Do()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to create Google event: %v", err)
+	}
+
+	stored := map[string]*domain.Meeting{
+		"google":    {EventID: "deleted-on-google", Provider: ProviderGoogle},
+		"microsoft": {EventID: "AAMkAGI2", Provider: ProviderMicrosoft},
+		"caldav":    {EventID: "https://dav.example.com/cal/1.ics", Provider: ProviderCalDAV},
+	}
+	for _, meeting := range stored {
+		meeting.Title = "Stored " + meeting.Provider
+		meeting.StartTime, meeting.EndTime = start, start.Add(time.Hour)
+		meeting.CalendarID = "primary"
+		meeting.CreatedBy = user.Email
+		if err := meetings.CreateMeeting(ctx, meeting); err != nil {
+			t.Fatalf("failed to create meeting: %v", err)
+		}
+	}
+
+	result, err := sync.SyncCalendar(ctx, user.Email, "primary")
+	if err != nil {
+		t.Fatalf("SyncCalendar: %v", err)
+	}
+	if !result.FullSync || result.Deleted != 1 {
+		t.Errorf("result = %+v; want a full sync deleting one event", result)
+	}
+
+	if got, _ := meetings.GetMeetingByID(ctx, stored["google"].ID); got != nil {
+		t.Errorf("Google event missing from Google was kept")
+	}
+	for _, provider := range []string{"microsoft", "caldav"} {
+		if got, err := meetings.GetMeetingByID(ctx, stored[provider].ID); got == nil || err != nil {
+			t.Errorf("%s event was purged by the Google sync: %v", provider, err)
+		}
+	}
+	if got, _ := meetings.GetMeetingByEventID(ctx, user.Email, listed.Id); got == nil {
+		t.Errorf("event listed by Google was not stored")
+	}
+}
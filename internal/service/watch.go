@@ -0,0 +1,271 @@
+// internal/service/watch.go
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/api/calendar/v3"
+)
+
+const (
+	channelTTL          = 7 * 24 * time.Hour // Google caps event channels at about a week anyway
+	channelRenewBefore  = 24 * time.Hour     // Replace channels that expire within this window
+	notificationTimeout = 2 * time.Minute    // Upper bound for a sync started by a notification
+)
+
+type watchService struct {
+	webhookURL  string
+	channelRepo repository.WatchChannelRepository
+	userRepo    repository.UserRepository
+	clients     GoogleClientProvider
+	syncService SyncService
+
+	mu      sync.Mutex
+	pending map[string]bool // "user|calendar" -> whether a notification came in during its running sync
+}
+
+// NewWatchService creates a new WatchService instance. Without a webhook URL
+// in cfg no channels are registered.
+func NewWatchService(cfg *config.Config, channelRepo repository.WatchChannelRepository, userRepo repository.UserRepository, clients GoogleClientProvider, syncService SyncService) *watchService {
+	return &watchService{
+		webhookURL:  cfg.GoogleWebhookURL,
+		channelRepo: channelRepo,
+		userRepo:    userRepo,
+		clients:     clients,
+		syncService: syncService,
+		pending:     make(map[string]bool),
+	}
+}
+
+func (s *watchService) WatchCalendars(ctx context.Context, userEmail string) ([]domain.WatchChannel, error) {
+	if s.webhookURL == "" {
+		return nil, nil
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, userEmail)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	existing, err := s.channelRepo.ListChannelsByUser(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load channels: %w", err)
+	}
+	watched := make(map[string]bool, len(existing))
+	for _, channel := range existing {
+		if time.Until(channel.Expiration) > 0 {
+			watched[channel.CalendarID] = true // RenewChannels replaces it before it runs out
+		}
+	}
+
+	var created []domain.WatchChannel
+	err = s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		var calendarIDs []string
+		err := service.CalendarList.List().Pages(ctx, func(page *calendar.CalendarList) error {
+			for _, item := range page.Items {
+				calendarIDs = append(calendarIDs, item.Id)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, calendarID := range calendarIDs {
+			if watched[calendarID] {
+				continue
+			}
+			channel, err := s.watch(ctx, service, userEmail, calendarID)
+			if err != nil {
+				// Some calendars (holidays, birthdays) can't be watched; keep going.
+				log.Printf("⚠️ Failed to watch calendar %s of %s: %v", calendarID, userEmail, err)
+				continue
+			}
+			watched[calendarID] = true
+			created = append(created, *channel)
+		}
+		return nil
+	})
+	if err != nil {
+		return created, fmt.Errorf("failed to watch calendars: %w", err)
+	}
+	return created, nil
+}
+
+func (s *watchService) HandleNotification(ctx context.Context, notification Notification) error {
+	channel, err := s.channelRepo.GetChannel(ctx, notification.ChannelID)
+	if err != nil {
+		return fmt.Errorf("failed to load channel: %w", err)
+	}
+	if channel == nil ||
+		subtle.ConstantTimeCompare([]byte(channel.Token), []byte(notification.Token)) != 1 ||
+		(notification.ResourceID != "" && notification.ResourceID != channel.ResourceID) {
+		return ErrInvalidChannel
+	}
+
+	switch notification.ResourceState {
+	case "sync", "exists", "not_exists":
+	default:
+		return fmt.Errorf("%w: unknown resource state %q", ErrInvalidChannel, notification.ResourceState)
+	}
+
+	// Google wants a quick answer, so sync in the background. One sync runs
+	// per calendar at a time; notifications that arrive meanwhile make it run
+	// once more when it is done, since it may have listed before their change.
+	key := channel.UserEmail + "|" + channel.CalendarID
+	s.mu.Lock()
+	_, running := s.pending[key]
+	s.pending[key] = running
+	s.mu.Unlock()
+	if running {
+		return nil
+	}
+	go func() {
+		for {
+			s.syncAfterNotification(ctx, channel)
+			if !s.syncDone(key) {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// syncAfterNotification syncs the calendar of channel, detached from the
+// notification's request.
+func (s *watchService) syncAfterNotification(ctx context.Context, channel *domain.WatchChannel) {
+	syncCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), notificationTimeout)
+	defer cancel()
+	if _, err := s.syncService.SyncCalendar(syncCtx, channel.UserEmail, channel.CalendarID); err != nil {
+		log.Printf("❌ Sync after notification failed for %s/%s: %v", channel.UserEmail, channel.CalendarID, err)
+	}
+}
+
+// syncDone marks the sync of key as finished and reports whether a
+// notification came in while it ran, in which case it has to run again.
+func (s *watchService) syncDone(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[key] {
+		s.pending[key] = false
+		return true
+	}
+	delete(s.pending, key)
+	return false
+}
+
+func (s *watchService) RenewChannels(ctx context.Context) error {
+	if s.webhookURL == "" {
+		return nil
+	}
+
+	expiring, err := s.channelRepo.ListChannelsExpiringBefore(ctx, time.Now().Add(channelRenewBefore))
+	if err != nil {
+		return fmt.Errorf("failed to load expiring channels: %w", err)
+	}
+	for i := range expiring {
+		s.replaceChannel(ctx, &expiring[i])
+	}
+
+	// Pick up new users and calendars, and channels whose renewal failed.
+	users, err := s.userRepo.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load users: %w", err)
+	}
+	for _, user := range users {
+		if user.GoogleID == "" {
+			continue // Push notifications are Google only
+		}
+		if _, err := s.WatchCalendars(ctx, user.Email); err != nil {
+			log.Printf("⚠️ Failed to watch calendars of %s: %v", user.Email, err)
+		}
+	}
+	return nil
+}
+
+// replaceChannel opens a new channel for the calendar of old, then stops and
+// forgets old. Failures are logged; the next run tries again.
+func (s *watchService) replaceChannel(ctx context.Context, old *domain.WatchChannel) {
+	user, err := s.userRepo.GetUserByEmail(ctx, old.UserEmail)
+	if err != nil || user == nil {
+		log.Printf("⚠️ Dropping channel %s of unknown user %s", old.ChannelID, old.UserEmail)
+		s.channelRepo.DeleteChannel(ctx, old)
+		return
+	}
+
+	err = s.clients.WithCalendar(ctx, user, func(service *calendar.Service) error {
+		if time.Until(old.Expiration) > 0 {
+			if _, err := s.watch(ctx, service, old.UserEmail, old.CalendarID); err != nil {
+				return err
+			}
+		}
+		// Overlapping channels are fine; a stale one only costs duplicate syncs.
+		err := service.Channels.Stop(&calendar.Channel{Id: old.ChannelID, ResourceId: old.ResourceID}).Context(ctx).Do()
+		if err != nil && !isNotFoundError(err) {
+			log.Printf("⚠️ Failed to stop channel %s: %v", old.ChannelID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("❌ Failed to renew channel %s for %s/%s: %v", old.ChannelID, old.UserEmail, old.CalendarID, err)
+		return
+	}
+	if err := s.channelRepo.DeleteChannel(ctx, old); err != nil {
+		log.Printf("⚠️ Failed to delete channel %s: %v", old.ChannelID, err)
+	}
+}
+
+// watch registers a channel for calendarID and stores it.
+func (s *watchService) watch(ctx context.Context, service *calendar.Service, userEmail, calendarID string) (*domain.WatchChannel, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	request := &calendar.Channel{
+		Id:         uuid.New().String(),
+		Type:       "web_hook",
+		Address:    s.webhookURL,
+		Token:      token,
+		Expiration: time.Now().Add(channelTTL).UnixMilli(),
+	}
+	response, err := service.Events.Watch(calendarID, request).Context(ctx).Do()
+	if err != nil {
+		return nil, err
+	}
+
+	channel := &domain.WatchChannel{
+		ChannelID:  request.Id,
+		ResourceID: response.ResourceId,
+		UserEmail:  userEmail,
+		CalendarID: calendarID,
+		Token:      token,
+		Expiration: time.UnixMilli(response.Expiration),
+	}
+	if err := s.channelRepo.CreateChannel(ctx, channel); err != nil {
+		// Without the row notifications would be rejected, so stop it again.
+		service.Channels.Stop(&calendar.Channel{Id: request.Id, ResourceId: response.ResourceId}).Context(ctx).Do()
+		return nil, fmt.Errorf("failed to store channel: %w", err)
+	}
+	log.Printf("🔔 Watching calendar %s of %s until %s", calendarID, userEmail, channel.Expiration.Format(time.RFC3339))
+	return channel, nil
+}
+
+// randomToken returns a hex encoded 32 byte secret.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,87 @@
+// internal/service/watch_test.go
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+)
+
+// blockingSync is a SyncService whose syncs wait for release.
+type blockingSync struct {
+	started chan string
+	release chan struct{}
+	running atomic.Int32
+	most    atomic.Int32 // Most syncs seen running at once
+}
+
+func (s *blockingSync) SyncCalendar(ctx context.Context, userEmail, calendarID string) (*SyncResult, error) {
+	n := s.running.Add(1)
+	defer s.running.Add(-1)
+	for most := s.most.Load(); n > most && !s.most.CompareAndSwap(most, n); most = s.most.Load() {
+	}
+	s.started <- calendarID
+	<-s.release
+	return &SyncResult{}, nil
+}
+
+// TestNotificationsDuringSync sends notifications while the sync of a
+// calendar runs: they must not start a second sync next to it, but one more
+// after it, since the running sync may have missed their change.
+func TestNotificationsDuringSync(t *testing.T) {
+	channels := repository.NewWatchChannelRepository(newTestDB(t))
+	ctx := context.Background()
+	channel := &domain.WatchChannel{ChannelID: "channel-1", ResourceID: "resource-1", UserEmail: "alice@example.com", CalendarID: "primary", Token: "secret"}
+	if err := channels.CreateChannel(ctx, channel); err != nil {
+		t.Fatal(err)
+	}
+	syncs := &blockingSync{started: make(chan string, 10), release: make(chan struct{})}
+	watch := NewWatchService(&config.Config{}, channels, nil, nil, syncs)
+	notify := func() {
+		t.Helper()
+		err := watch.HandleNotification(ctx, Notification{ChannelID: "channel-1", Token: "secret", ResourceID: "resource-1", ResourceState: "exists"})
+		if err != nil {
+			t.Fatalf("HandleNotification: %v", err)
+		}
+	}
+	waitStarted := func(what string) {
+		t.Helper()
+		select {
+		case <-syncs.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no sync started %s", what)
+		}
+	}
+	expectIdle := func(what string) {
+		t.Helper()
+		select {
+		case <-syncs.started:
+			t.Fatalf("a sync started %s", what)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	notify()
+	waitStarted("for the first notification")
+	notify()
+	notify()
+	expectIdle("while one was running")
+
+	syncs.release <- struct{}{}
+	waitStarted("for the notifications that came in during the first sync")
+	syncs.release <- struct{}{}
+	expectIdle("after the follow-up sync")
+
+	// Once the calendar is idle, a notification starts a sync again.
+	notify()
+	waitStarted("after the calendar went idle")
+	syncs.release <- struct{}{}
+	if most := syncs.most.Load(); most != 1 {
+		t.Errorf("%d syncs ran at once; want 1", most)
+	}
+}
@@ -0,0 +1,423 @@
+// internal/service/webhook.go
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/domain"
+	"google-calendar-api/internal/repository"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhookMaxAttempts  = 8                // 30s, 1m, 2m, ... between attempts; gives up after about an hour
+	webhookBaseBackoff  = 30 * time.Second // Wait after the first failed attempt, doubled after each one
+	webhookBatchSize    = 50               // Deliveries sent per DeliverPending run
+	webhookTimeout      = 10 * time.Second
+	webhookDeliveryList = 100         // Deliveries returned by ListDeliveries
+	webhookLease        = time.Minute // How long a claimed delivery is left alone by other workers
+)
+
+var webhookEventTypes = []string{EventMeetingCreated, EventMeetingUpdated, EventMeetingCancelled, EventMeetingRSVPChanged}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	client      *http.Client
+	allowHTTP   bool
+	allowLocal  bool
+}
+
+// NewWebhookService creates a new WebhookService instance. Users pick the
+// webhook URLs, so unless the config says otherwise they have to be HTTPS
+// and may not lead into the server's own network.
+func NewWebhookService(cfg *config.Config, webhookRepo repository.WebhookRepository) *webhookService {
+	dialer := &net.Dialer{Timeout: webhookTimeout}
+	if !cfg.WebhookAllowLocal {
+		dialer.Control = dialPublicOnly("webhook")
+	}
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		client: &http.Client{
+			Timeout:   webhookTimeout,
+			Transport: &http.Transport{DialContext: dialer.DialContext}, // No proxy, which would do the dialing instead
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse // A redirect counts as a failed delivery
+			},
+		},
+		allowHTTP:  cfg.WebhookAllowHTTP,
+		allowLocal: cfg.WebhookAllowLocal,
+	}
+}
+
+// webhookPayload is the JSON body posted to subscribers.
+type webhookPayload struct {
+	ID         string          `json:"id"` // Same as the X-Webhook-ID header
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Meeting    *domain.Meeting `json:"meeting"`
+}
+
+func (s *webhookService) CreateSubscription(ctx context.Context, input CreateSubscriptionInput) (*domain.WebhookSubscription, error) {
+	target, err := url.Parse(input.URL)
+	if err != nil || target.Host == "" || target.User != nil {
+		return nil, fmt.Errorf("%w: url must be an absolute https URL", ErrInvalidWebhook)
+	}
+	if target.Scheme != "https" && !(target.Scheme == "http" && s.allowHTTP) {
+		return nil, fmt.Errorf("%w: url must be an absolute https URL", ErrInvalidWebhook)
+	}
+	if !s.allowLocal {
+		// Catches the obvious cases early; names are checked when dialing.
+		if localHost(target.Hostname()) {
+			return nil, fmt.Errorf("%w: url must point to a public host", ErrInvalidWebhook)
+		}
+	}
+	for _, eventType := range input.EventTypes {
+		if !slices.Contains(webhookEventTypes, eventType) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, eventType)
+		}
+	}
+
+	secret := input.Secret
+	if secret == "" {
+		if secret, err = randomToken(); err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &domain.WebhookSubscription{
+		UserEmail:  input.UserEmail,
+		URL:        target.String(),
+		Secret:     secret,
+		EventTypes: input.EventTypes,
+	}
+	if err := s.webhookRepo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, fmt.Errorf("failed to store webhook subscription: %w", err)
+	}
+	return subscription, nil
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context, userEmail string) ([]domain.WebhookSubscription, error) {
+	subscriptions, err := s.webhookRepo.ListSubscriptionsByUser(ctx, userEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+func (s *webhookService) DeleteSubscription(ctx context.Context, id uint, userEmail string) error {
+	subscription, err := s.ownedSubscription(ctx, id, userEmail)
+	if err != nil {
+		return err
+	}
+	if err := s.webhookRepo.DeleteSubscription(ctx, subscription); err != nil {
+		return fmt.Errorf("failed to delete webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, subscriptionID uint, userEmail string) ([]domain.WebhookDelivery, error) {
+	if _, err := s.ownedSubscription(ctx, subscriptionID, userEmail); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.webhookRepo.ListDeliveries(ctx, subscriptionID, webhookDeliveryList)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (s *webhookService) ReplayDelivery(ctx context.Context, deliveryID uint, userEmail string) (*domain.WebhookDelivery, error) {
+	original, err := s.webhookRepo.GetDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook delivery: %w", err)
+	}
+	if original == nil {
+		return nil, ErrWebhookNotFound
+	}
+	if _, err := s.ownedSubscription(ctx, original.SubscriptionID, userEmail); err != nil {
+		return nil, err
+	}
+
+	// The original stays in the log as it was; the replay gets its own row.
+	replay := &domain.WebhookDelivery{
+		SubscriptionID: original.SubscriptionID,
+		MessageID:      original.MessageID,
+		EventType:      original.EventType,
+		Payload:        original.Payload,
+		Status:         "pending",
+		NextAttemptAt:  time.Now(),
+		ReplayOf:       &original.ID,
+	}
+	if err := s.webhookRepo.CreateDelivery(ctx, replay); err != nil {
+		return nil, fmt.Errorf("failed to queue webhook delivery: %w", err)
+	}
+	return replay, nil
+}
+
+func (s *webhookService) Publish(ctx context.Context, eventType string, meeting *domain.Meeting) error {
+	subscriptions, err := s.webhookRepo.ListSubscriptionsByUser(ctx, meeting.CreatedBy)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook subscriptions: %w", err)
+	}
+
+	payload := webhookPayload{
+		ID:         uuid.New().String(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Meeting:    meeting,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	for _, subscription := range subscriptions {
+		if len(subscription.EventTypes) > 0 && !slices.Contains(subscription.EventTypes, eventType) {
+			continue
+		}
+		delivery := &domain.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			MessageID:      payload.ID,
+			EventType:      eventType,
+			Payload:        string(body),
+			Status:         "pending",
+			NextAttemptAt:  payload.OccurredAt,
+		}
+		if err := s.webhookRepo.CreateDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *webhookService) DeliverPending(ctx context.Context) error {
+	now := time.Now()
+	deliveries, err := s.webhookRepo.ListDueDeliveries(ctx, now, webhookBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load due webhook deliveries: %w", err)
+	}
+
+	subscriptions := make(map[uint]*domain.WebhookSubscription)
+	for i := range deliveries {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		delivery := &deliveries[i]
+		claimed, err := s.webhookRepo.ClaimDelivery(ctx, delivery, now, time.Now().Add(webhookLease))
+		if err != nil {
+			return fmt.Errorf("failed to claim webhook delivery: %w", err)
+		}
+		if !claimed {
+			continue // Another worker has it
+		}
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok {
+			if subscription, err = s.webhookRepo.GetSubscription(ctx, delivery.SubscriptionID); err != nil {
+				return fmt.Errorf("failed to load webhook subscription: %w", err)
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		if subscription == nil {
+			delivery.Status = "failed"
+			delivery.LastError = "subscription was deleted"
+		} else {
+			s.attempt(ctx, subscription, delivery)
+		}
+		if err := s.webhookRepo.UpdateDelivery(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to update webhook delivery: %w", err)
+		}
+	}
+	return nil
+}
+
+// attempt posts delivery once and records the outcome on it: delivered,
+// pending with the next attempt pushed back, or failed for good.
+func (s *webhookService) attempt(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery) {
+	delivery.Attempts++
+	status, err := s.post(ctx, subscription, delivery)
+	delivery.ResponseStatus = status
+	if err == nil {
+		now := time.Now()
+		delivery.Status = "delivered"
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		return
+	}
+
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = "failed"
+		log.Printf("❌ Giving up on webhook delivery %d to %s: %v", delivery.ID, subscription.URL, err)
+		return
+	}
+	delivery.NextAttemptAt = time.Now().Add(webhookBaseBackoff << (delivery.Attempts - 1))
+	log.Printf("⚠️ Webhook delivery %d to %s failed (attempt %d): %v", delivery.ID, subscription.URL, delivery.Attempts, err)
+}
+
+// post sends the payload signed with the subscription's secret. Any 2xx
+// answer counts as delivered.
+func (s *webhookService) post(ctx context.Context, subscription *domain.WebhookSubscription, delivery *domain.WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", delivery.MessageID)
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+signPayload(subscription.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return resp.StatusCode, fmt.Errorf("receiver answered %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// sharedAddrSpace is the carrier-grade NAT range, private in all but name.
+var sharedAddrSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddr reports whether ip is a unicast address on the internet rather
+// than loopback, private, link-local (which has cloud metadata services) or
+// unspecified.
+func publicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddrSpace.Contains(ip)
+}
+
+// dialPublicOnly returns a net.Dialer Control hook that refuses addresses
+// publicAddr turns down. It checks the address actually dialed, so a name
+// that resolves to a public address when it is checked and to a private one
+// later, or a redirect, gets nowhere. what names the connection in errors.
+func dialPublicOnly(what string) func(network, address string, _ syscall.RawConn) error {
+	return func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		if ip, err := netip.ParseAddr(host); err != nil || !publicAddr(ip) {
+			return fmt.Errorf("%s address %s is not public", what, host)
+		}
+		return nil
+	}
+}
+
+// localHost reports whether host is obviously not public: a non-public IP
+// address or localhost. Other names are only known once dialed.
+func localHost(host string) bool {
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return !publicAddr(ip)
+	}
+	return strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost")
+}
+
+// signPayload returns the hex HMAC-SHA256 of "timestamp.payload". Signing the
+// timestamp too lets receivers reject old messages that are sent again.
+func signPayload(secret, timestamp, payload string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ownedSubscription loads a subscription of userEmail. Subscriptions of other
+// users are reported as not found.
+func (s *webhookService) ownedSubscription(ctx context.Context, id uint, userEmail string) (*domain.WebhookSubscription, error) {
+	subscription, err := s.webhookRepo.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load webhook subscription: %w", err)
+	}
+	if subscription == nil || subscription.UserEmail != userEmail {
+		return nil, ErrWebhookNotFound
+	}
+	return subscription, nil
+}
+
+// publishChanges announces the difference between two states of a meeting.
+// before is nil for a meeting that was just stored for the first time.
+// Failures are only logged; the change itself already happened.
+func publishChanges(ctx context.Context, webhooks WebhookService, before, after *domain.Meeting) {
+	for _, eventType := range meetingChanges(before, after) {
+		if err := webhooks.Publish(ctx, eventType, after); err != nil {
+			log.Printf("⚠️ Failed to publish %s for event %s: %v", eventType, after.EventID, err)
+		}
+	}
+}
+
+// meetingChanges lists the lifecycle events between two states of a meeting.
+func meetingChanges(before, after *domain.Meeting) []string {
+	cancelled := after.Status == "cancelled"
+	if before == nil {
+		if cancelled {
+			return []string{EventMeetingCancelled} // A cancelled occurrence seen for the first time
+		}
+		return []string{EventMeetingCreated}
+	}
+	if cancelled {
+		if before.Status == "cancelled" {
+			return nil
+		}
+		return []string{EventMeetingCancelled}
+	}
+
+	var changes []string
+	if before.Title != after.Title ||
+		before.Description != after.Description ||
+		!before.StartTime.Equal(after.StartTime) ||
+		!before.EndTime.Equal(after.EndTime) ||
+		before.AllDay != after.AllDay ||
+		!slices.Equal(attendeeEmails(before), attendeeEmails(after)) ||
+		!slices.Equal(before.Recurrence, after.Recurrence) ||
+		before.ConferenceURL != after.ConferenceURL ||
+		before.Status != after.Status {
+		changes = append(changes, EventMeetingUpdated)
+	}
+	if !slices.Equal(attendeeResponses(before), attendeeResponses(after)) {
+		changes = append(changes, EventMeetingRSVPChanged)
+	}
+	return changes
+}
+
+func attendeeEmails(meeting *domain.Meeting) []string {
+	emails := make([]string, 0, len(meeting.Attendees))
+	for _, a := range meeting.Attendees {
+		emails = append(emails, strings.ToLower(a.Email))
+	}
+	slices.Sort(emails)
+	return emails
+}
+
+// attendeeResponses lists email=responseStatus of the attendees in a fixed
+// order, so a changed answer shows up as a changed list.
+func attendeeResponses(meeting *domain.Meeting) []string {
+	pairs := make([]string, 0, len(meeting.Attendees))
+	for _, a := range meeting.Attendees {
+		pairs = append(pairs, strings.ToLower(a.Email)+"="+a.ResponseStatus)
+	}
+	slices.Sort(pairs)
+	return pairs
+}
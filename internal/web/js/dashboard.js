@@ -18,9 +18,10 @@ document.addEventListener('DOMContentLoaded', () => {
             // Add new list items for each event
             data.events.forEach(event => { // Access events array
                 const listItem = document.createElement('li');
-                const startTime = new Date(event.StartTime).toLocaleString();
-                const endTime = new Date(event.EndTime).toLocaleString();
-                listItem.textContent = `${event.Title} - ${startTime} to ${endTime} - Attendees: ${event.Attendees.join(', ')}`;
+                const startTime = new Date(event.start_time).toLocaleString();
+                const endTime = new Date(event.end_time).toLocaleString();
+                const attendees = event.attendees || [];
+                listItem.textContent = `${event.title} - ${startTime} to ${endTime} - Attendees: ${attendees.map(a => a.email).join(', ')}`;
                 eventList.appendChild(listItem);
             });
 
@@ -2,29 +2,77 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	// "github.com/gorilla/mux"
 	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/service"
 )
 
+// commands maps each subcommand to its entry point, which returns the exit code.
+var commands = map[string]func(args []string) int{
+	"serve":     runServe,
+	"migrate":   runMigrate,
+	"sync":      runSync,
+	"reconcile": runReconcile,
+	"export":    runExport,
+}
+
 func main() {
+	// Without a subcommand the server starts, as it always has.
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	run, ok := commands[name]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	os.Exit(run(args))
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: %s <command> [flags]
+
+commands:
+  serve                          run the HTTP server (default)
+  migrate up|down|status         apply, revert or list schema migrations
+  sync -user EMAIL               sync a user's calendar from Google
+  reconcile [-user EMAIL]        compare stored meetings with Google
+  export -user EMAIL -format ics write a user's meetings to a file
+
+Run "%s <command> -h" for the flags of a command.
+`, os.Args[0], os.Args[0])
+}
+
+// runServe is the serve command: it runs the HTTP server and the background
+// jobs until SIGINT or SIGTERM.
+func runServe(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Parse(args)
+
 	// Load configuration.  This is done *before* calling InitializeApp.
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("❌ Configuration Error: %v", err)
+		log.Printf("❌ Configuration Error: %v", err)
+		return 1
 	}
 
 	// Initialize the application using Wire.  This is the *only* place
 	// where we manually create anything. The rest is handled by Wire.
 	app, err := InitializeApp(context.Background(), &cfg)
 	if err != nil {
-		log.Fatalf("❌ Application Initialization Error: %v", err)
+		log.Printf("❌ Application Initialization Error: %v", err)
+		return 1
 	}
 
 	// Create HTTP server.
@@ -36,6 +84,30 @@ func main() {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	// Background jobs stop when the server shuts down.
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go func() {
+		// Logins fail with 503 and /readyz reports it until this succeeds.
+		if err := app.Auth.DiscoverIssuer(jobsCtx); err == nil {
+			log.Println("✅ OIDC issuer discovered")
+		}
+	}()
+	go runEvery(jobsCtx, time.Hour, "channel renewal", app.Watcher.RenewChannels)
+	go runEvery(jobsCtx, 10*time.Second, "outbox", app.Events.ProcessOutbox)
+	go runEvery(jobsCtx, 15*time.Second, "webhook delivery", app.Webhooks.DeliverPending)
+	if cfg.ReconcileInterval > 0 {
+		go runEvery(jobsCtx, cfg.ReconcileInterval, "reconcile", func(ctx context.Context) error {
+			report, err := app.Reconciler.Reconcile(ctx, service.ReconcileInput{DryRun: cfg.ReconcileDryRun})
+			if err != nil {
+				return err
+			}
+			log.Printf("🔍 Reconciled %d meetings (dry run: %t): %d deleted, %d modified, %d orphaned, %d errors",
+				report.Checked, report.DryRun, report.Deleted, report.Modified, report.Orphaned, report.Errors)
+			return nil
+		})
+	}
+
 	// Start server in a goroutine.
 	serverErrors := make(chan error, 1)
 	go func() {
@@ -49,16 +121,20 @@ func main() {
 
 	select {
 	case err := <-serverErrors:
-		log.Fatalf("❌ Server error: %v", err)
+		log.Printf("❌ Server error: %v", err)
+		return 1
 	case sig := <-stop:
 		log.Printf("🛑 Received signal %v. Initiating shutdown...", sig)
 	}
 
+	stopJobs()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatalf("❌ Error during server shutdown: %v", err)
+		log.Printf("❌ Error during server shutdown: %v", err)
+		return 1
 	}
 
 	// Close the database connection (obtained from the initialized app).
@@ -67,4 +143,22 @@ func main() {
 	}
 
 	log.Println("✅ Server shutdown completed")
+	return 0
+}
+
+// runEvery runs job right away and then every interval until ctx is done.
+// Errors are logged; the next run tries again.
+func runEvery(ctx context.Context, interval time.Duration, name string, job func(context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := job(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️ Background job %s failed: %v", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
 }
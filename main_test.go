@@ -0,0 +1,316 @@
+// main_test.go
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google-calendar-api/internal/config"
+	"google-calendar-api/internal/fakegoogle"
+	"google-calendar-api/internal/repository"
+)
+
+// testApp is the whole application, wired as in production, in front of a
+// fake Google.
+type testApp struct {
+	*App
+	URL    string
+	fake   *fakegoogle.Server
+	client *http.Client // Keeps the cookies of one browser
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	fake := fakegoogle.NewServer()
+	t.Cleanup(fake.Close)
+
+	// The redirect URL has to be known before the router exists.
+	var router http.Handler
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		DatabaseDriver:    repository.DriverSQLite,
+		DatabaseURL:       filepath.Join(t.TempDir(), "app.db"),
+		JWTSecret:         []byte("test-secret"),
+		GoogleRedirectURL: server.URL + "/auth/google/callback",
+		Env:               "test",
+	}
+	fake.Configure(cfg)
+	app, err := InitializeApp(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("InitializeApp: %v", err)
+	}
+	t.Cleanup(func() { app.CloseDB() })
+	router = app.Router
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testApp{App: app, URL: server.URL, fake: fake, client: &http.Client{Jar: jar}}
+}
+
+// login goes through the Google login as a browser would, which leaves the
+// session cookie in the client.
+func (a *testApp) login(t *testing.T, email string) {
+	t.Helper()
+	a.fake.AddUser(fakegoogle.User{Email: email})
+	resp, err := a.client.Get(a.URL + "/auth/google/login")
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/api/dashboard" {
+		t.Fatalf("login ended at %s with %d; want the dashboard", resp.Request.URL, resp.StatusCode)
+	}
+	base, _ := url.Parse(a.URL)
+	for _, cookie := range a.client.Jar.Cookies(base) {
+		if cookie.Name == "token" {
+			return
+		}
+	}
+	t.Fatal("login set no token cookie")
+}
+
+// do sends a JSON request with the session and decodes the response into out,
+// error responses too when they are JSON.
+func (a *testApp) do(t *testing.T, method, path string, body, out any) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, a.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	if out != nil && (resp.StatusCode < 300 || resp.Header.Get("Content-Type") == "application/json") {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decoding response: %v", method, path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestGoogleLogin(t *testing.T) {
+	app := newTestApp(t)
+
+	if status := app.do(t, http.MethodGet, "/api/events", nil, nil); status != http.StatusUnauthorized {
+		t.Errorf("GET /api/events before login = %d; want 401", status)
+	}
+	app.login(t, "alice@example.com")
+
+	user, err := repository.NewUserRepository(app.DB).GetUserByEmail(context.Background(), "alice@example.com")
+	if err != nil || user == nil {
+		t.Fatalf("GetUserByEmail = %v, %v", user, err)
+	}
+	if user.GoogleID == "" || user.AccessToken == "" || user.RefreshToken == "" {
+		t.Errorf("stored user = %+v; want the Google subject and tokens", user)
+	}
+	if status := app.do(t, http.MethodGet, "/api/events", nil, nil); status != http.StatusOK {
+		t.Errorf("GET /api/events after login = %d; want 200", status)
+	}
+
+	// A callback that doesn't come back with the state of the login is a
+	// forgery.
+	resp, err := app.client.Get(app.URL + "/auth/google/callback?code=anything&state=forged")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("callback with a forged state = %d; want 400", resp.StatusCode)
+	}
+}
+
+func TestEventLifecycle(t *testing.T) {
+	app := newTestApp(t)
+	const email = "alice@example.com"
+	app.login(t, email)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour).UTC()
+
+	var created struct {
+		EventID string `json:"event_id"`
+	}
+	status := app.do(t, http.MethodPost, "/api/events", map[string]any{
+		"title":      "Planning",
+		"start_time": start.Format(time.RFC3339),
+		"end_time":   start.Add(time.Hour).Format(time.RFC3339),
+		"attendees":  []string{"bob@example.com"},
+	}, &created)
+	if status != http.StatusCreated || created.EventID == "" {
+		t.Fatalf("POST /api/events = %d, %+v; want 201 with an event ID", status, created)
+	}
+	events := app.fake.Events(email)
+	if len(events) != 1 || events[0].Id != created.EventID || events[0].Summary != "Planning" {
+		t.Fatalf("Google has %+v; want the new event", events)
+	}
+
+	var listed struct {
+		Events []struct {
+			EventID string `json:"event_id"`
+			Title   string `json:"title"`
+		} `json:"events"`
+	}
+	query := url.Values{
+		"time_min": {start.Add(-time.Hour).Format(time.RFC3339)},
+		"time_max": {start.Add(2 * time.Hour).Format(time.RFC3339)},
+	}
+	if status := app.do(t, http.MethodGet, "/api/events?"+query.Encode(), nil, &listed); status != http.StatusOK {
+		t.Fatalf("GET /api/events = %d", status)
+	}
+	if len(listed.Events) != 1 || listed.Events[0].EventID != created.EventID {
+		t.Errorf("GET /api/events = %+v; want the new event", listed.Events)
+	}
+	if status := app.do(t, http.MethodGet, "/api/events?cursor=garbage", nil, nil); status != http.StatusBadRequest {
+		t.Errorf("GET /api/events with a garbage cursor = %d; want 400", status)
+	}
+
+	if status := app.do(t, http.MethodPatch, "/api/events/"+created.EventID, map[string]any{"title": "Planning Q3"}, nil); status != http.StatusOK {
+		t.Fatalf("PATCH /api/events/%s = %d", created.EventID, status)
+	}
+	if events := app.fake.Events(email); len(events) != 1 || events[0].Summary != "Planning Q3" {
+		t.Errorf("Google has %+v after the patch; want the new title", events)
+	}
+
+	if status := app.do(t, http.MethodDelete, "/api/events/"+created.EventID, nil, nil); status != http.StatusOK {
+		t.Fatalf("DELETE /api/events/%s = %d", created.EventID, status)
+	}
+	for _, event := range app.fake.Events(email) {
+		if event.Id == created.EventID && event.Status != "cancelled" {
+			t.Errorf("Google still has the deleted event: %+v", event)
+		}
+	}
+}
+
+// TestCreateEventFailure creates an event in a calendar Google doesn't know,
+// which fails the operation; the answer has to name it and say why.
+func TestCreateEventFailure(t *testing.T) {
+	app := newTestApp(t)
+	const email = "alice@example.com"
+	app.login(t, email)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour).UTC()
+
+	var failed struct {
+		Status      string `json:"status"`
+		OperationID uint   `json:"operation_id"`
+		LastError   string `json:"last_error"`
+	}
+	status := app.do(t, http.MethodPost, "/api/events", map[string]any{
+		"title":       "Nowhere",
+		"start_time":  start.Format(time.RFC3339),
+		"end_time":    start.Add(time.Hour).Format(time.RFC3339),
+		"calendar_id": "missing@group.calendar.google.com",
+	}, &failed)
+	if status != http.StatusNotFound || failed.Status != "failed" || failed.OperationID == 0 || failed.LastError == "" {
+		t.Fatalf("POST /api/events into a missing calendar = %d, %+v; want 404 naming the failed operation", status, failed)
+	}
+
+	var op struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+	}
+	path := fmt.Sprintf("/api/operations/%d", failed.OperationID)
+	if status := app.do(t, http.MethodGet, path, nil, &op); status != http.StatusOK || op.Status != "failed" || op.Error != failed.LastError {
+		t.Errorf("GET %s = %d, %+v; want the failed operation", path, status, op)
+	}
+	if events := app.fake.Events(email); len(events) != 0 {
+		t.Errorf("Google has %d events; want none", len(events))
+	}
+}
+
+// TestAccessTokenRefresh checks that requests go on after Google stops
+// taking the access token, and that the refreshed token is saved.
+func TestAccessTokenRefresh(t *testing.T) {
+	app := newTestApp(t)
+	const email = "alice@example.com"
+	app.login(t, email)
+	users := repository.NewUserRepository(app.DB)
+	ctx := context.Background()
+	before, err := users.GetUserByEmail(ctx, email)
+	if err != nil || before == nil {
+		t.Fatalf("GetUserByEmail = %v, %v", before, err)
+	}
+
+	app.fake.ExpireAccessTokens(email)
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Hour).UTC()
+	status := app.do(t, http.MethodPost, "/api/events", map[string]any{
+		"title":      "After expiry",
+		"start_time": start.Format(time.RFC3339),
+		"end_time":   start.Add(time.Hour).Format(time.RFC3339),
+	}, nil)
+	if status != http.StatusCreated {
+		t.Fatalf("POST /api/events with an expired access token = %d; want 201", status)
+	}
+	if events := app.fake.Events(email); len(events) != 1 {
+		t.Errorf("Google has %d events; want 1", len(events))
+	}
+
+	after, err := users.GetUserByEmail(ctx, email)
+	if err != nil || after == nil {
+		t.Fatalf("GetUserByEmail = %v, %v", after, err)
+	}
+	if after.AccessToken == before.AccessToken {
+		t.Errorf("access token was not replaced after the refresh")
+	}
+}
+
+func TestFreeBusy(t *testing.T) {
+	app := newTestApp(t)
+	const email = "alice@example.com"
+	app.login(t, email)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Hour).UTC()
+
+	status := app.do(t, http.MethodPost, "/api/events", map[string]any{
+		"title":      "Busy",
+		"start_time": start.Format(time.RFC3339),
+		"end_time":   start.Add(time.Hour).Format(time.RFC3339),
+	}, nil)
+	if status != http.StatusCreated {
+		t.Fatalf("POST /api/events = %d", status)
+	}
+
+	var output struct {
+		Calendars map[string]struct {
+			Busy []struct {
+				Start time.Time `json:"start"`
+				End   time.Time `json:"end"`
+			} `json:"busy"`
+		} `json:"calendars"`
+	}
+	status = app.do(t, http.MethodPost, "/api/freebusy", map[string]any{
+		"attendees": []string{email},
+		"time_min":  start.Add(-2 * time.Hour).Format(time.RFC3339),
+		"time_max":  start.Add(2 * time.Hour).Format(time.RFC3339),
+	}, &output)
+	if status != http.StatusOK {
+		t.Fatalf("POST /api/freebusy = %d", status)
+	}
+	busy := output.Calendars[email].Busy
+	if len(busy) != 1 || !busy[0].Start.Equal(start) || !busy[0].End.Equal(start.Add(time.Hour)) {
+		t.Errorf("busy = %+v; want %s for an hour", busy, start)
+	}
+}
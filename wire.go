@@ -15,15 +15,19 @@ import (
 
 	"github.com/google/wire"
 	"github.com/gorilla/mux"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 // App struct to hold the top-level application components.
 type App struct {
-	Router *mux.Router
-	DB     *gorm.DB
-	SqlDB  *sql.DB // Add this to close the raw *sql.DB
+	Router     *mux.Router
+	DB         *gorm.DB
+	SqlDB      *sql.DB                  // Add this to close the raw *sql.DB
+	Events     service.EventService     // Retries pending outbox operations in the background
+	Watcher    service.WatchService     // Renews push notification channels in the background
+	Webhooks   service.WebhookService   // Sends queued webhook deliveries in the background
+	Reconciler service.ReconcileService // Compares stored meetings with Google on a schedule
+	Auth       service.AuthService      // Discovers Google's OIDC issuer in the background
 }
 
 // CloseDB closes the database connection.
@@ -40,8 +44,25 @@ func InitializeApp(ctx context.Context, cfg *config.Config) (*App, error) {
 		NewDB,
 		repository.NewUserRepository,
 		repository.NewMeetingRepository,
+		repository.NewSyncStateRepository,
+		repository.NewWatchChannelRepository,
+		repository.NewWebhookRepository,
+		repository.NewOutboxRepository,
+		repository.NewCalendarAccountRepository,
 		service.NewAuthService,
+		service.NewGoogleClientProvider,
+		service.NewGoogleCalendarProvider,
+		service.NewMicrosoftProvider,
+		service.NewCalDAVProvider,
+		service.NewCalendarProviders,
+		service.NewWebhookService,
 		service.NewEventService,
+		service.NewSyncService,
+		service.NewWatchService,
+		service.NewReconcileService,
+		service.NewExportService,
+		service.NewImportService,
+		service.NewAccountService,
 		handler.NewHandler,
 		NewRouter,
 		NewApp,
@@ -49,9 +70,63 @@ func InitializeApp(ctx context.Context, cfg *config.Config) (*App, error) {
 	return &App{}, nil // This return is replaced by Wire.
 }
 
+// InitializeMigrateCommand is the Wire injector for the migrate command. The
+// schema is not checked, since fixing it is the point.
+func InitializeMigrateCommand(cfg *config.Config) (*MigrateCommand, error) {
+	wire.Build(
+		openDB,
+		NewMigrateCommand,
+	)
+	return &MigrateCommand{}, nil
+}
+
+// InitializeSyncCommand is the Wire injector for the sync command.
+func InitializeSyncCommand(ctx context.Context, cfg *config.Config) (*SyncCommand, error) {
+	wire.Build(
+		NewDB,
+		repository.NewUserRepository,
+		repository.NewMeetingRepository,
+		repository.NewSyncStateRepository,
+		repository.NewWebhookRepository,
+		service.NewGoogleClientProvider,
+		service.NewWebhookService,
+		service.NewSyncService,
+		NewSyncCommand,
+	)
+	return &SyncCommand{}, nil
+}
+
+// InitializeReconcileCommand is the Wire injector for the reconcile command.
+func InitializeReconcileCommand(ctx context.Context, cfg *config.Config) (*ReconcileCommand, error) {
+	wire.Build(
+		NewDB,
+		repository.NewUserRepository,
+		repository.NewMeetingRepository,
+		repository.NewWebhookRepository,
+		service.NewGoogleClientProvider,
+		service.NewWebhookService,
+		service.NewReconcileService,
+		NewReconcileCommand,
+	)
+	return &ReconcileCommand{}, nil
+}
+
+// InitializeExportCommand is the Wire injector for the export command.
+func InitializeExportCommand(ctx context.Context, cfg *config.Config) (*ExportCommand, error) {
+	wire.Build(
+		NewDB,
+		repository.NewUserRepository,
+		repository.NewMeetingRepository,
+		service.NewGoogleClientProvider,
+		service.NewExportService,
+		NewExportCommand,
+	)
+	return &ExportCommand{}, nil
+}
+
 // NewApp creates a new App instance.  This is a *provider*.
-func NewApp(router *mux.Router, db *gorm.DB, sqlDB *sql.DB) *App {
-	return &App{Router: router, DB: db, SqlDB: sqlDB}
+func NewApp(router *mux.Router, db *gorm.DB, sqlDB *sql.DB, events service.EventService, watcher service.WatchService, webhooks service.WebhookService, reconciler service.ReconcileService, auth service.AuthService) *App {
+	return &App{Router: router, DB: db, SqlDB: sqlDB, Events: events, Watcher: watcher, Webhooks: webhooks, Reconciler: reconciler, Auth: auth}
 }
 
 // NewRouter creates a new mux.Router. This is a *provider*.
@@ -61,17 +136,33 @@ func NewRouter(h *handler.Handler) *mux.Router {
 	return router
 }
 
-// NewDB creates anew gorm.DB connection. This is a *provider*.
-func NewDB(cfg *config.Config) (*gorm.DB, *sql.DB, error) {
-	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+// NewDB creates a new gorm.DB connection and refuses to start when the
+// schema is behind; run the migrate command first. A SQLite database is
+// local to this process, so it is migrated right away. This is a *provider*.
+func NewDB(ctx context.Context, cfg *config.Config) (*gorm.DB, *sql.DB, error) {
+	db, sqlDB, err := openDB(cfg)
 	if err != nil {
-		return nil, nil, err // Return nil for both if there's an error
+		return nil, nil, err
 	}
-
-	//Run Migrations
-	if err := repository.MigrateDB(db); err != nil {
+	if cfg.DatabaseDriver == repository.DriverSQLite {
+		if _, err := repository.MigrateUp(ctx, sqlDB, cfg.DatabaseDriver); err != nil {
+			sqlDB.Close()
+			return nil, nil, err
+		}
+	}
+	if err := repository.CheckSchema(ctx, sqlDB, cfg.DatabaseDriver); err != nil {
+		sqlDB.Close()
 		return nil, nil, err
 	}
+	return db, sqlDB, nil
+}
+
+// openDB connects to the database without looking at the schema.
+func openDB(cfg *config.Config) (*gorm.DB, *sql.DB, error) {
+	db, err := repository.OpenDB(cfg.DatabaseDriver, cfg.DatabaseURL)
+	if err != nil {
+		return nil, nil, err // Return nil for both if there's an error
+	}
 
 	sqlDB, err := db.DB() //get the standard sql.DB
 	if err != nil {
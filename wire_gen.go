@@ -9,13 +9,11 @@ package main
 import (
 	"context"
 	"database/sql"
-	"github.com/google/wire"
 	"github.com/gorilla/mux"
 	"google-calendar-api/internal/config"
 	"google-calendar-api/internal/handler"
 	"google-calendar-api/internal/repository"
 	"google-calendar-api/internal/service"
-	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
@@ -23,27 +21,107 @@ import (
 
 // InitializeApp is the Wire injector function.
 func InitializeApp(ctx context.Context, cfg *config.Config) (*App, error) {
-	db, sqlDB, err := NewDB(cfg)
+	db, sqlDB, err := NewDB(ctx, cfg)
 	if err != nil {
 		return nil, err
 	}
 	userRepository := repository.NewUserRepository(db)
-	authService := service.NewAuthService(cfg, userRepository)
+	calendarAccountRepository := repository.NewCalendarAccountRepository(db)
+	authService := service.NewAuthService(cfg, userRepository, calendarAccountRepository)
 	meetingRepository := repository.NewMeetingRepository(db)
-	eventService := service.NewEventService(meetingRepository, userRepository, cfg)
-	handlerHandler := handler.NewHandler(authService, eventService, cfg)
+	googleClientProvider := service.NewGoogleClientProvider(cfg, userRepository)
+	googleCalendarProvider := service.NewGoogleCalendarProvider(googleClientProvider)
+	microsoftProvider := service.NewMicrosoftProvider(cfg, calendarAccountRepository)
+	caldavProvider := service.NewCalDAVProvider(cfg, calendarAccountRepository)
+	calendarProviders := service.NewCalendarProviders(googleCalendarProvider, microsoftProvider, caldavProvider)
+	webhookRepository := repository.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(cfg, webhookRepository)
+	outboxRepository := repository.NewOutboxRepository(db)
+	eventService := service.NewEventService(meetingRepository, userRepository, googleClientProvider, calendarProviders, webhookService, outboxRepository)
+	syncStateRepository := repository.NewSyncStateRepository(db)
+	syncService := service.NewSyncService(meetingRepository, userRepository, syncStateRepository, googleClientProvider, webhookService)
+	watchChannelRepository := repository.NewWatchChannelRepository(db)
+	watchService := service.NewWatchService(cfg, watchChannelRepository, userRepository, googleClientProvider, syncService)
+	reconcileService := service.NewReconcileService(meetingRepository, userRepository, googleClientProvider, webhookService)
+	exportService := service.NewExportService(meetingRepository, userRepository, googleClientProvider)
+	importService := service.NewImportService(eventService, meetingRepository, userRepository, googleClientProvider)
+	accountService := service.NewAccountService(userRepository, calendarAccountRepository, calendarProviders, caldavProvider)
+	handlerHandler := handler.NewHandler(authService, eventService, syncService, watchService, webhookService, exportService, importService, accountService, cfg)
 	router := NewRouter(handlerHandler)
-	app := NewApp(router, db, sqlDB)
+	app := NewApp(router, db, sqlDB, eventService, watchService, webhookService, reconcileService, authService)
 	return app, nil
 }
 
+// InitializeMigrateCommand is the Wire injector for the migrate command. The
+// schema is not checked, since fixing it is the point.
+func InitializeMigrateCommand(cfg *config.Config) (*MigrateCommand, error) {
+	_, sqlDB, err := openDB(cfg)
+	if err != nil {
+		return nil, err
+	}
+	migrateCommand := NewMigrateCommand(cfg, sqlDB)
+	return migrateCommand, nil
+}
+
+// InitializeSyncCommand is the Wire injector for the sync command.
+func InitializeSyncCommand(ctx context.Context, cfg *config.Config) (*SyncCommand, error) {
+	db, sqlDB, err := NewDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	meetingRepository := repository.NewMeetingRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	syncStateRepository := repository.NewSyncStateRepository(db)
+	googleClientProvider := service.NewGoogleClientProvider(cfg, userRepository)
+	webhookRepository := repository.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(cfg, webhookRepository)
+	syncService := service.NewSyncService(meetingRepository, userRepository, syncStateRepository, googleClientProvider, webhookService)
+	syncCommand := NewSyncCommand(sqlDB, syncService)
+	return syncCommand, nil
+}
+
+// InitializeReconcileCommand is the Wire injector for the reconcile command.
+func InitializeReconcileCommand(ctx context.Context, cfg *config.Config) (*ReconcileCommand, error) {
+	db, sqlDB, err := NewDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	meetingRepository := repository.NewMeetingRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	googleClientProvider := service.NewGoogleClientProvider(cfg, userRepository)
+	webhookRepository := repository.NewWebhookRepository(db)
+	webhookService := service.NewWebhookService(cfg, webhookRepository)
+	reconcileService := service.NewReconcileService(meetingRepository, userRepository, googleClientProvider, webhookService)
+	reconcileCommand := NewReconcileCommand(sqlDB, reconcileService)
+	return reconcileCommand, nil
+}
+
+// InitializeExportCommand is the Wire injector for the export command.
+func InitializeExportCommand(ctx context.Context, cfg *config.Config) (*ExportCommand, error) {
+	db, sqlDB, err := NewDB(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	meetingRepository := repository.NewMeetingRepository(db)
+	userRepository := repository.NewUserRepository(db)
+	googleClientProvider := service.NewGoogleClientProvider(cfg, userRepository)
+	exportService := service.NewExportService(meetingRepository, userRepository, googleClientProvider)
+	exportCommand := NewExportCommand(sqlDB, exportService)
+	return exportCommand, nil
+}
+
 // wire.go:
 
 // App struct to hold the top-level application components.
 type App struct {
-	Router  *mux.Router
-	DB      *gorm.DB
-	SqlDB   *sql.DB // Add this to close the raw *sql.DB
+	Router     *mux.Router
+	DB         *gorm.DB
+	SqlDB      *sql.DB                  // Add this to close the raw *sql.DB
+	Events     service.EventService     // Retries pending outbox operations in the background
+	Watcher    service.WatchService     // Renews push notification channels in the background
+	Webhooks   service.WebhookService   // Sends queued webhook deliveries in the background
+	Reconciler service.ReconcileService // Compares stored meetings with Google on a schedule
+	Auth       service.AuthService      // Discovers Google's OIDC issuer in the background
 }
 
 // CloseDB closes the database connection.
@@ -55,8 +133,8 @@ func (a *App) CloseDB() error {
 }
 
 // NewApp creates a new App instance.  This is a *provider*.
-func NewApp(router *mux.Router, db *gorm.DB, sqlDB *sql.DB) *App {
-	return &App{Router: router, DB: db, SqlDB: sqlDB}
+func NewApp(router *mux.Router, db *gorm.DB, sqlDB *sql.DB, events service.EventService, watcher service.WatchService, webhooks service.WebhookService, reconciler service.ReconcileService, auth service.AuthService) *App {
+	return &App{Router: router, DB: db, SqlDB: sqlDB, Events: events, Watcher: watcher, Webhooks: webhooks, Reconciler: reconciler, Auth: auth}
 }
 
 // NewRouter creates a new mux.Router. This is a *provider*.
@@ -66,17 +144,33 @@ func NewRouter(h *handler.Handler) *mux.Router {
 	return router
 }
 
-// NewDB creates a new gorm.DB connection. This is a *provider*.
-func NewDB(cfg *config.Config) (*gorm.DB, *sql.DB, error) {
-	db, err := gorm.Open(postgres.Open(cfg.DatabaseURL), &gorm.Config{})
+// NewDB creates a new gorm.DB connection and refuses to start when the
+// schema is behind; run the migrate command first. A SQLite database is
+// local to this process, so it is migrated right away. This is a *provider*.
+func NewDB(ctx context.Context, cfg *config.Config) (*gorm.DB, *sql.DB, error) {
+	db, sqlDB, err := openDB(cfg)
 	if err != nil {
-		return nil, nil, err // Return nil for both if there's an error
+		return nil, nil, err
 	}
-
-	//Run Migrations
-	if err := repository.MigrateDB(db); err != nil {
+	if cfg.DatabaseDriver == repository.DriverSQLite {
+		if _, err := repository.MigrateUp(ctx, sqlDB, cfg.DatabaseDriver); err != nil {
+			sqlDB.Close()
+			return nil, nil, err
+		}
+	}
+	if err := repository.CheckSchema(ctx, sqlDB, cfg.DatabaseDriver); err != nil {
+		sqlDB.Close()
 		return nil, nil, err
 	}
+	return db, sqlDB, nil
+}
+
+// openDB connects to the database without looking at the schema.
+func openDB(cfg *config.Config) (*gorm.DB, *sql.DB, error) {
+	db, err := repository.OpenDB(cfg.DatabaseDriver, cfg.DatabaseURL)
+	if err != nil {
+		return nil, nil, err // Return nil for both if there's an error
+	}
 
 	sqlDB, err := db.DB() //get the standard sql.DB
 	if err != nil {